@@ -3,12 +3,12 @@ package main
 import (
 	"os"
 	"os/signal"
-	"reflect"
 	"syscall"
 
 	"github.com/hiepd/galedb/pkg/entity"
 
 	"github.com/hiepd/galedb/pkg/storage"
+	"github.com/hiepd/galedb/pkg/types"
 
 	"github.com/hiepd/galedb/pkg/server"
 	"github.com/sirupsen/logrus"
@@ -28,7 +28,7 @@ func main() {
 			logrus.WithError(err).Fatal("failed to start database server")
 		}
 	}()
-	sigCh := make(chan os.Signal)
+	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
 	<-sigCh
 	logrus.Warn("Closing database")
@@ -39,10 +39,10 @@ func main() {
 
 func mockDb() *storage.Database {
 	cols := []entity.Column{
-		{Kind: reflect.Int, Name: "id"},
-		{Kind: reflect.String, Name: "user_type"},
-		{Kind: reflect.String, Name: "email"},
-		{Kind: reflect.Int, Name: "age"},
+		{Type: types.Int, Name: "id"},
+		{Type: types.Text, Name: "user_type"},
+		{Type: types.Text, Name: "email"},
+		{Type: types.Int, Name: "age"},
 	}
 	tbl := storage.NewPersisentTable(cols)
 	tbl.AddRow(entity.Row{Values: []entity.Value{1, "customer", "customer1@example.com", 24}})
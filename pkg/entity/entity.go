@@ -1,9 +1,16 @@
 package entity
 
-import "reflect"
+import (
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/types"
+)
 
+// Value is a value of a row. NULL is the nil Value, Null.
 type Value interface{}
 
+// Null is the NULL value.
+var Null Value
+
 type Key int
 
 type Row struct {
@@ -12,6 +19,16 @@ type Row struct {
 }
 
 type Column struct {
-	Kind reflect.Kind
+	Type types.T
 	Name string
+	// Default is the expression evaluated for the rows that don't set the
+	// column, or nil when they hold NULL.
+	Default parser.Expr
+	// NotNull is set on the columns that don't accept NULL.
+	NotNull bool
+	// Table is the name the column's table is referred to by in a query.
+	Table string
+	// Merged is set on the columns a USING or NATURAL join merged into a
+	// single column. They can only be referred to qualified by Table.
+	Merged bool
 }
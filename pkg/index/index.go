@@ -7,6 +7,7 @@ import (
 type Index interface {
 	Add(row entity.Row) (entity.Key, error)
 	Remove(key entity.Key) error
+	Update(key entity.Key, row entity.Row) error
 	Get(key entity.Key) (entity.Row, error)
 	Iterator() Iterator
 	Size() int
@@ -0,0 +1,147 @@
+package index
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/hiepd/galedb/pkg/entity"
+)
+
+// InvertedIndex maps the terms of the values of a column, like the keys of
+// JSON documents, to the rows holding them. Rows are added with the key the
+// table gave them, and the index keeps them so that it can return them.
+type InvertedIndex struct {
+	Name string
+	// Column is the position of the indexed column in the rows.
+	Column   int
+	terms    func(entity.Value) []string
+	rows     map[entity.Key]entity.Row
+	postings map[string]map[entity.Key]struct{}
+}
+
+// NewInvertedIndex returns an index of column, whose values are broken into
+// terms by the terms function.
+func NewInvertedIndex(name string, column int, terms func(entity.Value) []string) *InvertedIndex {
+	return &InvertedIndex{
+		Name:     name,
+		Column:   column,
+		terms:    terms,
+		rows:     make(map[entity.Key]entity.Row),
+		postings: make(map[string]map[entity.Key]struct{}),
+	}
+}
+
+func (ii *InvertedIndex) Add(row entity.Row) (entity.Key, error) {
+	if _, ok := ii.rows[row.Key]; ok {
+		return 0, errors.New("duplicate key")
+	}
+	ii.rows[row.Key] = row
+	for _, term := range ii.terms(row.Values[ii.Column]) {
+		keys, ok := ii.postings[term]
+		if !ok {
+			keys = make(map[entity.Key]struct{})
+			ii.postings[term] = keys
+		}
+		keys[row.Key] = struct{}{}
+	}
+	return row.Key, nil
+}
+
+func (ii *InvertedIndex) Remove(key entity.Key) error {
+	row, ok := ii.rows[key]
+	if !ok {
+		return errors.New("invalid key")
+	}
+	for _, term := range ii.terms(row.Values[ii.Column]) {
+		delete(ii.postings[term], key)
+		if len(ii.postings[term]) == 0 {
+			delete(ii.postings, term)
+		}
+	}
+	delete(ii.rows, key)
+	return nil
+}
+
+func (ii *InvertedIndex) Update(key entity.Key, row entity.Row) error {
+	if err := ii.Remove(key); err != nil {
+		return err
+	}
+	row.Key = key
+	_, err := ii.Add(row)
+	return err
+}
+
+func (ii *InvertedIndex) Get(key entity.Key) (entity.Row, error) {
+	row, ok := ii.rows[key]
+	if !ok {
+		return entity.Row{}, errors.New("invalid key")
+	}
+	return row, nil
+}
+
+// Iterator returns the rows of the index in the order of their keys.
+func (ii *InvertedIndex) Iterator() Iterator {
+	keys := make([]entity.Key, 0, len(ii.rows))
+	for key := range ii.rows {
+		keys = append(keys, key)
+	}
+	return ii.iterator(keys)
+}
+
+func (ii *InvertedIndex) Size() int {
+	return len(ii.rows)
+}
+
+// Lookup returns the rows holding all the terms, in the order of their keys.
+// Without terms, it returns every row.
+func (ii *InvertedIndex) Lookup(terms []string) Iterator {
+	if len(terms) == 0 {
+		return ii.Iterator()
+	}
+	// start from the rarest term to intersect the fewest keys
+	sorted := make([]map[entity.Key]struct{}, len(terms))
+	for i, term := range terms {
+		sorted[i] = ii.postings[term]
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) < len(sorted[j]) })
+	var keys []entity.Key
+	for key := range sorted[0] {
+		found := true
+		for _, other := range sorted[1:] {
+			if _, ok := other[key]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			keys = append(keys, key)
+		}
+	}
+	return ii.iterator(keys)
+}
+
+func (ii *InvertedIndex) iterator(keys []entity.Key) Iterator {
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return &InvertedIterator{
+		index: ii,
+		keys:  keys,
+	}
+}
+
+// InvertedIterator returns the rows of an inverted index by their keys.
+type InvertedIterator struct {
+	index *InvertedIndex
+	keys  []entity.Key
+}
+
+func (it *InvertedIterator) Next() (entity.Row, error) {
+	for len(it.keys) > 0 {
+		key := it.keys[0]
+		it.keys = it.keys[1:]
+		// the row may be gone since the iterator was created
+		if row, ok := it.index.rows[key]; ok {
+			return row, nil
+		}
+	}
+	return entity.Row{}, EndOfIterator
+}
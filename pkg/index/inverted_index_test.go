@@ -0,0 +1,90 @@
+package index
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func words(val entity.Value) []string {
+	s, _ := val.(string)
+	return strings.Fields(s)
+}
+
+func collectKeys(t *testing.T, iter Iterator) []entity.Key {
+	var keys []entity.Key
+	for {
+		row, err := iter.Next()
+		if err == EndOfIterator {
+			return keys
+		}
+		require.NoError(t, err)
+		keys = append(keys, row.Key)
+	}
+}
+
+func TestInvertedIndex_Lookup(t *testing.T) {
+	idx := NewInvertedIndex("idx", 1, words)
+	for i, text := range []string{"red blue", "green", "blue green red", "blue"} {
+		key, err := idx.Add(entity.Row{Key: entity.Key(i + 1), Values: []entity.Value{i, text}})
+		require.NoError(t, err)
+		assert.Equal(t, entity.Key(i+1), key)
+	}
+	_, err := idx.Add(entity.Row{Key: 1, Values: []entity.Value{0, "red"}})
+	assert.Error(t, err)
+
+	tests := []struct {
+		name  string
+		terms []string
+		want  []entity.Key
+	}{
+		{
+			name:  "single term",
+			terms: []string{"blue"},
+			want:  []entity.Key{1, 3, 4},
+		},
+		{
+			name:  "every term",
+			terms: []string{"red", "blue"},
+			want:  []entity.Key{1, 3},
+		},
+		{
+			name:  "unknown term",
+			terms: []string{"blue", "yellow"},
+		},
+		{
+			name: "no terms",
+			want: []entity.Key{1, 2, 3, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, collectKeys(t, idx.Lookup(tt.terms)))
+		})
+	}
+}
+
+func TestInvertedIndex_Update(t *testing.T) {
+	idx := NewInvertedIndex("idx", 0, words)
+	for i, text := range []string{"red", "red blue"} {
+		_, err := idx.Add(entity.Row{Key: entity.Key(i + 1), Values: []entity.Value{text}})
+		require.NoError(t, err)
+	}
+	iter := idx.Lookup([]string{"red"})
+
+	require.NoError(t, idx.Update(2, entity.Row{Values: []entity.Value{"blue"}}))
+	assert.Equal(t, []entity.Key{1}, collectKeys(t, idx.Lookup([]string{"red"})))
+	assert.Equal(t, []entity.Key{2}, collectKeys(t, idx.Lookup([]string{"blue"})))
+
+	require.NoError(t, idx.Remove(1))
+	assert.Error(t, idx.Remove(1))
+	assert.Equal(t, 1, idx.Size())
+	// rows removed after the lookup are skipped
+	assert.Equal(t, []entity.Key{2}, collectKeys(t, iter))
+	row, err := idx.Get(2)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Row{Key: 2, Values: []entity.Value{"blue"}}, row)
+}
@@ -38,7 +38,7 @@ func (si *ScanIndex) Add(row entity.Row) (entity.Key, error) {
 
 func (si *ScanIndex) Remove(key entity.Key) error {
 	position := int(key - 1)
-	if position < 0 || position >= len(si.rows) {
+	if position < 0 || position >= len(si.rows) || si.rows[position] == nil {
 		return errors.New("invalid key")
 	}
 	si.rows[position] = nil
@@ -46,6 +46,16 @@ func (si *ScanIndex) Remove(key entity.Key) error {
 	return nil
 }
 
+func (si *ScanIndex) Update(key entity.Key, row entity.Row) error {
+	position := int(key - 1)
+	if position < 0 || position >= len(si.rows) || si.rows[position] == nil {
+		return errors.New("invalid key")
+	}
+	row.Key = key
+	si.rows[position] = &row
+	return nil
+}
+
 func (si *ScanIndex) Get(key entity.Key) (entity.Row, error) {
 	position := int(key - 1)
 	if position < 0 || position >= len(si.rows) {
@@ -80,3 +90,10 @@ func (si *ScanIterator) Next() (entity.Row, error) {
 	}
 	return *si.index.rows[si.position], nil
 }
+
+func (si *ScanIterator) Current() (entity.Row, error) {
+	if si.position < 0 || si.position >= len(si.index.rows) || si.index.rows[si.position] == nil {
+		return entity.Row{}, errors.New("invalid cursor")
+	}
+	return *si.index.rows[si.position], nil
+}
@@ -309,6 +309,124 @@ func TestScanIndex_Remove(t *testing.T) {
 	}
 }
 
+func TestScanIndex_Update(t *testing.T) {
+	type fields struct {
+		rows []*entity.Row
+		free []int
+	}
+	type args struct {
+		key entity.Key
+		row entity.Row
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    fields
+		wantErr error
+	}{
+		{
+			name: "No existing",
+			fields: fields{
+				rows: []*entity.Row{},
+				free: nil,
+			},
+			args: args{
+				key: 1,
+				row: entity.Row{
+					Values: []entity.Value{"val1"},
+				},
+			},
+			want: fields{
+				rows: []*entity.Row{},
+				free: nil,
+			},
+			wantErr: errors.New("invalid key"),
+		},
+		{
+			name: "Some existing - No Free Indexes",
+			fields: fields{
+				rows: []*entity.Row{
+					{
+						Key:    1,
+						Values: []entity.Value{"val1"},
+					},
+					{
+						Key:    2,
+						Values: []entity.Value{"val2"},
+					},
+				},
+				free: nil,
+			},
+			args: args{
+				key: 2,
+				row: entity.Row{
+					Values: []entity.Value{"val3"},
+				},
+			},
+			want: fields{
+				rows: []*entity.Row{
+					{
+						Key:    1,
+						Values: []entity.Value{"val1"},
+					},
+					{
+						Key:    2,
+						Values: []entity.Value{"val3"},
+					},
+				},
+				free: nil,
+			},
+			wantErr: nil,
+		},
+		{
+			name: "Some existing - Free Index",
+			fields: fields{
+				rows: []*entity.Row{
+					nil,
+					{
+						Key:    2,
+						Values: []entity.Value{"val2"},
+					},
+				},
+				free: []int{0},
+			},
+			args: args{
+				key: 1,
+				row: entity.Row{
+					Values: []entity.Value{"val3"},
+				},
+			},
+			want: fields{
+				rows: []*entity.Row{
+					nil,
+					{
+						Key:    2,
+						Values: []entity.Value{"val2"},
+					},
+				},
+				free: []int{0},
+			},
+			wantErr: errors.New("invalid key"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			si := &ScanIndex{
+				rows: tt.fields.rows,
+				free: list.New(),
+			}
+			for _, i := range tt.fields.free {
+				si.free.PushBack(i)
+			}
+			err := si.Update(tt.args.key, tt.args.row)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want.rows, si.rows)
+			assert.Equal(t, len(tt.want.free), si.free.Len())
+		})
+	}
+}
+
 func TestScanIndex_Get(t *testing.T) {
 	type fields struct {
 		rows []*entity.Row
@@ -642,7 +760,8 @@ func TestScanIterator_Next(t *testing.T) {
 				index:    sidx,
 				position: tt.fields.position,
 			}
-			assert.Equal(t, tt.want, si.Next())
+			_, err := si.Next()
+			assert.Equal(t, tt.want, err == nil)
 		})
 	}
 }
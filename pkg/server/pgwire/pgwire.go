@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/hiepd/galedb/pkg/entity"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/hiepd/galedb/pkg/sql/parser"
 	"github.com/hiepd/galedb/pkg/sql/planner"
 	"github.com/hiepd/galedb/pkg/storage"
+	"github.com/hiepd/galedb/pkg/types"
 	"github.com/sirupsen/logrus"
 )
 
@@ -61,6 +63,7 @@ func (s *Server) Start() error {
 			sc := &sessionConn{
 				netConn: c,
 				parser:  s.Parser,
+				session: planner.NewSession(),
 			}
 
 			// Handle the connection in a new goroutine.
@@ -84,6 +87,8 @@ func (s *Server) Close() error {
 type sessionConn struct {
 	netConn net.Conn
 	parser  *parser.Parser
+	// session holds the settings SET changes for the connection
+	session *planner.Session
 }
 
 func (sc *sessionConn) serveConn(db *storage.Database, closeCh chan struct{}, wg *sync.WaitGroup) {
@@ -128,8 +133,8 @@ func (sc *sessionConn) serveConn(db *storage.Database, closeCh chan struct{}, wg
 		payload:  payload,
 	}
 
-	logrus.WithField("source", "pgwire").Info("---RECEIVING STARTUP MSG---")
-	logrus.WithField("source", "pgwire").Info(startupMsg.string())
+	logrus.WithField("source", "pgwire").Debug("---RECEIVING STARTUP MSG---")
+	logrus.WithField("source", "pgwire").Debug(startupMsg.string())
 	if err := authOk.writeConn(sc.netConn); err != nil {
 		logrus.Error(err)
 		return
@@ -164,12 +169,15 @@ func (sc *sessionConn) serveConn(db *storage.Database, closeCh chan struct{}, wg
 			tag:     tag,
 			payload: payload,
 		}
-		logrus.WithField("source", "pgwire").Info("---RECEIVING MSG---")
-		logrus.WithField("source", "pgwire").Info(req.string())
+		logrus.WithField("source", "pgwire").Debug("---RECEIVING MSG---")
+		logrus.WithField("source", "pgwire").Debug(req.string())
 		cc := &commandComplete{}
-		if err := sc.handle(db, req); err != nil {
+		cmdTag, err := sc.handle(db, req)
+		if err != nil {
 			logrus.WithError(err).Error("failed to handle query")
 			cc.value = err.Error()
+		} else {
+			cc.value = cmdTag
 		}
 		if err := cc.message().writeConn(sc.netConn); err != nil {
 			logrus.WithError(err).Error("failed to send command complete")
@@ -183,48 +191,63 @@ func (sc *sessionConn) serveConn(db *storage.Database, closeCh chan struct{}, wg
 	}
 }
 
-func (sc *sessionConn) handle(db *storage.Database, msg *message) error {
+func (sc *sessionConn) handle(db *storage.Database, msg *message) (string, error) {
 	// ctx, _ := context.WithDeadline(context.Background(), time.Now().Add(time.Duration(30)*time.Second))
 	parsed, err := sc.parser.Parse(string(msg.payload))
 	if err != nil {
-		return err
+		return "", err
 	}
 	logrus.Debugf("parsed tree:\n%s", parsed.String())
 	pl := planner.New(db)
+	pl.Session = sc.session
 	plan, err := pl.Prepare(parsed)
 	if err != nil {
-		return err
+		return "", err
 	}
 	logrus.WithField("plan", plan).Debugf("query plan")
 	// execute plan
+	if !plan.IsQuery() {
+		return plan.Exec()
+	}
 	iter := plan.Iter()
 	cols := plan.Columns()
+	typs := plan.Types()
 	fields := make([]*field, len(cols))
 	for i, col := range cols {
+		typ := typs[i]
+		// columns of NULLs are described as text
+		if typ.Family == types.UnknownFamily {
+			typ = types.Text
+		}
 		fields[i] = &field{
-			name: col + "\x00",
+			name:    col + "\x00",
+			typeOid: int32(typ.Oid),
+			typeLen: int16(typ.Size()),
+			typeMod: int32(typ.Modifier()),
 		}
 	}
 	rd := &rowDescription{
 		fields: fields,
 	}
 	if err := rd.message().writeConn(sc.netConn); err != nil {
-		return err
+		return "", err
 	}
+	n := 0
 	for {
 		row, err := iter.Next()
 		if err == index.EndOfIterator {
 			break
 		} else if err != nil {
 			logrus.WithError(err).Error("failed to iterate results")
-			break
+			return "", err
 		}
-		dr := convertRowToDataRow(&row)
+		dr := convertRowToDataRow(&row, typs, sc.session.TimeZone)
 		if err := dr.message().writeConn(sc.netConn); err != nil {
-			return err
+			return "", err
 		}
+		n++
 	}
-	return nil
+	return fmt.Sprintf("SELECT %d", n), nil
 }
 
 type message struct {
@@ -272,8 +295,7 @@ func (msg *message) bytes() []byte {
 }
 
 func (msg *message) writeConn(c net.Conn) error {
-	fmt.Println("---WRITING MSG---")
-	fmt.Println(msg.bytes())
+	logrus.WithField("source", "pgwire").Debugf("writing message %c", msg.tag)
 	_, err := c.Write(msg.bytes())
 	return err
 }
@@ -287,7 +309,6 @@ func readBytes(reader *bufio.Reader, n int) ([]byte, error) {
 	for i := 0; i < n; i++ {
 		b, err := reader.ReadByte()
 		if err != nil {
-			fmt.Println(err)
 			return nil, err
 		}
 		res[i] = b
@@ -369,22 +390,22 @@ func (dr *dataRow) message() *message {
 
 func (c *col) bytes() []byte {
 	res := make([]byte, 0)
-	res = append(res, int32ToBytes(int32(len(c.data)))...)
+	res = append(res, int32ToBytes(c.dataLen)...)
 	res = append(res, c.data...)
 	return res
 }
 
-func convertRowToDataRow(row *entity.Row) *dataRow {
+// convertRowToDataRow formats a row as text, showing timestamps with time
+// zone in loc.
+func convertRowToDataRow(row *entity.Row, typs []types.T, loc *time.Location) *dataRow {
 	cols := make([]col, len(row.Values))
 	for i, val := range row.Values {
-		res := ""
-		switch v := val.(type) {
-		case int, int16, int32, int64:
-			res = fmt.Sprintf("%d", v)
-		case string:
-			res = v
-		default:
+		// NULL is sent as a length of -1 with no data
+		if val == entity.Null {
+			cols[i] = col{dataLen: -1}
+			continue
 		}
+		res := typs[i].FormatIn(val, loc)
 		cols[i] = col{
 			dataLen: int32(len(res)),
 			data:    []byte(res),
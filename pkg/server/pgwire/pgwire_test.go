@@ -0,0 +1,167 @@
+package pgwire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/sql/planner"
+	"github.com/hiepd/galedb/pkg/storage"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+// decodeDataRow returns the values of a DataRow payload, with nil for NULLs.
+func decodeDataRow(t *testing.T, payload []byte) [][]byte {
+	require.True(t, len(payload) >= 2)
+	n := int(int16(binary.BigEndian.Uint16(payload)))
+	payload = payload[2:]
+	vals := make([][]byte, n)
+	for i := range vals {
+		require.True(t, len(payload) >= 4)
+		size := int(int32(binary.BigEndian.Uint32(payload)))
+		payload = payload[4:]
+		if size == -1 {
+			continue
+		}
+		require.True(t, size >= 0 && len(payload) >= size)
+		vals[i] = payload[:size]
+		payload = payload[size:]
+	}
+	assert.Empty(t, payload)
+	return vals
+}
+
+func TestConvertRowToDataRow(t *testing.T) {
+	row := entity.Row{Values: []entity.Value{1, nil, "a", nil}}
+	typs := []types.T{types.Int, types.Text, types.Text, types.Int}
+	msg := convertRowToDataRow(&row, typs, time.UTC).message()
+	assert.Equal(t, byte('D'), msg.tag)
+	// NULLs are a length of -1 without data
+	assert.Equal(t, []byte{
+		0, 4,
+		0, 0, 0, 1, '1',
+		0xff, 0xff, 0xff, 0xff,
+		0, 0, 0, 1, 'a',
+		0xff, 0xff, 0xff, 0xff,
+	}, msg.payload)
+	assert.Equal(t, [][]byte{[]byte("1"), nil, []byte("a"), nil}, decodeDataRow(t, msg.payload))
+}
+
+// client is the frontend side of a session served over an in-memory
+// connection.
+type client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newClient(t *testing.T, db *storage.Database) *client {
+	server, conn := net.Pipe()
+	sc := &sessionConn{
+		netConn: server,
+		parser:  parser.New(),
+		session: planner.NewSession(),
+	}
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go sc.serveConn(db, make(chan struct{}, 1), wg)
+	c := &client{conn: conn, reader: bufio.NewReader(conn)}
+
+	payload := []byte("user\x00test\x00\x00")
+	startup := append(int32ToBytes(int32(len(payload)+8)), int32ToBytes(196608)...)
+	_, err := conn.Write(append(startup, payload...))
+	require.NoError(t, err)
+	tag, _ := c.read(t)
+	assert.Equal(t, byte('R'), tag)
+	tag, _ = c.read(t)
+	assert.Equal(t, byte('Z'), tag)
+	return c
+}
+
+func (c *client) read(t *testing.T) (byte, []byte) {
+	tag, err := c.reader.ReadByte()
+	require.NoError(t, err)
+	lenBytes, err := readBytes(c.reader, 4)
+	require.NoError(t, err)
+	payload, err := readBytes(c.reader, int(binary.BigEndian.Uint32(lenBytes))-4)
+	require.NoError(t, err)
+	return tag, payload
+}
+
+// query sends a simple query and returns the rows and the tag of the
+// CommandComplete message sent before ReadyForQuery.
+func (c *client) query(t *testing.T, sql string) ([][][]byte, string) {
+	msg := &message{tag: 'Q', payload: []byte(sql + "\x00")}
+	_, err := c.conn.Write(msg.bytes())
+	require.NoError(t, err)
+	var rows [][][]byte
+	var cmdTag string
+	for {
+		tag, payload := c.read(t)
+		switch tag {
+		case 'D':
+			rows = append(rows, decodeDataRow(t, payload))
+		case 'C':
+			require.True(t, len(payload) > 0 && payload[len(payload)-1] == 0)
+			cmdTag = string(payload[:len(payload)-1])
+		case 'Z':
+			return rows, cmdTag
+		}
+	}
+}
+
+func TestSessionConn_CommandComplete(t *testing.T) {
+	c := newClient(t, &storage.Database{Name: "test"})
+	defer c.conn.Close()
+
+	tests := []struct {
+		sql      string
+		wantRows [][][]byte
+		wantTag  string
+	}{
+		{
+			sql:     "create table users (id integer, name text)",
+			wantTag: "CREATE TABLE",
+		},
+		{
+			sql:     "insert into users values (1, 'a'), (2, null), (3, 'c')",
+			wantTag: "INSERT 0 3",
+		},
+		{
+			sql:      "select id, name from users order by id",
+			wantRows: [][][]byte{{[]byte("1"), []byte("a")}, {[]byte("2"), nil}, {[]byte("3"), []byte("c")}},
+			wantTag:  "SELECT 3",
+		},
+		{
+			sql:     "update users set name = null where id <> 2",
+			wantTag: "UPDATE 2",
+		},
+		{
+			sql:     "delete from users where id > 1",
+			wantTag: "DELETE 2",
+		},
+		{
+			sql:      "select name, id from users",
+			wantRows: [][][]byte{{nil, []byte("1")}},
+			wantTag:  "SELECT 1",
+		},
+		{
+			sql:     "select id from users where id > 1",
+			wantTag: "SELECT 0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			rows, tag := c.query(t, tt.sql)
+			assert.Equal(t, tt.wantRows, rows)
+			assert.Equal(t, tt.wantTag, tag)
+		})
+	}
+}
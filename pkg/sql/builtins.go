@@ -0,0 +1,767 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+var (
+	text     = types.Text
+	smallint = types.SmallInt
+	integer  = types.Int
+	bigint   = types.BigInt
+	double   = types.Float
+	numeric  = types.Numeric
+
+	date        = types.Date
+	clock       = types.Time
+	timestamp   = types.Timestamp
+	timestamptz = types.TimestampTZ
+	interval    = types.Interval
+
+	json  = types.JSON
+	jsonb = types.JSONB
+)
+
+// builtins is the standard library of functions.
+var builtins = []*Function{
+	// strings
+	immutable("lower", text, fnLower, text),
+	immutable("upper", text, fnUpper, text),
+	immutable("length", integer, fnLength, text),
+	immutable("char_length", integer, fnLength, text),
+	immutable("substring", text, fnSubstring, text, integer),
+	immutable("substring", text, fnSubstring, text, integer, integer),
+	immutable("trim", text, fnTrim(strings.Trim), text),
+	immutable("trim", text, fnTrim(strings.Trim), text, text),
+	immutable("btrim", text, fnTrim(strings.Trim), text),
+	immutable("btrim", text, fnTrim(strings.Trim), text, text),
+	immutable("ltrim", text, fnTrim(strings.TrimLeft), text),
+	immutable("ltrim", text, fnTrim(strings.TrimLeft), text, text),
+	immutable("rtrim", text, fnTrim(strings.TrimRight), text),
+	immutable("rtrim", text, fnTrim(strings.TrimRight), text, text),
+	{Name: "concat", Args: []types.T{types.Any}, Variadic: true, Return: text, Volatility: Immutable, Impl: fnConcat},
+	immutable("replace", text, fnReplace, text, text, text),
+	immutable("split_part", text, fnSplitPart, text, text, integer),
+	// math
+	// wider integers come first, so that arguments of mixed widths take
+	// the wider one
+	immutable("abs", bigint, fnAbsInt(bigint), bigint),
+	immutable("abs", integer, fnAbsInt(integer), integer),
+	immutable("abs", smallint, fnAbsInt(smallint), smallint),
+	immutable("abs", double, fnMath(math.Abs), double),
+	immutable("abs", numeric, fnDecimal(types.Decimal.Abs), numeric),
+	immutable("round", double, fnMath(math.Round), double),
+	immutable("round", double, fnRound, double, integer),
+	immutable("round", numeric, fnDecimal(func(d types.Decimal) types.Decimal { return d.Round(0) }), numeric),
+	immutable("round", numeric, fnRoundDecimal, numeric, integer),
+	immutable("floor", double, fnMath(math.Floor), double),
+	immutable("floor", numeric, fnDecimal(types.Decimal.Floor), numeric),
+	immutable("ceil", double, fnMath(math.Ceil), double),
+	immutable("ceil", numeric, fnDecimal(types.Decimal.Ceil), numeric),
+	immutable("ceiling", double, fnMath(math.Ceil), double),
+	immutable("ceiling", numeric, fnDecimal(types.Decimal.Ceil), numeric),
+	immutable("mod", bigint, fnModInt, bigint, bigint),
+	immutable("mod", integer, fnModInt, integer, integer),
+	immutable("mod", smallint, fnModInt, smallint, smallint),
+	immutable("mod", numeric, fnModDecimal, numeric, numeric),
+	immutable("mod", double, fnMod, double, double),
+	immutable("power", double, fnPower, double, double),
+	immutable("sqrt", double, fnSqrt, double),
+	// date and time
+	{Name: "now", Return: timestamptz, Volatility: Stable, ClockImpl: fnNow},
+	{Name: "current_timestamp", Return: timestamptz, Volatility: Stable, ClockImpl: fnNow},
+	{Name: "clock_timestamp", Return: timestamptz, Volatility: Volatile, Impl: fnClockTimestamp},
+	{Name: "current_date", Return: date, Volatility: Stable, ClockImpl: fnCurrentDate},
+	{Name: "localtimestamp", Return: timestamp, Volatility: Stable, ClockImpl: fnLocalTimestamp},
+	{Name: "localtime", Return: clock, Volatility: Stable, ClockImpl: fnLocalTime},
+	immutable("to_timestamp", timestamptz, fnToTimestamp, double),
+	immutable("timezone", timestamp, fnTimezone, text, timestamptz),
+	immutable("timezone", timestamptz, fnTimezone, text, timestamp),
+	zoned("extract", numeric, Immutable, fnExtract, text, timestamp),
+	zoned("extract", numeric, Stable, fnExtract, text, timestamptz),
+	zoned("extract", numeric, Immutable, fnExtract, text, date),
+	zoned("extract", numeric, Immutable, fnExtract, text, clock),
+	zoned("extract", numeric, Immutable, fnExtract, text, interval),
+	zoned("date_part", double, Immutable, fnDatePart, text, timestamp),
+	zoned("date_part", double, Stable, fnDatePart, text, timestamptz),
+	zoned("date_part", double, Immutable, fnDatePart, text, date),
+	zoned("date_part", double, Immutable, fnDatePart, text, clock),
+	zoned("date_part", double, Immutable, fnDatePart, text, interval),
+	immutable("date_trunc", timestamp, fnDateTrunc, text, timestamp),
+	zoned("date_trunc", timestamptz, Stable, fnDateTruncTZ, text, timestamptz),
+	// json
+	{Name: "jsonb_build_object", Return: jsonb, Volatility: Immutable, Impl: fnEmptyObject},
+	{Name: "jsonb_build_object", Args: []types.T{types.Any}, Variadic: true, Return: jsonb, Volatility: Stable, ZonedImpl: fnBuildObject},
+	{Name: "json_build_object", Return: json, Volatility: Immutable, Impl: fnEmptyObject},
+	{Name: "json_build_object", Args: []types.T{types.Any}, Variadic: true, Return: json, Volatility: Stable, ZonedImpl: fnBuildObject},
+	{Name: "jsonb_build_array", Return: jsonb, Volatility: Immutable, Impl: fnEmptyArray},
+	{Name: "jsonb_build_array", Args: []types.T{types.Any}, Variadic: true, Return: jsonb, Volatility: Stable, ZonedImpl: fnBuildArray},
+	{Name: "json_build_array", Return: json, Volatility: Immutable, Impl: fnEmptyArray},
+	{Name: "json_build_array", Args: []types.T{types.Any}, Variadic: true, Return: json, Volatility: Stable, ZonedImpl: fnBuildArray},
+	zoned("to_jsonb", jsonb, Stable, fnToJSON, types.Any),
+	zoned("to_json", json, Stable, fnToJSON, types.Any),
+	immutable("jsonb_typeof", text, fnJSONTypeOf, jsonb),
+	immutable("json_typeof", text, fnJSONTypeOf, json),
+	immutable("jsonb_array_length", integer, fnJSONArrayLength, jsonb),
+	immutable("json_array_length", integer, fnJSONArrayLength, json),
+}
+
+// tableBuiltins are the set-returning functions.
+var tableBuiltins = []*Function{
+	setReturning("jsonb_array_elements", fnJSONArrayElements(jsonElem), []types.T{jsonb}, column("value", jsonb)),
+	setReturning("json_array_elements", fnJSONArrayElements(jsonElem), []types.T{json}, column("value", json)),
+	setReturning("jsonb_array_elements_text", fnJSONArrayElements(types.JSONText), []types.T{jsonb}, column("value", text)),
+	setReturning("json_array_elements_text", fnJSONArrayElements(types.JSONText), []types.T{json}, column("value", text)),
+	setReturning("jsonb_each", fnJSONEach(jsonElem), []types.T{jsonb}, column("key", text), column("value", jsonb)),
+	setReturning("json_each", fnJSONEach(jsonElem), []types.T{json}, column("key", text), column("value", json)),
+	setReturning("jsonb_each_text", fnJSONEach(types.JSONText), []types.T{jsonb}, column("key", text), column("value", text)),
+	setReturning("json_each_text", fnJSONEach(types.JSONText), []types.T{json}, column("key", text), column("value", text)),
+	setReturning("jsonb_object_keys", fnJSONObjectKeys, []types.T{jsonb}, column("jsonb_object_keys", text)),
+	setReturning("json_object_keys", fnJSONObjectKeys, []types.T{json}, column("json_object_keys", text)),
+}
+
+func init() {
+	for _, fn := range builtins {
+		if err := RegisterFunction(fn); err != nil {
+			panic(err)
+		}
+	}
+	for _, fn := range tableBuiltins {
+		if err := RegisterTableFunction(fn); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// immutable declares a strict immutable function.
+func immutable(name string, ret types.T, impl func([]entity.Value) (entity.Value, error), args ...types.T) *Function {
+	return &Function{Name: name, Args: args, Return: ret, Volatility: Immutable, Strict: true, Impl: impl}
+}
+
+// setReturning declares a strict immutable set-returning function.
+func setReturning(name string, impl func([]entity.Value) ([][]entity.Value, error), args []types.T, cols ...entity.Column) *Function {
+	return &Function{Name: name, Args: args, Volatility: Immutable, Strict: true, SetImpl: impl, Columns: cols}
+}
+
+func column(name string, typ types.T) entity.Column {
+	return entity.Column{Name: name, Type: typ}
+}
+
+// zoned declares a strict function that is passed the time zone of the
+// session. Those whose result depends on it are stable, not immutable.
+func zoned(name string, ret types.T, v Volatility, impl func([]entity.Value, *time.Location) (entity.Value, error), args ...types.T) *Function {
+	return &Function{Name: name, Args: args, Return: ret, Volatility: v, Strict: true, ZonedImpl: impl}
+}
+
+func fnLower(args []entity.Value) (entity.Value, error) {
+	return strings.ToLower(args[0].(string)), nil
+}
+
+func fnUpper(args []entity.Value) (entity.Value, error) {
+	return strings.ToUpper(args[0].(string)), nil
+}
+
+func fnLength(args []entity.Value) (entity.Value, error) {
+	return utf8.RuneCountInString(args[0].(string)), nil
+}
+
+// fnSubstring returns the characters from a 1-based position, up to a given
+// count. Positions before the first character count towards it.
+func fnSubstring(args []entity.Value) (entity.Value, error) {
+	s := []rune(args[0].(string))
+	start := args[1].(int)
+	end := len(s) + 1
+	if len(args) > 2 {
+		n := args[2].(int)
+		if n < 0 {
+			return nil, errors.New("negative substring length not allowed")
+		}
+		if start+n < end {
+			end = start + n
+		}
+	}
+	if start < 1 {
+		start = 1
+	}
+	if start >= end {
+		return "", nil
+	}
+	return string(s[start-1 : end-1]), nil
+}
+
+// fnTrim removes spaces, or the characters of the second argument, from the
+// string.
+func fnTrim(trim func(string, string) string) func([]entity.Value) (entity.Value, error) {
+	return func(args []entity.Value) (entity.Value, error) {
+		chars := " "
+		if len(args) > 1 {
+			chars = args[1].(string)
+		}
+		return trim(args[0].(string), chars), nil
+	}
+}
+
+// fnConcat joins the text of its arguments, leaving out NULLs.
+func fnConcat(args []entity.Value) (entity.Value, error) {
+	var b strings.Builder
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case nil:
+		case string:
+			b.WriteString(v)
+		case int:
+			b.WriteString(strconv.Itoa(v))
+		case float64:
+			b.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+		case bool:
+			if v {
+				b.WriteString("t")
+			} else {
+				b.WriteString("f")
+			}
+		default:
+			fmt.Fprintf(&b, "%v", v)
+		}
+	}
+	return b.String(), nil
+}
+
+func fnReplace(args []entity.Value) (entity.Value, error) {
+	s, from := args[0].(string), args[1].(string)
+	if from == "" {
+		return s, nil
+	}
+	return strings.ReplaceAll(s, from, args[2].(string)), nil
+}
+
+// fnSplitPart returns a field of a string split on a delimiter. Negative
+// positions count from the last field.
+func fnSplitPart(args []entity.Value) (entity.Value, error) {
+	s, delim, n := args[0].(string), args[1].(string), args[2].(int)
+	if n == 0 {
+		return nil, errors.New("field position must not be zero")
+	}
+	fields := []string{s}
+	if delim != "" {
+		fields = strings.Split(s, delim)
+	}
+	if n < 0 {
+		n += len(fields) + 1
+	}
+	if n < 1 || n > len(fields) {
+		return "", nil
+	}
+	return fields[n-1], nil
+}
+
+// fnAbsInt returns the absolute value of an integer of type typ, which may
+// not fit it when the integer is the most negative one.
+func fnAbsInt(typ types.T) func([]entity.Value) (entity.Value, error) {
+	return func(args []entity.Value) (entity.Value, error) {
+		n := args[0].(int)
+		if n >= 0 {
+			return n, nil
+		}
+		if n == math.MinInt64 {
+			return nil, fmt.Errorf("%s out of range", typ)
+		}
+		return typ.Coerce(-n)
+	}
+}
+
+func fnMath(f func(float64) float64) func([]entity.Value) (entity.Value, error) {
+	return func(args []entity.Value) (entity.Value, error) {
+		return f(args[0].(float64)), nil
+	}
+}
+
+func fnDecimal(f func(types.Decimal) types.Decimal) func([]entity.Value) (entity.Value, error) {
+	return func(args []entity.Value) (entity.Value, error) {
+		return f(args[0].(types.Decimal)), nil
+	}
+}
+
+// fnRound rounds to a number of decimal places, or to tens, hundreds and so
+// on when it is negative.
+func fnRound(args []entity.Value) (entity.Value, error) {
+	x, places := args[0].(float64), args[1].(int)
+	scale := math.Pow(10, float64(places))
+	res := math.Round(x*scale) / scale
+	if math.IsInf(res, 0) || math.IsNaN(res) {
+		return x, nil
+	}
+	return res, nil
+}
+
+func fnRoundDecimal(args []entity.Value) (entity.Value, error) {
+	return args[0].(types.Decimal).Round(args[1].(int)), nil
+}
+
+func fnModInt(args []entity.Value) (entity.Value, error) {
+	b := args[1].(int)
+	if b == 0 {
+		return nil, errors.New("division by zero")
+	}
+	return args[0].(int) % b, nil
+}
+
+func fnMod(args []entity.Value) (entity.Value, error) {
+	b := args[1].(float64)
+	if b == 0 {
+		return nil, errors.New("division by zero")
+	}
+	return math.Mod(args[0].(float64), b), nil
+}
+
+func fnModDecimal(args []entity.Value) (entity.Value, error) {
+	return args[0].(types.Decimal).Rem(args[1].(types.Decimal))
+}
+
+func fnPower(args []entity.Value) (entity.Value, error) {
+	x, y := args[0].(float64), args[1].(float64)
+	if x == 0 && y < 0 {
+		return nil, errors.New("zero raised to a negative power is undefined")
+	}
+	if x < 0 && y != math.Trunc(y) {
+		return nil, errors.New("a negative number raised to a non-integer power yields a complex result")
+	}
+	return math.Pow(x, y), nil
+}
+
+func fnSqrt(args []entity.Value) (entity.Value, error) {
+	x := args[0].(float64)
+	if x < 0 {
+		return nil, errors.New("cannot take square root of a negative number")
+	}
+	return math.Sqrt(x), nil
+}
+
+// fnNow returns the time the statement started, like the functions below
+// but clock_timestamp, so that they agree within a statement.
+func fnNow(args []entity.Value, now time.Time, loc *time.Location) (entity.Value, error) {
+	return types.TimestampTZOf(now), nil
+}
+
+func fnClockTimestamp(args []entity.Value) (entity.Value, error) {
+	return types.TimestampTZOf(time.Now()), nil
+}
+
+func fnCurrentDate(args []entity.Value, now time.Time, loc *time.Location) (entity.Value, error) {
+	return types.DateOf(now.In(loc)), nil
+}
+
+func fnLocalTimestamp(args []entity.Value, now time.Time, loc *time.Location) (entity.Value, error) {
+	return types.TimestampOf(now.In(loc)), nil
+}
+
+func fnLocalTime(args []entity.Value, now time.Time, loc *time.Location) (entity.Value, error) {
+	return types.TimestampOf(now.In(loc)).Clock(), nil
+}
+
+// fnToTimestamp returns the instant of a number of seconds since the Unix
+// epoch.
+func fnToTimestamp(args []entity.Value) (entity.Value, error) {
+	secs := args[0].(float64)
+	if math.IsNaN(secs) || math.Abs(secs) > 1e13 {
+		return nil, errors.New("timestamp out of range")
+	}
+	return types.TimestampTZOf(time.Unix(0, 0).Add(time.Duration(math.Round(secs*1e6)) * time.Microsecond)), nil
+}
+
+// fnTimezone converts a timestamp with time zone to the timestamp the clocks
+// of a time zone show, and a timestamp to the instant they show it.
+func fnTimezone(args []entity.Value) (entity.Value, error) {
+	loc, err := time.LoadLocation(args[0].(string))
+	if err != nil {
+		return nil, fmt.Errorf("time zone %q not recognized", args[0])
+	}
+	switch ts := args[1].(type) {
+	case types.TimestampTZValue:
+		return ts.Local(loc), nil
+	case types.TimestampValue:
+		return ts.Zoned(loc), nil
+	}
+	return nil, fmt.Errorf("cannot convert %v to time zone %s", args[1], loc)
+}
+
+// fnExtract returns a field of a date, a time, a timestamp or an interval.
+func fnExtract(args []entity.Value, loc *time.Location) (entity.Value, error) {
+	return extractField(dateField(args[0].(string)), args[1], loc)
+}
+
+// fnDatePart is like fnExtract, returning a double precision.
+func fnDatePart(args []entity.Value, loc *time.Location) (entity.Value, error) {
+	d, err := extractField(dateField(args[0].(string)), args[1], loc)
+	if err != nil {
+		return nil, err
+	}
+	return d.Float64(), nil
+}
+
+// dateField returns the name of a field of a date or time in the singular,
+// like "microsecond" for "microseconds".
+func dateField(s string) string {
+	return strings.TrimSuffix(strings.ToLower(s), "s")
+}
+
+// extractField returns a field of a date or time value as a numeric.
+// Timestamps with time zone are read on the clocks of loc.
+func extractField(field string, v entity.Value, loc *time.Location) (types.Decimal, error) {
+	switch v := v.(type) {
+	case types.TimestampValue:
+		if d, ok := timestampField(field, v.Time(), int64(v)); ok {
+			return d, nil
+		}
+	case types.TimestampTZValue:
+		t := v.Time().In(loc)
+		if field == "timezone" {
+			_, off := t.Zone()
+			return types.NewDecimal(int64(off), 0), nil
+		}
+		if d, ok := timestampField(field, t, int64(v)); ok {
+			return d, nil
+		}
+	case types.DateValue:
+		switch field {
+		case "hour", "minute", "second", "millisecond", "microsecond":
+			return types.Decimal{}, fmt.Errorf("unit %q not supported for type date", field)
+		}
+		if d, ok := timestampField(field, v.Time(), int64(v.Timestamp())); ok {
+			return d, nil
+		}
+	case types.TimeValue:
+		us := int64(v)
+		switch field {
+		case "hour":
+			return types.NewDecimal(us/usPerHour, 0), nil
+		case "minute":
+			return types.NewDecimal(us/usPerMinute%60, 0), nil
+		case "epoch":
+			return types.NewDecimal(us, 6), nil
+		}
+		if d, ok := secondsField(field, us%usPerMinute); ok {
+			return d, nil
+		}
+	case types.IntervalValue:
+		if d, ok := intervalField(field, v); ok {
+			return d, nil
+		}
+	}
+	return types.Decimal{}, fmt.Errorf("unit %q not recognized for type %s", field, types.Of(v))
+}
+
+const (
+	usPerMinute = int64(time.Minute / time.Microsecond)
+	usPerHour   = int64(time.Hour / time.Microsecond)
+	usPerDay    = 24 * usPerHour
+	// usPerYear is the length of a year of an interval when it is turned
+	// into seconds, as Postgres counts it.
+	usPerYear = usPerDay * 36525 / 100
+)
+
+// secondsField returns the seconds of a time as a field in seconds,
+// milliseconds or microseconds. us is less than a minute.
+func secondsField(field string, us int64) (types.Decimal, bool) {
+	switch field {
+	case "second":
+		return types.NewDecimal(us, 6), true
+	case "millisecond":
+		return types.NewDecimal(us, 3), true
+	case "microsecond":
+		return types.NewDecimal(us, 0), true
+	}
+	return types.Decimal{}, false
+}
+
+// timestampField returns a field of a time on its clocks, or of its number of
+// microseconds since the epoch for "epoch".
+func timestampField(field string, t time.Time, epoch int64) (types.Decimal, bool) {
+	n := func(i int) (types.Decimal, bool) {
+		return types.NewDecimal(int64(i), 0), true
+	}
+	year := t.Year()
+	switch field {
+	case "millennium":
+		return n((year + 999) / 1000)
+	case "century":
+		return n((year + 99) / 100)
+	case "decade":
+		return n(year / 10)
+	case "year":
+		return n(year)
+	case "isoyear":
+		y, _ := t.ISOWeek()
+		return n(y)
+	case "quarter":
+		return n((int(t.Month())-1)/3 + 1)
+	case "month":
+		return n(int(t.Month()))
+	case "week":
+		_, week := t.ISOWeek()
+		return n(week)
+	case "day":
+		return n(t.Day())
+	case "dow":
+		return n(int(t.Weekday()))
+	case "isodow":
+		return n((int(t.Weekday())+6)%7 + 1)
+	case "doy":
+		return n(t.YearDay())
+	case "hour":
+		return n(t.Hour())
+	case "minute":
+		return n(t.Minute())
+	case "epoch":
+		return types.NewDecimal(epoch, 6), true
+	}
+	return secondsField(field, int64(t.Second())*1e6+int64(t.Nanosecond()/1e3))
+}
+
+// intervalField returns a field of an interval. Years are counted as 365.25
+// days and months as 30 days in its number of seconds.
+func intervalField(field string, v types.IntervalValue) (types.Decimal, bool) {
+	n := func(i int64) (types.Decimal, bool) {
+		return types.NewDecimal(i, 0), true
+	}
+	months := int64(v.Months)
+	switch field {
+	case "millennium":
+		return n(months / 12000)
+	case "century":
+		return n(months / 1200)
+	case "decade":
+		return n(months / 120)
+	case "year":
+		return n(months / 12)
+	case "quarter":
+		return n(months%12/3 + 1)
+	case "month":
+		return n(months % 12)
+	case "day":
+		return n(int64(v.Days))
+	case "hour":
+		return n(v.Micros / usPerHour)
+	case "minute":
+		return n(v.Micros / usPerMinute % 60)
+	case "epoch":
+		us := months/12*usPerYear + months%12*30*usPerDay + int64(v.Days)*usPerDay + v.Micros
+		return types.NewDecimal(us, 6), true
+	}
+	return secondsField(field, v.Micros%usPerMinute)
+}
+
+// fnDateTrunc truncates a timestamp to the start of a unit of time.
+func fnDateTrunc(args []entity.Value) (entity.Value, error) {
+	ts := args[1].(types.TimestampValue)
+	t, err := truncTime(dateField(args[0].(string)), ts.Time())
+	if err != nil {
+		return nil, err
+	}
+	return types.TimestampOf(t), nil
+}
+
+// fnDateTruncTZ truncates a timestamp with time zone on the clocks of the
+// session.
+func fnDateTruncTZ(args []entity.Value, loc *time.Location) (entity.Value, error) {
+	ts := args[1].(types.TimestampTZValue)
+	t, err := truncTime(dateField(args[0].(string)), ts.Time().In(loc))
+	if err != nil {
+		return nil, err
+	}
+	return types.TimestampTZOf(t), nil
+}
+
+func truncTime(field string, t time.Time) (time.Time, error) {
+	y, m, d := t.Date()
+	switch field {
+	case "microsecond":
+		return t.Truncate(time.Microsecond), nil
+	case "millisecond":
+		return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond()/1e6*1e6, t.Location()), nil
+	case "second":
+		return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, t.Location()), nil
+	case "minute":
+		return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, t.Location()), nil
+	case "hour":
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location()), nil
+	case "day":
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location()), nil
+	case "week":
+		// weeks start on Monday
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location()), nil
+	case "month":
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location()), nil
+	case "quarter":
+		return time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, t.Location()), nil
+	case "year":
+		return time.Date(y, 1, 1, 0, 0, 0, 0, t.Location()), nil
+	case "decade":
+		return time.Date(y-y%10, 1, 1, 0, 0, 0, 0, t.Location()), nil
+	case "century":
+		return time.Date((y-1)/100*100+1, 1, 1, 0, 0, 0, 0, t.Location()), nil
+	case "millennium":
+		return time.Date((y-1)/1000*1000+1, 1, 1, 0, 0, 0, 0, t.Location()), nil
+	}
+	return time.Time{}, fmt.Errorf("unit %q not recognized for type timestamp", field)
+}
+
+func fnEmptyObject(args []entity.Value) (entity.Value, error) {
+	return types.JSONValue("{}"), nil
+}
+
+func fnEmptyArray(args []entity.Value) (entity.Value, error) {
+	return types.JSONValue("[]"), nil
+}
+
+// fnBuildObject returns an object of the alternating keys and values of its
+// arguments. Keys are converted to text and can't be NULL.
+func fnBuildObject(args []entity.Value, loc *time.Location) (entity.Value, error) {
+	if len(args)%2 != 0 {
+		return nil, errors.New("argument list must have even number of elements")
+	}
+	obj := make(map[string]interface{}, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		if args[i] == entity.Null {
+			return nil, errors.New("null value not allowed for object key")
+		}
+		key, err := jsonKey(args[i], loc)
+		if err != nil {
+			return nil, err
+		}
+		if obj[key], err = types.ToJSON(args[i+1], loc); err != nil {
+			return nil, err
+		}
+	}
+	return types.EncodeJSON(obj), nil
+}
+
+// jsonKey returns the text of a value used as the key of an object.
+func jsonKey(v entity.Value, loc *time.Location) (string, error) {
+	doc, err := types.ToJSON(v, loc)
+	if err != nil {
+		return "", err
+	}
+	if s, ok := doc.(string); ok {
+		return s, nil
+	}
+	return string(types.EncodeJSON(doc)), nil
+}
+
+func fnBuildArray(args []entity.Value, loc *time.Location) (entity.Value, error) {
+	arr := make([]interface{}, len(args))
+	for i, arg := range args {
+		var err error
+		if arr[i], err = types.ToJSON(arg, loc); err != nil {
+			return nil, err
+		}
+	}
+	return types.EncodeJSON(arr), nil
+}
+
+func fnToJSON(args []entity.Value, loc *time.Location) (entity.Value, error) {
+	doc, err := types.ToJSON(args[0], loc)
+	if err != nil {
+		return nil, err
+	}
+	return types.EncodeJSON(doc), nil
+}
+
+func fnJSONTypeOf(args []entity.Value) (entity.Value, error) {
+	doc, err := args[0].(types.JSONValue).Doc()
+	if err != nil {
+		return nil, err
+	}
+	return types.JSONTypeOf(doc), nil
+}
+
+func fnJSONArrayLength(args []entity.Value) (entity.Value, error) {
+	arr, err := jsonArray(args[0], "get array length of")
+	if err != nil {
+		return nil, err
+	}
+	return len(arr), nil
+}
+
+// jsonArray decodes a document that must be an array for the action.
+func jsonArray(v entity.Value, action string) ([]interface{}, error) {
+	doc, err := v.(types.JSONValue).Doc()
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := doc.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot %s a non-array", action)
+	}
+	return arr, nil
+}
+
+// jsonObject decodes a document that must be an object for the function.
+func jsonObject(v entity.Value, name string) (map[string]interface{}, error) {
+	doc, err := v.(types.JSONValue).Doc()
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot call %s on a non-object", name)
+	}
+	return obj, nil
+}
+
+// jsonElem returns an element of a document as a document.
+func jsonElem(doc interface{}) interface{} {
+	return types.EncodeJSON(doc)
+}
+
+// fnJSONArrayElements returns a row for every element of an array, converted
+// by elem.
+func fnJSONArrayElements(elem func(interface{}) interface{}) func([]entity.Value) ([][]entity.Value, error) {
+	return func(args []entity.Value) ([][]entity.Value, error) {
+		arr, err := jsonArray(args[0], "extract elements from")
+		if err != nil {
+			return nil, err
+		}
+		rows := make([][]entity.Value, len(arr))
+		for i, doc := range arr {
+			rows[i] = []entity.Value{elem(doc)}
+		}
+		return rows, nil
+	}
+}
+
+// fnJSONEach returns a row for every field of an object, with its key and its
+// value converted by elem.
+func fnJSONEach(elem func(interface{}) interface{}) func([]entity.Value) ([][]entity.Value, error) {
+	return func(args []entity.Value) ([][]entity.Value, error) {
+		obj, err := jsonObject(args[0], "json_each")
+		if err != nil {
+			return nil, err
+		}
+		rows := make([][]entity.Value, 0, len(obj))
+		for _, key := range types.JSONKeys(obj) {
+			rows = append(rows, []entity.Value{key, elem(obj[key])})
+		}
+		return rows, nil
+	}
+}
+
+func fnJSONObjectKeys(args []entity.Value) ([][]entity.Value, error) {
+	obj, err := jsonObject(args[0], "json_object_keys")
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]entity.Value, 0, len(obj))
+	for _, key := range types.JSONKeys(obj) {
+		rows = append(rows, []entity.Value{key})
+	}
+	return rows, nil
+}
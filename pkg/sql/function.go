@@ -0,0 +1,248 @@
+package sql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+// Volatility tells when a function may return different results for the same
+// arguments.
+type Volatility int
+
+const (
+	// Immutable functions always return the same result for the same
+	// arguments, so calls with constant arguments are evaluated once when
+	// a statement is planned.
+	Immutable Volatility = iota + 1
+	// Stable functions return the same result within a statement.
+	Stable
+	// Volatile functions may return a different result on every call.
+	Volatile
+)
+
+// Function is a function that can be called from SQL. Scalar functions
+// return a value, and set-returning functions, called in the FROM clause of a
+// query, return rows.
+type Function struct {
+	Name string
+	// Args are the types of the arguments, which accept any type of their
+	// family, or of any family for types.Any. Numeric arguments accept
+	// integers, floating point arguments any number, and timestamps dates,
+	// which are converted before the call.
+	Args []types.T
+	// Variadic functions take their last argument one or more times.
+	Variadic   bool
+	Return     types.T
+	Volatility Volatility
+	// Strict functions return NULL without being called when any argument
+	// is NULL.
+	Strict bool
+	Impl   func(args []entity.Value) (entity.Value, error)
+	// ZonedImpl is set instead of Impl by functions that depend on the time
+	// zone of the session, which is passed to them.
+	ZonedImpl func(args []entity.Value, loc *time.Location) (entity.Value, error)
+	// ClockImpl is set instead of Impl by functions that return the time
+	// the statement started, which is passed to them with the time zone.
+	ClockImpl func(args []entity.Value, now time.Time, loc *time.Location) (entity.Value, error)
+	// SetImpl is set instead of Impl by set-returning functions, whose rows
+	// have the given Columns.
+	SetImpl func(args []entity.Value) ([][]entity.Value, error)
+	Columns []entity.Column
+}
+
+// Call calls the function with the given arguments, which must be of the types
+// it was looked up with. It runs in UTC.
+func (fn *Function) Call(args []entity.Value) (entity.Value, error) {
+	return fn.CallIn(args, time.UTC)
+}
+
+// CallIn is like Call for a session whose time zone is loc.
+func (fn *Function) CallIn(args []entity.Value, loc *time.Location) (entity.Value, error) {
+	return fn.CallAt(args, time.Now(), loc)
+}
+
+// CallAt is like CallIn for a statement that started at now.
+func (fn *Function) CallAt(args []entity.Value, now time.Time, loc *time.Location) (entity.Value, error) {
+	vals, err := fn.convert(args, loc)
+	if err != nil || vals == nil {
+		return entity.Null, err
+	}
+	switch {
+	case fn.ZonedImpl != nil:
+		return fn.ZonedImpl(vals, loc)
+	case fn.ClockImpl != nil:
+		return fn.ClockImpl(vals, now, loc)
+	}
+	return fn.Impl(vals)
+}
+
+// CallSet calls a set-returning function and returns its rows. A strict one
+// returns no rows when any argument is NULL.
+func (fn *Function) CallSet(args []entity.Value, loc *time.Location) ([][]entity.Value, error) {
+	vals, err := fn.convert(args, loc)
+	if err != nil || vals == nil {
+		return nil, err
+	}
+	return fn.SetImpl(vals)
+}
+
+// convert converts the arguments to the types of the parameters. It returns
+// nil when the function is strict and an argument is NULL.
+func (fn *Function) convert(args []entity.Value, loc *time.Location) ([]entity.Value, error) {
+	vals := make([]entity.Value, len(args))
+	for i, arg := range args {
+		if arg == entity.Null && fn.Strict {
+			return nil, nil
+		}
+		switch fn.argType(i).Family {
+		case types.FloatFamily:
+			if f, ok := types.ToFloat(arg); ok {
+				arg = f
+			}
+		case types.NumericFamily:
+			if d, ok := types.ToDecimal(arg); ok {
+				arg = d
+			}
+		case types.TimestampFamily, types.TimestampTZFamily:
+			if _, ok := arg.(types.DateValue); ok {
+				var err error
+				if arg, err = fn.argType(i).CoerceIn(arg, loc); err != nil {
+					return nil, err
+				}
+			}
+		}
+		vals[i] = arg
+	}
+	return vals, nil
+}
+
+// argType returns the type of the i-th argument.
+func (fn *Function) argType(i int) types.T {
+	if i >= len(fn.Args) {
+		return fn.Args[len(fn.Args)-1]
+	}
+	return fn.Args[i]
+}
+
+// accepts tells how well the function matches arguments of the given types.
+// It returns the number of arguments that have to be converted, or -1 if the
+// arguments don't fit. NULL arguments have the Unknown type and fit any type.
+func (fn *Function) accepts(typs []types.T) int {
+	if len(typs) != len(fn.Args) && !(fn.Variadic && len(typs) >= len(fn.Args)) {
+		return -1
+	}
+	conversions := 0
+	for i, typ := range typs {
+		want := fn.argType(i).Family
+		switch {
+		case want == types.IntFamily && typ.Family == types.IntFamily && fn.argType(i).Oid != typ.Oid:
+			// integers of another width fit, but not as well
+			conversions++
+		case want == typ.Family || want == types.AnyFamily || typ.Family == types.UnknownFamily:
+		case want == types.FloatFamily && typ.IsNumber(),
+			want == types.NumericFamily && typ.Family == types.IntFamily,
+			(want == types.TimestampFamily || want == types.TimestampTZFamily) && typ.Family == types.DateFamily:
+			conversions++
+		default:
+			return -1
+		}
+	}
+	return conversions
+}
+
+// Registry holds functions by name. A name can have several functions that
+// take different arguments.
+type Registry struct {
+	mu    sync.RWMutex
+	funcs map[string][]*Function
+}
+
+func NewRegistry() *Registry {
+	return &Registry{funcs: make(map[string][]*Function)}
+}
+
+// Register adds a function. Names are case insensitive.
+func (r *Registry) Register(fn *Function) error {
+	impls := 0
+	if fn.Impl != nil {
+		impls++
+	}
+	if fn.ZonedImpl != nil {
+		impls++
+	}
+	if fn.ClockImpl != nil {
+		impls++
+	}
+	if fn.SetImpl != nil {
+		impls++
+	}
+	if fn.Name == "" || impls != 1 {
+		return fmt.Errorf("function must have a name and one implementation")
+	}
+	if (fn.SetImpl == nil) != (len(fn.Columns) == 0) {
+		return fmt.Errorf("function %s must have columns if and only if it returns a set", fn.Name)
+	}
+	if fn.Variadic && len(fn.Args) == 0 {
+		return fmt.Errorf("variadic function %s must have an argument", fn.Name)
+	}
+	if fn.Volatility == 0 {
+		return fmt.Errorf("function %s must have a volatility", fn.Name)
+	}
+	name := strings.ToLower(fn.Name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, other := range r.funcs[name] {
+		if other.Variadic == fn.Variadic && reflect.DeepEqual(other.Args, fn.Args) {
+			return fmt.Errorf("function %s already exists with the same argument types", name)
+		}
+	}
+	r.funcs[name] = append(r.funcs[name], fn)
+	return nil
+}
+
+// Lookup finds the function of a name that best fits arguments of the given
+// types: the one converting the fewest of them, or the first registered among
+// those that are as good. It returns nil when there is none.
+func (r *Registry) Lookup(name string, typs []types.T) *Function {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var best *Function
+	least := -1
+	for _, fn := range r.funcs[strings.ToLower(name)] {
+		if n := fn.accepts(typs); n >= 0 && (least < 0 || n < least) {
+			best, least = fn, n
+		}
+	}
+	return best
+}
+
+// Functions is the registry function calls are resolved in. It holds the
+// built-in functions, and applications embedding the database add their own
+// with RegisterFunction.
+var Functions = NewRegistry()
+
+// RegisterFunction adds a function to Functions.
+func RegisterFunction(fn *Function) error {
+	if fn.SetImpl != nil {
+		return fmt.Errorf("function %s returns a set", fn.Name)
+	}
+	return Functions.Register(fn)
+}
+
+// TableFunctions is the registry of the set-returning functions called in
+// FROM clauses.
+var TableFunctions = NewRegistry()
+
+// RegisterTableFunction adds a set-returning function to TableFunctions.
+func RegisterTableFunction(fn *Function) error {
+	if fn.SetImpl == nil {
+		return fmt.Errorf("function %s does not return a set", fn.Name)
+	}
+	return TableFunctions.Register(fn)
+}
@@ -0,0 +1,278 @@
+package sql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+func TestRegistry_Lookup(t *testing.T) {
+	r := NewRegistry()
+	impl := func(args []entity.Value) (entity.Value, error) { return nil, nil }
+	intFn := &Function{Name: "f", Args: []types.T{types.Int}, Return: types.Int, Volatility: Immutable, Impl: impl}
+	floatFn := &Function{Name: "F", Args: []types.T{types.Float}, Return: types.Float, Volatility: Immutable, Impl: impl}
+	varFn := &Function{Name: "f", Args: []types.T{types.Text, types.Any}, Variadic: true, Return: types.Text, Volatility: Volatile, Impl: impl}
+	for _, fn := range []*Function{intFn, floatFn, varFn} {
+		require.NoError(t, r.Register(fn))
+	}
+	tests := []struct {
+		name string
+		typs []types.T
+		want *Function
+	}{
+		{
+			name: "exact",
+			typs: []types.T{types.Float},
+			want: floatFn,
+		},
+		{
+			name: "fewest conversions",
+			typs: []types.T{types.BigInt},
+			want: intFn,
+		},
+		{
+			name: "null matches first registered",
+			typs: []types.T{types.Unknown},
+			want: intFn,
+		},
+		{
+			name: "variadic",
+			typs: []types.T{types.MakeVarChar(3), types.Int, types.Bool},
+			want: varFn,
+		},
+		{
+			name: "variadic needs every argument",
+			typs: []types.T{types.Text},
+		},
+		{
+			name: "no match",
+			typs: []types.T{types.Bool},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, r.Lookup("f", tt.typs))
+		})
+	}
+	assert.Error(t, r.Register(&Function{Name: "f", Args: []types.T{types.Int}, Volatility: Stable, Impl: impl}))
+	assert.Error(t, r.Register(&Function{Name: "g", Volatility: Stable}))
+	assert.Error(t, r.Register(&Function{Name: "g", Impl: impl}))
+}
+
+func TestBuiltins(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []entity.Value
+		want    entity.Value
+		wantErr bool
+	}{
+		{name: "lower", args: []entity.Value{"AbC"}, want: "abc"},
+		{name: "upper", args: []entity.Value{"AbC"}, want: "ABC"},
+		{name: "length", args: []entity.Value{"héllo"}, want: 5},
+		{name: "length", args: []entity.Value{nil}, want: nil},
+		{name: "substring", args: []entity.Value{"hello", 2}, want: "ello"},
+		{name: "substring", args: []entity.Value{"hello", 2, 3}, want: "ell"},
+		{name: "substring", args: []entity.Value{"hello", 0, 3}, want: "he"},
+		{name: "substring", args: []entity.Value{"hello", 9, 3}, want: ""},
+		{name: "substring", args: []entity.Value{"hello", 1, -1}, wantErr: true},
+		{name: "trim", args: []entity.Value{"  hi  "}, want: "hi"},
+		{name: "trim", args: []entity.Value{"xxhixy", "xy"}, want: "hi"},
+		{name: "ltrim", args: []entity.Value{"  hi  "}, want: "hi  "},
+		{name: "rtrim", args: []entity.Value{"  hi  "}, want: "  hi"},
+		{name: "concat", args: []entity.Value{"a", nil, 1, true}, want: "a1t"},
+		{name: "replace", args: []entity.Value{"abcabc", "b", "xy"}, want: "axycaxyc"},
+		{name: "replace", args: []entity.Value{"abc", "", "x"}, want: "abc"},
+		{name: "split_part", args: []entity.Value{"a,b,c", ",", 2}, want: "b"},
+		{name: "split_part", args: []entity.Value{"a,b,c", ",", -1}, want: "c"},
+		{name: "split_part", args: []entity.Value{"a,b,c", ",", 4}, want: ""},
+		{name: "split_part", args: []entity.Value{"a,b,c", ",", 0}, wantErr: true},
+		{name: "abs", args: []entity.Value{-3}, want: 3},
+		{name: "abs", args: []entity.Value{-2.5}, want: 2.5},
+		{name: "round", args: []entity.Value{2.5}, want: 3.0},
+		{name: "round", args: []entity.Value{-2.5}, want: -3.0},
+		{name: "round", args: []entity.Value{3.14159, 2}, want: 3.14},
+		{name: "round", args: []entity.Value{1234, -2}, want: 1200.0},
+		{name: "floor", args: []entity.Value{-1.5}, want: -2.0},
+		{name: "ceil", args: []entity.Value{1.2}, want: 2.0},
+		{name: "mod", args: []entity.Value{7, 3}, want: 1},
+		{name: "mod", args: []entity.Value{-7, 3}, want: -1},
+		{name: "mod", args: []entity.Value{7, 0}, wantErr: true},
+		{name: "abs", args: []entity.Value{types.NewDecimal(-150, 2)}, want: types.NewDecimal(150, 2)},
+		{name: "round", args: []entity.Value{types.NewDecimal(-25, 1)}, want: types.NewDecimal(-3, 0)},
+		{name: "round", args: []entity.Value{types.NewDecimal(1005, 3), 2}, want: types.NewDecimal(101, 2)},
+		{name: "floor", args: []entity.Value{types.NewDecimal(-15, 1)}, want: types.NewDecimal(-2, 0)},
+		{name: "ceil", args: []entity.Value{types.NewDecimal(12, 1)}, want: types.NewDecimal(2, 0)},
+		{name: "mod", args: []entity.Value{types.NewDecimal(75, 1), 2}, want: types.NewDecimal(15, 1)},
+		{name: "mod", args: []entity.Value{types.NewDecimal(75, 1), 0}, wantErr: true},
+		{name: "sqrt", args: []entity.Value{types.NewDecimal(225, 2)}, want: 1.5},
+		{name: "power", args: []entity.Value{2, 10}, want: 1024.0},
+		{name: "power", args: []entity.Value{0, -1}, wantErr: true},
+		{name: "power", args: []entity.Value{-8, 1.0 / 3}, wantErr: true},
+		{name: "sqrt", args: []entity.Value{16}, want: 4.0},
+		{name: "sqrt", args: []entity.Value{-1}, wantErr: true},
+		{name: "date_part", args: []entity.Value{"year", tsVal("2024-03-15 10:20:30.5")}, want: 2024.0},
+		{name: "date_part", args: []entity.Value{"second", tsVal("2024-03-15 10:20:30.5")}, want: 30.5},
+		{name: "date_part", args: []entity.Value{"dow", dateVal("2024-03-15")}, want: 5.0},
+		{name: "date_part", args: []entity.Value{"epoch", tstzVal("1970-01-02 00:00:00+00")}, want: 86400.0},
+		{name: "date_part", args: []entity.Value{"hours", dateVal("2024-03-15")}, wantErr: true},
+		{name: "date_part", args: []entity.Value{"fortnight", tsVal("2024-03-15")}, wantErr: true},
+		{name: "extract", args: []entity.Value{"second", tsVal("2024-03-15 10:20:30.5")}, want: types.NewDecimal(30500000, 6)},
+		{name: "extract", args: []entity.Value{"century", dateVal("2001-01-01")}, want: types.NewDecimal(21, 0)},
+		{name: "extract", args: []entity.Value{"isodow", dateVal("2024-03-17")}, want: types.NewDecimal(7, 0)},
+		{name: "extract", args: []entity.Value{"timezone", tstzVal("2024-03-15 10:20:30+02")}, want: types.NewDecimal(0, 0)},
+		{name: "extract", args: []entity.Value{"minute", types.TimeValue(3723500000)}, want: types.NewDecimal(2, 0)},
+		{name: "extract", args: []entity.Value{"hour", intervalVal("1 day 26 hours")}, want: types.NewDecimal(26, 0)},
+		{name: "extract", args: []entity.Value{"epoch", intervalVal("1 year 1 mon 1 day 1.5 sec")}, want: types.NewDecimal(34236001500000, 6)},
+		{name: "date_trunc", args: []entity.Value{"month", tsVal("2024-03-15 10:20:30")}, want: tsVal("2024-03-01")},
+		{name: "date_trunc", args: []entity.Value{"week", tstzVal("2024-03-17 10:20:30+02")}, want: tstzVal("2024-03-11 00:00:00+00")},
+		{name: "date_trunc", args: []entity.Value{"quarter", dateVal("2024-08-15")}, want: tsVal("2024-07-01")},
+		{name: "date_trunc", args: []entity.Value{"hour", tsVal("2024-03-15 10:20:30.25")}, want: tsVal("2024-03-15 10:00:00")},
+		{name: "date_trunc", args: []entity.Value{"century", tsVal("2000-12-31")}, want: tsVal("1901-01-01")},
+		{name: "timezone", args: []entity.Value{"Asia/Tokyo", tstzVal("2024-03-15 10:20:30+00")}, want: tsVal("2024-03-15 19:20:30")},
+		{name: "timezone", args: []entity.Value{"Asia/Tokyo", tsVal("2024-03-15 19:20:30")}, want: tstzVal("2024-03-15 10:20:30+00")},
+		{name: "timezone", args: []entity.Value{"Mars/Olympus", tsVal("2024-03-15")}, wantErr: true},
+		{name: "to_timestamp", args: []entity.Value{86400.5}, want: tstzVal("1970-01-02 00:00:00.5+00")},
+		{name: "jsonb_build_object", args: []entity.Value{"b", 1, "a", nil}, want: types.JSONValue(`{"a": null, "b": 1}`)},
+		{name: "jsonb_build_object", args: []entity.Value{1, dateVal("2024-03-15")}, want: types.JSONValue(`{"1": "2024-03-15"}`)},
+		{name: "jsonb_build_object", args: []entity.Value{"a"}, wantErr: true},
+		{name: "jsonb_build_object", args: []entity.Value{nil, 1}, wantErr: true},
+		{name: "jsonb_build_object", want: types.JSONValue("{}")},
+		{name: "jsonb_build_array", args: []entity.Value{1.5, true, types.JSONValue(`{"a": [1]}`)}, want: types.JSONValue(`[1.5, true, {"a": [1]}]`)},
+		{name: "to_jsonb", args: []entity.Value{"a\"b"}, want: types.JSONValue(`"a\"b"`)},
+		{name: "jsonb_typeof", args: []entity.Value{types.JSONValue(`[1]`)}, want: "array"},
+		{name: "jsonb_typeof", args: []entity.Value{types.JSONValue(`null`)}, want: "null"},
+		{name: "jsonb_array_length", args: []entity.Value{types.JSONValue(`[1, [2, 3]]`)}, want: 2},
+		{name: "jsonb_array_length", args: []entity.Value{types.JSONValue(`{}`)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typs := make([]types.T, len(tt.args))
+			for i, arg := range tt.args {
+				typs[i] = types.Of(arg)
+			}
+			fn := Functions.Lookup(tt.name, typs)
+			require.NotNil(t, fn)
+			got, err := fn.Call(tt.args)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBuiltins_Now(t *testing.T) {
+	fn := Functions.Lookup("now", nil)
+	require.NotNil(t, fn)
+	assert.Equal(t, Stable, fn.Volatility)
+	got, err := fn.Call(nil)
+	require.NoError(t, err)
+	year, err := Functions.Lookup("date_part", []types.T{types.Text, types.TimestampTZ}).Call([]entity.Value{"year", got})
+	require.NoError(t, err)
+	assert.True(t, year.(float64) >= 2020)
+}
+
+func TestBuiltins_TimeZone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	val := tstzVal("2024-03-10 06:30:00+00")
+	fn := Functions.Lookup("date_trunc", []types.T{types.Text, types.TimestampTZ})
+	require.NotNil(t, fn)
+	got, err := fn.CallIn([]entity.Value{"day", val}, loc)
+	require.NoError(t, err)
+	assert.Equal(t, tstzVal("2024-03-10 05:00:00+00"), got)
+	fn = Functions.Lookup("extract", []types.T{types.Text, types.TimestampTZ})
+	require.NotNil(t, fn)
+	got, err = fn.CallIn([]entity.Value{"hour", val}, loc)
+	require.NoError(t, err)
+	assert.Equal(t, types.NewDecimal(1, 0), got)
+}
+
+func TestTableFunctions(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     entity.Value
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name: "jsonb_array_elements",
+			arg:  types.JSONValue(`[1, "a", null]`),
+			want: [][]entity.Value{{types.JSONValue("1")}, {types.JSONValue(`"a"`)}, {types.JSONValue("null")}},
+		},
+		{
+			name: "jsonb_array_elements_text",
+			arg:  types.JSONValue(`[1, "a", null]`),
+			want: [][]entity.Value{{"1"}, {"a"}, {nil}},
+		},
+		{
+			name:    "jsonb_array_elements",
+			arg:     types.JSONValue(`{"a": 1}`),
+			wantErr: true,
+		},
+		{
+			name: "jsonb_array_elements",
+			arg:  nil,
+		},
+		{
+			name: "jsonb_each",
+			arg:  types.JSONValue(`{"bb": [1], "a": "x"}`),
+			want: [][]entity.Value{{"a", types.JSONValue(`"x"`)}, {"bb", types.JSONValue("[1]")}},
+		},
+		{
+			name: "jsonb_object_keys",
+			arg:  types.JSONValue(`{"bb": 1, "a": 2}`),
+			want: [][]entity.Value{{"a"}, {"bb"}},
+		},
+		{
+			name:    "jsonb_object_keys",
+			arg:     types.JSONValue(`[1]`),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := TableFunctions.Lookup(tt.name, []types.T{types.Of(tt.arg)})
+			require.NotNil(t, fn)
+			got, err := fn.CallSet([]entity.Value{tt.arg}, time.UTC)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+	assert.Nil(t, Functions.Lookup("jsonb_array_elements", []types.T{types.JSONB}))
+}
+
+func tsVal(s string) entity.Value {
+	return mustCoerce(types.Timestamp, s)
+}
+
+func tstzVal(s string) entity.Value {
+	return mustCoerce(types.TimestampTZ, s)
+}
+
+func dateVal(s string) entity.Value {
+	return mustCoerce(types.Date, s)
+}
+
+func intervalVal(s string) entity.Value {
+	return mustCoerce(types.Interval, s)
+}
+
+func mustCoerce(typ types.T, s string) entity.Value {
+	v, err := typ.Coerce(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
@@ -3,8 +3,118 @@ package parser
 import (
 	"fmt"
 	"strings"
+)
+
+const (
+	OpAnd = "AND"
+	OpOr  = "OR"
+	OpNot = "NOT"
+	OpAdd = "+"
+	OpSub = "-"
+	OpMul = "*"
+	OpDiv = "/"
+	OpMod = "%"
+
+	OpLike    = "LIKE"
+	OpILike   = "ILIKE"
+	OpSimilar = "SIMILAR TO"
+
+	// regular expression matches, case insensitive with *
+	OpMatch     = "~"
+	OpIMatch    = "~*"
+	OpNotMatch  = "!~"
+	OpNotIMatch = "!~*"
+
+	// JSON operators
+	OpJSONField     = "->"
+	OpJSONFieldText = "->>"
+	OpJSONPath      = "#>"
+	OpJSONPathText  = "#>>"
+	OpContains      = "@>"
+	OpContainedBy   = "<@"
+	OpHasKey        = "?"
+)
+
+// NullsOrder places NULLs before or after other values when sorting. By
+// default NULLs sort as if larger than any other value.
+type NullsOrder int
+
+const (
+	NullsDefault NullsOrder = iota
+	NullsFirst
+	NullsLast
+)
+
+type JoinKind int
+
+const (
+	JoinInner JoinKind = iota
+	JoinLeft
+	JoinRight
+	JoinFull
+	JoinCross
+	// JoinSemi and JoinAnti aren't SQL syntax. The planner runs EXISTS and
+	// IN subqueries as semi and anti joins.
+	JoinSemi
+	JoinAnti
+)
+
+var joinNames = map[JoinKind]string{
+	JoinInner: "JOIN",
+	JoinLeft:  "LEFT JOIN",
+	JoinRight: "RIGHT JOIN",
+	JoinFull:  "FULL JOIN",
+	JoinCross: "CROSS JOIN",
+	JoinSemi:  "SEMI JOIN",
+	JoinAnti:  "ANTI JOIN",
+}
+
+type ColumnOptionKind int
+
+const (
+	OptionNotNull ColumnOptionKind = iota + 1
+	OptionNull
+	OptionDefault
+)
+
+// SetOpKind is the way a SetOp combines the rows of its queries.
+type SetOpKind int
+
+const (
+	SetUnion SetOpKind = iota
+	SetIntersect
+	SetExcept
+)
+
+var setOpNames = map[SetOpKind]string{
+	SetUnion:     "UNION",
+	SetIntersect: "INTERSECT",
+	SetExcept:    "EXCEPT",
+}
+
+func (kind SetOpKind) String() string {
+	return setOpNames[kind]
+}
+
+// FrameMode tells whether the bounds of a window frame count rows, or rows
+// with different ORDER BY values.
+type FrameMode int
+
+const (
+	FrameRows FrameMode = iota
+	FrameRange
+)
+
+// BoundKind is the kind of a bound of a window frame. Bounds must not come
+// before the start bound in this order.
+type BoundKind int
 
-	"github.com/sirupsen/logrus"
+const (
+	UnboundedPreceding BoundKind = iota + 1
+	Preceding
+	CurrentRow
+	Following
+	UnboundedFollowing
 )
 
 type (
@@ -13,76 +123,1200 @@ type (
 		String() string
 	}
 
+	// Select is a query. A compound query combines the rows of the queries
+	// of SetOp instead of listing columns, and its ORDER BY and LIMIT apply
+	// to the combined rows.
 	Select struct {
-		Cols  []string
-		From  *From
-		Where *Where
+		With     *With
+		SetOp    *SetOp
+		Distinct *Distinct
+		Cols     []*SelectItem
+		From     *From
+		Where    *Where
+		GroupBy  []Expr
+		Having   Expr
+		OrderBy  []*OrderItem
+		Limit    *Limit
 	}
 
-	From struct {
+	// Distinct removes duplicate rows from the result of a query. With On
+	// it keeps the first row of each set of rows equal on its expressions.
+	Distinct struct {
+		On []Expr
+	}
+
+	// With lists the common table expressions a query can refer to by name.
+	// Those of a recursive WITH can also refer to themselves.
+	With struct {
+		Recursive bool
+		CTEs      []*CTE
+	}
+
+	// CTE is a named query of a WITH clause. Columns renames the columns of
+	// the query.
+	CTE struct {
+		Name    string
+		Columns []string
+		Query   *Select
+	}
+
+	// SetOp combines the rows of two queries. Duplicate rows are removed
+	// unless All is set.
+	SetOp struct {
+		Op    SetOpKind
+		All   bool
+		Left  *Select
+		Right *Select
+	}
+
+	OrderItem struct {
+		Expr  Expr
+		Desc  bool
+		Nulls NullsOrder
+	}
+
+	SelectItem struct {
+		Expr  Expr
+		Alias string
+	}
+
+	// Limit restricts the number of rows returned by a query. A nil Count
+	// returns all rows after Offset.
+	Limit struct {
+		Count  Expr
+		Offset Expr
+	}
+
+	Insert struct {
+		TableName string
+		Cols      []string
+		Rows      [][]Expr
+	}
+
+	Update struct {
 		TableName string
+		Set       []*Assignment
+		Where     *Where
+	}
+
+	Assignment struct {
+		Column string
+		Value  Expr
+	}
+
+	Delete struct {
+		TableName string
+		Where     *Where
+	}
+
+	CreateTable struct {
+		TableName   string
+		IfNotExists bool
+		Columns     []*ColumnDef
+	}
+
+	ColumnDef struct {
+		Name    string
+		Type    *TypeName
+		Options []*ColumnOption
+	}
+
+	ColumnOption struct {
+		Kind  ColumnOptionKind
+		Value Expr
+	}
+
+	TypeName struct {
+		Name      string
+		Modifiers []int
+	}
+
+	DropTable struct {
+		TableNames []string
+		IfExists   bool
+	}
+
+	// CreateIndex adds an index over a column of a table. Name is empty
+	// when the index is named after them, and Method when it is the
+	// default.
+	CreateIndex struct {
+		Name        string
+		TableName   string
+		Method      string
+		Column      string
+		IfNotExists bool
+	}
+
+	// SetVar changes a setting of the session. An empty Value restores its
+	// default.
+	SetVar struct {
+		Name  string
+		Value string
+	}
+
+	// ShowVar returns the value of a setting of the session.
+	ShowVar struct {
+		Name string
+	}
+
+	// From lists the comma separated table expressions of a FROM clause.
+	From struct {
+		Tables []TableExpr
+	}
+
+	TableExpr interface {
+		iTableExpr()
+		String() string
+	}
+
+	// TableRef refers to a table, under its name unless Alias is set.
+	TableRef struct {
+		Name  string
+		Alias string
+	}
+
+	// Join joins two table expressions. Cond is nil for cross joins.
+	Join struct {
+		Kind  JoinKind
+		Left  TableExpr
+		Right TableExpr
+		Cond  *JoinCond
+	}
+
+	// DerivedTable is a subquery in a FROM clause, referred to by Alias.
+	DerivedTable struct {
+		Select *Select
+		Alias  string
+	}
+
+	// FuncTable is a call of a set-returning function in a FROM clause,
+	// referred to by Alias or else the name of the function.
+	FuncTable struct {
+		Call  *FuncCall
+		Alias string
+	}
+
+	// JoinCond is the condition of a join. Natural joins and joins with
+	// Using match rows on the columns of the same name.
+	JoinCond struct {
+		On      Expr
+		Using   []string
+		Natural bool
 	}
 
 	Where struct {
-		Conditions []*Condition
+		Expr Expr
+	}
+
+	Expr interface {
+		iExpr()
+		String() string
+	}
+
+	ColumnRef struct {
+		Table string
+		Name  string
+	}
+
+	// Star stands for every column of the input, or of one table of the
+	// input when Table is set.
+	Star struct {
+		Table string
+	}
+
+	Literal struct {
+		Value interface{}
+	}
+
+	Default struct{}
+
+	BinaryExpr struct {
+		Op  string
+		LHS Expr
+		RHS Expr
+	}
+
+	UnaryExpr struct {
+		Op   string
+		Expr Expr
+	}
+
+	// FuncCall calls a function by name. Star is set for count(*), and
+	// Distinct restricts an aggregate to the distinct values of its
+	// arguments.
+	FuncCall struct {
+		Name     string
+		Args     []Expr
+		Star     bool
+		Distinct bool
+		// Over is set on calls of window functions
+		Over *WindowSpec
+	}
+
+	// WindowSpec is the window of a window function call. The rows of the
+	// query are partitioned and ordered, and the function is evaluated for
+	// each row over the rows of its partition in the frame around it.
+	WindowSpec struct {
+		PartitionBy []Expr
+		OrderBy     []*OrderItem
+		Frame       *Frame
+	}
+
+	// Frame selects the rows of a partition a window function sees. Without
+	// one, a window with ORDER BY frames a row with the rows up to its last
+	// peer and a window without ORDER BY frames the whole partition.
+	Frame struct {
+		Mode  FrameMode
+		Start *FrameBound
+		End   *FrameBound
 	}
 
-	Condition struct {
-		Relation string
-		LHS      interface{}
-		RHS      interface{}
+	// FrameBound is a bound of a window frame. Offset is set for PRECEDING
+	// and FOLLOWING.
+	FrameBound struct {
+		Kind   BoundKind
+		Offset Expr
+	}
+
+	// Subquery is a parenthesized query used as a value. It must return a
+	// single column and at most one row.
+	Subquery struct {
+		Select *Select
+	}
+
+	// ExistsExpr is true when its subquery returns any rows.
+	ExistsExpr struct {
+		Subquery *Select
+	}
+
+	// InExpr tests whether the value of Expr is among the values of List,
+	// or among the rows returned by a subquery.
+	InExpr struct {
+		Expr     Expr
+		List     []Expr
+		Subquery *Select
+		Not      bool
+	}
+
+	// BetweenExpr tests whether the value of Expr lies between Low and High,
+	// both included.
+	BetweenExpr struct {
+		Expr Expr
+		Low  Expr
+		High Expr
+		Not  bool
+	}
+
+	// LikeExpr matches a string against a pattern. LIKE and ILIKE patterns
+	// stand for any string with %, and for any character with _. SIMILAR TO
+	// patterns are SQL regular expressions. Escape replaces the backslash
+	// escaping these characters, or disables escaping when empty.
+	LikeExpr struct {
+		Op      string
+		Expr    Expr
+		Pattern Expr
+		Escape  Expr
+		Not     bool
+	}
+
+	// IsNullExpr tests whether the value of Expr is NULL.
+	IsNullExpr struct {
+		Expr Expr
+		Not  bool
+	}
+
+	// IsDistinctExpr tests whether two values differ, taking NULL to be
+	// a value equal to itself.
+	IsDistinctExpr struct {
+		LHS Expr
+		RHS Expr
+		Not bool
+	}
+
+	// CaseExpr picks the result of the first WHEN clause whose condition
+	// holds, or Else. With an Operand the conditions are values compared to
+	// it.
+	CaseExpr struct {
+		Operand Expr
+		Whens   []*When
+		Else    Expr
+	}
+
+	// When is a WHEN clause of a CASE expression.
+	When struct {
+		Cond   Expr
+		Result Expr
+	}
+
+	// CastExpr converts the value of Expr to a type. A literal preceded by
+	// the name of a type, like DATE '2026-01-01', is a cast of the string.
+	CastExpr struct {
+		Expr Expr
+		Type *TypeName
 	}
 )
 
 func (*Select) iStatement() {}
 func (sel *Select) String() string {
-	return fmt.Sprintf("SELECT %s\n--%s", sel.Cols, sel.From.String())
+	cols := make([]string, len(sel.Cols))
+	for i, col := range sel.Cols {
+		cols[i] = col.String()
+	}
+	res := "SELECT "
+	if sel.Distinct != nil {
+		res += sel.Distinct.String() + " "
+	}
+	res += strings.Join(cols, ", ")
+	if sel.SetOp != nil {
+		res = sel.SetOp.String()
+	}
+	if sel.With != nil {
+		res = sel.With.String() + "\n--" + res
+	}
+	if sel.From != nil {
+		res += "\n--" + sel.From.String()
+	}
+	if sel.Where != nil {
+		res += "\n--" + sel.Where.String()
+	}
+	if len(sel.GroupBy) > 0 {
+		exprs := make([]string, len(sel.GroupBy))
+		for i, expr := range sel.GroupBy {
+			exprs[i] = expr.String()
+		}
+		res += "\n--GROUP BY " + strings.Join(exprs, ", ")
+	}
+	if sel.Having != nil {
+		res += fmt.Sprintf("\n--HAVING %s", sel.Having)
+	}
+	if len(sel.OrderBy) > 0 {
+		items := make([]string, len(sel.OrderBy))
+		for i, item := range sel.OrderBy {
+			items[i] = item.String()
+		}
+		res += "\n--ORDER BY " + strings.Join(items, ", ")
+	}
+	if sel.Limit != nil {
+		res += "\n--" + sel.Limit.String()
+	}
+	return res
+}
+
+func (d *Distinct) String() string {
+	if len(d.On) == 0 {
+		return "DISTINCT"
+	}
+	exprs := make([]string, len(d.On))
+	for i, expr := range d.On {
+		exprs[i] = expr.String()
+	}
+	return fmt.Sprintf("DISTINCT ON (%s)", strings.Join(exprs, ", "))
+}
+
+func (with *With) String() string {
+	ctes := make([]string, len(with.CTEs))
+	for i, cte := range with.CTEs {
+		ctes[i] = cte.String()
+	}
+	if with.Recursive {
+		return "WITH RECURSIVE " + strings.Join(ctes, ", ")
+	}
+	return "WITH " + strings.Join(ctes, ", ")
+}
+
+func (cte *CTE) String() string {
+	if len(cte.Columns) > 0 {
+		return fmt.Sprintf("%s(%s) AS (%s)", cte.Name, strings.Join(cte.Columns, ", "), cte.Query)
+	}
+	return fmt.Sprintf("%s AS (%s)", cte.Name, cte.Query)
+}
+
+func (op *SetOp) String() string {
+	name := op.Op.String()
+	if op.All {
+		name += " ALL"
+	}
+	return fmt.Sprintf("(%s)\n--%s\n--(%s)", op.Left, name, op.Right)
+}
+
+func (item *OrderItem) String() string {
+	res := item.Expr.String()
+	if item.Desc {
+		res += " DESC"
+	}
+	switch item.Nulls {
+	case NullsFirst:
+		res += " NULLS FIRST"
+	case NullsLast:
+		res += " NULLS LAST"
+	}
+	return res
+}
+
+func (item *SelectItem) String() string {
+	if item.Alias == "" {
+		return item.Expr.String()
+	}
+	return fmt.Sprintf("%s AS %s", item.Expr, item.Alias)
+}
+
+func (*Insert) iStatement() {}
+func (ins *Insert) String() string {
+	rows := make([]string, len(ins.Rows))
+	for i, row := range ins.Rows {
+		vals := make([]string, len(row))
+		for j, val := range row {
+			vals[j] = val.String()
+		}
+		rows[i] = "(" + strings.Join(vals, ", ") + ")"
+	}
+	cols := ""
+	if len(ins.Cols) > 0 {
+		cols = " (" + strings.Join(ins.Cols, ", ") + ")"
+	}
+	return fmt.Sprintf("INSERT INTO %s%s VALUES %s", ins.TableName, cols, strings.Join(rows, ", "))
+}
+
+func (*Update) iStatement() {}
+func (upd *Update) String() string {
+	set := make([]string, len(upd.Set))
+	for i, a := range upd.Set {
+		set[i] = a.String()
+	}
+	res := fmt.Sprintf("UPDATE %s SET %s", upd.TableName, strings.Join(set, ", "))
+	if upd.Where != nil {
+		res += "\n--" + upd.Where.String()
+	}
+	return res
+}
+
+func (a *Assignment) String() string {
+	return fmt.Sprintf("%s = %s", a.Column, a.Value)
+}
+
+func (*Delete) iStatement() {}
+func (del *Delete) String() string {
+	res := fmt.Sprintf("DELETE FROM %s", del.TableName)
+	if del.Where != nil {
+		res += "\n--" + del.Where.String()
+	}
+	return res
+}
+
+func (*CreateTable) iStatement() {}
+func (ct *CreateTable) String() string {
+	cols := make([]string, len(ct.Columns))
+	for i, col := range ct.Columns {
+		cols[i] = col.String()
+	}
+	ifNotExists := ""
+	if ct.IfNotExists {
+		ifNotExists = "IF NOT EXISTS "
+	}
+	return fmt.Sprintf("CREATE TABLE %s%s (%s)", ifNotExists, ct.TableName, strings.Join(cols, ", "))
+}
+
+func (def *ColumnDef) String() string {
+	res := def.Name + " " + def.Type.String()
+	for _, opt := range def.Options {
+		res += " " + opt.String()
+	}
+	return res
+}
+
+func (opt *ColumnOption) String() string {
+	switch opt.Kind {
+	case OptionNotNull:
+		return "NOT NULL"
+	case OptionNull:
+		return "NULL"
+	case OptionDefault:
+		return "DEFAULT " + opt.Value.String()
+	}
+	return ""
+}
+
+func (typ *TypeName) String() string {
+	if len(typ.Modifiers) == 0 {
+		return typ.Name
+	}
+	mods := make([]string, len(typ.Modifiers))
+	for i, mod := range typ.Modifiers {
+		mods[i] = fmt.Sprintf("%d", mod)
+	}
+	return fmt.Sprintf("%s(%s)", typ.Name, strings.Join(mods, ","))
+}
+
+func (*DropTable) iStatement() {}
+func (dt *DropTable) String() string {
+	ifExists := ""
+	if dt.IfExists {
+		ifExists = "IF EXISTS "
+	}
+	return fmt.Sprintf("DROP TABLE %s%s", ifExists, strings.Join(dt.TableNames, ", "))
+}
+
+func (*CreateIndex) iStatement() {}
+func (ci *CreateIndex) String() string {
+	var b strings.Builder
+	b.WriteString("CREATE INDEX ")
+	if ci.IfNotExists {
+		b.WriteString("IF NOT EXISTS ")
+	}
+	if ci.Name != "" {
+		b.WriteString(ci.Name + " ")
+	}
+	b.WriteString("ON " + ci.TableName)
+	if ci.Method != "" {
+		b.WriteString(" USING " + ci.Method)
+	}
+	fmt.Fprintf(&b, " (%s)", ci.Column)
+	return b.String()
+}
+
+func (*SetVar) iStatement() {}
+func (set *SetVar) String() string {
+	if set.Value == "" {
+		return fmt.Sprintf("SET %s TO DEFAULT", set.Name)
+	}
+	return fmt.Sprintf("SET %s TO '%s'", set.Name, strings.ReplaceAll(set.Value, "'", "''"))
+}
+
+func (*ShowVar) iStatement() {}
+func (show *ShowVar) String() string {
+	return "SHOW " + show.Name
 }
 
 func (*From) iStatement() {}
 func (from *From) String() string {
-	return fmt.Sprintf("FROM %s", from.TableName)
+	tables := make([]string, len(from.Tables))
+	for i, table := range from.Tables {
+		tables[i] = table.String()
+	}
+	return fmt.Sprintf("FROM %s", strings.Join(tables, ", "))
+}
+
+func (*TableRef) iTableExpr() {}
+func (ref *TableRef) String() string {
+	if ref.Alias == "" {
+		return ref.Name
+	}
+	return fmt.Sprintf("%s AS %s", ref.Name, ref.Alias)
+}
+
+func (*Join) iTableExpr() {}
+func (join *Join) String() string {
+	if join.Cond == nil {
+		return fmt.Sprintf("(%s %s %s)", join.Left, joinNames[join.Kind], join.Right)
+	}
+	if join.Cond.Natural {
+		return fmt.Sprintf("(%s NATURAL %s %s)", join.Left, joinNames[join.Kind], join.Right)
+	}
+	if join.Cond.On != nil {
+		return fmt.Sprintf("(%s %s %s ON %s)", join.Left, joinNames[join.Kind], join.Right, join.Cond.On)
+	}
+	return fmt.Sprintf("(%s %s %s USING (%s))", join.Left, joinNames[join.Kind], join.Right, strings.Join(join.Cond.Using, ", "))
 }
 
-func (*Condition) iStatement() {}
-func (cond *Condition) String() string {
-	return fmt.Sprintf("%v %s %v", cond.LHS, cond.Relation, cond.RHS)
+func (*DerivedTable) iTableExpr() {}
+func (dt *DerivedTable) String() string {
+	return fmt.Sprintf("(%s) AS %s", dt.Select, dt.Alias)
+}
+
+func (*FuncTable) iTableExpr() {}
+func (ft *FuncTable) String() string {
+	if ft.Alias == "" {
+		return ft.Call.String()
+	}
+	return fmt.Sprintf("%s AS %s", ft.Call, ft.Alias)
+}
+
+func (*BinaryExpr) iExpr() {}
+func (expr *BinaryExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", expr.LHS, expr.Op, expr.RHS)
+}
+
+func (*UnaryExpr) iExpr() {}
+func (expr *UnaryExpr) String() string {
+	return fmt.Sprintf("(%s %s)", expr.Op, expr.Expr)
+}
+
+func (*FuncCall) iExpr() {}
+func (call *FuncCall) String() string {
+	if call.Star {
+		return call.Name + "(*)"
+	}
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		args[i] = arg.String()
+	}
+	res := strings.Join(args, ", ")
+	if call.Distinct {
+		res = "DISTINCT " + res
+	}
+	if call.Over != nil {
+		return fmt.Sprintf("%s(%s) OVER (%s)", call.Name, res, call.Over)
+	}
+	return fmt.Sprintf("%s(%s)", call.Name, res)
+}
+
+func (spec *WindowSpec) String() string {
+	parts := make([]string, 0, 3)
+	if len(spec.PartitionBy) > 0 {
+		exprs := make([]string, len(spec.PartitionBy))
+		for i, expr := range spec.PartitionBy {
+			exprs[i] = expr.String()
+		}
+		parts = append(parts, "PARTITION BY "+strings.Join(exprs, ", "))
+	}
+	if len(spec.OrderBy) > 0 {
+		items := make([]string, len(spec.OrderBy))
+		for i, item := range spec.OrderBy {
+			items[i] = item.String()
+		}
+		parts = append(parts, "ORDER BY "+strings.Join(items, ", "))
+	}
+	if spec.Frame != nil {
+		parts = append(parts, spec.Frame.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func (frame *Frame) String() string {
+	mode := "ROWS"
+	if frame.Mode == FrameRange {
+		mode = "RANGE"
+	}
+	return fmt.Sprintf("%s BETWEEN %s AND %s", mode, frame.Start, frame.End)
+}
+
+func (bound *FrameBound) String() string {
+	switch bound.Kind {
+	case UnboundedPreceding:
+		return "UNBOUNDED PRECEDING"
+	case Preceding:
+		return fmt.Sprintf("%s PRECEDING", bound.Offset)
+	case CurrentRow:
+		return "CURRENT ROW"
+	case Following:
+		return fmt.Sprintf("%s FOLLOWING", bound.Offset)
+	}
+	return "UNBOUNDED FOLLOWING"
+}
+
+func (*Subquery) iExpr() {}
+func (sub *Subquery) String() string {
+	return fmt.Sprintf("(%s)", sub.Select)
+}
+
+func (*ExistsExpr) iExpr() {}
+func (expr *ExistsExpr) String() string {
+	return fmt.Sprintf("EXISTS (%s)", expr.Subquery)
+}
+
+func (*InExpr) iExpr() {}
+func (expr *InExpr) String() string {
+	op := "IN"
+	if expr.Not {
+		op = "NOT IN"
+	}
+	if expr.Subquery == nil {
+		items := make([]string, len(expr.List))
+		for i, item := range expr.List {
+			items[i] = item.String()
+		}
+		return fmt.Sprintf("(%s %s (%s))", expr.Expr, op, strings.Join(items, ", "))
+	}
+	return fmt.Sprintf("(%s %s (%s))", expr.Expr, op, expr.Subquery)
+}
+
+func (*BetweenExpr) iExpr() {}
+func (expr *BetweenExpr) String() string {
+	op := "BETWEEN"
+	if expr.Not {
+		op = "NOT BETWEEN"
+	}
+	return fmt.Sprintf("(%s %s %s AND %s)", expr.Expr, op, expr.Low, expr.High)
+}
+
+func (*LikeExpr) iExpr() {}
+func (expr *LikeExpr) String() string {
+	op := expr.Op
+	if expr.Not {
+		op = "NOT " + op
+	}
+	if expr.Escape != nil {
+		return fmt.Sprintf("(%s %s %s ESCAPE %s)", expr.Expr, op, expr.Pattern, expr.Escape)
+	}
+	return fmt.Sprintf("(%s %s %s)", expr.Expr, op, expr.Pattern)
+}
+
+func (*IsNullExpr) iExpr() {}
+func (expr *IsNullExpr) String() string {
+	if expr.Not {
+		return fmt.Sprintf("(%s IS NOT NULL)", expr.Expr)
+	}
+	return fmt.Sprintf("(%s IS NULL)", expr.Expr)
+}
+
+func (*IsDistinctExpr) iExpr() {}
+func (expr *IsDistinctExpr) String() string {
+	if expr.Not {
+		return fmt.Sprintf("(%s IS NOT DISTINCT FROM %s)", expr.LHS, expr.RHS)
+	}
+	return fmt.Sprintf("(%s IS DISTINCT FROM %s)", expr.LHS, expr.RHS)
+}
+
+func (*CaseExpr) iExpr() {}
+func (expr *CaseExpr) String() string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	if expr.Operand != nil {
+		b.WriteString(" " + expr.Operand.String())
+	}
+	for _, when := range expr.Whens {
+		b.WriteString(" " + when.String())
+	}
+	if expr.Else != nil {
+		b.WriteString(" ELSE " + expr.Else.String())
+	}
+	b.WriteString(" END")
+	return b.String()
+}
+
+func (when *When) String() string {
+	return fmt.Sprintf("WHEN %s THEN %s", when.Cond, when.Result)
+}
+
+func (*CastExpr) iExpr() {}
+func (expr *CastExpr) String() string {
+	return fmt.Sprintf("CAST(%s AS %s)", expr.Expr, expr.Type)
+}
+
+func (*ColumnRef) iExpr() {}
+func (ref *ColumnRef) String() string {
+	if ref.Table == "" {
+		return ref.Name
+	}
+	return ref.Table + "." + ref.Name
+}
+
+func (*Star) iExpr() {}
+func (star *Star) String() string {
+	if star.Table == "" {
+		return "*"
+	}
+	return star.Table + ".*"
+}
+
+func (*Literal) iExpr() {}
+func (lit *Literal) String() string {
+	switch v := lit.Value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func (*Default) iExpr() {}
+func (*Default) String() string {
+	return "DEFAULT"
+}
+
+func (*Limit) iStatement() {}
+func (limit *Limit) String() string {
+	res := make([]string, 0, 2)
+	if limit.Count != nil {
+		res = append(res, fmt.Sprintf("LIMIT %s", limit.Count))
+	}
+	if limit.Offset != nil {
+		res = append(res, fmt.Sprintf("OFFSET %s", limit.Offset))
+	}
+	return strings.Join(res, " ")
 }
 
 func (*Where) iStatement() {}
 func (where *Where) String() string {
-	conds := make([]string, len(where.Conditions))
-	for i, cond := range where.Conditions {
-		conds[i] = cond.String()
+	return fmt.Sprintf("WHERE %s", where.Expr)
+}
+
+func NewSelect(distinct *Distinct, cols []*SelectItem, from Statement, where *Where, groupBy []Expr, having Expr) Statement {
+	sel := &Select{
+		Distinct: distinct,
+		Cols:     cols,
+		Where:    where,
+		GroupBy:  groupBy,
+		Having:   having,
+	}
+	if from != nil {
+		sel.From = from.(*From)
 	}
-	return fmt.Sprintf("WHERE %s", strings.Join(conds, "AND"))
+	return sel
 }
 
-func NewSelect(cols []string, from Statement, where *Where) Statement {
-	logrus.Infof("colexpr: %s", cols)
+// NewQuery adds the WITH, ORDER BY and LIMIT clauses to a simple or compound
+// query.
+func NewQuery(with *With, body Statement, orderBy []*OrderItem, limit *Limit) Statement {
+	sel := body.(*Select)
+	sel.With = with
+	sel.OrderBy = orderBy
+	sel.Limit = limit
+	return sel
+}
+
+// NewCompound combines the rows of two queries.
+func NewCompound(op SetOpKind, all bool, left, right Statement) Statement {
 	return &Select{
-		Cols:  cols,
-		From:  from.(*From),
-		Where: where,
+		SetOp: &SetOp{
+			Op:    op,
+			All:   all,
+			Left:  left.(*Select),
+			Right: right.(*Select),
+		},
 	}
 }
 
-func NewFrom(tableName string) Statement {
-	return &From{
+func NewDistinct(on []Expr) *Distinct {
+	return &Distinct{
+		On: on,
+	}
+}
+
+func NewWith(recursive bool, ctes []*CTE) *With {
+	return &With{
+		Recursive: recursive,
+		CTEs:      ctes,
+	}
+}
+
+func NewCTE(name string, cols []string, query Statement) *CTE {
+	return &CTE{
+		Name:    name,
+		Columns: cols,
+		Query:   query.(*Select),
+	}
+}
+
+func NewSelectItem(expr Expr, alias string) *SelectItem {
+	return &SelectItem{
+		Expr:  expr,
+		Alias: alias,
+	}
+}
+
+func NewOrderItem(expr Expr, desc bool, nulls NullsOrder) *OrderItem {
+	return &OrderItem{
+		Expr:  expr,
+		Desc:  desc,
+		Nulls: nulls,
+	}
+}
+
+// NewLimit combines a LIMIT or FETCH FIRST count with an OFFSET. Either may be
+// missing from the query.
+func NewLimit(count, offset Expr) *Limit {
+	return &Limit{
+		Count:  count,
+		Offset: offset,
+	}
+}
+
+func NewInsert(tableName string, cols []string, rows [][]Expr) Statement {
+	return &Insert{
+		TableName: tableName,
+		Cols:      cols,
+		Rows:      rows,
+	}
+}
+
+func NewUpdate(tableName string, set []*Assignment, where *Where) Statement {
+	return &Update{
+		TableName: tableName,
+		Set:       set,
+		Where:     where,
+	}
+}
+
+func NewAssignment(column string, value Expr) *Assignment {
+	return &Assignment{
+		Column: column,
+		Value:  value,
+	}
+}
+
+func NewDelete(tableName string, where *Where) Statement {
+	return &Delete{
 		TableName: tableName,
+		Where:     where,
 	}
 }
 
-func NewCondition(relation string, lhs interface{}, rhs interface{}) *Condition {
-	return &Condition{
-		Relation: relation,
-		LHS:      lhs,
-		RHS:      rhs,
+func NewCreateTable(tableName string, ifNotExists bool, cols []*ColumnDef) Statement {
+	return &CreateTable{
+		TableName:   tableName,
+		IfNotExists: ifNotExists,
+		Columns:     cols,
 	}
 }
 
-func NewWhere(conds []*Condition) *Where {
+func NewColumnDef(name string, typ *TypeName, opts []*ColumnOption) *ColumnDef {
+	return &ColumnDef{
+		Name:    name,
+		Type:    typ,
+		Options: opts,
+	}
+}
+
+func NewColumnOption(kind ColumnOptionKind, value Expr) *ColumnOption {
+	return &ColumnOption{
+		Kind:  kind,
+		Value: value,
+	}
+}
+
+func NewTypeName(name string, mods []int) *TypeName {
+	return &TypeName{
+		Name:      name,
+		Modifiers: mods,
+	}
+}
+
+func NewDropTable(tableNames []string, ifExists bool) Statement {
+	return &DropTable{
+		TableNames: tableNames,
+		IfExists:   ifExists,
+	}
+}
+
+func NewCreateIndex(name, tableName, method, column string, ifNotExists bool) Statement {
+	return &CreateIndex{
+		Name:        name,
+		TableName:   tableName,
+		Method:      method,
+		Column:      column,
+		IfNotExists: ifNotExists,
+	}
+}
+
+func NewSetVar(name, value string) Statement {
+	return &SetVar{
+		Name:  name,
+		Value: value,
+	}
+}
+
+func NewShowVar(name string) Statement {
+	return &ShowVar{
+		Name: name,
+	}
+}
+
+func NewFrom(tables []TableExpr) Statement {
+	return &From{
+		Tables: tables,
+	}
+}
+
+func NewTableRef(name, alias string) TableExpr {
+	return &TableRef{
+		Name:  name,
+		Alias: alias,
+	}
+}
+
+func NewJoin(kind JoinKind, left, right TableExpr, cond *JoinCond) TableExpr {
+	return &Join{
+		Kind:  kind,
+		Left:  left,
+		Right: right,
+		Cond:  cond,
+	}
+}
+
+func NewDerivedTable(sel Statement, alias string) TableExpr {
+	return &DerivedTable{
+		Select: sel.(*Select),
+		Alias:  alias,
+	}
+}
+
+func NewFuncTable(call Expr, alias string) TableExpr {
+	return &FuncTable{
+		Call:  call.(*FuncCall),
+		Alias: alias,
+	}
+}
+
+func NewBinaryExpr(op string, lhs Expr, rhs Expr) Expr {
+	return &BinaryExpr{
+		Op:  op,
+		LHS: lhs,
+		RHS: rhs,
+	}
+}
+
+func NewUnaryExpr(op string, expr Expr) Expr {
+	return &UnaryExpr{
+		Op:   op,
+		Expr: expr,
+	}
+}
+
+func NewFuncCall(name string, args []Expr, star, distinct bool) Expr {
+	return &FuncCall{
+		Name:     name,
+		Args:     args,
+		Star:     star,
+		Distinct: distinct,
+	}
+}
+
+// NewWindowCall sets the window of a function call.
+func NewWindowCall(call Expr, over *WindowSpec) Expr {
+	call.(*FuncCall).Over = over
+	return call
+}
+
+func NewWindowSpec(partitionBy []Expr, orderBy []*OrderItem, frame *Frame) *WindowSpec {
+	return &WindowSpec{
+		PartitionBy: partitionBy,
+		OrderBy:     orderBy,
+		Frame:       frame,
+	}
+}
+
+// NewFrame returns a frame between two bounds. A frame given by its start
+// only ends at the current row.
+func NewFrame(mode FrameMode, start, end *FrameBound) *Frame {
+	if end == nil {
+		end = NewFrameBound(CurrentRow, nil)
+	}
+	return &Frame{
+		Mode:  mode,
+		Start: start,
+		End:   end,
+	}
+}
+
+func NewFrameBound(kind BoundKind, offset Expr) *FrameBound {
+	return &FrameBound{
+		Kind:   kind,
+		Offset: offset,
+	}
+}
+
+func NewSubquery(sel Statement) Expr {
+	return &Subquery{
+		Select: sel.(*Select),
+	}
+}
+
+func NewExistsExpr(sel Statement) Expr {
+	return &ExistsExpr{
+		Subquery: sel.(*Select),
+	}
+}
+
+func NewInExpr(expr Expr, sel Statement, not bool) Expr {
+	return &InExpr{
+		Expr:     expr,
+		Subquery: sel.(*Select),
+		Not:      not,
+	}
+}
+
+func NewInList(expr Expr, list []Expr, not bool) Expr {
+	return &InExpr{
+		Expr: expr,
+		List: list,
+		Not:  not,
+	}
+}
+
+func NewBetweenExpr(expr, low, high Expr, not bool) Expr {
+	return &BetweenExpr{
+		Expr: expr,
+		Low:  low,
+		High: high,
+		Not:  not,
+	}
+}
+
+func NewLikeExpr(op string, expr, pattern, escape Expr, not bool) Expr {
+	return &LikeExpr{
+		Op:      op,
+		Expr:    expr,
+		Pattern: pattern,
+		Escape:  escape,
+		Not:     not,
+	}
+}
+
+func NewIsNullExpr(expr Expr, not bool) Expr {
+	return &IsNullExpr{
+		Expr: expr,
+		Not:  not,
+	}
+}
+
+func NewIsDistinctExpr(lhs, rhs Expr, not bool) Expr {
+	return &IsDistinctExpr{
+		LHS: lhs,
+		RHS: rhs,
+		Not: not,
+	}
+}
+
+func NewCaseExpr(operand Expr, whens []*When, els Expr) Expr {
+	return &CaseExpr{
+		Operand: operand,
+		Whens:   whens,
+		Else:    els,
+	}
+}
+
+func NewWhen(cond, result Expr) *When {
+	return &When{
+		Cond:   cond,
+		Result: result,
+	}
+}
+
+func NewCastExpr(expr Expr, typ *TypeName) Expr {
+	return &CastExpr{
+		Expr: expr,
+		Type: typ,
+	}
+}
+
+func NewWhere(expr Expr) *Where {
 	return &Where{
-		Conditions: conds,
+		Expr: expr,
+	}
+}
+
+func NewColumnRef(table string, name string) Expr {
+	return &ColumnRef{
+		Table: table,
+		Name:  name,
+	}
+}
+
+func NewStar(table string) Expr {
+	return &Star{
+		Table: table,
+	}
+}
+
+func NewLiteral(value interface{}) Expr {
+	return &Literal{
+		Value: value,
 	}
 }
+
+func NewDefault() Expr {
+	return &Default{}
+}
@@ -3,21 +3,106 @@ package parser
 import (
 	"bytes"
 	"errors"
+	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 
+	"github.com/hiepd/galedb/pkg/types"
 	"github.com/sirupsen/logrus"
 )
 
 var keywords = map[string]int{
-	"select": SELECT,
-	"from":   FROM,
-	"where":  WHERE,
-	"and":    AND,
-	"=":      RELATION,
-	"<":      RELATION,
-	">":      RELATION,
-	">=":     RELATION,
-	"<=":     RELATION,
+	"select":    SELECT,
+	"from":      FROM,
+	"where":     WHERE,
+	"join":      JOIN,
+	"inner":     INNER,
+	"left":      LEFT,
+	"right":     RIGHT,
+	"full":      FULL,
+	"outer":     OUTER,
+	"cross":     CROSS,
+	"natural":   NATURAL,
+	"on":        ON,
+	"using":     USING,
+	"with":      WITH,
+	"recursive": RECURSIVE,
+	"union":     UNION,
+	"intersect": INTERSECT,
+	"except":    EXCEPT,
+	"group":     GROUP,
+	"having":    HAVING,
+	"distinct":  DISTINCT,
+	"and":       AND,
+	"or":        OR,
+	"as":        AS,
+	"order":     ORDER,
+	"by":        BY,
+	"asc":       ASC,
+	"desc":      DESC,
+	"nulls":     NULLS,
+	"first":     FIRST,
+	"last":      LAST,
+	"limit":     LIMIT,
+	"offset":    OFFSET,
+	"fetch":     FETCH,
+	"next":      NEXT,
+	"row":       ROW,
+	"rows":      ROWS,
+	"only":      ONLY,
+	"all":       ALL,
+	"insert":    INSERT,
+	"into":      INTO,
+	"values":    VALUES,
+	"default":   DEFAULT,
+	"null":      NULLX,
+	"true":      TRUE,
+	"false":     FALSE,
+	"is":        IS,
+	"between":   BETWEEN,
+	"like":      LIKE,
+	"ilike":     ILIKE,
+	"similar":   SIMILAR,
+	"to":        TO,
+	"escape":    ESCAPE,
+	"case":      CASE,
+	"when":      WHEN,
+	"then":      THEN,
+	"else":      ELSE,
+	"end":       END,
+	"over":      OVER,
+	"partition": PARTITION,
+	"range":     RANGE,
+	"unbounded": UNBOUNDED,
+	"preceding": PRECEDING,
+	"following": FOLLOWING,
+	"current":   CURRENT,
+	"update":    UPDATE,
+	"set":       SET,
+	"delete":    DELETE,
+	"create":    CREATE,
+	"drop":      DROP,
+	"table":     TABLE,
+	"if":        IF,
+	"not":       NOT,
+	"exists":    EXISTS,
+	"in":        IN,
+	"cast":      CAST,
+	"extract":   EXTRACT,
+	"at":        AT,
+	"show":      SHOW,
+	"index":     INDEX,
+	"precision": PRECISION,
+	"varying":   VARYING,
+	"without":   WITHOUT,
+	"=":         RELATION,
+	"<":         RELATION,
+	">":         RELATION,
+	">=":        RELATION,
+	"<=":        RELATION,
+	"<>":        RELATION,
+	"!=":        RELATION,
 }
 
 //go:generate go run golang.org/x/tools/cmd/goyacc -l -o sql.go sql.y
@@ -26,6 +111,9 @@ type Lexer struct {
 	ParseTree Statement
 	Pos       int
 	Err       error
+	// scanErr explains a token the lexer rejected, such as a number that
+	// is out of range
+	scanErr error
 }
 
 func NewLexer(input []byte) *Lexer {
@@ -41,6 +129,8 @@ func (l *Lexer) Lex(lval *yySymType) int {
 		lval.str = v
 	case int:
 		lval.num = v
+	case types.Decimal:
+		lval.dec = v
 	default:
 		panic("invalid lexing type")
 	}
@@ -53,22 +143,85 @@ func (l *Lexer) Scan() (int, interface{}) {
 		switch {
 		case unicode.IsSpace(rune(b)):
 			continue
-		case unicode.IsLetter(rune(b)):
+		case (b == 'e' || b == 'E') && l.peek() == '\'':
+			l.next()
+			sym, val := l.scanQuoted('\'', true)
+			return sym, val
+		case isIdentStart(l.lastRune()):
 			l.backup()
 			sym, val := l.scanString()
 			return sym, val
-		case unicode.IsDigit(rune(b)):
+		case unicode.IsDigit(rune(b)) || (b == '.' && unicode.IsDigit(rune(l.peek()))):
 			l.backup()
 			sym, val := l.scanNumber()
 			return sym, val
 		default:
 			switch b {
-			case '=', '<', '>':
+			case '!':
+				if l.peek() == '~' {
+					l.next()
+					sym, val := l.scanMatch("!~")
+					return sym, val
+				}
+				l.backup()
+				sym, val := l.scanRelation()
+				return sym, val
+			case '<':
+				if l.peek() == '@' {
+					l.next()
+					return JSON_OP, "<@"
+				}
 				l.backup()
 				sym, val := l.scanRelation()
 				return sym, val
-			case '+', '-':
+			case '=', '>':
+				l.backup()
+				sym, val := l.scanRelation()
+				return sym, val
+			case '~':
+				sym, val := l.scanMatch("~")
+				return sym, val
+			case '\'':
+				sym, val := l.scanQuoted('\'', false)
+				return sym, val
+			case '"':
+				sym, val := l.scanQuoted('"', false)
+				if sym == STRING {
+					sym = NAME
+				}
+				return sym, val
+			case '-':
+				if l.peek() == '>' {
+					l.next()
+					return l.scanJSONPath("->")
+				}
 				return OPERATOR, string(b)
+			case '#':
+				if l.peek() == '>' {
+					l.next()
+					return l.scanJSONPath("#>")
+				}
+				return LEX_ERROR, string(b)
+			case '@':
+				if l.peek() == '>' {
+					l.next()
+					return JSON_OP, "@>"
+				}
+				return LEX_ERROR, string(b)
+			case '?':
+				return JSON_OP, string(b)
+			case '+':
+				return OPERATOR, string(b)
+			case '*':
+				return ASTERISK, string(b)
+			case '(', ')', '.', '/', '%':
+				return int(b), string(b)
+			case ':':
+				if l.peek() == ':' {
+					l.next()
+					return TYPECAST, "::"
+				}
+				return LEX_ERROR, string(b)
 			case ',':
 				return COMMA, ","
 			case ';':
@@ -86,7 +239,7 @@ func (l *Lexer) scanRelation() (int, string) {
 	for {
 		b := l.next()
 		switch b {
-		case '=', '<', '>':
+		case '=', '<', '>', '!':
 			buf.WriteByte(b)
 		default:
 			l.backup()
@@ -95,43 +248,218 @@ func (l *Lexer) scanRelation() (int, string) {
 			if !ok {
 				return LEX_ERROR, str
 			}
+			if str == "!=" {
+				str = "<>"
+			}
 			return val, str
 		}
 	}
 }
 
+// scanMatch scans a regular expression match operator, which is made case
+// insensitive by a trailing *.
+func (l *Lexer) scanMatch(op string) (int, string) {
+	if l.peek() == '*' {
+		l.next()
+		op += "*"
+	}
+	return MATCH_OP, op
+}
+
+// scanJSONPath scans a JSON operator getting a field or path, which returns
+// text with a trailing >.
+func (l *Lexer) scanJSONPath(op string) (int, string) {
+	if l.peek() == '>' {
+		l.next()
+		op += ">"
+	}
+	return JSON_OP, op
+}
+
 func (l *Lexer) scanString() (int, string) {
 	buf := bytes.NewBuffer(nil)
 	for {
-		b := l.next()
-		if unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b)) || b == '_' {
-			buf.WriteByte(b)
+		if r, n := l.peekRune(); isIdentStart(r) || unicode.IsDigit(r) {
+			buf.Write(l.Input[l.Pos : l.Pos+n])
+			l.Pos += n
 		} else {
-			l.backup()
-			str := buf.String()
+			str := strings.ToLower(buf.String())
 			val, ok := keywords[str]
 			if !ok {
 				return NAME, str
 			}
+			// NOT IN, NOT LIKE and the like bind like the operator they
+			// negate rather than like a prefix NOT
+			if val == NOT {
+				switch l.peekKeyword() {
+				case IN, BETWEEN, LIKE, ILIKE, SIMILAR:
+					return NOT_LA, str
+				}
+			}
 			return val, str
 		}
 	}
 }
 
-func (l *Lexer) scanNumber() (int, int) {
-	res := 0
+// peekKeyword returns the keyword following the current position without
+// consuming it, or 0 when the next token isn't a keyword.
+func (l *Lexer) peekKeyword() int {
+	pos := l.Pos
+	defer func() {
+		l.Pos = pos
+	}()
+	for unicode.IsSpace(rune(l.peek())) {
+		l.next()
+	}
+	if r, _ := l.peekRune(); !isIdentStart(r) {
+		return 0
+	}
+	sym, _ := l.scanString()
+	if sym == NAME {
+		return 0
+	}
+	return sym
+}
+
+// scanQuoted scans the body of a quoted token after its opening quote. A
+// doubled quote stands for the quote itself. When escape is set, backslash
+// sequences are interpreted as in Postgres E'...' strings.
+func (l *Lexer) scanQuoted(quote byte, escape bool) (int, string) {
+	buf := bytes.NewBuffer(nil)
 	for {
 		b := l.next()
-		if unicode.IsDigit(rune(b)) {
-			res *= 10
-			res += int(b - '0')
-		} else if unicode.IsSpace(rune(b)) || b == ';' {
+		switch {
+		case b == 0:
+			return LEX_ERROR, buf.String()
+		case b == quote:
+			if l.peek() != quote {
+				return STRING, buf.String()
+			}
+			l.next()
+			buf.WriteByte(quote)
+		case b == '\\' && escape:
+			if err := l.scanEscape(buf); err != nil {
+				return LEX_ERROR, buf.String()
+			}
+		default:
+			buf.WriteByte(b)
+		}
+	}
+}
+
+func (l *Lexer) scanEscape(buf *bytes.Buffer) error {
+	b := l.next()
+	switch b {
+	case 0:
+		return errors.New("unterminated escape sequence")
+	case 'b':
+		buf.WriteByte('\b')
+	case 'f':
+		buf.WriteByte('\f')
+	case 'n':
+		buf.WriteByte('\n')
+	case 'r':
+		buf.WriteByte('\r')
+	case 't':
+		buf.WriteByte('\t')
+	case 'x':
+		return l.scanCodePoint(buf, 16, 2, 1)
+	case 'u':
+		return l.scanCodePoint(buf, 16, 4, 4)
+	case 'U':
+		return l.scanCodePoint(buf, 16, 8, 8)
+	default:
+		if b >= '0' && b <= '7' {
 			l.backup()
-			return NUMBER, res
-		} else {
-			return LEX_ERROR, 0
+			return l.scanCodePoint(buf, 8, 3, 1)
+		}
+		buf.WriteByte(b)
+	}
+	return nil
+}
+
+// scanCodePoint reads between min and max digits in the given base and writes
+// the resulting character. Hex and octal escapes produce a single byte while
+// unicode escapes produce the UTF-8 encoding of the code point.
+func (l *Lexer) scanCodePoint(buf *bytes.Buffer, base, max, min int) error {
+	digits := make([]byte, 0, max)
+	for len(digits) < max {
+		b := l.peek()
+		if !isDigitOf(b, base) {
+			break
+		}
+		digits = append(digits, l.next())
+	}
+	if len(digits) < min {
+		return errors.New("invalid escape sequence")
+	}
+	n, err := strconv.ParseUint(string(digits), base, 32)
+	if err != nil {
+		return err
+	}
+	if max >= 4 {
+		buf.WriteRune(rune(n))
+	} else {
+		buf.WriteByte(byte(n))
+	}
+	return nil
+}
+
+func isDigitOf(b byte, base int) bool {
+	switch {
+	case b >= '0' && b <= '7':
+		return true
+	case b == '8' || b == '9':
+		return base > 8
+	case b >= 'a' && b <= 'f', b >= 'A' && b <= 'F':
+		return base == 16
+	}
+	return false
+}
+
+// scanNumber scans an integer, or a number with a fraction or an exponent,
+// which is a NUMERIC. So are integers too large for an int.
+func (l *Lexer) scanNumber() (int, interface{}) {
+	start := l.Pos
+	exact := true
+	l.scanDigits()
+	if l.peek() == '.' {
+		l.next()
+		exact = false
+		l.scanDigits()
+	}
+	if b := l.peek(); b == 'e' || b == 'E' {
+		l.next()
+		if b := l.peek(); b == '+' || b == '-' {
+			l.next()
+		}
+		if !unicode.IsDigit(rune(l.peek())) {
+			return LEX_ERROR, string(l.Input[start:l.Pos])
+		}
+		exact = false
+		l.scanDigits()
+	}
+	text := string(l.Input[start:l.Pos])
+	if r, _ := l.peekRune(); isIdentStart(r) {
+		return LEX_ERROR, text
+	}
+	if exact {
+		if n, err := strconv.Atoi(text); err == nil {
+			return NUMBER, n
 		}
 	}
+	d, err := types.ParseDecimal(text)
+	if err != nil {
+		l.scanErr = err
+		return LEX_ERROR, text
+	}
+	return DECIMALNUM, d
+}
+
+func (l *Lexer) scanDigits() {
+	for unicode.IsDigit(rune(l.peek())) {
+		l.next()
+	}
 }
 
 func (l *Lexer) backup() {
@@ -141,6 +469,35 @@ func (l *Lexer) backup() {
 	l.Pos--
 }
 
+func (l *Lexer) peek() byte {
+	if l.Pos >= len(l.Input) || l.Pos == -1 {
+		return 0
+	}
+	return l.Input[l.Pos]
+}
+
+// peekRune decodes the character at the current position, which may take
+// several bytes of the input.
+func (l *Lexer) peekRune() (rune, int) {
+	if l.Pos >= len(l.Input) || l.Pos == -1 {
+		return 0, 0
+	}
+	return utf8.DecodeRune(l.Input[l.Pos:])
+}
+
+// lastRune decodes the character starting at the byte returned by next.
+func (l *Lexer) lastRune() rune {
+	if l.Pos <= 0 {
+		return 0
+	}
+	r, _ := utf8.DecodeRune(l.Input[l.Pos-1:])
+	return r
+}
+
+func isIdentStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
 func (l *Lexer) next() byte {
 	if l.Pos >= len(l.Input) || l.Pos == -1 {
 		l.Pos = -1
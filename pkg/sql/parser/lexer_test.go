@@ -3,6 +3,7 @@ package parser
 import (
 	"testing"
 
+	"github.com/hiepd/galedb/pkg/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -81,6 +82,36 @@ func Test_Lexer_Lex(t *testing.T) {
 			},
 			want: []int{SELECT, ASTERISK, FROM, NAME},
 		},
+		{
+			name: "string literal",
+			fields: fields{
+				input: []byte("select email from users where user_type = 'customer'"),
+			},
+			args: args{
+				lval: &yySymType{},
+			},
+			want: []int{SELECT, NAME, FROM, NAME, WHERE, NAME, RELATION, STRING},
+		},
+		{
+			name: "quoted identifier",
+			fields: fields{
+				input: []byte(`select "User Type" from users`),
+			},
+			args: args{
+				lval: &yySymType{},
+			},
+			want: []int{SELECT, NAME, FROM, NAME},
+		},
+		{
+			name: "unterminated string",
+			fields: fields{
+				input: []byte("'customer"),
+			},
+			args: args{
+				lval: &yySymType{},
+			},
+			want: []int{LEX_ERROR},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -97,3 +128,273 @@ func Test_Lexer_Lex(t *testing.T) {
 		})
 	}
 }
+
+func Test_Lexer_Scan(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantSym int
+		wantVal interface{}
+	}{
+		{
+			name:    "keyword is case insensitive",
+			input:   "SELECT",
+			wantSym: SELECT,
+			wantVal: "select",
+		},
+		{
+			name:    "name is folded to lower case",
+			input:   "UserType",
+			wantSym: NAME,
+			wantVal: "usertype",
+		},
+		{
+			name:    "quoted identifier keeps case",
+			input:   `"UserType"`,
+			wantSym: NAME,
+			wantVal: "UserType",
+		},
+		{
+			name:    "quoted identifier with escaped quote",
+			input:   `"a""b"`,
+			wantSym: NAME,
+			wantVal: `a"b`,
+		},
+		{
+			name:    "name with non-ASCII letters",
+			input:   "Crème_Brûlée2",
+			wantSym: NAME,
+			wantVal: "crème_brûlée2",
+		},
+		{
+			name:    "name starting with a non-ASCII letter",
+			input:   "Ürün",
+			wantSym: NAME,
+			wantVal: "ürün",
+		},
+		{
+			name:    "name ends before a non-ASCII symbol",
+			input:   "price€",
+			wantSym: NAME,
+			wantVal: "price",
+		},
+		{
+			name:    "string",
+			input:   "'customer'",
+			wantSym: STRING,
+			wantVal: "customer",
+		},
+		{
+			name:    "empty string",
+			input:   "''",
+			wantSym: STRING,
+			wantVal: "",
+		},
+		{
+			name:    "string with escaped quote",
+			input:   "'it''s'",
+			wantSym: STRING,
+			wantVal: "it's",
+		},
+		{
+			name:    "string keeps backslash",
+			input:   `'a\nb'`,
+			wantSym: STRING,
+			wantVal: `a\nb`,
+		},
+		{
+			name:    "escape string",
+			input:   `E'a\nb\'c'`,
+			wantSym: STRING,
+			wantVal: "a\nb'c",
+		},
+		{
+			name:    "escape string with code points",
+			input:   `e'\x41\101\u00e9'`,
+			wantSym: STRING,
+			wantVal: "AAé",
+		},
+		{
+			name:    "type cast",
+			input:   "::",
+			wantSym: TYPECAST,
+			wantVal: "::",
+		},
+		{
+			name:    "json field",
+			input:   "->'a'",
+			wantSym: JSON_OP,
+			wantVal: "->",
+		},
+		{
+			name:    "json field as text",
+			input:   "->>'a'",
+			wantSym: JSON_OP,
+			wantVal: "->>",
+		},
+		{
+			name:    "json path",
+			input:   "#>'{a}'",
+			wantSym: JSON_OP,
+			wantVal: "#>",
+		},
+		{
+			name:    "json path as text",
+			input:   "#>>'{a}'",
+			wantSym: JSON_OP,
+			wantVal: "#>>",
+		},
+		{
+			name:    "json contains",
+			input:   "@>",
+			wantSym: JSON_OP,
+			wantVal: "@>",
+		},
+		{
+			name:    "json contained by",
+			input:   "<@",
+			wantSym: JSON_OP,
+			wantVal: "<@",
+		},
+		{
+			name:    "json has key",
+			input:   "?'a'",
+			wantSym: JSON_OP,
+			wantVal: "?",
+		},
+		{
+			name:    "single hash",
+			input:   "#",
+			wantSym: LEX_ERROR,
+			wantVal: "#",
+		},
+		{
+			name:    "single colon",
+			input:   ":",
+			wantSym: LEX_ERROR,
+			wantVal: ":",
+		},
+		{
+			name:    "not equal",
+			input:   "<>",
+			wantSym: RELATION,
+			wantVal: "<>",
+		},
+		{
+			name:    "not equal alias",
+			input:   "!=",
+			wantSym: RELATION,
+			wantVal: "<>",
+		},
+		{
+			name:    "greater or equal",
+			input:   ">=1",
+			wantSym: RELATION,
+			wantVal: ">=",
+		},
+		{
+			name:    "invalid relation",
+			input:   "=>",
+			wantSym: LEX_ERROR,
+			wantVal: "=>",
+		},
+		{
+			name:    "regular expression match",
+			input:   "~*'a'",
+			wantSym: MATCH_OP,
+			wantVal: "~*",
+		},
+		{
+			name:    "negated regular expression match",
+			input:   "!~ 'a'",
+			wantSym: MATCH_OP,
+			wantVal: "!~",
+		},
+		{
+			name:    "not before like",
+			input:   "NOT like",
+			wantSym: NOT_LA,
+			wantVal: "not",
+		},
+		{
+			name:    "not before other keyword",
+			input:   "not exists",
+			wantSym: NOT,
+			wantVal: "not",
+		},
+		{
+			name:    "number followed by paren",
+			input:   "30)",
+			wantSym: NUMBER,
+			wantVal: 30,
+		},
+		{
+			name:    "decimal",
+			input:   "3.14)",
+			wantSym: DECIMALNUM,
+			wantVal: types.NewDecimal(314, 2),
+		},
+		{
+			name:    "decimal without integer part",
+			input:   ".5",
+			wantSym: DECIMALNUM,
+			wantVal: types.NewDecimal(5, 1),
+		},
+		{
+			name:    "decimal without fraction",
+			input:   "2.",
+			wantSym: DECIMALNUM,
+			wantVal: types.NewDecimal(2, 0),
+		},
+		{
+			name:    "exponent",
+			input:   "1e10",
+			wantSym: DECIMALNUM,
+			wantVal: types.NewDecimal(10000000000, 0),
+		},
+		{
+			name:    "negative exponent",
+			input:   "2.5E-3",
+			wantSym: DECIMALNUM,
+			wantVal: types.NewDecimal(25, 4),
+		},
+		{
+			name:    "integer too large for an int",
+			input:   "9223372036854775808",
+			wantSym: DECIMALNUM,
+			wantVal: types.NewDecimal(922337203685477580, 0).Mul(types.NewDecimal(10, 0)).Add(types.NewDecimal(8, 0)),
+		},
+		{
+			name:    "exponent without digits",
+			input:   "1e+",
+			wantSym: LEX_ERROR,
+			wantVal: "1e+",
+		},
+		{
+			name:    "number followed by letters",
+			input:   "1.5x",
+			wantSym: LEX_ERROR,
+			wantVal: "1.5",
+		},
+		{
+			name:    "number followed by a non-ASCII letter",
+			input:   "1é",
+			wantSym: LEX_ERROR,
+			wantVal: "1",
+		},
+		{
+			name:    "boolean",
+			input:   "TRUE",
+			wantSym: TRUE,
+			wantVal: "true",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLexer([]byte(tt.input))
+			sym, val := l.Scan()
+			assert.Equal(t, tt.wantSym, sym)
+			assert.Equal(t, tt.wantVal, val)
+		})
+	}
+}
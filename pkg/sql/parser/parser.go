@@ -11,10 +11,20 @@ func New() *Parser {
 }
 
 func (p *Parser) Parse(sql string) (Statement, error) {
+	return Parse(sql)
+}
+
+func Parse(sql string) (Statement, error) {
 	lexer := NewLexer([]byte(sql))
 	if yyParse(lexer) != 0 {
+		if lexer.scanErr != nil {
+			return nil, lexer.scanErr
+		}
 		sym, val := lexer.Scan()
 		return nil, fmt.Errorf("invalid statement with: %d %s", sym, val)
 	}
+	if lexer.Err != nil {
+		return nil, lexer.Err
+	}
 	return lexer.ParseTree, nil
 }
@@ -3,6 +3,7 @@ package parser
 import (
 	"testing"
 
+	"github.com/hiepd/galedb/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -23,12 +24,1192 @@ func TestParse(t *testing.T) {
 				sql: "select * from table1",
 			},
 			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &Star{}},
+				},
+				From: &From{
+					Tables: []TableExpr{&TableRef{Name: "table1"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "qualified references",
+			args: args{
+				sql: "select users.*, users.id from public.users where users.age > 1",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &Star{Table: "users"}},
+					{Expr: &ColumnRef{Table: "users", Name: "id"}},
+				},
+				From: &From{
+					Tables: []TableExpr{&TableRef{Name: "users"}},
+				},
+				Where: &Where{
+					Expr: &BinaryExpr{
+						Op:  ">",
+						LHS: &ColumnRef{Table: "users", Name: "age"},
+						RHS: &Literal{Value: 1},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown schema",
+			args: args{
+				sql: "select * from private.users",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "numeric exponent out of range",
+			args: args{
+				sql: "select 1e999999999",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "string literal",
+			args: args{
+				sql: "select email from users where user_type = 'customer'",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &ColumnRef{Name: "email"}},
+				},
+				From: &From{
+					Tables: []TableExpr{&TableRef{Name: "users"}},
+				},
+				Where: &Where{
+					Expr: &BinaryExpr{
+						Op:  "=",
+						LHS: &ColumnRef{Name: "user_type"},
+						RHS: &Literal{Value: "customer"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "column to column",
+			args: args{
+				sql: "select email from users where user_type = driver",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &ColumnRef{Name: "email"}},
+				},
+				From: &From{
+					Tables: []TableExpr{&TableRef{Name: "users"}},
+				},
+				Where: &Where{
+					Expr: &BinaryExpr{
+						Op:  "=",
+						LHS: &ColumnRef{Name: "user_type"},
+						RHS: &ColumnRef{Name: "driver"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "boolean expression",
+			args: args{
+				sql: "select email from users where id = 1 or (age = 2 and not user_type = 'driver')",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &ColumnRef{Name: "email"}},
+				},
+				From: &From{
+					Tables: []TableExpr{&TableRef{Name: "users"}},
+				},
+				Where: &Where{
+					Expr: &BinaryExpr{
+						Op: OpOr,
+						LHS: &BinaryExpr{
+							Op:  "=",
+							LHS: &ColumnRef{Name: "id"},
+							RHS: &Literal{Value: 1},
+						},
+						RHS: &BinaryExpr{
+							Op: OpAnd,
+							LHS: &BinaryExpr{
+								Op:  "=",
+								LHS: &ColumnRef{Name: "age"},
+								RHS: &Literal{Value: 2},
+							},
+							RHS: &UnaryExpr{
+								Op: OpNot,
+								Expr: &BinaryExpr{
+									Op:  "=",
+									LHS: &ColumnRef{Name: "user_type"},
+									RHS: &Literal{Value: "driver"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "and binds tighter than or",
+			args: args{
+				sql: "select email from users where id = 1 or id = 2 and age = 3",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &ColumnRef{Name: "email"}},
+				},
+				From: &From{
+					Tables: []TableExpr{&TableRef{Name: "users"}},
+				},
+				Where: &Where{
+					Expr: &BinaryExpr{
+						Op: OpOr,
+						LHS: &BinaryExpr{
+							Op:  "=",
+							LHS: &ColumnRef{Name: "id"},
+							RHS: &Literal{Value: 1},
+						},
+						RHS: &BinaryExpr{
+							Op: OpAnd,
+							LHS: &BinaryExpr{
+								Op:  "=",
+								LHS: &ColumnRef{Name: "id"},
+								RHS: &Literal{Value: 2},
+							},
+							RHS: &BinaryExpr{
+								Op:  "=",
+								LHS: &ColumnRef{Name: "age"},
+								RHS: &Literal{Value: 3},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "arithmetic",
+			args: args{
+				sql: "select age + 1, id * 2 as doubled, -age neg from users where age - 5 > 20 % 3",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{
+						Expr: &BinaryExpr{
+							Op:  OpAdd,
+							LHS: &ColumnRef{Name: "age"},
+							RHS: &Literal{Value: 1},
+						},
+					},
+					{
+						Expr: &BinaryExpr{
+							Op:  OpMul,
+							LHS: &ColumnRef{Name: "id"},
+							RHS: &Literal{Value: 2},
+						},
+						Alias: "doubled",
+					},
+					{
+						Expr: &UnaryExpr{
+							Op:   OpSub,
+							Expr: &ColumnRef{Name: "age"},
+						},
+						Alias: "neg",
+					},
+				},
+				From: &From{
+					Tables: []TableExpr{&TableRef{Name: "users"}},
+				},
+				Where: &Where{
+					Expr: &BinaryExpr{
+						Op: ">",
+						LHS: &BinaryExpr{
+							Op:  OpSub,
+							LHS: &ColumnRef{Name: "age"},
+							RHS: &Literal{Value: 5},
+						},
+						RHS: &BinaryExpr{
+							Op:  OpMod,
+							LHS: &Literal{Value: 20},
+							RHS: &Literal{Value: 3},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "arithmetic precedence",
+			args: args{
+				sql: "select 1 - 2 - 3 * 4 / (5 + 6)",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{
+						Expr: &BinaryExpr{
+							Op: OpSub,
+							LHS: &BinaryExpr{
+								Op:  OpSub,
+								LHS: &Literal{Value: 1},
+								RHS: &Literal{Value: 2},
+							},
+							RHS: &BinaryExpr{
+								Op: OpDiv,
+								LHS: &BinaryExpr{
+									Op:  OpMul,
+									LHS: &Literal{Value: 3},
+									RHS: &Literal{Value: 4},
+								},
+								RHS: &BinaryExpr{
+									Op:  OpAdd,
+									LHS: &Literal{Value: 5},
+									RHS: &Literal{Value: 6},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "insert",
+			args: args{
+				sql: "insert into users values (1, 'customer')",
+			},
+			want: &Insert{
+				TableName: "users",
+				Rows: [][]Expr{
+					{&Literal{Value: 1}, &Literal{Value: "customer"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "insert multiple rows with columns",
+			args: args{
+				sql: "INSERT INTO users (id, email) VALUES (1, NULL), (2, DEFAULT)",
+			},
+			want: &Insert{
+				TableName: "users",
+				Cols:      []string{"id", "email"},
+				Rows: [][]Expr{
+					{&Literal{Value: 1}, &Literal{Value: nil}},
+					{&Literal{Value: 2}, &Default{}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "insert without values",
+			args: args{
+				sql: "insert into users (id)",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "update",
+			args: args{
+				sql: "update users set age = 30, email = DEFAULT where id = 1",
+			},
+			want: &Update{
+				TableName: "users",
+				Set: []*Assignment{
+					{Column: "age", Value: &Literal{Value: 30}},
+					{Column: "email", Value: &Default{}},
+				},
+				Where: &Where{
+					Expr: &BinaryExpr{
+						Op:  "=",
+						LHS: &ColumnRef{Name: "id"},
+						RHS: &Literal{Value: 1},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "update with invalid assignment",
+			args: args{
+				sql: "update users set age > 30",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "delete",
+			args: args{
+				sql: "delete from users",
+			},
+			want: &Delete{
+				TableName: "users",
+			},
+			wantErr: false,
+		},
+		{
+			name: "create table",
+			args: args{
+				sql: "create table if not exists orders (id integer not null, item varchar(255) default 'none', price numeric(10, 2), ratio double precision)",
+			},
+			want: &CreateTable{
+				TableName:   "orders",
+				IfNotExists: true,
+				Columns: []*ColumnDef{
+					{
+						Name: "id",
+						Type: &TypeName{Name: "integer"},
+						Options: []*ColumnOption{
+							{Kind: OptionNotNull},
+						},
+					},
+					{
+						Name: "item",
+						Type: &TypeName{Name: "varchar", Modifiers: []int{255}},
+						Options: []*ColumnOption{
+							{Kind: OptionDefault, Value: &Literal{Value: "none"}},
+						},
+					},
+					{
+						Name: "price",
+						Type: &TypeName{Name: "numeric", Modifiers: []int{10, 2}},
+					},
+					{
+						Name: "ratio",
+						Type: &TypeName{Name: "double precision"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "drop table",
+			args: args{
+				sql: "drop table if exists orders, users",
+			},
+			want: &DropTable{
+				TableNames: []string{"orders", "users"},
+				IfExists:   true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "order by",
+			args: args{
+				sql: "select id from users where age > 1 order by age desc nulls last, 1, id asc nulls first",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &ColumnRef{Name: "id"}},
+				},
+				From: &From{
+					Tables: []TableExpr{&TableRef{Name: "users"}},
+				},
+				Where: &Where{
+					Expr: &BinaryExpr{
+						Op:  ">",
+						LHS: &ColumnRef{Name: "age"},
+						RHS: &Literal{Value: 1},
+					},
+				},
+				OrderBy: []*OrderItem{
+					{Expr: &ColumnRef{Name: "age"}, Desc: true, Nulls: NullsLast},
+					{Expr: &Literal{Value: 1}},
+					{Expr: &ColumnRef{Name: "id"}, Nulls: NullsFirst},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "order by without from",
+			args: args{
+				sql: "select 1 + 2 as n order by n",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{
+						Expr: &BinaryExpr{
+							Op:  "+",
+							LHS: &Literal{Value: 1},
+							RHS: &Literal{Value: 2},
+						},
+						Alias: "n",
+					},
+				},
+				OrderBy: []*OrderItem{
+					{Expr: &ColumnRef{Name: "n"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "limit and offset",
+			args: args{
+				sql: "select id from users order by id limit 10 offset 20",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &ColumnRef{Name: "id"}},
+				},
+				From: &From{
+					Tables: []TableExpr{&TableRef{Name: "users"}},
+				},
+				OrderBy: []*OrderItem{
+					{Expr: &ColumnRef{Name: "id"}},
+				},
+				Limit: &Limit{
+					Count:  &Literal{Value: 10},
+					Offset: &Literal{Value: 20},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "fetch first",
+			args: args{
+				sql: "select id from users offset 5 rows fetch first 3 rows only",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &ColumnRef{Name: "id"}},
+				},
+				From: &From{
+					Tables: []TableExpr{&TableRef{Name: "users"}},
+				},
+				Limit: &Limit{
+					Count:  &Literal{Value: 3},
+					Offset: &Literal{Value: 5},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "fetch without only",
+			args: args{
+				sql: "select id from users fetch first 3 rows",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "group by and having",
+			args: args{
+				sql: "select user_type, count(*), count(distinct age) from users group by user_type having sum(age) > 10",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &ColumnRef{Name: "user_type"}},
+					{Expr: &FuncCall{Name: "count", Star: true}},
+					{Expr: &FuncCall{Name: "count", Args: []Expr{&ColumnRef{Name: "age"}}, Distinct: true}},
+				},
+				From: &From{
+					Tables: []TableExpr{&TableRef{Name: "users"}},
+				},
+				GroupBy: []Expr{&ColumnRef{Name: "user_type"}},
+				Having: &BinaryExpr{
+					Op:  ">",
+					LHS: &FuncCall{Name: "sum", Args: []Expr{&ColumnRef{Name: "age"}}},
+					RHS: &Literal{Value: 10},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "join with aliases",
+			args: args{
+				sql: "select u.id, o.item from users u left outer join orders as o on u.id = o.user_id",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &ColumnRef{Table: "u", Name: "id"}},
+					{Expr: &ColumnRef{Table: "o", Name: "item"}},
+				},
+				From: &From{
+					Tables: []TableExpr{
+						&Join{
+							Kind:  JoinLeft,
+							Left:  &TableRef{Name: "users", Alias: "u"},
+							Right: &TableRef{Name: "orders", Alias: "o"},
+							Cond: &JoinCond{
+								On: &BinaryExpr{
+									Op:  "=",
+									LHS: &ColumnRef{Table: "u", Name: "id"},
+									RHS: &ColumnRef{Table: "o", Name: "user_id"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "joins and comma list",
+			args: args{
+				sql: "select * from a natural join b join c using (id, name), (d cross join e)",
+			},
+			want: &Select{
+				Cols: []*SelectItem{{Expr: &Star{}}},
+				From: &From{
+					Tables: []TableExpr{
+						&Join{
+							Kind: JoinInner,
+							Left: &Join{
+								Kind:  JoinInner,
+								Left:  &TableRef{Name: "a"},
+								Right: &TableRef{Name: "b"},
+								Cond:  &JoinCond{Natural: true},
+							},
+							Right: &TableRef{Name: "c"},
+							Cond:  &JoinCond{Using: []string{"id", "name"}},
+						},
+						&Join{
+							Kind:  JoinCross,
+							Left:  &TableRef{Name: "d"},
+							Right: &TableRef{Name: "e"},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "join without condition",
+			args: args{
+				sql: "select * from a join b",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "subqueries",
+			args: args{
+				sql: "select (select max(id) from orders) from (select id from users) as t where id not in (select user_id from orders) and exists (select 1)",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &Subquery{Select: &Select{
+						Cols: []*SelectItem{{Expr: &FuncCall{Name: "max", Args: []Expr{&ColumnRef{Name: "id"}}}}},
+						From: &From{Tables: []TableExpr{&TableRef{Name: "orders"}}},
+					}}},
+				},
+				From: &From{
+					Tables: []TableExpr{
+						&DerivedTable{
+							Select: &Select{
+								Cols: []*SelectItem{{Expr: &ColumnRef{Name: "id"}}},
+								From: &From{Tables: []TableExpr{&TableRef{Name: "users"}}},
+							},
+							Alias: "t",
+						},
+					},
+				},
+				Where: &Where{
+					Expr: &BinaryExpr{
+						Op: "AND",
+						LHS: &InExpr{
+							Expr: &ColumnRef{Name: "id"},
+							Subquery: &Select{
+								Cols: []*SelectItem{{Expr: &ColumnRef{Name: "user_id"}}},
+								From: &From{Tables: []TableExpr{&TableRef{Name: "orders"}}},
+							},
+							Not: true,
+						},
+						RHS: &ExistsExpr{Subquery: &Select{
+							Cols: []*SelectItem{{Expr: &Literal{Value: 1}}},
+						}},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "not in binds tighter than comparison",
+			args: args{
+				sql: "select a = b not in (select c) from t",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &BinaryExpr{
+						Op:  "=",
+						LHS: &ColumnRef{Name: "a"},
+						RHS: &InExpr{
+							Expr:     &ColumnRef{Name: "b"},
+							Subquery: &Select{Cols: []*SelectItem{{Expr: &ColumnRef{Name: "c"}}}},
+							Not:      true,
+						},
+					}},
+				},
+				From: &From{Tables: []TableExpr{&TableRef{Name: "t"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "with",
+			args: args{
+				sql: "with t as (select id from users), u(n) as (select id from t) select n from u",
+			},
+			want: &Select{
+				With: &With{
+					CTEs: []*CTE{
+						{
+							Name: "t",
+							Query: &Select{
+								Cols: []*SelectItem{{Expr: &ColumnRef{Name: "id"}}},
+								From: &From{Tables: []TableExpr{&TableRef{Name: "users"}}},
+							},
+						},
+						{
+							Name:    "u",
+							Columns: []string{"n"},
+							Query: &Select{
+								Cols: []*SelectItem{{Expr: &ColumnRef{Name: "id"}}},
+								From: &From{Tables: []TableExpr{&TableRef{Name: "t"}}},
+							},
+						},
+					},
+				},
+				Cols: []*SelectItem{{Expr: &ColumnRef{Name: "n"}}},
+				From: &From{Tables: []TableExpr{&TableRef{Name: "u"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "with recursive",
+			args: args{
+				sql: "with recursive t(n) as (select 1 union all select n + 1 from t where n < 3) select n from t",
+			},
+			want: &Select{
+				With: &With{
+					Recursive: true,
+					CTEs: []*CTE{
+						{
+							Name:    "t",
+							Columns: []string{"n"},
+							Query: &Select{SetOp: &SetOp{
+								Op:   SetUnion,
+								All:  true,
+								Left: &Select{Cols: []*SelectItem{{Expr: &Literal{Value: 1}}}},
+								Right: &Select{
+									Cols: []*SelectItem{{Expr: &BinaryExpr{Op: "+", LHS: &ColumnRef{Name: "n"}, RHS: &Literal{Value: 1}}}},
+									From: &From{Tables: []TableExpr{&TableRef{Name: "t"}}},
+									Where: &Where{
+										Expr: &BinaryExpr{Op: "<", LHS: &ColumnRef{Name: "n"}, RHS: &Literal{Value: 3}},
+									},
+								},
+							}},
+						},
+					},
+				},
+				Cols: []*SelectItem{{Expr: &ColumnRef{Name: "n"}}},
+				From: &From{Tables: []TableExpr{&TableRef{Name: "t"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "set operations",
+			args: args{
+				sql: "select a from t union all select b from u intersect select c from v except select d from w order by 1 limit 2",
+			},
+			want: &Select{
+				SetOp: &SetOp{
+					Op: SetExcept,
+					Left: &Select{SetOp: &SetOp{
+						Op:   SetUnion,
+						All:  true,
+						Left: &Select{Cols: []*SelectItem{{Expr: &ColumnRef{Name: "a"}}}, From: &From{Tables: []TableExpr{&TableRef{Name: "t"}}}},
+						Right: &Select{SetOp: &SetOp{
+							Op:    SetIntersect,
+							Left:  &Select{Cols: []*SelectItem{{Expr: &ColumnRef{Name: "b"}}}, From: &From{Tables: []TableExpr{&TableRef{Name: "u"}}}},
+							Right: &Select{Cols: []*SelectItem{{Expr: &ColumnRef{Name: "c"}}}, From: &From{Tables: []TableExpr{&TableRef{Name: "v"}}}},
+						}},
+					}},
+					Right: &Select{Cols: []*SelectItem{{Expr: &ColumnRef{Name: "d"}}}, From: &From{Tables: []TableExpr{&TableRef{Name: "w"}}}},
+				},
+				OrderBy: []*OrderItem{{Expr: &Literal{Value: 1}}},
+				Limit:   &Limit{Count: &Literal{Value: 2}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "order by in set operation operand",
+			args: args{
+				sql: "select a from t order by a union select b from u",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "distinct",
+			args: args{
+				sql: "select distinct user_type from users",
+			},
+			want: &Select{
+				Distinct: &Distinct{},
+				Cols:     []*SelectItem{{Expr: &ColumnRef{Name: "user_type"}}},
+				From:     &From{Tables: []TableExpr{&TableRef{Name: "users"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "distinct on",
+			args: args{
+				sql: "select distinct on (user_type, age / 10) id from users order by user_type",
+			},
+			want: &Select{
+				Distinct: &Distinct{On: []Expr{
+					&ColumnRef{Name: "user_type"},
+					&BinaryExpr{Op: OpDiv, LHS: &ColumnRef{Name: "age"}, RHS: &Literal{Value: 10}},
+				}},
+				Cols:    []*SelectItem{{Expr: &ColumnRef{Name: "id"}}},
+				From:    &From{Tables: []TableExpr{&TableRef{Name: "users"}}},
+				OrderBy: []*OrderItem{{Expr: &ColumnRef{Name: "user_type"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "select all",
+			args: args{
+				sql: "select all id from users",
+			},
+			want: &Select{
+				Cols: []*SelectItem{{Expr: &ColumnRef{Name: "id"}}},
+				From: &From{Tables: []TableExpr{&TableRef{Name: "users"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "in list and between",
+			args: args{
+				sql: "select id from users where id not in (1, 2) and age between 18 and age + 2 and id = 1",
+			},
+			want: &Select{
+				Cols: []*SelectItem{{Expr: &ColumnRef{Name: "id"}}},
+				From: &From{Tables: []TableExpr{&TableRef{Name: "users"}}},
+				Where: &Where{
+					Expr: &BinaryExpr{
+						Op: OpAnd,
+						LHS: &BinaryExpr{
+							Op: OpAnd,
+							LHS: &InExpr{
+								Expr: &ColumnRef{Name: "id"},
+								List: []Expr{&Literal{Value: 1}, &Literal{Value: 2}},
+								Not:  true,
+							},
+							RHS: &BetweenExpr{
+								Expr: &ColumnRef{Name: "age"},
+								Low:  &Literal{Value: 18},
+								High: &BinaryExpr{Op: OpAdd, LHS: &ColumnRef{Name: "age"}, RHS: &Literal{Value: 2}},
+							},
+						},
+						RHS: &BinaryExpr{Op: "=", LHS: &ColumnRef{Name: "id"}, RHS: &Literal{Value: 1}},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "pattern matching",
+			args: args{
+				sql: "select email not like 'a!%%' escape '!', email ilike 'A%', email similar to '(a|b)%', email !~* '^a' from users",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &LikeExpr{
+						Op:      OpLike,
+						Expr:    &ColumnRef{Name: "email"},
+						Pattern: &Literal{Value: "a!%%"},
+						Escape:  &Literal{Value: "!"},
+						Not:     true,
+					}},
+					{Expr: &LikeExpr{Op: OpILike, Expr: &ColumnRef{Name: "email"}, Pattern: &Literal{Value: "A%"}}},
+					{Expr: &LikeExpr{Op: OpSimilar, Expr: &ColumnRef{Name: "email"}, Pattern: &Literal{Value: "(a|b)%"}}},
+					{Expr: &BinaryExpr{Op: OpNotIMatch, LHS: &ColumnRef{Name: "email"}, RHS: &Literal{Value: "^a"}}},
+				},
+				From: &From{Tables: []TableExpr{&TableRef{Name: "users"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "null tests",
+			args: args{
+				sql: "select age is null, age is not null, a = b is not distinct from c, age is distinct from 1 from users",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &IsNullExpr{Expr: &ColumnRef{Name: "age"}}},
+					{Expr: &IsNullExpr{Expr: &ColumnRef{Name: "age"}, Not: true}},
+					{Expr: &IsDistinctExpr{
+						LHS: &BinaryExpr{Op: "=", LHS: &ColumnRef{Name: "a"}, RHS: &ColumnRef{Name: "b"}},
+						RHS: &ColumnRef{Name: "c"},
+						Not: true,
+					}},
+					{Expr: &IsDistinctExpr{LHS: &ColumnRef{Name: "age"}, RHS: &Literal{Value: 1}}},
+				},
+				From: &From{Tables: []TableExpr{&TableRef{Name: "users"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "case expressions",
+			args: args{
+				sql: "select case when age < 18 then 'minor' else 'adult' end, case user_type when 'driver' then 1 end as d, coalesce(age, 0) from users",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &CaseExpr{
+						Whens: []*When{
+							{
+								Cond:   &BinaryExpr{Op: "<", LHS: &ColumnRef{Name: "age"}, RHS: &Literal{Value: 18}},
+								Result: &Literal{Value: "minor"},
+							},
+						},
+						Else: &Literal{Value: "adult"},
+					}},
+					{Expr: &CaseExpr{
+						Operand: &ColumnRef{Name: "user_type"},
+						Whens:   []*When{{Cond: &Literal{Value: "driver"}, Result: &Literal{Value: 1}}},
+					}, Alias: "d"},
+					{Expr: &FuncCall{Name: "coalesce", Args: []Expr{&ColumnRef{Name: "age"}, &Literal{Value: 0}}}},
+				},
+				From: &From{Tables: []TableExpr{&TableRef{Name: "users"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "case without when",
+			args: args{
+				sql: "select case else 1 end from users",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "window functions",
+			args: args{
+				sql: "select row_number() over (), sum(age) over (partition by user_type order by id rows between unbounded preceding and current row), lag(id, 2) over (order by id desc range 1 preceding) from users",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &FuncCall{Name: "row_number", Over: &WindowSpec{}}},
+					{Expr: &FuncCall{
+						Name: "sum",
+						Args: []Expr{&ColumnRef{Name: "age"}},
+						Over: &WindowSpec{
+							PartitionBy: []Expr{&ColumnRef{Name: "user_type"}},
+							OrderBy:     []*OrderItem{{Expr: &ColumnRef{Name: "id"}}},
+							Frame: &Frame{
+								Mode:  FrameRows,
+								Start: &FrameBound{Kind: UnboundedPreceding},
+								End:   &FrameBound{Kind: CurrentRow},
+							},
+						},
+					}},
+					{Expr: &FuncCall{
+						Name: "lag",
+						Args: []Expr{&ColumnRef{Name: "id"}, &Literal{Value: 2}},
+						Over: &WindowSpec{
+							OrderBy: []*OrderItem{{Expr: &ColumnRef{Name: "id"}, Desc: true}},
+							Frame: &Frame{
+								Mode:  FrameRange,
+								Start: &FrameBound{Kind: Preceding, Offset: &Literal{Value: 1}},
+								End:   &FrameBound{Kind: CurrentRow},
+							},
+						},
+					}},
+				},
+				From: &From{Tables: []TableExpr{&TableRef{Name: "users"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "window frame without bound",
+			args: args{
+				sql: "select count(*) over (rows between 1 and 2) from users",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "null literal",
+			args: args{
+				sql: "select null, age = null from users where not null",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &Literal{}},
+					{Expr: &BinaryExpr{Op: "=", LHS: &ColumnRef{Name: "age"}, RHS: &Literal{}}},
+				},
+				From:  &From{Tables: []TableExpr{&TableRef{Name: "users"}}},
+				Where: &Where{Expr: &UnaryExpr{Op: "NOT", Expr: &Literal{}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "numeric and boolean literals",
+			args: args{
+				sql: "select price * 1.5, -2.5e1 from items where is_active = true and not false",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &BinaryExpr{Op: "*", LHS: &ColumnRef{Name: "price"}, RHS: &Literal{Value: types.NewDecimal(15, 1)}}},
+					{Expr: &UnaryExpr{Op: "-", Expr: &Literal{Value: types.NewDecimal(25, 0)}}},
+				},
+				From: &From{Tables: []TableExpr{&TableRef{Name: "items"}}},
+				Where: &Where{Expr: &BinaryExpr{
+					Op:  "AND",
+					LHS: &BinaryExpr{Op: "=", LHS: &ColumnRef{Name: "is_active"}, RHS: &Literal{Value: true}},
+					RHS: &UnaryExpr{Op: "NOT", Expr: &Literal{Value: false}},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "date and time literals and casts",
+			args: args{
+				sql: "select date '2026-01-01', interval '3 days', cast(ts as date), ts::timestamptz, extract(year from ts), ts at time zone 'UTC' from events",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &CastExpr{Expr: &Literal{Value: "2026-01-01"}, Type: &TypeName{Name: "date"}}},
+					{Expr: &CastExpr{Expr: &Literal{Value: "3 days"}, Type: &TypeName{Name: "interval"}}},
+					{Expr: &CastExpr{Expr: &ColumnRef{Name: "ts"}, Type: &TypeName{Name: "date"}}},
+					{Expr: &CastExpr{Expr: &ColumnRef{Name: "ts"}, Type: &TypeName{Name: "timestamptz"}}},
+					{Expr: &FuncCall{Name: "extract", Args: []Expr{&Literal{Value: "year"}, &ColumnRef{Name: "ts"}}}},
+					{Expr: &FuncCall{Name: "timezone", Args: []Expr{&Literal{Value: "UTC"}, &ColumnRef{Name: "ts"}}}},
+				},
+				From: &From{Tables: []TableExpr{&TableRef{Name: "events"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "cast binds tighter than minus",
+			args: args{
+				sql: "select -1::numeric(5, 2)",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &UnaryExpr{Op: "-", Expr: &CastExpr{Expr: &Literal{Value: 1}, Type: &TypeName{Name: "numeric", Modifiers: []int{5, 2}}}}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "cast to types of several words",
+			args: args{
+				sql: "select 2::double precision, ts::timestamp with time zone, ts::time without time zone t, name::character varying(10) as n from events",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &CastExpr{Expr: &Literal{Value: 2}, Type: &TypeName{Name: "double precision"}}},
+					{Expr: &CastExpr{Expr: &ColumnRef{Name: "ts"}, Type: &TypeName{Name: "timestamp with time zone"}}},
+					{Expr: &CastExpr{Expr: &ColumnRef{Name: "ts"}, Type: &TypeName{Name: "time without time zone"}}, Alias: "t"},
+					{Expr: &CastExpr{Expr: &ColumnRef{Name: "name"}, Type: &TypeName{Name: "character varying", Modifiers: []int{10}}}, Alias: "n"},
+				},
+				From: &From{Tables: []TableExpr{&TableRef{Name: "events"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "cast followed by an alias",
+			args: args{
+				sql: "select ts::timestamp zone from events where id::text = '1'",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &CastExpr{Expr: &ColumnRef{Name: "ts"}, Type: &TypeName{Name: "timestamp"}}, Alias: "zone"},
+				},
+				From: &From{Tables: []TableExpr{&TableRef{Name: "events"}}},
+				Where: &Where{Expr: &BinaryExpr{
+					Op:  "=",
+					LHS: &CastExpr{Expr: &ColumnRef{Name: "id"}, Type: &TypeName{Name: "text"}},
+					RHS: &Literal{Value: "1"},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "cast to unknown time zone clause",
+			args: args{
+				sql: "select ts::timestamp with local zone from events",
+			},
+			wantErr: true,
+		},
+		{
+			name: "timestamp with time zone column",
+			args: args{
+				sql: "create table events (at_utc timestamp with time zone, at_local timestamp without time zone)",
+			},
+			want: &CreateTable{
+				TableName: "events",
+				Columns: []*ColumnDef{
+					{Name: "at_utc", Type: &TypeName{Name: "timestamp with time zone"}},
+					{Name: "at_local", Type: &TypeName{Name: "timestamp without time zone"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unreserved keywords as names",
+			args: args{
+				sql: "create table index (id int, at timestamptz, precision int, first text)",
+			},
+			want: &CreateTable{
+				TableName: "index",
+				Columns: []*ColumnDef{
+					{Name: "id", Type: &TypeName{Name: "int"}},
+					{Name: "at", Type: &TypeName{Name: "timestamptz"}},
+					{Name: "precision", Type: &TypeName{Name: "int"}},
+					{Name: "first", Type: &TypeName{Name: "text"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unreserved keywords in expressions",
+			args: args{
+				sql: "select at at time zone 'UTC' as at, e.first from index e order by at",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &FuncCall{Name: "timezone", Args: []Expr{&Literal{Value: "UTC"}, &ColumnRef{Name: "at"}}}, Alias: "at"},
+					{Expr: &ColumnRef{Table: "e", Name: "first"}},
+				},
+				From:    &From{Tables: []TableExpr{&TableRef{Name: "index", Alias: "e"}}},
+				OrderBy: []*OrderItem{{Expr: &ColumnRef{Name: "at"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "set time zone",
+			args: args{
+				sql: "set time zone 'Europe/Paris'",
+			},
+			want:    &SetVar{Name: "timezone", Value: "Europe/Paris"},
+			wantErr: false,
+		},
+		{
+			name: "set to default",
+			args: args{
+				sql: "set timezone = default",
+			},
+			want:    &SetVar{Name: "timezone"},
+			wantErr: false,
+		},
+		{
+			name: "show time zone",
+			args: args{
+				sql: "show time zone",
+			},
+			want:    &ShowVar{Name: "timezone"},
+			wantErr: false,
+		},
+		{
+			name: "set unknown words",
+			args: args{
+				sql: "set time place 'UTC'",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "json operators",
+			args: args{
+				sql: "select data->'a'->>'b' from docs where data @> '{\"x\": 1}' and data ? 'k'",
+			},
+			want: &Select{
+				Cols: []*SelectItem{
+					{Expr: &BinaryExpr{
+						Op: "->>",
+						LHS: &BinaryExpr{
+							Op:  "->",
+							LHS: &ColumnRef{Name: "data"},
+							RHS: &Literal{Value: "a"},
+						},
+						RHS: &Literal{Value: "b"},
+					}},
+				},
+				From: &From{
+					Tables: []TableExpr{&TableRef{Name: "docs"}},
+				},
+				Where: &Where{
+					Expr: &BinaryExpr{
+						Op: "AND",
+						LHS: &BinaryExpr{
+							Op:  "@>",
+							LHS: &ColumnRef{Name: "data"},
+							RHS: &Literal{Value: `{"x": 1}`},
+						},
+						RHS: &BinaryExpr{
+							Op:  "?",
+							LHS: &ColumnRef{Name: "data"},
+							RHS: &Literal{Value: "k"},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "function in from",
+			args: args{
+				sql: "select * from docs, jsonb_array_elements(docs.data) as e",
+			},
+			want: &Select{
+				Cols: []*SelectItem{{Expr: &Star{}}},
 				From: &From{
-					TableName: "table1",
+					Tables: []TableExpr{
+						&TableRef{Name: "docs"},
+						&FuncTable{
+							Call: &FuncCall{
+								Name: "jsonb_array_elements",
+								Args: []Expr{&ColumnRef{Table: "docs", Name: "data"}},
+							},
+							Alias: "e",
+						},
+					},
 				},
 			},
 			wantErr: false,
 		},
+		{
+			name: "create index",
+			args: args{
+				sql: "create index if not exists docs_idx on docs using gin (data)",
+			},
+			want: &CreateIndex{
+				Name:        "docs_idx",
+				TableName:   "docs",
+				Method:      "gin",
+				Column:      "data",
+				IfNotExists: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "create index without name",
+			args: args{
+				sql: "create index on docs (data)",
+			},
+			want:    &CreateIndex{TableName: "docs", Column: "data"},
+			wantErr: false,
+		},
+		{
+			name: "unterminated string",
+			args: args{
+				sql: "select email from users where user_type = 'customer",
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
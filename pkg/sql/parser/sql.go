@@ -3,117 +3,222 @@ package parser
 
 import __yyfmt__ "fmt"
 
+import "github.com/hiepd/galedb/pkg/types"
+
 func setParseTree(yylex yyLexer, stmt Statement) {
 	yylex.(*Lexer).ParseTree = stmt
 }
 
+// qualifiedTable resolves a schema qualified table name. Tables all live in
+// the public schema.
+func qualifiedTable(yylex yyLexer, schema, table string) string {
+	if schema != "public" {
+		yylex.Error("schema " + schema + " does not exist")
+	}
+	return table
+}
+
+func expectRelation(yylex yyLexer, got, want string) {
+	if got != want {
+		yylex.Error("syntax error at or near " + got)
+	}
+}
+
+// expectTimeZone checks for the words TIME ZONE, which aren't keywords.
+func expectTimeZone(yylex yyLexer, time, zone string) {
+	expectRelation(yylex, time, "time")
+	expectRelation(yylex, zone, "zone")
+}
+
 type yySymType struct {
 	yys       int
 	str       string
 	num       int
+	dec       types.Decimal
 	statement Statement
 	strs      []string
-	cond      *Condition
-	conds     []*Condition
 	where     *Where
+	expr      Expr
+	exprs     []Expr
+	rows      [][]Expr
+	assign    *Assignment
+	assigns   []*Assignment
+	flag      bool
+	nums      []int
+	typ       *TypeName
+	coldef    *ColumnDef
+	coldefs   []*ColumnDef
+	colopt    *ColumnOption
+	colopts   []*ColumnOption
+	item      *SelectItem
+	items     []*SelectItem
+	order     *OrderItem
+	orders    []*OrderItem
+	nulls     NullsOrder
+	limit     *Limit
+	table     TableExpr
+	tables    []TableExpr
+	joinKind  JoinKind
+	joinCond  *JoinCond
+	with      *With
+	cte       *CTE
+	ctes      []*CTE
+	distinct  *Distinct
+	when      *When
+	whens     []*When
+	window    *WindowSpec
+	frame     *Frame
+	bound     *FrameBound
+	frameMode FrameMode
 }
 
 const LEX_ERROR = 57346
 const NAME = 57347
 const NUMBER = 57348
-const STRING = 57349
-const INTNUM = 57350
-const APPROXNUM = 57351
-const OR = 57352
-const AND = 57353
-const NOT = 57354
-const RELATION = 57355
-const OPERATOR = 57356
-const ASTERISK = 57357
-const ALL = 57358
-const AMMSC = 57359
-const ANY = 57360
-const ASC = 57361
-const AS = 57362
-const AUTHORIZATION = 57363
-const AVG = 57364
-const BETWEEN = 57365
-const BY = 57366
-const CHARACTER = 57367
-const CHECK = 57368
-const CLOSE = 57369
-const COMMIT = 57370
-const CONTINUE = 57371
-const CREATE = 57372
-const CURRENT = 57373
-const COMMA = 57374
-const CURSOR = 57375
-const DECIMAL = 57376
-const DECLARE = 57377
-const DEFAULT = 57378
-const DELETE = 57379
-const DESC = 57380
-const DISTINCT = 57381
-const DOUBLE = 57382
-const ESCAPE = 57383
-const EXISTS = 57384
-const FETCH = 57385
-const FLOAT = 57386
-const FOR = 57387
-const FOREIGN = 57388
-const FOUND = 57389
-const FROM = 57390
-const GOTO = 57391
-const GRANT = 57392
-const GROUP = 57393
-const HAVING = 57394
-const IN = 57395
-const INDICATOR = 57396
-const INSERT = 57397
-const INTEGER = 57398
-const INTO = 57399
-const IS = 57400
-const MIN = 57401
-const MAX = 57402
-const KEY = 57403
-const LANGUAGE = 57404
-const LIKE = 57405
-const NULLX = 57406
-const NUMERIC = 57407
-const OF = 57408
-const ON = 57409
-const OPEN = 57410
-const OPTION = 57411
-const ORDER = 57412
-const PARAMETER = 57413
-const PRECISION = 57414
-const PRIMARY = 57415
-const PRIVILEGES = 57416
-const PROCEDURE = 57417
-const PUBLIC = 57418
-const REAL = 57419
-const REFERENCES = 57420
-const ROLLBACK = 57421
-const SCHEMA = 57422
-const SELECT = 57423
-const SET = 57424
-const SMALLINT = 57425
-const SOME = 57426
-const SQLCODE = 57427
-const SQLERROR = 57428
-const SUM = 57429
-const TABLE = 57430
-const TO = 57431
-const UNION = 57432
-const UNIQUE = 57433
-const UPDATE = 57434
-const USER = 57435
-const VALUES = 57436
-const VIEW = 57437
-const WHENEVER = 57438
-const WHERE = 57439
-const WITH = 57440
-const WORK = 57441
+const DECIMALNUM = 57349
+const STRING = 57350
+const INTNUM = 57351
+const APPROXNUM = 57352
+const UNION = 57353
+const EXCEPT = 57354
+const INTERSECT = 57355
+const JOIN = 57356
+const CROSS = 57357
+const LEFT = 57358
+const RIGHT = 57359
+const FULL = 57360
+const INNER = 57361
+const NATURAL = 57362
+const OR = 57363
+const AND = 57364
+const NOT = 57365
+const IS = 57366
+const RELATION = 57367
+const IN = 57368
+const NOT_LA = 57369
+const BETWEEN = 57370
+const LIKE = 57371
+const ILIKE = 57372
+const SIMILAR = 57373
+const ESCAPE = 57374
+const MATCH_OP = 57375
+const JSON_OP = 57376
+const OPERATOR = 57377
+const ASTERISK = 57378
+const AT = 57379
+const UMINUS = 57380
+const TYPECAST = 57381
+const ALL = 57382
+const AMMSC = 57383
+const ANY = 57384
+const ASC = 57385
+const AS = 57386
+const AUTHORIZATION = 57387
+const AVG = 57388
+const BY = 57389
+const CHARACTER = 57390
+const CHECK = 57391
+const CLOSE = 57392
+const COMMIT = 57393
+const CONTINUE = 57394
+const CREATE = 57395
+const CURRENT = 57396
+const COMMA = 57397
+const CURSOR = 57398
+const DECIMAL = 57399
+const DECLARE = 57400
+const DEFAULT = 57401
+const DELETE = 57402
+const DESC = 57403
+const DISTINCT = 57404
+const DOUBLE = 57405
+const EXISTS = 57406
+const FETCH = 57407
+const FLOAT = 57408
+const FOR = 57409
+const FOREIGN = 57410
+const FOUND = 57411
+const FROM = 57412
+const GOTO = 57413
+const GRANT = 57414
+const GROUP = 57415
+const HAVING = 57416
+const INDICATOR = 57417
+const INSERT = 57418
+const INTEGER = 57419
+const INTO = 57420
+const MIN = 57421
+const MAX = 57422
+const KEY = 57423
+const LANGUAGE = 57424
+const NULLX = 57425
+const NUMERIC = 57426
+const OF = 57427
+const ON = 57428
+const OPEN = 57429
+const OPTION = 57430
+const ORDER = 57431
+const PARAMETER = 57432
+const PRECISION = 57433
+const PRIMARY = 57434
+const PRIVILEGES = 57435
+const PROCEDURE = 57436
+const PUBLIC = 57437
+const REAL = 57438
+const REFERENCES = 57439
+const ROLLBACK = 57440
+const SCHEMA = 57441
+const SELECT = 57442
+const SET = 57443
+const SMALLINT = 57444
+const SOME = 57445
+const SQLCODE = 57446
+const SQLERROR = 57447
+const SUM = 57448
+const TABLE = 57449
+const TO = 57450
+const UNIQUE = 57451
+const UPDATE = 57452
+const USER = 57453
+const VALUES = 57454
+const VIEW = 57455
+const WHENEVER = 57456
+const WHERE = 57457
+const WITH = 57458
+const WORK = 57459
+const DROP = 57460
+const IF = 57461
+const NULLS = 57462
+const FIRST = 57463
+const LAST = 57464
+const LIMIT = 57465
+const OFFSET = 57466
+const NEXT = 57467
+const ROW = 57468
+const ROWS = 57469
+const ONLY = 57470
+const OUTER = 57471
+const USING = 57472
+const RECURSIVE = 57473
+const CASE = 57474
+const WHEN = 57475
+const THEN = 57476
+const ELSE = 57477
+const END = 57478
+const OVER = 57479
+const PARTITION = 57480
+const RANGE = 57481
+const UNBOUNDED = 57482
+const PRECEDING = 57483
+const FOLLOWING = 57484
+const TRUE = 57485
+const FALSE = 57486
+const CAST = 57487
+const EXTRACT = 57488
+const SHOW = 57489
+const INDEX = 57490
+const VARYING = 57491
+const WITHOUT = 57492
 
 var yyToknames = [...]string{
 	"$end",
@@ -122,16 +227,42 @@ var yyToknames = [...]string{
 	"LEX_ERROR",
 	"NAME",
 	"NUMBER",
+	"DECIMALNUM",
 	"STRING",
 	"INTNUM",
 	"APPROXNUM",
+	"UNION",
+	"EXCEPT",
+	"INTERSECT",
+	"JOIN",
+	"CROSS",
+	"LEFT",
+	"RIGHT",
+	"FULL",
+	"INNER",
+	"NATURAL",
 	"OR",
 	"AND",
 	"NOT",
+	"IS",
 	"RELATION",
+	"IN",
+	"NOT_LA",
+	"BETWEEN",
+	"LIKE",
+	"ILIKE",
+	"SIMILAR",
+	"ESCAPE",
+	"MATCH_OP",
+	"JSON_OP",
 	"OPERATOR",
-	"'.'",
 	"ASTERISK",
+	"'/'",
+	"'%'",
+	"AT",
+	"UMINUS",
+	"TYPECAST",
+	"'.'",
 	"ALL",
 	"AMMSC",
 	"ANY",
@@ -139,7 +270,6 @@ var yyToknames = [...]string{
 	"AS",
 	"AUTHORIZATION",
 	"AVG",
-	"BETWEEN",
 	"BY",
 	"CHARACTER",
 	"CHECK",
@@ -157,7 +287,6 @@ var yyToknames = [...]string{
 	"DESC",
 	"DISTINCT",
 	"DOUBLE",
-	"ESCAPE",
 	"EXISTS",
 	"FETCH",
 	"FLOAT",
@@ -169,17 +298,14 @@ var yyToknames = [...]string{
 	"GRANT",
 	"GROUP",
 	"HAVING",
-	"IN",
 	"INDICATOR",
 	"INSERT",
 	"INTEGER",
 	"INTO",
-	"IS",
 	"MIN",
 	"MAX",
 	"KEY",
 	"LANGUAGE",
-	"LIKE",
 	"NULLX",
 	"NUMERIC",
 	"OF",
@@ -206,7 +332,6 @@ var yyToknames = [...]string{
 	"SUM",
 	"TABLE",
 	"TO",
-	"UNION",
 	"UNIQUE",
 	"UPDATE",
 	"USER",
@@ -216,9 +341,41 @@ var yyToknames = [...]string{
 	"WHERE",
 	"WITH",
 	"WORK",
+	"DROP",
+	"IF",
+	"NULLS",
+	"FIRST",
+	"LAST",
+	"LIMIT",
+	"OFFSET",
+	"NEXT",
+	"ROW",
+	"ROWS",
+	"ONLY",
+	"OUTER",
+	"USING",
+	"RECURSIVE",
+	"CASE",
+	"WHEN",
+	"THEN",
+	"ELSE",
+	"END",
+	"OVER",
+	"PARTITION",
+	"RANGE",
+	"UNBOUNDED",
+	"PRECEDING",
+	"FOLLOWING",
+	"TRUE",
+	"FALSE",
+	"CAST",
+	"EXTRACT",
+	"SHOW",
+	"INDEX",
+	"VARYING",
+	"WITHOUT",
 	"'('",
 	"')'",
-	"','",
 }
 
 var yyStatenames = [...]string{}
@@ -231,82 +388,490 @@ var yyExca = [...]int{
 	-1, 1,
 	1, -1,
 	-2, 0,
+	-1, 257,
+	26, 0,
+	27, 0,
+	28, 0,
+	29, 0,
+	30, 0,
+	31, 0,
+	-2, 171,
+	-1, 258,
+	26, 0,
+	27, 0,
+	28, 0,
+	29, 0,
+	30, 0,
+	31, 0,
+	-2, 175,
+	-1, 326,
+	26, 0,
+	27, 0,
+	28, 0,
+	29, 0,
+	30, 0,
+	31, 0,
+	-2, 173,
+	-1, 327,
+	26, 0,
+	27, 0,
+	28, 0,
+	29, 0,
+	30, 0,
+	31, 0,
+	-2, 177,
+	-1, 338,
+	26, 0,
+	27, 0,
+	28, 0,
+	29, 0,
+	30, 0,
+	31, 0,
+	-2, 179,
+	-1, 396,
+	26, 0,
+	27, 0,
+	28, 0,
+	29, 0,
+	30, 0,
+	31, 0,
+	-2, 181,
+	-1, 407,
+	24, 0,
+	-2, 187,
+	-1, 448,
+	24, 0,
+	-2, 188,
 }
 
 const yyPrivate = 57344
 
-const yyLast = 28
+const yyLast = 1608
 
 var yyAct = [...]int{
-	13, 4, 8, 17, 20, 23, 22, 21, 6, 26,
-	27, 18, 19, 28, 24, 15, 7, 11, 10, 9,
-	3, 2, 1, 12, 16, 25, 5, 14,
+	117, 157, 139, 147, 363, 434, 472, 133, 364, 315,
+	69, 382, 234, 309, 266, 216, 236, 8, 283, 166,
+	30, 285, 476, 224, 138, 465, 142, 111, 174, 175,
+	30, 189, 176, 181, 182, 183, 184, 185, 186, 213,
+	187, 188, 177, 178, 179, 180, 190, 425, 191, 272,
+	450, 361, 425, 361, 344, 235, 274, 30, 361, 361,
+	296, 453, 30, 213, 441, 30, 416, 389, 388, 387,
+	365, 350, 348, 277, 29, 467, 114, 423, 275, 346,
+	140, 141, 368, 286, 77, 195, 140, 148, 174, 175,
+	343, 189, 176, 181, 182, 183, 184, 185, 186, 324,
+	187, 188, 177, 178, 179, 180, 190, 196, 191, 288,
+	281, 80, 345, 347, 355, 12, 85, 248, 212, 90,
+	205, 201, 19, 200, 199, 149, 84, 457, 21, 480,
+	481, 202, 299, 102, 284, 419, 485, 198, 17, 458,
+	284, 383, 358, 370, 477, 59, 482, 449, 442, 424,
+	413, 140, 218, 30, 30, 390, 360, 295, 112, 103,
+	214, 303, 454, 14, 208, 225, 226, 102, 271, 22,
+	64, 114, 18, 30, 240, 371, 372, 159, 20, 238,
+	13, 160, 61, 20, 256, 155, 144, 161, 207, 209,
+	67, 101, 103, 167, 270, 231, 328, 232, 259, 193,
+	194, 23, 78, 197, 27, 99, 254, 219, 220, 15,
+	66, 140, 73, 100, 151, 140, 294, 278, 204, 290,
+	463, 135, 276, 270, 28, 101, 83, 58, 211, 374,
+	293, 196, 26, 297, 289, 330, 331, 332, 333, 30,
+	427, 334, 223, 305, 406, 238, 300, 301, 321, 144,
+	320, 256, 169, 112, 318, 319, 256, 352, 241, 242,
+	243, 244, 245, 246, 247, 263, 322, 171, 257, 258,
+	323, 260, 261, 325, 57, 104, 340, 341, 335, 429,
+	70, 72, 71, 55, 279, 308, 307, 312, 313, 314,
+	311, 310, 150, 91, 291, 349, 65, 339, 140, 218,
+	105, 357, 361, 428, 136, 75, 154, 264, 30, 145,
+	30, 167, 366, 153, 238, 56, 238, 94, 386, 376,
+	93, 378, 106, 362, 381, 384, 385, 76, 262, 418,
+	256, 256, 256, 256, 256, 326, 327, 162, 92, 306,
+	163, 164, 391, 338, 483, 484, 392, 107, 108, 402,
+	227, 152, 397, 398, 399, 400, 401, 81, 375, 109,
+	435, 73, 146, 221, 192, 417, 414, 317, 359, 79,
+	140, 430, 291, 82, 95, 178, 179, 180, 190, 30,
+	191, 30, 30, 191, 190, 238, 191, 238, 238, 334,
+	433, 210, 437, 438, 256, 86, 60, 432, 440, 439,
+	486, 379, 377, 71, 436, 96, 97, 158, 471, 316,
+	410, 452, 396, 331, 332, 333, 443, 451, 334, 62,
+	403, 404, 268, 412, 411, 407, 408, 249, 461, 250,
+	251, 252, 253, 369, 462, 393, 415, 342, 464, 280,
+	459, 30, 420, 468, 265, 422, 59, 238, 330, 331,
+	332, 333, 469, 88, 334, 68, 25, 256, 431, 380,
+	24, 312, 313, 314, 311, 118, 128, 129, 127, 140,
+	148, 478, 5, 7, 256, 6, 4, 3, 444, 445,
+	479, 11, 10, 9, 168, 170, 2, 256, 473, 447,
+	448, 1, 37, 487, 354, 255, 456, 455, 353, 33,
+	177, 178, 179, 180, 190, 460, 191, 291, 282, 291,
+	34, 356, 203, 291, 124, 74, 16, 475, 329, 466,
+	287, 206, 273, 36, 304, 373, 123, 120, 122, 121,
+	470, 330, 331, 332, 333, 137, 143, 334, 367, 41,
+	181, 182, 183, 184, 185, 186, 132, 187, 188, 177,
+	178, 179, 180, 190, 47, 191, 426, 215, 217, 222,
+	98, 302, 174, 175, 50, 189, 176, 181, 182, 183,
+	184, 185, 186, 52, 187, 188, 177, 178, 179, 180,
+	190, 228, 191, 44, 38, 42, 165, 110, 43, 409,
+	330, 331, 332, 333, 49, 134, 334, 118, 128, 129,
+	127, 45, 48, 474, 46, 39, 130, 131, 125, 126,
+	51, 40, 53, 54, 119, 115, 446, 187, 188, 177,
+	178, 179, 180, 190, 37, 191, 63, 116, 89, 233,
+	298, 33, 405, 187, 188, 177, 178, 179, 180, 190,
+	87, 191, 34, 267, 32, 0, 0, 0, 0, 35,
+	0, 0, 0, 0, 292, 36, 0, 0, 0, 120,
+	395, 187, 188, 177, 178, 179, 180, 190, 0, 191,
+	0, 41, 0, 118, 128, 129, 127, 0, 132, 187,
+	188, 177, 178, 179, 180, 190, 47, 191, 0, 0,
+	0, 115, 0, 0, 0, 0, 50, 0, 0, 0,
+	37, 0, 0, 116, 0, 52, 0, 33, 308, 307,
+	312, 313, 314, 311, 310, 44, 38, 42, 34, 0,
+	43, 0, 0, 0, 0, 35, 49, 134, 0, 0,
+	0, 36, 0, 45, 48, 120, 46, 39, 130, 131,
+	125, 126, 51, 40, 53, 54, 119, 41, 0, 118,
+	128, 129, 127, 0, 132, 0, 0, 0, 0, 0,
+	0, 0, 47, 0, 0, 0, 0, 115, 0, 0,
+	0, 0, 50, 0, 0, 0, 37, 0, 0, 116,
+	113, 52, 0, 33, 0, 0, 0, 20, 0, 0,
+	0, 44, 38, 42, 34, 0, 43, 0, 0, 0,
+	0, 35, 49, 134, 0, 0, 0, 36, 0, 45,
+	48, 120, 46, 39, 130, 131, 125, 126, 51, 40,
+	53, 54, 119, 41, 0, 118, 128, 129, 127, 0,
+	132, 0, 0, 0, 0, 0, 0, 0, 47, 0,
+	0, 0, 0, 115, 0, 0, 0, 0, 50, 0,
+	0, 0, 37, 0, 0, 116, 0, 52, 0, 33,
+	0, 0, 0, 156, 0, 0, 0, 44, 38, 42,
+	34, 0, 43, 0, 0, 0, 0, 35, 49, 134,
+	0, 0, 0, 36, 0, 45, 48, 120, 46, 39,
+	130, 131, 125, 126, 51, 40, 53, 54, 119, 41,
+	0, 118, 128, 129, 127, 0, 132, 176, 181, 182,
+	183, 184, 185, 186, 47, 187, 188, 177, 178, 179,
+	180, 190, 0, 191, 50, 0, 0, 0, 37, 0,
+	0, 255, 0, 52, 0, 33, 0, 0, 0, 0,
+	0, 0, 0, 44, 38, 42, 34, 0, 43, 0,
+	0, 0, 0, 475, 49, 134, 0, 0, 0, 36,
+	0, 45, 48, 120, 46, 39, 130, 131, 125, 126,
+	51, 40, 53, 54, 119, 41, 0, 0, 0, 0,
+	0, 0, 132, 0, 0, 0, 0, 0, 0, 0,
+	47, 0, 0, 0, 0, 0, 0, 0, 0, 175,
+	50, 189, 176, 181, 182, 183, 184, 185, 186, 52,
+	187, 188, 177, 178, 179, 180, 190, 0, 191, 44,
+	38, 42, 0, 0, 43, 0, 0, 0, 0, 0,
+	49, 134, 0, 118, 128, 129, 127, 45, 48, 474,
+	46, 39, 130, 131, 125, 126, 51, 40, 53, 54,
+	119, 115, 394, 187, 188, 177, 178, 179, 180, 190,
+	37, 191, 0, 116, 0, 0, 0, 33, 337, 187,
+	188, 177, 178, 179, 180, 190, 0, 191, 34, 0,
+	0, 0, 0, 0, 0, 35, 0, 0, 0, 0,
+	0, 36, 0, 0, 0, 120, 336, 187, 188, 177,
+	178, 179, 180, 190, 0, 191, 0, 41, 0, 118,
+	128, 129, 127, 0, 132, 0, 0, 0, 0, 0,
+	0, 0, 47, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 50, 0, 0, 0, 37, 0, 0, 255,
+	0, 52, 0, 33, 0, 0, 0, 0, 0, 0,
+	0, 44, 38, 42, 34, 0, 43, 0, 0, 0,
+	0, 35, 49, 134, 0, 0, 0, 36, 0, 45,
+	48, 120, 46, 39, 130, 131, 125, 126, 51, 40,
+	53, 54, 119, 41, 0, 239, 0, 0, 0, 0,
+	132, 0, 0, 0, 0, 0, 0, 0, 47, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 50, 0,
+	0, 0, 37, 0, 0, 0, 0, 52, 0, 33,
+	0, 0, 0, 0, 0, 0, 0, 44, 38, 42,
+	34, 0, 43, 0, 0, 0, 0, 35, 49, 134,
+	0, 0, 0, 36, 0, 45, 48, 173, 46, 39,
+	130, 131, 125, 126, 51, 40, 53, 54, 119, 41,
+	0, 239, 0, 174, 175, 0, 189, 176, 181, 182,
+	183, 184, 185, 186, 47, 187, 188, 177, 178, 179,
+	180, 190, 0, 191, 50, 0, 0, 0, 37, 172,
+	0, 0, 0, 52, 0, 33, 0, 0, 0, 20,
+	0, 0, 0, 44, 38, 42, 34, 0, 43, 0,
+	0, 0, 0, 35, 49, 0, 0, 0, 0, 36,
+	0, 45, 48, 0, 46, 39, 0, 0, 0, 0,
+	51, 40, 53, 54, 237, 41, 0, 31, 0, 174,
+	175, 0, 189, 176, 181, 182, 183, 184, 185, 186,
+	47, 187, 188, 177, 178, 179, 180, 190, 0, 191,
+	50, 0, 0, 0, 37, 351, 0, 0, 269, 52,
+	0, 33, 0, 0, 0, 0, 0, 0, 0, 44,
+	38, 42, 34, 0, 43, 0, 0, 0, 0, 35,
+	49, 0, 0, 0, 0, 36, 0, 45, 48, 0,
+	46, 39, 0, 0, 0, 0, 51, 40, 53, 54,
+	237, 41, 0, 31, 0, 174, 175, 0, 189, 176,
+	181, 182, 183, 184, 185, 186, 47, 187, 188, 177,
+	178, 179, 180, 190, 0, 191, 50, 0, 0, 0,
+	37, 0, 0, 0, 0, 52, 0, 33, 0, 0,
+	0, 0, 0, 0, 0, 44, 38, 42, 34, 0,
+	43, 0, 0, 0, 0, 35, 49, 0, 0, 0,
+	0, 36, 0, 45, 48, 0, 46, 39, 0, 0,
+	0, 0, 51, 40, 53, 54, 0, 41, 0, 0,
+	0, 174, 175, 0, 189, 176, 181, 182, 183, 184,
+	185, 186, 47, 187, 188, 177, 178, 179, 180, 190,
+	0, 191, 50, 0, 0, 0, 0, 0, 0, 0,
+	0, 52, 0, 225, 226, 0, 0, 0, 0, 0,
+	0, 44, 38, 42, 0, 0, 43, 0, 0, 0,
+	0, 0, 49, 0, 0, 0, 0, 0, 0, 45,
+	48, 0, 46, 39, 0, 0, 0, 0, 51, 40,
+	53, 54, 174, 175, 0, 189, 176, 181, 182, 183,
+	184, 185, 186, 0, 187, 188, 177, 178, 179, 180,
+	190, 0, 191, 0, 0, 0, 0, 229, 0, 189,
+	176, 181, 182, 183, 184, 185, 186, 0, 187, 188,
+	177, 178, 179, 180, 190, 230, 191, 421,
 }
 
 var yyPact = [...]int{
-	-81, -1000, -1000, -1000, 11, -31, -1000, -1000, 11, -98,
-	10, -1000, -1000, 6, -1000, -11, -4, -1000, -7, -8,
-	9, 6, 4, 7, -1000, -1000, -1000, -1000, -1000,
+	59, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, 18, 91, 455, 451, 101, 143, 1408, 210,
+	140, 60, 60, 48, 185, 450, 269, 262, 1408, 98,
+	327, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, 1408, 299, 441, -1000, -28,
+	1408, 372, 448, 1408, 226, 312, 312, 312, -1000, 65,
+	257, 257, 257, 309, 744, -1000, 132, -28, 1408, 1408,
+	68, 441, 299, 315, 1408, -29, 225, 125, -1000, 293,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 32,
+	99, 820, 53, 1028, 101, -1000, -1000, 101, 101, 1028,
+	194, -1000, 1242, -1000, 322, 1028, 1028, -1000, 77, 668,
+	-30, -1000, -1000, -1000, -1000, -31, -33, -1000, -1000, -1000,
+	-1000, -1000, -1000, -9, 1028, -34, 73, 131, -1000, 366,
+	-1000, -1000, -1000, -1000, 1028, -1000, -36, 5, -1000, 1408,
+	-1000, 1408, 1408, -1000, -1000, 541, -1000, 321, 1028, -1000,
+	-1000, 1394, 390, -1000, 390, 292, -1000, 1541, 68, 744,
+	-1000, 1256, 1408, -1000, 1028, 1028, 1028, 1028, 1028, 1028,
+	1028, -37, 401, 1104, 1028, 1028, 87, 1028, 1028, 242,
+	439, 417, 1332, 1565, 342, -1000, 13, 67, -82, 64,
+	1028, 434, -44, -2, 541, 1028, -1000, -45, -1000, 1408,
+	592, 541, 64, 1408, -1000, 2, -1000, -1000, 417, -1,
+	-1000, 1408, 36, 541, -1000, -1000, -1000, 1028, 38, -1000,
+	-1000, 167, -1000, 281, 694, 362, -1000, 1180, 362, -47,
+	-1000, 977, 1565, 514, 339, 345, 345, 345, 668, -55,
+	1104, 1028, 1028, 85, 496, 1104, -1000, 1064, 1036, 1028,
+	465, 465, -1000, 211, 204, 432, -1000, -64, -40, -1000,
+	-1000, -1000, -83, 1028, -1000, -1000, -1000, -1000, -84, 1318,
+	184, -27, 4, -1000, 1028, 1, 541, 265, 592, -1000,
+	-1000, 541, -1000, -85, -1000, -1000, 1408, -1000, -72, 428,
+	12, -1000, -1000, 51, 152, 308, 1256, 388, 1256, 387,
+	445, -1000, 9, 9, 9, -1000, 1408, -1000, -86, -87,
+	694, -1000, -88, 0, 668, 413, 1020, 628, 1028, 1104,
+	1104, 1104, 1104, 1104, 417, 348, 1028, 1028, 600, -1000,
+	171, 1028, 1028, 404, -1000, -1000, 419, 418, -1000, -5,
+	-1000, 417, 1028, -89, 120, 279, -4, -1000, 1028, 1470,
+	-1000, 1028, -77, -6, -1000, -1000, -1000, 217, 1408, -1000,
+	-1000, -1000, -1000, -1000, 1028, 1028, 694, 1256, 271, 1256,
+	1256, 385, -1000, -1000, -1000, -1000, -1000, -1000, 362, -1000,
+	-1000, -91, -7, 1104, 1028, 1028, 584, 555, 377, 348,
+	348, 348, -1000, 646, 646, 1028, 1028, 882, 342, -8,
+	-1000, 412, 406, -1000, -94, 7, -1000, -3, 1028, -1000,
+	541, 1028, 541, 592, -1000, 592, -1000, 134, -1000, 592,
+	-130, 541, 244, -1000, -1000, 1028, -79, 271, -1000, 1256,
+	-1000, -1000, -1000, 555, 646, 646, 1028, 646, 882, -1000,
+	402, -1000, -1000, -1000, -1000, -1000, 460, -1000, -1000, 244,
+	541, -11, -1000, -1000, -1000, -1000, 541, 1408, -1000, -1000,
+	646, -1000, -1000, 896, -15, 17, 200, -1000, -19, 378,
+	-1000, -1000, -1000, -1000, -1000, -1000, 896, -1000,
 }
 
 var yyPgo = [...]int{
-	0, 27, 8, 26, 3, 24, 23, 22, 21, 20,
-	19, 19, 19, 19, 19, 19, 19, 19, 19, 19,
-	19, 19, 19, 19, 19, 19, 19, 19, 19, 19,
-	19, 19, 19, 19, 19, 19, 19, 19,
+	0, 55, 2, 1, 644, 643, 9, 338, 640, 630,
+	12, 16, 629, 13, 5, 3, 226, 628, 396, 626,
+	589, 14, 27, 587, 19, 10, 586, 581, 561, 560,
+	205, 213, 559, 15, 558, 557, 556, 538, 536, 26,
+	24, 535, 83, 22, 0, 529, 528, 8, 526, 525,
+	4, 21, 524, 522, 521, 520, 516, 227, 315, 275,
+	515, 514, 512, 511, 18, 508, 498, 497, 6, 496,
+	494, 7, 491, 486, 17, 232, 485, 484, 483, 482,
+	481, 477, 476, 475, 473, 472, 472, 472, 472, 472,
+	472, 472, 472, 472, 472, 472, 407, 23, 11, 407,
+	407, 407,
 }
 
 var yyR1 = [...]int{
-	0, 7, 11, 13, 13, 14, 14, 15, 15, 16,
-	18, 18, 19, 19, 20, 23, 23, 24, 24, 21,
-	3, 3, 17, 2, 25, 25, 8, 26, 27, 28,
-	29, 30, 30, 31, 31, 33, 34, 9, 9, 10,
-	6, 5, 5, 4, 4, 4, 32, 32, 32, 35,
-	35, 35, 36, 36, 36, 1, 1, 22, 22, 22,
-	37, 12,
+	0, 72, 72, 72, 72, 72, 72, 86, 88, 88,
+	89, 89, 90, 90, 81, 18, 18, 35, 35, 33,
+	34, 37, 37, 36, 36, 36, 85, 8, 8, 9,
+	9, 82, 19, 19, 17, 17, 15, 15, 83, 83,
+	83, 7, 7, 7, 84, 84, 91, 2, 16, 16,
+	73, 73, 73, 73, 92, 93, 78, 54, 55, 55,
+	50, 50, 47, 47, 79, 41, 41, 40, 80, 94,
+	95, 74, 75, 75, 75, 75, 60, 60, 60, 60,
+	56, 56, 56, 58, 58, 57, 59, 59, 59, 52,
+	52, 49, 49, 25, 25, 26, 26, 24, 27, 27,
+	27, 28, 28, 28, 29, 29, 29, 29, 29, 30,
+	30, 30, 31, 31, 32, 32, 96, 96, 97, 97,
+	23, 23, 22, 22, 22, 22, 22, 77, 77, 76,
+	12, 12, 10, 10, 10, 10, 10, 6, 6, 6,
+	11, 11, 11, 11, 11, 13, 13, 13, 13, 98,
+	98, 14, 14, 38, 39, 39, 42, 42, 42, 42,
+	42, 42, 42, 42, 42, 42, 42, 42, 42, 42,
+	42, 42, 42, 42, 42, 42, 42, 42, 42, 42,
+	42, 42, 42, 42, 42, 42, 42, 42, 42, 42,
+	42, 42, 43, 43, 43, 43, 43, 43, 43, 44,
+	44, 44, 44, 44, 44, 44, 44, 44, 44, 61,
+	62, 62, 65, 65, 64, 63, 63, 51, 51, 48,
+	48, 71, 71, 71, 66, 70, 70, 67, 67, 67,
+	69, 69, 68, 68, 68, 68, 68, 53, 53, 53,
+	45, 45, 99, 99, 99, 100, 100, 100, 46, 46,
+	46, 46, 46, 46, 1, 1, 3, 3, 4, 4,
+	4, 4, 4, 4, 4, 4, 4, 4, 4, 4,
+	4, 4, 4, 4, 4, 4, 4, 4, 4, 4,
+	21, 21, 5, 5, 5, 5, 5, 20, 20, 101,
+	87,
 }
 
 var yyR2 = [...]int{
-	0, 1, 5, 0, 1, 1, 2, 1, 1, 6,
-	1, 3, 1, 1, 3, 0, 2, 2, 3, 4,
-	1, 3, 4, 1, 0, 3, 1, 1, 2, 5,
-	4, 1, 3, 1, 1, 1, 1, 4, 3, 2,
-	2, 1, 3, 3, 3, 3, 1, 1, 1, 1,
-	2, 3, 1, 1, 1, 1, 3, 1, 1, 1,
-	1, 1,
+	0, 1, 1, 1, 1, 1, 1, 5, 0, 1,
+	1, 2, 1, 1, 7, 0, 3, 1, 3, 1,
+	3, 0, 2, 2, 1, 2, 10, 0, 1, 0,
+	2, 4, 0, 2, 1, 3, 1, 3, 4, 4,
+	4, 1, 1, 1, 2, 3, 4, 1, 0, 3,
+	1, 1, 1, 1, 1, 2, 5, 2, 3, 5,
+	1, 3, 1, 1, 5, 1, 3, 3, 4, 1,
+	1, 4, 7, 4, 4, 4, 0, 1, 1, 5,
+	0, 2, 3, 1, 3, 6, 0, 1, 1, 0,
+	3, 0, 2, 0, 3, 1, 3, 3, 0, 1,
+	1, 0, 2, 2, 0, 1, 1, 2, 2, 2,
+	2, 5, 2, 3, 0, 1, 1, 1, 1, 1,
+	1, 3, 1, 3, 2, 1, 3, 0, 1, 2,
+	1, 3, 2, 1, 3, 4, 2, 0, 2, 1,
+	4, 4, 5, 4, 5, 1, 2, 2, 2, 0,
+	1, 2, 4, 2, 0, 1, 3, 3, 2, 3,
+	3, 3, 3, 3, 2, 5, 6, 5, 6, 5,
+	6, 3, 5, 4, 6, 3, 5, 4, 6, 4,
+	6, 5, 7, 3, 3, 3, 4, 5, 6, 5,
+	3, 1, 3, 3, 3, 3, 2, 3, 1, 3,
+	3, 4, 1, 1, 1, 1, 6, 2, 6, 5,
+	0, 1, 1, 2, 4, 0, 2, 1, 3, 1,
+	5, 3, 4, 5, 3, 0, 3, 0, 2, 5,
+	1, 1, 2, 2, 2, 2, 2, 0, 1, 1,
+	1, 3, 1, 1, 1, 1, 2, 3, 1, 1,
+	1, 1, 1, 1, 1, 3, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 4, 1, 2, 2, 4, 4, 1, 3, 1,
+	1,
 }
 
 var yyChk = [...]int{
-	-1000, -7, -8, -9, 82, -3, -2, 5, 33, -10,
-	49, -2, -6, 98, -1, 5, -5, -4, 5, 6,
-	15, 11, 13, 13, 5, -4, 5, 6, 6,
+	-1000, -72, -73, -81, -82, -85, -83, -84, -74, -78,
+	-79, -80, 56, 121, 104, 150, -56, 79, 113, 63,
+	119, 110, 151, 110, 5, 5, -75, 103, 81, -1,
+	-3, 5, -4, 39, 50, 57, 63, 32, 124, 145,
+	151, 79, 125, 128, 123, 141, 144, 94, 142, 134,
+	104, 150, 113, 152, 153, 73, -58, 134, -57, 5,
+	-18, 122, -18, -19, 122, 111, 25, 5, 5, -25,
+	11, 13, 12, 92, -60, 43, 65, -1, 104, 42,
+	-1, 58, -58, -16, 154, -1, 23, -8, 5, -17,
+	-1, 67, -7, 8, 5, 62, -7, -7, -29, -30,
+	-31, 126, 68, 127, -59, 43, 65, -59, -59, 50,
+	-23, -22, -42, 36, -3, 23, 35, -44, 5, 154,
+	67, -45, -46, -48, -61, 148, 149, 8, 6, 7,
+	146, 147, 86, -71, 135, 89, -16, -41, -40, -2,
+	-3, -3, -39, -38, 118, -57, 47, -15, -2, 154,
+	67, 89, 58, -31, -30, -42, 43, -3, -96, 124,
+	128, -42, -75, -75, -75, -26, -24, -42, -77, 58,
+	-76, 73, 47, 5, 21, 22, 25, 35, 36, 37,
+	38, 26, 27, 28, 29, 30, 31, 33, 34, 24,
+	39, 41, 42, -42, -42, 8, 154, -42, -74, 154,
+	154, 154, 140, -62, -42, 154, -54, 115, -39, 58,
+	25, -42, 154, 58, 155, -35, -33, -34, -2, -1,
+	-1, 42, -32, -42, -97, 129, 130, 58, -27, 46,
+	64, -39, -22, -12, -10, -1, -11, 154, -71, 5,
+	-3, -42, -42, -42, -42, -42, -42, -42, 154, 26,
+	28, 29, 30, 31, -43, 35, -44, -42, -42, 111,
+	-42, -42, 86, 23, 65, 5, -21, -5, 5, 36,
+	-3, 155, 36, -53, 43, 65, 155, 155, -74, -42,
+	5, 154, -65, -64, 136, -51, -42, -55, 154, -40,
+	-47, -42, 62, -74, -2, 155, 58, -21, -9, 133,
+	-97, -24, -28, 123, -52, 76, 58, 15, 14, -13,
+	20, 19, 16, 17, 18, -6, 47, 5, -11, -74,
+	-10, -6, -74, -51, 154, -43, -42, -42, 111, 22,
+	35, 36, 37, 38, 41, -43, 32, 32, -42, 86,
+	65, 73, 5, 154, 94, 152, 119, 153, 155, -51,
+	155, 47, 73, -66, -70, 141, -63, -64, 138, -42,
+	155, 58, 58, -50, -47, 155, -33, -37, 154, 5,
+	131, 124, 125, -49, 77, 50, -10, 14, -10, 14,
+	14, -13, -98, 132, -98, -98, -3, 155, 155, 155,
+	155, -74, -51, 22, 32, 32, -42, -43, -43, -43,
+	-43, -43, -21, -42, -42, 32, 73, -42, -42, -20,
+	6, 5, 5, 155, -21, -42, 155, -25, 50, 139,
+	-42, 137, -42, 154, 155, 58, -36, 23, 86, 62,
+	-2, -42, -51, -10, -14, 89, 133, -10, -10, 14,
+	-6, 155, 155, -43, -42, -42, 32, -42, -42, 155,
+	58, 5, 5, 155, 155, -67, -69, 130, 142, -51,
+	-42, -50, -47, 86, -47, 155, -42, 154, -14, -10,
+	-42, 6, -68, 28, 143, 57, -43, 155, -15, -68,
+	144, 145, 129, 144, 145, 155, 22, -68,
 }
 
 var yyDef = [...]int{
-	0, -2, 1, 26, 0, 0, 20, 23, 0, 38,
-	0, 21, 37, 0, 39, 55, 40, 41, 0, 0,
-	0, 0, 0, 0, 56, 42, 43, 44, 45,
+	80, -2, 1, 2, 3, 4, 5, 6, 50, 51,
+	52, 53, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 15, 15, 32, 0, 44, 93, 76, 0, 0,
+	254, 256, 257, 258, 259, 260, 261, 262, 263, 264,
+	265, 266, 267, 268, 269, 270, 271, 272, 273, 274,
+	275, 276, 277, 278, 279, 0, 81, 0, 83, 48,
+	0, 0, 27, 0, 0, 0, 0, 0, 45, 104,
+	86, 86, 86, 0, 0, 77, 78, 48, 0, 0,
+	154, 0, 82, 0, 0, 0, 0, 0, 28, 31,
+	34, 33, 38, 41, 42, 43, 39, 40, 71, 105,
+	106, 0, 0, 0, 0, 87, 88, 0, 0, 0,
+	127, 120, 122, 125, 240, 0, 0, 191, 256, 80,
+	0, 202, 203, 204, 205, 0, 0, 248, 249, 250,
+	251, 252, 253, 219, 210, 0, 0, 154, 65, 0,
+	47, 255, 68, 155, 0, 84, 0, 0, 36, 0,
+	16, 0, 0, 107, 108, 109, 110, 240, 114, 116,
+	117, 112, 73, 74, 75, 94, 95, 98, 154, 0,
+	128, 0, 0, 124, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 158, 164, 207, 237, 0, 0, 80,
+	0, 0, 0, 0, 211, 0, 56, 0, 64, 0,
+	0, 153, 80, 0, 49, 0, 17, 19, 0, 29,
+	35, 0, 0, 115, 113, 118, 119, 0, 101, 99,
+	100, 89, 121, 129, 130, 137, 133, 80, 137, 256,
+	123, 156, 157, 159, 160, 161, 162, 163, 80, 0,
+	0, 0, 0, 0, 0, 0, 198, -2, -2, 0,
+	183, 184, 185, 0, 0, 0, 190, 280, 282, 126,
+	241, 221, 0, 0, 238, 239, 199, 200, 0, 0,
+	0, 225, 215, 212, 0, 0, 217, 57, 0, 66,
+	67, 62, 63, 0, 37, 14, 0, 21, 0, 0,
+	0, 96, 97, 0, 91, 0, 0, 0, 0, 0,
+	0, 145, 149, 149, 149, 132, 0, 139, 133, 0,
+	0, 136, 0, 0, 80, 0, -2, -2, 0, 0,
+	0, 0, 0, 0, 0, 196, 0, 0, -2, 186,
+	0, 0, 0, 0, 283, 284, 0, 0, 222, 0,
+	201, 0, 0, 0, 93, 0, 0, 213, 0, 0,
+	79, 0, 0, 0, 60, 85, 18, 20, 0, 30,
+	111, 102, 103, 72, 0, 0, 131, 0, 0, 0,
+	0, 0, 146, 150, 147, 148, 138, 134, 137, 165,
+	167, 0, 0, 0, 0, 0, -2, 169, 192, 193,
+	194, 195, 197, 172, 176, 0, 0, -2, 189, 0,
+	287, 0, 0, 223, 0, 0, 220, 227, 0, 209,
+	216, 0, 218, 0, 58, 0, 22, 0, 24, 0,
+	0, 92, 90, 140, 141, 0, 0, 0, 143, 0,
+	135, 166, 168, 170, 174, 178, 0, 180, -2, 281,
+	0, 285, 286, 206, 208, 224, 0, 230, 231, 226,
+	214, 0, 61, 23, 25, 26, 151, 0, 142, 144,
+	182, 288, 228, 0, 0, 260, 0, 59, 0, 0,
+	232, 233, 234, 235, 236, 152, 0, 229,
 }
 
 var yyTok1 = [...]int{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	101, 102, 3, 3, 103, 3, 15,
+	3, 3, 3, 3, 3, 3, 3, 38, 3, 3,
+	154, 155, 3, 3, 3, 3, 42, 37,
 }
 
 var yyTok2 = [...]int{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
-	12, 13, 14, 16, 17, 18, 19, 20, 21, 22,
-	23, 24, 25, 26, 27, 28, 29, 30, 31, 32,
-	33, 34, 35, 36, 37, 38, 39, 40, 41, 42,
-	43, 44, 45, 46, 47, 48, 49, 50, 51, 52,
-	53, 54, 55, 56, 57, 58, 59, 60, 61, 62,
-	63, 64, 65, 66, 67, 68, 69, 70, 71, 72,
-	73, 74, 75, 76, 77, 78, 79, 80, 81, 82,
-	83, 84, 85, 86, 87, 88, 89, 90, 91, 92,
-	93, 94, 95, 96, 97, 98, 99, 100,
+	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
+	22, 23, 24, 25, 26, 27, 28, 29, 30, 31,
+	32, 33, 34, 35, 36, 39, 40, 41, 43, 44,
+	45, 46, 47, 48, 49, 50, 51, 52, 53, 54,
+	55, 56, 57, 58, 59, 60, 61, 62, 63, 64,
+	65, 66, 67, 68, 69, 70, 71, 72, 73, 74,
+	75, 76, 77, 78, 79, 80, 81, 82, 83, 84,
+	85, 86, 87, 88, 89, 90, 91, 92, 93, 94,
+	95, 96, 97, 98, 99, 100, 101, 102, 103, 104,
+	105, 106, 107, 108, 109, 110, 111, 112, 113, 114,
+	115, 116, 117, 118, 119, 120, 121, 122, 123, 124,
+	125, 126, 127, 128, 129, 130, 131, 132, 133, 134,
+	135, 136, 137, 138, 139, 140, 141, 142, 143, 144,
+	145, 146, 147, 148, 149, 150, 151, 152, 153,
 }
 
 var yyTok3 = [...]int{
@@ -653,80 +1218,1217 @@ yydefault:
 		{
 			setParseTree(yylex, yyDollar[1].statement)
 		}
-	case 20:
+	case 2:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
-			yyVAL.strs = []string{yyDollar[1].str}
+			setParseTree(yylex, yyDollar[1].statement)
 		}
-	case 21:
+	case 3:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			setParseTree(yylex, yyDollar[1].statement)
+		}
+	case 4:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			setParseTree(yylex, yyDollar[1].statement)
+		}
+	case 5:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			setParseTree(yylex, yyDollar[1].statement)
+		}
+	case 6:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			setParseTree(yylex, yyDollar[1].statement)
+		}
+	case 14:
+		yyDollar = yyS[yypt-7 : yypt+1]
+		{
+			yyVAL.statement = NewCreateTable(yyDollar[4].str, yyDollar[3].flag, yyDollar[6].coldefs)
+		}
+	case 15:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.flag = false
+		}
+	case 16:
 		yyDollar = yyS[yypt-3 : yypt+1]
 		{
-			yyVAL.strs = append(yyDollar[1].strs, yyDollar[3].str)
+			yyVAL.flag = true
+		}
+	case 17:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.coldefs = []*ColumnDef{yyDollar[1].coldef}
+		}
+	case 18:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.coldefs = append(yyDollar[1].coldefs, yyDollar[3].coldef)
+		}
+	case 19:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.coldef = yyDollar[1].coldef
+		}
+	case 20:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.coldef = NewColumnDef(yyDollar[1].str, yyDollar[2].typ, yyDollar[3].colopts)
+		}
+	case 21:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.colopts = nil
+		}
+	case 22:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.colopts = append(yyDollar[1].colopts, yyDollar[2].colopt)
 		}
 	case 23:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.colopt = NewColumnOption(OptionNotNull, nil)
+		}
+	case 24:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
-			yyVAL.str = yyDollar[1].str
+			yyVAL.colopt = NewColumnOption(OptionNull, nil)
+		}
+	case 25:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.colopt = NewColumnOption(OptionDefault, yyDollar[2].expr)
 		}
 	case 26:
+		yyDollar = yyS[yypt-10 : yypt+1]
+		{
+			yyVAL.statement = NewCreateIndex(yyDollar[4].str, yyDollar[6].str, yyDollar[7].str, yyDollar[9].str, yyDollar[3].flag)
+		}
+	case 27:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.str = ""
+		}
+	case 28:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
-			yyVAL.statement = yyDollar[1].statement
+			yyVAL.str = yyDollar[1].str
 		}
-	case 37:
+	case 29:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.str = ""
+		}
+	case 30:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.str = yyDollar[2].str
+		}
+	case 31:
 		yyDollar = yyS[yypt-4 : yypt+1]
 		{
-			yyVAL.statement = NewSelect(yyDollar[2].strs, yyDollar[3].statement, yyDollar[4].where)
+			yyVAL.statement = NewDropTable(yyDollar[4].strs, yyDollar[3].flag)
 		}
-	case 38:
+	case 32:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.flag = false
+		}
+	case 33:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.flag = true
+		}
+	case 34:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.strs = []string{yyDollar[1].str}
+		}
+	case 35:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.strs = append(yyDollar[1].strs, yyDollar[3].str)
+		}
+	case 36:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.strs = []string{yyDollar[1].str}
+		}
+	case 37:
 		yyDollar = yyS[yypt-3 : yypt+1]
 		{
-			yyVAL.statement = NewSelect(yyDollar[2].strs, yyDollar[3].statement, nil)
+			yyVAL.strs = append(yyDollar[1].strs, yyDollar[3].str)
+		}
+	case 38:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.statement = NewSetVar(yyDollar[2].str, yyDollar[4].str)
 		}
 	case 39:
-		yyDollar = yyS[yypt-2 : yypt+1]
+		yyDollar = yyS[yypt-4 : yypt+1]
 		{
-			yyVAL.statement = NewFrom(yyDollar[2].str)
+			expectRelation(yylex, yyDollar[3].str, "=")
+			yyVAL.statement = NewSetVar(yyDollar[2].str, yyDollar[4].str)
 		}
 	case 40:
-		yyDollar = yyS[yypt-2 : yypt+1]
+		yyDollar = yyS[yypt-4 : yypt+1]
 		{
-			yyVAL.where = NewWhere(yyDollar[2].conds)
+			expectTimeZone(yylex, yyDollar[2].str, yyDollar[3].str)
+			yyVAL.statement = NewSetVar("timezone", yyDollar[4].str)
 		}
 	case 41:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
-			yyVAL.conds = []*Condition{yyDollar[1].cond}
+			yyVAL.str = yyDollar[1].str
 		}
 	case 42:
-		yyDollar = yyS[yypt-3 : yypt+1]
+		yyDollar = yyS[yypt-1 : yypt+1]
 		{
-			yyVAL.conds = append(yyDollar[1].conds, yyDollar[3].cond)
+			yyVAL.str = yyDollar[1].str
 		}
 	case 43:
-		yyDollar = yyS[yypt-3 : yypt+1]
+		yyDollar = yyS[yypt-1 : yypt+1]
 		{
-			yyVAL.cond = NewCondition(yyDollar[2].str, yyDollar[1].str, yyDollar[3].str)
+			yyVAL.str = ""
 		}
 	case 44:
-		yyDollar = yyS[yypt-3 : yypt+1]
+		yyDollar = yyS[yypt-2 : yypt+1]
 		{
-			yyVAL.cond = NewCondition(yyDollar[2].str, yyDollar[1].str, yyDollar[3].num)
+			yyVAL.statement = NewShowVar(yyDollar[2].str)
 		}
 	case 45:
 		yyDollar = yyS[yypt-3 : yypt+1]
 		{
-			yyVAL.cond = NewCondition(yyDollar[2].str, yyDollar[1].num, yyDollar[3].num)
+			expectTimeZone(yylex, yyDollar[2].str, yyDollar[3].str)
+			yyVAL.statement = NewShowVar("timezone")
 		}
-	case 55:
+	case 47:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.str = yyDollar[1].str
 		}
+	case 48:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.strs = nil
+		}
+	case 49:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.strs = yyDollar[2].strs
+		}
+	case 50:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.statement = yyDollar[1].statement
+		}
+	case 51:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.statement = yyDollar[1].statement
+		}
+	case 52:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.statement = yyDollar[1].statement
+		}
+	case 53:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.statement = yyDollar[1].statement
+		}
 	case 56:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.statement = NewInsert(yyDollar[3].str, yyDollar[4].strs, yyDollar[5].rows)
+		}
+	case 57:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.rows = yyDollar[2].rows
+		}
+	case 58:
 		yyDollar = yyS[yypt-3 : yypt+1]
 		{
-			yyVAL.str = yyDollar[1].str
+			yyVAL.rows = [][]Expr{yyDollar[2].exprs}
+		}
+	case 59:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.rows = append(yyDollar[1].rows, yyDollar[4].exprs)
+		}
+	case 60:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.exprs = []Expr{yyDollar[1].expr}
+		}
+	case 61:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.exprs = append(yyDollar[1].exprs, yyDollar[3].expr)
+		}
+	case 62:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 63:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = NewDefault()
+		}
+	case 64:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.statement = NewUpdate(yyDollar[2].str, yyDollar[4].assigns, yyDollar[5].where)
+		}
+	case 65:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.assigns = []*Assignment{yyDollar[1].assign}
+		}
+	case 66:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.assigns = append(yyDollar[1].assigns, yyDollar[3].assign)
+		}
+	case 67:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			expectRelation(yylex, yyDollar[2].str, "=")
+			yyVAL.assign = NewAssignment(yyDollar[1].str, yyDollar[3].expr)
+		}
+	case 68:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.statement = NewDelete(yyDollar[3].str, yyDollar[4].where)
+		}
+	case 71:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.statement = NewQuery(yyDollar[1].with, yyDollar[2].statement, yyDollar[3].orders, yyDollar[4].limit)
+		}
+	case 72:
+		yyDollar = yyS[yypt-7 : yypt+1]
+		{
+			yyVAL.statement = NewSelect(yyDollar[2].distinct, yyDollar[3].items, yyDollar[4].statement, yyDollar[5].where, yyDollar[6].exprs, yyDollar[7].expr)
+		}
+	case 73:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.statement = NewCompound(SetUnion, yyDollar[3].flag, yyDollar[1].statement, yyDollar[4].statement)
+		}
+	case 74:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.statement = NewCompound(SetIntersect, yyDollar[3].flag, yyDollar[1].statement, yyDollar[4].statement)
+		}
+	case 75:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.statement = NewCompound(SetExcept, yyDollar[3].flag, yyDollar[1].statement, yyDollar[4].statement)
+		}
+	case 76:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.distinct = nil
+		}
+	case 77:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.distinct = nil
+		}
+	case 78:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.distinct = NewDistinct(nil)
+		}
+	case 79:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.distinct = NewDistinct(yyDollar[4].exprs)
+		}
+	case 80:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.with = nil
+		}
+	case 81:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.with = NewWith(false, yyDollar[2].ctes)
+		}
+	case 82:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.with = NewWith(true, yyDollar[3].ctes)
+		}
+	case 83:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.ctes = []*CTE{yyDollar[1].cte}
+		}
+	case 84:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.ctes = append(yyDollar[1].ctes, yyDollar[3].cte)
+		}
+	case 85:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.cte = NewCTE(yyDollar[1].str, yyDollar[2].strs, yyDollar[5].statement)
+		}
+	case 86:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.flag = false
+		}
+	case 87:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.flag = true
+		}
+	case 88:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.flag = false
+		}
+	case 89:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.exprs = nil
+		}
+	case 90:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.exprs = yyDollar[3].exprs
+		}
+	case 91:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.expr = nil
+		}
+	case 92:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[2].expr
+		}
+	case 93:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.orders = nil
+		}
+	case 94:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.orders = yyDollar[3].orders
+		}
+	case 95:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.orders = []*OrderItem{yyDollar[1].order}
+		}
+	case 96:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.orders = append(yyDollar[1].orders, yyDollar[3].order)
+		}
+	case 97:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.order = NewOrderItem(yyDollar[1].expr, yyDollar[2].flag, yyDollar[3].nulls)
+		}
+	case 98:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.flag = false
+		}
+	case 99:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.flag = false
+		}
+	case 100:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.flag = true
+		}
+	case 101:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.nulls = NullsDefault
+		}
+	case 102:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.nulls = NullsFirst
+		}
+	case 103:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.nulls = NullsLast
+		}
+	case 104:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.limit = nil
+		}
+	case 105:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.limit = NewLimit(yyDollar[1].expr, nil)
+		}
+	case 106:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.limit = NewLimit(nil, yyDollar[1].expr)
+		}
+	case 107:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.limit = NewLimit(yyDollar[1].expr, yyDollar[2].expr)
+		}
+	case 108:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.limit = NewLimit(yyDollar[2].expr, yyDollar[1].expr)
+		}
+	case 109:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[2].expr
+		}
+	case 110:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.expr = NewLiteral(nil)
+		}
+	case 111:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[3].expr
+		}
+	case 112:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[2].expr
+		}
+	case 113:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[2].expr
+		}
+	case 114:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.expr = NewLiteral(1)
+		}
+	case 115:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 120:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.items = []*SelectItem{yyDollar[1].item}
+		}
+	case 121:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.items = append(yyDollar[1].items, yyDollar[3].item)
+		}
+	case 122:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.item = NewSelectItem(yyDollar[1].expr, "")
+		}
+	case 123:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.item = NewSelectItem(yyDollar[1].expr, yyDollar[3].str)
+		}
+	case 124:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.item = NewSelectItem(yyDollar[1].expr, yyDollar[2].str)
+		}
+	case 125:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.item = NewSelectItem(NewStar(""), "")
+		}
+	case 126:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.item = NewSelectItem(NewStar(yyDollar[1].str), "")
+		}
+	case 127:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.statement = nil
+		}
+	case 128:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.statement = yyDollar[1].statement
+		}
+	case 129:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.statement = NewFrom(yyDollar[2].tables)
+		}
+	case 130:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.tables = []TableExpr{yyDollar[1].table}
+		}
+	case 131:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.tables = append(yyDollar[1].tables, yyDollar[3].table)
+		}
+	case 132:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.table = NewTableRef(yyDollar[1].str, yyDollar[2].str)
+		}
+	case 133:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.table = yyDollar[1].table
+		}
+	case 134:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.table = yyDollar[2].table
+		}
+	case 135:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.table = NewDerivedTable(yyDollar[2].statement, yyDollar[4].str)
+		}
+	case 136:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.table = NewFuncTable(yyDollar[1].expr, yyDollar[2].str)
+		}
+	case 137:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.str = ""
+		}
+	case 138:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.str = yyDollar[2].str
+		}
+	case 139:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.str = yyDollar[1].str
+		}
+	case 140:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.table = NewJoin(JoinCross, yyDollar[1].table, yyDollar[4].table, nil)
+		}
+	case 141:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.table = NewJoin(JoinInner, yyDollar[1].table, yyDollar[3].table, yyDollar[4].joinCond)
+		}
+	case 142:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.table = NewJoin(yyDollar[2].joinKind, yyDollar[1].table, yyDollar[4].table, yyDollar[5].joinCond)
+		}
+	case 143:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.table = NewJoin(JoinInner, yyDollar[1].table, yyDollar[4].table, &JoinCond{Natural: true})
+		}
+	case 144:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.table = NewJoin(yyDollar[3].joinKind, yyDollar[1].table, yyDollar[5].table, &JoinCond{Natural: true})
+		}
+	case 145:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.joinKind = JoinInner
+		}
+	case 146:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.joinKind = JoinLeft
+		}
+	case 147:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.joinKind = JoinRight
+		}
+	case 148:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.joinKind = JoinFull
+		}
+	case 151:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.joinCond = &JoinCond{On: yyDollar[2].expr}
+		}
+	case 152:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.joinCond = &JoinCond{Using: yyDollar[3].strs}
+		}
+	case 153:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.where = NewWhere(yyDollar[2].expr)
+		}
+	case 154:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.where = nil
+		}
+	case 155:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.where = yyDollar[1].where
+		}
+	case 156:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewBinaryExpr(OpOr, yyDollar[1].expr, yyDollar[3].expr)
+		}
+	case 157:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewBinaryExpr(OpAnd, yyDollar[1].expr, yyDollar[3].expr)
+		}
+	case 158:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.expr = NewUnaryExpr(OpNot, yyDollar[2].expr)
+		}
+	case 159:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewBinaryExpr(yyDollar[2].str, yyDollar[1].expr, yyDollar[3].expr)
+		}
+	case 160:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewBinaryExpr(yyDollar[2].str, yyDollar[1].expr, yyDollar[3].expr)
+		}
+	case 161:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewBinaryExpr(OpMul, yyDollar[1].expr, yyDollar[3].expr)
+		}
+	case 162:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewBinaryExpr(OpDiv, yyDollar[1].expr, yyDollar[3].expr)
+		}
+	case 163:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewBinaryExpr(OpMod, yyDollar[1].expr, yyDollar[3].expr)
+		}
+	case 164:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.expr = NewUnaryExpr(yyDollar[1].str, yyDollar[2].expr)
+		}
+	case 165:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.expr = NewInExpr(yyDollar[1].expr, yyDollar[4].statement, false)
+		}
+	case 166:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.expr = NewInExpr(yyDollar[1].expr, yyDollar[5].statement, true)
+		}
+	case 167:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.expr = NewInList(yyDollar[1].expr, yyDollar[4].exprs, false)
+		}
+	case 168:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.expr = NewInList(yyDollar[1].expr, yyDollar[5].exprs, true)
+		}
+	case 169:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.expr = NewBetweenExpr(yyDollar[1].expr, yyDollar[3].expr, yyDollar[5].expr, false)
+		}
+	case 170:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.expr = NewBetweenExpr(yyDollar[1].expr, yyDollar[4].expr, yyDollar[6].expr, true)
+		}
+	case 171:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewLikeExpr(OpLike, yyDollar[1].expr, yyDollar[3].expr, nil, false)
+		}
+	case 172:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.expr = NewLikeExpr(OpLike, yyDollar[1].expr, yyDollar[3].expr, yyDollar[5].expr, false)
+		}
+	case 173:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.expr = NewLikeExpr(OpLike, yyDollar[1].expr, yyDollar[4].expr, nil, true)
+		}
+	case 174:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.expr = NewLikeExpr(OpLike, yyDollar[1].expr, yyDollar[4].expr, yyDollar[6].expr, true)
+		}
+	case 175:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewLikeExpr(OpILike, yyDollar[1].expr, yyDollar[3].expr, nil, false)
+		}
+	case 176:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.expr = NewLikeExpr(OpILike, yyDollar[1].expr, yyDollar[3].expr, yyDollar[5].expr, false)
+		}
+	case 177:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.expr = NewLikeExpr(OpILike, yyDollar[1].expr, yyDollar[4].expr, nil, true)
+		}
+	case 178:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.expr = NewLikeExpr(OpILike, yyDollar[1].expr, yyDollar[4].expr, yyDollar[6].expr, true)
+		}
+	case 179:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.expr = NewLikeExpr(OpSimilar, yyDollar[1].expr, yyDollar[4].expr, nil, false)
+		}
+	case 180:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.expr = NewLikeExpr(OpSimilar, yyDollar[1].expr, yyDollar[4].expr, yyDollar[6].expr, false)
+		}
+	case 181:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.expr = NewLikeExpr(OpSimilar, yyDollar[1].expr, yyDollar[5].expr, nil, true)
+		}
+	case 182:
+		yyDollar = yyS[yypt-7 : yypt+1]
+		{
+			yyVAL.expr = NewLikeExpr(OpSimilar, yyDollar[1].expr, yyDollar[5].expr, yyDollar[7].expr, true)
+		}
+	case 183:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewBinaryExpr(yyDollar[2].str, yyDollar[1].expr, yyDollar[3].expr)
+		}
+	case 184:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewBinaryExpr(yyDollar[2].str, yyDollar[1].expr, yyDollar[3].expr)
+		}
+	case 185:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewIsNullExpr(yyDollar[1].expr, false)
+		}
+	case 186:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.expr = NewIsNullExpr(yyDollar[1].expr, true)
+		}
+	case 187:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.expr = NewIsDistinctExpr(yyDollar[1].expr, yyDollar[5].expr, false)
+		}
+	case 188:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.expr = NewIsDistinctExpr(yyDollar[1].expr, yyDollar[6].expr, true)
+		}
+	case 189:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			expectTimeZone(yylex, yyDollar[3].str, yyDollar[4].str)
+			yyVAL.expr = NewFuncCall("timezone", []Expr{yyDollar[5].expr, yyDollar[1].expr}, false, false)
+		}
+	case 190:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewCastExpr(yyDollar[1].expr, yyDollar[3].typ)
+		}
+	case 191:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 192:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewBinaryExpr(yyDollar[2].str, yyDollar[1].expr, yyDollar[3].expr)
+		}
+	case 193:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewBinaryExpr(OpMul, yyDollar[1].expr, yyDollar[3].expr)
+		}
+	case 194:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewBinaryExpr(OpDiv, yyDollar[1].expr, yyDollar[3].expr)
+		}
+	case 195:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewBinaryExpr(OpMod, yyDollar[1].expr, yyDollar[3].expr)
+		}
+	case 196:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.expr = NewUnaryExpr(yyDollar[1].str, yyDollar[2].expr)
+		}
+	case 197:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewCastExpr(yyDollar[1].expr, yyDollar[3].typ)
+		}
+	case 198:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 199:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[2].expr
+		}
+	case 200:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewSubquery(yyDollar[2].statement)
+		}
+	case 201:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.expr = NewExistsExpr(yyDollar[3].statement)
+		}
+	case 202:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 203:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 204:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 205:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 206:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.expr = NewCastExpr(yyDollar[3].expr, yyDollar[5].typ)
+		}
+	case 207:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.expr = NewCastExpr(NewLiteral(yyDollar[2].str), NewTypeName(yyDollar[1].str, nil))
+		}
+	case 208:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.expr = NewFuncCall("extract", []Expr{NewLiteral(yyDollar[3].str), yyDollar[5].expr}, false, false)
+		}
+	case 209:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.expr = NewCaseExpr(yyDollar[2].expr, yyDollar[3].whens, yyDollar[4].expr)
+		}
+	case 210:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.expr = nil
+		}
+	case 211:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 212:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.whens = []*When{yyDollar[1].when}
+		}
+	case 213:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.whens = append(yyDollar[1].whens, yyDollar[2].when)
+		}
+	case 214:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.when = NewWhen(yyDollar[2].expr, yyDollar[4].expr)
+		}
+	case 215:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.expr = nil
+		}
+	case 216:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[2].expr
+		}
+	case 217:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.exprs = []Expr{yyDollar[1].expr}
+		}
+	case 218:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.exprs = append(yyDollar[1].exprs, yyDollar[3].expr)
+		}
+	case 219:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 220:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.expr = NewWindowCall(yyDollar[1].expr, yyDollar[4].window)
+		}
+	case 221:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewFuncCall(yyDollar[1].str, nil, false, false)
+		}
+	case 222:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.expr = NewFuncCall(yyDollar[1].str, nil, true, false)
+		}
+	case 223:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.expr = NewFuncCall(yyDollar[1].str, yyDollar[4].exprs, false, yyDollar[3].flag)
+		}
+	case 224:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.window = NewWindowSpec(yyDollar[1].exprs, yyDollar[2].orders, yyDollar[3].frame)
+		}
+	case 225:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.exprs = nil
+		}
+	case 226:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.exprs = yyDollar[3].exprs
+		}
+	case 227:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.frame = nil
+		}
+	case 228:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.frame = NewFrame(yyDollar[1].frameMode, yyDollar[2].bound, nil)
+		}
+	case 229:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.frame = NewFrame(yyDollar[1].frameMode, yyDollar[3].bound, yyDollar[5].bound)
+		}
+	case 230:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.frameMode = FrameRows
+		}
+	case 231:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.frameMode = FrameRange
+		}
+	case 232:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.bound = NewFrameBound(UnboundedPreceding, nil)
+		}
+	case 233:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.bound = NewFrameBound(UnboundedFollowing, nil)
+		}
+	case 234:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.bound = NewFrameBound(CurrentRow, nil)
+		}
+	case 235:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.bound = NewFrameBound(Preceding, yyDollar[1].expr)
+		}
+	case 236:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.bound = NewFrameBound(Following, yyDollar[1].expr)
+		}
+	case 237:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.flag = false
+		}
+	case 238:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.flag = false
+		}
+	case 239:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.flag = true
+		}
+	case 240:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = NewColumnRef("", yyDollar[1].str)
+		}
+	case 241:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = NewColumnRef(yyDollar[1].str, yyDollar[3].str)
+		}
+	case 248:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = NewLiteral(yyDollar[1].str)
+		}
+	case 249:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = NewLiteral(yyDollar[1].num)
+		}
+	case 250:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = NewLiteral(yyDollar[1].dec)
+		}
+	case 251:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = NewLiteral(true)
+		}
+	case 252:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = NewLiteral(false)
+		}
+	case 253:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = NewLiteral(nil)
+		}
+	case 254:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.str = yyDollar[1].str
+		}
+	case 255:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.str = qualifiedTable(yylex, yyDollar[1].str, yyDollar[3].str)
+		}
+	case 256:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.str = yyDollar[1].str
+		}
+	case 257:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.str = yyDollar[1].str
+		}
+	case 280:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.typ = NewTypeName(yyDollar[1].str, nil)
+		}
+	case 281:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.typ = NewTypeName(yyDollar[1].str, yyDollar[3].nums)
+		}
+	case 282:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.str = yyDollar[1].str
+		}
+	case 283:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.str = yyDollar[1].str + " precision"
+		}
+	case 284:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.str = yyDollar[1].str + " varying"
+		}
+	case 285:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			expectTimeZone(yylex, yyDollar[3].str, yyDollar[4].str)
+			yyVAL.str = yyDollar[1].str + " with time zone"
+		}
+	case 286:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			expectTimeZone(yylex, yyDollar[3].str, yyDollar[4].str)
+			yyVAL.str = yyDollar[1].str + " without time zone"
+		}
+	case 287:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.nums = []int{yyDollar[1].num}
+		}
+	case 288:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.nums = append(yyDollar[1].nums, yyDollar[3].num)
 		}
 	}
 	goto yystack /* stack new state and value */
@@ -1,33 +1,103 @@
 %{
 package parser
 
+import "github.com/hiepd/galedb/pkg/types"
+
 func setParseTree(yylex yyLexer, stmt Statement) {
   yylex.(*Lexer).ParseTree = stmt
 }
+
+// qualifiedTable resolves a schema qualified table name. Tables all live in
+// the public schema.
+func qualifiedTable(yylex yyLexer, schema, table string) string {
+  if schema != "public" {
+    yylex.Error("schema " + schema + " does not exist")
+  }
+  return table
+}
+
+func expectRelation(yylex yyLexer, got, want string) {
+  if got != want {
+    yylex.Error("syntax error at or near " + got)
+  }
+}
+
+// expectTimeZone checks for the words TIME ZONE, which aren't keywords.
+func expectTimeZone(yylex yyLexer, time, zone string) {
+  expectRelation(yylex, time, "time")
+  expectRelation(yylex, zone, "zone")
+}
 %}
     /*symbolic tokens*/
 %union {
     str string
     num int
+    dec types.Decimal
     statement Statement
     strs []string
-    cond *Condition
-    conds []*Condition
     where *Where
+    expr Expr
+    exprs []Expr
+    rows [][]Expr
+    assign *Assignment
+    assigns []*Assignment
+    flag bool
+    nums []int
+    typ *TypeName
+    coldef *ColumnDef
+    coldefs []*ColumnDef
+    colopt *ColumnOption
+    colopts []*ColumnOption
+    item *SelectItem
+    items []*SelectItem
+    order *OrderItem
+    orders []*OrderItem
+    nulls NullsOrder
+    limit *Limit
+    table TableExpr
+    tables []TableExpr
+    joinKind JoinKind
+    joinCond *JoinCond
+    with *With
+    cte *CTE
+    ctes []*CTE
+    distinct *Distinct
+    when *When
+    whens []*When
+    window *WindowSpec
+    frame *Frame
+    bound *FrameBound
+    frameMode FrameMode
 }
 
 %token LEX_ERROR
 %token <str> NAME
 %token <num> NUMBER
-%token STRING
+%token <dec> DECIMALNUM
+%token <str> STRING
 %token INTNUM APPROXNUM
 
+    /* set operations are left associative, INTERSECT binds tighter */
+%left UNION EXCEPT
+%left INTERSECT
+
+    /* joins are left associative */
+%left JOIN CROSS LEFT RIGHT FULL INNER NATURAL
+
     /* operators */
 %left OR
 %left AND
-%left NOT
+%right NOT
+%nonassoc IS
 %left <str> RELATION
-%left OPERATOR
+%nonassoc IN NOT_LA BETWEEN LIKE ILIKE SIMILAR
+%nonassoc ESCAPE
+%left <str> MATCH_OP JSON_OP
+%left <str> OPERATOR
+%left ASTERISK '/' '%'
+%left <str> AT
+%right UMINUS
+%left TYPECAST
 %nonassoc '.'
 
     /*literal keyword tokens*/
@@ -41,15 +111,60 @@ func setParseTree(yylex yyLexer, stmt Statement) {
 %token <str> PUBLIC REAL REFERENCES ROLLBACK SCHEMA SELECT SET
 %token <str> SMALLINT SOME SQLCODE SQLERROR SUM TABLE TO UNION
 %token <str> UNIQUE UPDATE USER VALUES VIEW WHENEVER WHERE WITH WORK
-
-%type <str> table column
-%type <strs> column_commalist
-%type <cond> condition
-%type <conds> condition_list
-%type <where> where_clause
+%token <str> DROP IF NULLS FIRST LAST LIMIT OFFSET NEXT ROW ROWS ONLY
+%token <str> OUTER USING RECURSIVE INTERSECT EXCEPT ILIKE SIMILAR
+%token <str> CASE WHEN THEN ELSE END
+%token <str> OVER PARTITION RANGE UNBOUNDED PRECEDING FOLLOWING
+%token <str> TRUE FALSE
+%token <str> CAST EXTRACT SHOW INDEX VARYING WITHOUT
+
+%type <str> table column ident unreserved_keyword type_name opt_alias set_value opt_index_name opt_index_method
+%type <table> table_ref joined_table
+%type <tables> table_ref_commalist
+%type <joinKind> join_type
+%type <joinCond> join_qual
+%type <strs> column_commalist opt_column_commalist table_commalist
+%type <flag> opt_if_not_exists opt_if_exists
+%type <nums> type_modifier_commalist
+%type <typ> data_type
+%type <item> select_item
+%type <items> select_item_commalist
+%type <order> order_item
+%type <orders> opt_order_by_clause order_item_commalist
+%type <flag> opt_asc_desc
+%type <nulls> opt_nulls_order
+%type <limit> opt_limit_clause
+%type <expr> limit_clause offset_clause opt_fetch_count
+%type <coldef> base_table_element column_def
+%type <coldefs> base_table_element_commalist
+%type <colopt> column_def_opt
+%type <colopts> column_def_opt_list
+%type <where> where_clause opt_where_clause
+%type <assign> assignment
+%type <assigns> assignment_commalist
+%type <expr> expr b_expr simple_expr column_ref literal insert_atom function_call opt_having_clause
+%type <exprs> insert_atom_commalist expr_commalist opt_group_by_clause
+%type <flag> opt_all_distinct
+%type <rows> values_or_query_spec insert_row_commalist
+%type <with> opt_with_clause
+%type <cte> cte
+%type <ctes> cte_commalist
+%type <flag> opt_set_all
+%type <distinct> opt_distinct
+%type <expr> case_expr opt_case_arg opt_case_default
+%type <when> when_clause
+%type <whens> when_clause_list
+%type <window> window_spec
+%type <frame> opt_frame_clause
+%type <bound> frame_bound
+%type <frameMode> frame_mode
+%type <exprs> opt_partition_clause
+%type <expr> func_application
 
 %type <statement> sql
-%type <statement> manipulative_statement select_statement from_clause
+%type <statement> manipulative_statement select_statement select_body from_clause opt_from_clause
+%type <statement> insert_statement update_statement delete_statement
+%type <statement> base_table_def drop_table_def set_statement show_statement index_def
 
 %start sql
 
@@ -57,6 +172,11 @@ func setParseTree(yylex yyLexer, stmt Statement) {
 
 sql: 
     manipulative_statement { setParseTree(yylex, $1) }
+    | base_table_def { setParseTree(yylex, $1) }
+    | drop_table_def { setParseTree(yylex, $1) }
+    | index_def { setParseTree(yylex, $1) }
+    | set_statement { setParseTree(yylex, $1) }
+    | show_statement { setParseTree(yylex, $1) }
     ;
 
     /* schema */
@@ -82,35 +202,76 @@ schema_element:
     ;
 
 base_table_def:
-        CREATE TABLE table '(' base_table_element_commalist ')'
+        CREATE TABLE opt_if_not_exists table '(' base_table_element_commalist ')'
+        {
+            $$ = NewCreateTable($4, $3, $6)
+        }
+    ;
+
+opt_if_not_exists:
+        /* empty */ { $$ = false }
+    | IF NOT EXISTS { $$ = true }
     ;
 
 base_table_element_commalist:
-        base_table_element
-    | base_table_element_commalist ',' base_table_element
+        base_table_element { $$ = []*ColumnDef{$1} }
+    | base_table_element_commalist COMMA base_table_element { $$ = append($1, $3) }
     ;
 
 base_table_element: 
-        column_def
-    | table_constraint_def
+        column_def { $$ = $1 }
     ;
 
 column_def:
         column data_type column_def_opt_list
+        {
+            $$ = NewColumnDef($1, $2, $3)
+        }
     ;
 
 column_def_opt_list:
-        /* empty */
-    | column_def_opt_list column_def_opt
+        /* empty */ { $$ = nil }
+    | column_def_opt_list column_def_opt { $$ = append($1, $2) }
     ;
 
 column_def_opt:
-        NOT NULLX
-    | NOT NULLX UNIQUE
+        NOT NULLX { $$ = NewColumnOption(OptionNotNull, nil) }
+    | NULLX { $$ = NewColumnOption(OptionNull, nil) }
+    | DEFAULT insert_atom { $$ = NewColumnOption(OptionDefault, $2) }
+    ;
+
+index_def:
+        CREATE INDEX opt_if_not_exists opt_index_name ON table opt_index_method '(' column ')'
+        {
+            $$ = NewCreateIndex($4, $6, $7, $9, $3)
+        }
+    ;
+
+opt_index_name:
+        /* empty */ { $$ = "" }
+    | NAME { $$ = $1 }
     ;
 
-table_constraint_def:
-        UNIQUE '(' column_commalist ')'
+opt_index_method:
+        /* empty */ { $$ = "" }
+    | USING NAME { $$ = $2 }
+    ;
+
+drop_table_def:
+        DROP TABLE opt_if_exists table_commalist
+        {
+            $$ = NewDropTable($4, $3)
+        }
+    ;
+
+opt_if_exists:
+        /* empty */ { $$ = false }
+    | IF EXISTS { $$ = true }
+    ;
+
+table_commalist:
+        table { $$ = []string{$1} }
+    | table_commalist COMMA table { $$ = append($1, $3) }
     ;
 
 column_commalist:
@@ -118,26 +279,58 @@ column_commalist:
     | column_commalist COMMA column { $$ = append($1, $3) }
     ;
 
+set_statement:
+        SET NAME TO set_value { $$ = NewSetVar($2, $4) }
+    | SET NAME RELATION set_value
+        {
+            expectRelation(yylex, $3, "=")
+            $$ = NewSetVar($2, $4)
+        }
+    | SET NAME NAME set_value
+        {
+            expectTimeZone(yylex, $2, $3)
+            $$ = NewSetVar("timezone", $4)
+        }
+    ;
+
+set_value:
+        STRING { $$ = $1 }
+    | NAME { $$ = $1 }
+    | DEFAULT { $$ = "" }
+    ;
+
+show_statement:
+        SHOW NAME { $$ = NewShowVar($2) }
+    | SHOW NAME NAME
+        {
+            expectTimeZone(yylex, $2, $3)
+            $$ = NewShowVar("timezone")
+        }
+    ;
+
 view_def:
         CREATE VIEW table opt_column_commalist
     ;
 
 column:
-        NAME
+        ident
      {
      	$$ = $1
      }
     ;
 
 opt_column_commalist:
-        /* empty */
-    | '(' column_commalist ')'
+        /* empty */ { $$ = nil }
+    | '(' column_commalist ')' { $$ = $2 }
     ;
 
     /* manipulative statement */
 
 manipulative_statement:
     select_statement { $$ = $1 }
+    | insert_statement { $$ = $1 }
+    | update_statement { $$ = $1 }
+    | delete_statement { $$ = $1 }
     ;
 
 close_statement:
@@ -150,20 +343,55 @@ commit_statement:
 
 insert_statement:
         INSERT INTO table opt_column_commalist values_or_query_spec
+        {
+            $$ = NewInsert($3, $4, $5)
+        }
     ;
 
 values_or_query_spec:
-        VALUES '(' insert_atom_commalist ')'
+        VALUES insert_row_commalist { $$ = $2 }
+    ;
+
+insert_row_commalist:
+        '(' insert_atom_commalist ')' { $$ = [][]Expr{$2} }
+    | insert_row_commalist COMMA '(' insert_atom_commalist ')' { $$ = append($1, $4) }
     ;
 
 insert_atom_commalist:
-        insert_atom
-    | insert_atom_commalist ',' insert_atom
+        insert_atom { $$ = []Expr{$1} }
+    | insert_atom_commalist COMMA insert_atom { $$ = append($1, $3) }
     ;
 
 insert_atom:
-        atom
-    | NULLX
+        expr { $$ = $1 }
+    | DEFAULT { $$ = NewDefault() }
+    ;
+
+update_statement:
+        UPDATE table SET assignment_commalist opt_where_clause
+        {
+            $$ = NewUpdate($2, $4, $5)
+        }
+    ;
+
+assignment_commalist:
+        assignment { $$ = []*Assignment{$1} }
+    | assignment_commalist COMMA assignment { $$ = append($1, $3) }
+    ;
+
+assignment:
+        column RELATION insert_atom
+        {
+            expectRelation(yylex, $2, "=")
+            $$ = NewAssignment($1, $3)
+        }
+    ;
+
+delete_statement:
+        DELETE FROM table opt_where_clause
+        {
+            $$ = NewDelete($3, $4)
+        }
     ;
 
 open_statement:
@@ -175,41 +403,351 @@ rollback_statement:
     ;
 
 select_statement:
-    	/*  1       2       	3   				4		*/
-        SELECT column_commalist from_clause where_clause
+        opt_with_clause select_body opt_order_by_clause opt_limit_clause { $$ = NewQuery($1, $2, $3, $4) }
+    ;
+
+    /* ORDER BY and LIMIT apply to the rows of a whole set operation */
+select_body:
+        SELECT opt_distinct select_item_commalist opt_from_clause opt_where_clause opt_group_by_clause opt_having_clause
         { 
-            $$ = NewSelect($2, $3, $4)
-        }
-    |	SELECT column_commalist from_clause
-        {
-            $$ = NewSelect($2, $3, nil)
+            $$ = NewSelect($2, $3, $4, $5, $6, $7)
         }
+    | select_body UNION opt_set_all select_body { $$ = NewCompound(SetUnion, $3, $1, $4) }
+    | select_body INTERSECT opt_set_all select_body { $$ = NewCompound(SetIntersect, $3, $1, $4) }
+    | select_body EXCEPT opt_set_all select_body { $$ = NewCompound(SetExcept, $3, $1, $4) }
+    ;
+
+opt_distinct:
+        /* empty */ { $$ = nil }
+    | ALL { $$ = nil }
+    | DISTINCT { $$ = NewDistinct(nil) }
+    | DISTINCT ON '(' expr_commalist ')' { $$ = NewDistinct($4) }
+    ;
+
+opt_with_clause:
+        /* empty */ { $$ = nil }
+    | WITH cte_commalist { $$ = NewWith(false, $2) }
+    | WITH RECURSIVE cte_commalist { $$ = NewWith(true, $3) }
+    ;
+
+cte_commalist:
+        cte { $$ = []*CTE{$1} }
+    | cte_commalist COMMA cte { $$ = append($1, $3) }
+    ;
+
+cte:
+        NAME opt_column_commalist AS '(' select_statement ')' { $$ = NewCTE($1, $2, $5) }
+    ;
+
+opt_set_all:
+        /* empty */ { $$ = false }
+    | ALL { $$ = true }
+    | DISTINCT { $$ = false }
+    ;
+
+opt_group_by_clause:
+        /* empty */ { $$ = nil }
+    | GROUP BY expr_commalist { $$ = $3 }
+    ;
+
+opt_having_clause:
+        /* empty */ { $$ = nil }
+    | HAVING expr { $$ = $2 }
+    ;
+
+opt_order_by_clause:
+        /* empty */ { $$ = nil }
+    | ORDER BY order_item_commalist { $$ = $3 }
+    ;
+
+order_item_commalist:
+        order_item { $$ = []*OrderItem{$1} }
+    | order_item_commalist COMMA order_item { $$ = append($1, $3) }
+    ;
+
+order_item:
+        expr opt_asc_desc opt_nulls_order { $$ = NewOrderItem($1, $2, $3) }
+    ;
+
+opt_asc_desc:
+        /* empty */ { $$ = false }
+    | ASC { $$ = false }
+    | DESC { $$ = true }
+    ;
+
+opt_nulls_order:
+        /* empty */ { $$ = NullsDefault }
+    | NULLS FIRST { $$ = NullsFirst }
+    | NULLS LAST { $$ = NullsLast }
+    ;
+
+    /* LIMIT ALL is represented by a NULL count, which like in Postgres
+       returns every row. FETCH FIRST without a count returns one row. */
+opt_limit_clause:
+        /* empty */ { $$ = nil }
+    | limit_clause { $$ = NewLimit($1, nil) }
+    | offset_clause { $$ = NewLimit(nil, $1) }
+    | limit_clause offset_clause { $$ = NewLimit($1, $2) }
+    | offset_clause limit_clause { $$ = NewLimit($2, $1) }
+    ;
+
+limit_clause:
+        LIMIT expr { $$ = $2 }
+    | LIMIT ALL { $$ = NewLiteral(nil) }
+    | FETCH first_or_next opt_fetch_count row_or_rows ONLY { $$ = $3 }
+    ;
+
+offset_clause:
+        OFFSET expr { $$ = $2 }
+    | OFFSET expr row_or_rows { $$ = $2 }
+    ;
+
+opt_fetch_count:
+        /* empty */ { $$ = NewLiteral(1) }
+    | expr { $$ = $1 }
+    ;
+
+first_or_next:
+        FIRST
+    | NEXT
+    ;
+
+row_or_rows:
+        ROW
+    | ROWS
+    ;
+
+select_item_commalist:
+        select_item { $$ = []*SelectItem{$1} }
+    | select_item_commalist COMMA select_item { $$ = append($1, $3) }
+    ;
+
+select_item:
+        expr { $$ = NewSelectItem($1, "") }
+    | expr AS ident { $$ = NewSelectItem($1, $3) }
+    | expr NAME { $$ = NewSelectItem($1, $2) }
+    | ASTERISK { $$ = NewSelectItem(NewStar(""), "") }
+    | ident '.' ASTERISK { $$ = NewSelectItem(NewStar($1), "") }
+    ;
+
+opt_from_clause:
+        /* empty */ { $$ = nil }
+    | from_clause { $$ = $1 }
     ;
 
 from_clause:
     /*    1    2  */
-        FROM table
+        FROM table_ref_commalist
         {
             $$ = NewFrom($2)
         }
     ;
 
+table_ref_commalist:
+        table_ref { $$ = []TableExpr{$1} }
+    | table_ref_commalist COMMA table_ref { $$ = append($1, $3) }
+    ;
+
+table_ref:
+        table opt_alias { $$ = NewTableRef($1, $2) }
+    | joined_table { $$ = $1 }
+    | '(' joined_table ')' { $$ = $2 }
+    | '(' select_statement ')' opt_alias { $$ = NewDerivedTable($2, $4) }
+    | func_application opt_alias { $$ = NewFuncTable($1, $2) }
+    ;
+
+opt_alias:
+        /* empty */ { $$ = "" }
+    | AS ident { $$ = $2 }
+    | NAME { $$ = $1 }
+    ;
+
+joined_table:
+        table_ref CROSS JOIN table_ref { $$ = NewJoin(JoinCross, $1, $4, nil) }
+    | table_ref JOIN table_ref join_qual { $$ = NewJoin(JoinInner, $1, $3, $4) }
+    | table_ref join_type JOIN table_ref join_qual { $$ = NewJoin($2, $1, $4, $5) }
+    | table_ref NATURAL JOIN table_ref
+        {
+            $$ = NewJoin(JoinInner, $1, $4, &JoinCond{Natural: true})
+        }
+    | table_ref NATURAL join_type JOIN table_ref
+        {
+            $$ = NewJoin($3, $1, $5, &JoinCond{Natural: true})
+        }
+    ;
+
+join_type:
+        INNER { $$ = JoinInner }
+    | LEFT opt_outer { $$ = JoinLeft }
+    | RIGHT opt_outer { $$ = JoinRight }
+    | FULL opt_outer { $$ = JoinFull }
+    ;
+
+opt_outer:
+        /* empty */
+    | OUTER
+    ;
+
+join_qual:
+        ON expr { $$ = &JoinCond{On: $2} }
+    | USING '(' column_commalist ')' { $$ = &JoinCond{Using: $3} }
+    ;
+
 where_clause:
-		WHERE condition_list
+		WHERE expr
 		{
 			$$ = NewWhere($2)
 		}
 	;
 
-condition_list:
-	condition { $$ = []*Condition{$1} }
-	| condition_list AND condition { $$ = append($1, $3) }
+opt_where_clause:
+		/* empty */ { $$ = nil }
+	| where_clause { $$ = $1 }
+	;
+
+expr:
+	expr OR expr { $$ = NewBinaryExpr(OpOr, $1, $3) }
+	| expr AND expr { $$ = NewBinaryExpr(OpAnd, $1, $3) }
+	| NOT expr { $$ = NewUnaryExpr(OpNot, $2) }
+	| expr RELATION expr { $$ = NewBinaryExpr($2, $1, $3) }
+	| expr OPERATOR expr { $$ = NewBinaryExpr($2, $1, $3) }
+	| expr ASTERISK expr { $$ = NewBinaryExpr(OpMul, $1, $3) }
+	| expr '/' expr { $$ = NewBinaryExpr(OpDiv, $1, $3) }
+	| expr '%' expr { $$ = NewBinaryExpr(OpMod, $1, $3) }
+	| OPERATOR expr %prec UMINUS { $$ = NewUnaryExpr($1, $2) }
+	| expr IN '(' select_statement ')' { $$ = NewInExpr($1, $4, false) }
+	| expr NOT_LA IN '(' select_statement ')' { $$ = NewInExpr($1, $5, true) }
+	| expr IN '(' expr_commalist ')' { $$ = NewInList($1, $4, false) }
+	| expr NOT_LA IN '(' expr_commalist ')' { $$ = NewInList($1, $5, true) }
+	| expr BETWEEN b_expr AND b_expr %prec BETWEEN { $$ = NewBetweenExpr($1, $3, $5, false) }
+	| expr NOT_LA BETWEEN b_expr AND b_expr %prec BETWEEN { $$ = NewBetweenExpr($1, $4, $6, true) }
+	| expr LIKE expr { $$ = NewLikeExpr(OpLike, $1, $3, nil, false) }
+	| expr LIKE expr ESCAPE expr { $$ = NewLikeExpr(OpLike, $1, $3, $5, false) }
+	| expr NOT_LA LIKE expr { $$ = NewLikeExpr(OpLike, $1, $4, nil, true) }
+	| expr NOT_LA LIKE expr ESCAPE expr { $$ = NewLikeExpr(OpLike, $1, $4, $6, true) }
+	| expr ILIKE expr { $$ = NewLikeExpr(OpILike, $1, $3, nil, false) }
+	| expr ILIKE expr ESCAPE expr { $$ = NewLikeExpr(OpILike, $1, $3, $5, false) }
+	| expr NOT_LA ILIKE expr { $$ = NewLikeExpr(OpILike, $1, $4, nil, true) }
+	| expr NOT_LA ILIKE expr ESCAPE expr { $$ = NewLikeExpr(OpILike, $1, $4, $6, true) }
+	| expr SIMILAR TO expr %prec SIMILAR { $$ = NewLikeExpr(OpSimilar, $1, $4, nil, false) }
+	| expr SIMILAR TO expr ESCAPE expr { $$ = NewLikeExpr(OpSimilar, $1, $4, $6, false) }
+	| expr NOT_LA SIMILAR TO expr %prec SIMILAR { $$ = NewLikeExpr(OpSimilar, $1, $5, nil, true) }
+	| expr NOT_LA SIMILAR TO expr ESCAPE expr { $$ = NewLikeExpr(OpSimilar, $1, $5, $7, true) }
+	| expr MATCH_OP expr { $$ = NewBinaryExpr($2, $1, $3) }
+	| expr JSON_OP expr { $$ = NewBinaryExpr($2, $1, $3) }
+	| expr IS NULLX { $$ = NewIsNullExpr($1, false) }
+	| expr IS NOT NULLX { $$ = NewIsNullExpr($1, true) }
+	| expr IS DISTINCT FROM expr %prec IS { $$ = NewIsDistinctExpr($1, $5, false) }
+	| expr IS NOT DISTINCT FROM expr %prec IS { $$ = NewIsDistinctExpr($1, $6, true) }
+	| expr AT NAME NAME expr %prec AT
+		{
+			expectTimeZone(yylex, $3, $4)
+			$$ = NewFuncCall("timezone", []Expr{$5, $1}, false, false)
+		}
+	| expr TYPECAST data_type { $$ = NewCastExpr($1, $3) }
+	| simple_expr { $$ = $1 }
+	;
+
+    /* the bounds of BETWEEN can't use AND, nor other boolean operators */
+b_expr:
+	b_expr OPERATOR b_expr { $$ = NewBinaryExpr($2, $1, $3) }
+	| b_expr ASTERISK b_expr { $$ = NewBinaryExpr(OpMul, $1, $3) }
+	| b_expr '/' b_expr { $$ = NewBinaryExpr(OpDiv, $1, $3) }
+	| b_expr '%' b_expr { $$ = NewBinaryExpr(OpMod, $1, $3) }
+	| OPERATOR b_expr %prec UMINUS { $$ = NewUnaryExpr($1, $2) }
+	| b_expr TYPECAST data_type { $$ = NewCastExpr($1, $3) }
+	| simple_expr { $$ = $1 }
+	;
+
+simple_expr:
+	'(' expr ')' { $$ = $2 }
+	| '(' select_statement ')' { $$ = NewSubquery($2) }
+	| EXISTS '(' select_statement ')' { $$ = NewExistsExpr($3) }
+	| column_ref { $$ = $1 }
+	| literal { $$ = $1 }
+	| function_call { $$ = $1 }
+	| case_expr { $$ = $1 }
+	| CAST '(' expr AS data_type ')' { $$ = NewCastExpr($3, $5) }
+	| NAME STRING { $$ = NewCastExpr(NewLiteral($2), NewTypeName($1, nil)) }
+	| EXTRACT '(' NAME FROM expr ')' { $$ = NewFuncCall("extract", []Expr{NewLiteral($3), $5}, false, false) }
+	;
+
+case_expr:
+	CASE opt_case_arg when_clause_list opt_case_default END { $$ = NewCaseExpr($2, $3, $4) }
+	;
+
+opt_case_arg:
+	/* empty */ { $$ = nil }
+	| expr { $$ = $1 }
+	;
+
+when_clause_list:
+	when_clause { $$ = []*When{$1} }
+	| when_clause_list when_clause { $$ = append($1, $2) }
+	;
+
+when_clause:
+	WHEN expr THEN expr { $$ = NewWhen($2, $4) }
+	;
+
+opt_case_default:
+	/* empty */ { $$ = nil }
+	| ELSE expr { $$ = $2 }
+	;
+
+expr_commalist:
+	expr { $$ = []Expr{$1} }
+	| expr_commalist COMMA expr { $$ = append($1, $3) }
+	;
+
+function_call:
+	func_application { $$ = $1 }
+	| func_application OVER '(' window_spec ')' { $$ = NewWindowCall($1, $4) }
+	;
+
+func_application:
+	NAME '(' ')' { $$ = NewFuncCall($1, nil, false, false) }
+	| NAME '(' ASTERISK ')' { $$ = NewFuncCall($1, nil, true, false) }
+	| NAME '(' opt_all_distinct expr_commalist ')' { $$ = NewFuncCall($1, $4, false, $3) }
+	;
+
+window_spec:
+	opt_partition_clause opt_order_by_clause opt_frame_clause { $$ = NewWindowSpec($1, $2, $3) }
+	;
+
+opt_partition_clause:
+	/* empty */ { $$ = nil }
+	| PARTITION BY expr_commalist { $$ = $3 }
+	;
+
+opt_frame_clause:
+	/* empty */ { $$ = nil }
+	| frame_mode frame_bound { $$ = NewFrame($1, $2, nil) }
+	| frame_mode BETWEEN frame_bound AND frame_bound { $$ = NewFrame($1, $3, $5) }
 	;
 
-condition:
-	NAME RELATION NAME { $$ = NewCondition($2, $1, $3) }
-	| NAME RELATION NUMBER { $$ = NewCondition($2, $1, $3) }
-	| NUMBER RELATION NUMBER { $$ = NewCondition($2, $1, $3) }
+frame_mode:
+	ROWS { $$ = FrameRows }
+	| RANGE { $$ = FrameRange }
+	;
+
+frame_bound:
+	UNBOUNDED PRECEDING { $$ = NewFrameBound(UnboundedPreceding, nil) }
+	| UNBOUNDED FOLLOWING { $$ = NewFrameBound(UnboundedFollowing, nil) }
+	| CURRENT ROW { $$ = NewFrameBound(CurrentRow, nil) }
+	| b_expr PRECEDING { $$ = NewFrameBound(Preceding, $1) }
+	| b_expr FOLLOWING { $$ = NewFrameBound(Following, $1) }
+	;
+
+opt_all_distinct:
+	/* empty */ { $$ = false }
+	| ALL { $$ = false }
+	| DISTINCT { $$ = true }
+	;
+
+column_ref:
+	ident { $$ = NewColumnRef("", $1) }
+	| ident '.' ident { $$ = NewColumnRef($1, $3) }
 	;
 
 atom:
@@ -225,20 +763,58 @@ parameter_ref:
     ;
 
 literal:
-        STRING
-    | INTNUM
-    | APPROXNUM
+        STRING { $$ = NewLiteral($1) }
+    | NUMBER { $$ = NewLiteral($1) }
+    | DECIMALNUM { $$ = NewLiteral($1) }
+    | TRUE { $$ = NewLiteral(true) }
+    | FALSE { $$ = NewLiteral(false) }
+    | NULLX { $$ = NewLiteral(nil) }
     ;
 
 table: 
+        ident { $$ = $1 }
+    | ident '.' ident { $$ = qualifiedTable(yylex, $1, $3) }
+    ;
+
+    /* words that are keywords only where they follow other words can name
+       tables and columns */
+ident:
         NAME { $$ = $1 }
-    | NAME '.' NAME { $$ = $1 }
+    | unreserved_keyword { $$ = $1 }
+    ;
+
+unreserved_keyword:
+        AT | BY | CURRENT | DELETE | ESCAPE | FIRST | FOLLOWING | INDEX | INSERT
+    | LAST | NEXT | NULLS | PARTITION | PRECEDING | PRECISION | RANGE
+    | RECURSIVE | SET | SHOW | UPDATE | VARYING | WITHOUT
     ;
 
 data_type:
-        CHARACTER
-    | NUMERIC
-    | FLOAT
+        type_name { $$ = NewTypeName($1, nil) }
+    | type_name '(' type_modifier_commalist ')' { $$ = NewTypeName($1, $3) }
+    ;
+
+    /* the words after the first are keywords, so that the type of a :: cast
+       isn't taken for an alias */
+type_name:
+        NAME { $$ = $1 }
+    | NAME PRECISION { $$ = $1 + " precision" }
+    | NAME VARYING { $$ = $1 + " varying" }
+    | NAME WITH NAME NAME
+        {
+            expectTimeZone(yylex, $3, $4)
+            $$ = $1 + " with time zone"
+        }
+    | NAME WITHOUT NAME NAME
+        {
+            expectTimeZone(yylex, $3, $4)
+            $$ = $1 + " without time zone"
+        }
+    ;
+
+type_modifier_commalist:
+        NUMBER { $$ = []int{$1} }
+    | type_modifier_commalist COMMA NUMBER { $$ = append($1, $3) }
     ;
 
 parameter: 
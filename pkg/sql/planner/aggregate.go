@@ -0,0 +1,683 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+const (
+	// spillPartitions is the number of files the groups that don't fit in
+	// memory are spread over.
+	spillPartitions = 8
+	// maxSpillDepth bounds how often a partition is split again. Beyond it
+	// the groups of a partition are kept in memory regardless of size.
+	maxSpillDepth = 4
+)
+
+type (
+	// Aggregate groups the rows of its child by the GroupBy expressions and
+	// computes the aggregate Calls over each group. Its rows hold the values
+	// of the grouped expressions followed by the results of the calls.
+	// Without GROUP BY the whole input forms a single group, even when it
+	// is empty.
+	//
+	// Groups are kept in a hash table. Once it takes more than WorkMem bytes,
+	// rows of groups not in the table yet are written to temporary files by
+	// the hash of their group and aggregated after the table is emptied.
+	Aggregate struct {
+		GroupBy []parser.Expr
+		Calls   []*parser.FuncCall
+		// Items is the select list GROUP BY items can refer to by
+		// position or by alias.
+		Items   []*parser.SelectItem
+		WorkMem int
+		keys    []parser.Expr
+		inputs  []Expression
+		aggs    []*aggregateCall
+		cols    []entity.Column
+		PlanNode
+	}
+
+	aggregateCall struct {
+		fn aggregateFunc
+		// arg is the position of the argument among the inputs, or -1 for
+		// count(*)
+		arg      int
+		distinct bool
+	}
+
+	AggregateIter struct {
+		inputs  []Expression
+		aggs    []*aggregateCall
+		width   int
+		grouped bool
+		workMem int
+		table   *groupTable
+		pos     int
+		pending []spillPartition
+		PlanIter
+	}
+
+	spillPartition struct {
+		file  *spillFile
+		depth int
+	}
+
+	groupTable struct {
+		groups []*group
+		index  map[string]*group
+		size   int
+	}
+
+	group struct {
+		key  []entity.Value
+		accs []accumulator
+		seen []map[string]struct{}
+	}
+
+	accumulator interface {
+		add(val entity.Value) error
+		result() entity.Value
+	}
+
+	aggregateFunc struct {
+		// typ returns the type of the result for an argument of the
+		// given type.
+		typ func(arg types.T) (types.T, bool)
+		new func() accumulator
+		// nulls is set on the aggregates that are passed NULL arguments.
+		nulls bool
+	}
+
+	countAcc struct {
+		n int
+	}
+
+	// sumAcc adds up numbers of a single family, or intervals.
+	sumAcc struct {
+		sum entity.Value
+	}
+
+	avgAcc struct {
+		sumAcc
+		n int
+	}
+
+	minMaxAcc struct {
+		val entity.Value
+		max bool
+	}
+
+	// jsonAggAcc collects its values into a JSON array. Timestamps with time
+	// zone are shown in UTC.
+	jsonAggAcc struct {
+		elems []interface{}
+	}
+)
+
+// aggregates are the aggregate functions by name.
+var aggregates = map[string]aggregateFunc{
+	"count": {
+		typ: func(types.T) (types.T, bool) { return types.BigInt, true },
+		new: func() accumulator { return &countAcc{} },
+	},
+	"sum": {
+		typ: numberAggregate(types.BigInt),
+		new: func() accumulator { return &sumAcc{} },
+	},
+	"avg": {
+		typ: numberAggregate(types.Numeric),
+		new: func() accumulator { return &avgAcc{} },
+	},
+	"min": {
+		typ: orderedAggregate,
+		new: func() accumulator { return &minMaxAcc{} },
+	},
+	"max": {
+		typ: orderedAggregate,
+		new: func() accumulator { return &minMaxAcc{max: true} },
+	},
+	"jsonb_agg": {
+		typ:   func(types.T) (types.T, bool) { return types.JSONB, true },
+		new:   func() accumulator { return &jsonAggAcc{} },
+		nulls: true,
+	},
+	"json_agg": {
+		typ:   func(types.T) (types.T, bool) { return types.JSON, true },
+		new:   func() accumulator { return &jsonAggAcc{} },
+		nulls: true,
+	},
+}
+
+// numberAggregate returns the type of the result of an aggregate over
+// numbers, which is res for integers. Floats give double precision, numerics
+// a numeric and intervals an interval.
+func numberAggregate(res types.T) func(types.T) (types.T, bool) {
+	return func(arg types.T) (types.T, bool) {
+		switch arg.Family {
+		case types.FloatFamily:
+			return types.Float, true
+		case types.NumericFamily:
+			return types.Numeric, true
+		case types.IntervalFamily:
+			return types.Interval, true
+		}
+		return res, isInt(arg)
+	}
+}
+
+func orderedAggregate(arg types.T) (types.T, bool) {
+	switch arg.Family {
+	case types.IntFamily, types.TextFamily, types.FloatFamily, types.NumericFamily, types.UnknownFamily:
+		return arg, true
+	case types.DateFamily, types.TimeFamily, types.TimestampFamily, types.TimestampTZFamily, types.IntervalFamily:
+		return arg, true
+	}
+	return arg, false
+}
+
+// Aggregate Expression
+func (agg *Aggregate) Iter() index.Iterator {
+	return &AggregateIter{
+		inputs:  agg.inputs,
+		aggs:    agg.aggs,
+		width:   len(agg.keys),
+		grouped: len(agg.keys) > 0,
+		workMem: agg.WorkMem,
+		PlanIter: PlanIter{
+			ChildIter: agg.Child.Iter(),
+		},
+	}
+}
+func (agg *Aggregate) Columns() []entity.Column {
+	return agg.cols
+}
+func (agg *Aggregate) Prepare() error {
+	if agg.Child == nil {
+		return errors.New("no child node")
+	}
+	childCols := agg.Child.Columns()
+	c := &compiler{cols: childCols, scope: agg.scope}
+	keys := make([]parser.Expr, len(agg.GroupBy))
+	inputs := make([]Expression, 0, len(agg.GroupBy)+len(agg.Calls))
+	cols := make([]entity.Column, 0, len(agg.GroupBy)+len(agg.Calls))
+	for i, item := range agg.GroupBy {
+		key, err := agg.resolveGroupItem(item, childCols)
+		if err != nil {
+			return err
+		}
+		expr, err := c.compile(key)
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+		inputs = append(inputs, expr)
+		cols = append(cols, outputColumn(&parser.SelectItem{Expr: key}, expr))
+	}
+	aggs := make([]*aggregateCall, len(agg.Calls))
+	for i, call := range agg.Calls {
+		fn := aggregates[call.Name]
+		res := &aggregateCall{
+			fn:       fn,
+			arg:      -1,
+			distinct: call.Distinct,
+		}
+		typ := types.Unknown
+		if call.Star {
+			if call.Name != "count" {
+				return fmt.Errorf("function %s(*) does not exist", call.Name)
+			}
+		} else {
+			if len(call.Args) != 1 {
+				return fmt.Errorf("function %s takes exactly one argument", call.Name)
+			}
+			arg, err := c.compile(call.Args[0])
+			if err != nil {
+				return err
+			}
+			res.arg = len(inputs)
+			inputs = append(inputs, arg)
+			typ = arg.Type()
+		}
+		resType, ok := fn.typ(typ)
+		if !ok {
+			return fmt.Errorf("function %s(%s) does not exist", call.Name, typ)
+		}
+		aggs[i] = res
+		cols = append(cols, entity.Column{
+			Type: resType,
+			Name: call.Name,
+		})
+	}
+	agg.keys = keys
+	agg.inputs = inputs
+	agg.aggs = aggs
+	agg.cols = cols
+	if agg.WorkMem <= 0 {
+		agg.WorkMem = DefaultWorkMem
+	}
+	return nil
+}
+
+// resolveGroupItem replaces a GROUP BY position or output column name with the
+// select item it refers to. Like in Postgres, input columns take precedence
+// over output columns of the same name.
+func (agg *Aggregate) resolveGroupItem(item parser.Expr, cols []entity.Column) (parser.Expr, error) {
+	switch e := item.(type) {
+	case *parser.Literal:
+		n, ok := e.Value.(int)
+		if !ok {
+			return item, nil
+		}
+		if n < 1 || n > len(agg.Items) {
+			return nil, fmt.Errorf("GROUP BY position %d is not in select list", n)
+		}
+		if _, ok := agg.Items[n-1].Expr.(*parser.Star); ok {
+			return nil, fmt.Errorf("GROUP BY position %d refers to *", n)
+		}
+		return agg.Items[n-1].Expr, nil
+	case *parser.ColumnRef:
+		if e.Table != "" {
+			return item, nil
+		}
+		if _, err := resolveColumn(e, cols); err == nil {
+			return item, nil
+		}
+		for _, sel := range agg.Items {
+			if sel.Alias == e.Name {
+				return sel.Expr, nil
+			}
+		}
+	}
+	return item, nil
+}
+
+// lookup finds the output column holding the value of expr, when expr is a
+// grouped expression or an aggregate call. It returns nil otherwise.
+func (agg *Aggregate) lookup(expr parser.Expr) (Expression, error) {
+	switch e := expr.(type) {
+	case *parser.FuncCall:
+		if _, ok := aggregates[e.Name]; !ok || e.Over != nil {
+			break
+		}
+		for i, call := range agg.Calls {
+			if call.String() == e.String() {
+				return agg.column(len(agg.keys) + i), nil
+			}
+		}
+		return nil, fmt.Errorf("aggregate function %s is not allowed here", e.Name)
+	case *parser.ColumnRef:
+		col, err := lookupColumn(e, agg.Child.Columns())
+		if err != nil || col == nil {
+			return nil, err
+		}
+		for i, key := range agg.inputs[:len(agg.keys)] {
+			if keyCol, ok := key.(*ColumnExpr); ok && keyCol.Index == col.Index {
+				return agg.column(i), nil
+			}
+		}
+		return nil, nil
+	}
+	for i, key := range agg.keys {
+		if key.String() == expr.String() {
+			return agg.column(i), nil
+		}
+	}
+	return nil, nil
+}
+
+func (agg *Aggregate) column(i int) Expression {
+	return &ColumnExpr{Index: i, Column: agg.cols[i]}
+}
+
+// collectAggregates lists the distinct aggregate calls in exprs, without
+// looking into their arguments.
+func collectAggregates(exprs []parser.Expr) []*parser.FuncCall {
+	res := make([]*parser.FuncCall, 0)
+	seen := make(map[string]bool)
+	for _, expr := range exprs {
+		walkExpr(expr, func(e parser.Expr) bool {
+			call, ok := e.(*parser.FuncCall)
+			if !ok {
+				return true
+			}
+			// aggregates over a window are computed by a Window node
+			if _, ok := aggregates[call.Name]; !ok || call.Over != nil {
+				return true
+			}
+			if !seen[call.String()] {
+				seen[call.String()] = true
+				res = append(res, call)
+			}
+			return false
+		})
+	}
+	return res
+}
+
+func (iter *AggregateIter) Next() (entity.Row, error) {
+	if iter.table == nil {
+		input := &ProjectionIter{
+			exprs: iter.inputs,
+			PlanIter: PlanIter{
+				ChildIter: iter.ChildIter,
+			},
+		}
+		if err := iter.fill(input, 0); err != nil {
+			return entity.Row{}, err
+		}
+		if !iter.grouped && len(iter.table.groups) == 0 {
+			iter.table.add("", nil, iter.aggs)
+		}
+	}
+	for iter.pos >= len(iter.table.groups) {
+		if len(iter.pending) == 0 {
+			return entity.Row{}, index.EndOfIterator
+		}
+		part := iter.pending[0]
+		iter.pending = iter.pending[1:]
+		input, err := part.file.iterator()
+		if err != nil {
+			iter.close()
+			return entity.Row{}, err
+		}
+		if err := iter.fill(input, part.depth); err != nil {
+			return entity.Row{}, err
+		}
+	}
+	g := iter.table.groups[iter.pos]
+	iter.pos++
+	return g.result(), nil
+}
+
+// fill aggregates the rows of input, whose values are the grouped values
+// followed by the arguments of the aggregate calls, into a new table.
+func (iter *AggregateIter) fill(input index.Iterator, depth int) error {
+	table := &groupTable{
+		groups: make([]*group, 0),
+		index:  make(map[string]*group),
+	}
+	var parts []*spillFile
+	for {
+		row, err := input.Next()
+		if err == index.EndOfIterator {
+			break
+		} else if err != nil {
+			closeSpillFiles(parts)
+			iter.close()
+			return err
+		}
+		key := hashKey(row.Values[:iter.width])
+		g, ok := table.index[key]
+		if !ok && table.size > iter.workMem && depth < maxSpillDepth {
+			if parts == nil {
+				parts = make([]*spillFile, spillPartitions)
+			}
+			p := partition(key, depth)
+			if parts[p] == nil {
+				if parts[p], err = newSpillFile(); err != nil {
+					closeSpillFiles(parts)
+					iter.close()
+					return err
+				}
+			}
+			if err := parts[p].write(row); err != nil {
+				closeSpillFiles(parts)
+				iter.close()
+				return err
+			}
+			continue
+		}
+		if !ok {
+			g = table.add(key, row.Values[:iter.width], iter.aggs)
+		}
+		if err := table.update(g, row, iter.aggs); err != nil {
+			closeSpillFiles(parts)
+			iter.close()
+			return err
+		}
+	}
+	for _, part := range parts {
+		if part != nil {
+			iter.pending = append(iter.pending, spillPartition{file: part, depth: depth + 1})
+		}
+	}
+	iter.table = table
+	iter.pos = 0
+	return nil
+}
+
+// close releases the partitions that haven't been aggregated yet.
+func (iter *AggregateIter) close() {
+	for _, part := range iter.pending {
+		part.file.close()
+	}
+	iter.pending = nil
+}
+
+func closeSpillFiles(files []*spillFile) {
+	for _, f := range files {
+		if f != nil {
+			f.close()
+		}
+	}
+}
+
+func (t *groupTable) add(key string, vals []entity.Value, aggs []*aggregateCall) *group {
+	g := &group{
+		key:  append([]entity.Value(nil), vals...),
+		accs: make([]accumulator, len(aggs)),
+		seen: make([]map[string]struct{}, len(aggs)),
+	}
+	for i, call := range aggs {
+		g.accs[i] = call.fn.new()
+		if call.distinct {
+			g.seen[i] = make(map[string]struct{})
+		}
+	}
+	t.groups = append(t.groups, g)
+	t.index[key] = g
+	t.size += len(key) + rowSize(entity.Row{Values: g.key}) + 32*len(aggs)
+	return g
+}
+
+// update adds the arguments in row to the aggregates of g. NULL arguments
+// are ignored, except by the aggregates collecting them, and count(*) counts
+// every row.
+func (t *groupTable) update(g *group, row entity.Row, aggs []*aggregateCall) error {
+	for i, call := range aggs {
+		var val entity.Value = 1
+		if call.arg >= 0 {
+			val = row.Values[call.arg]
+		}
+		if val == nil && !call.fn.nulls {
+			continue
+		}
+		if call.distinct {
+			key := hashKey([]entity.Value{val})
+			if _, ok := g.seen[i][key]; ok {
+				continue
+			}
+			g.seen[i][key] = struct{}{}
+			t.size += len(key) + 16
+		}
+		if err := g.accs[i].add(val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *group) result() entity.Row {
+	vals := make([]entity.Value, 0, len(g.key)+len(g.accs))
+	vals = append(vals, g.key...)
+	for _, acc := range g.accs {
+		vals = append(vals, acc.result())
+	}
+	return entity.Row{
+		Values: vals,
+	}
+}
+
+// hashKey encodes values such that two lists of values have the same key
+// exactly when they are equal. NULLs are equal to each other.
+func hashKey(vals []entity.Value) string {
+	var b strings.Builder
+	for _, val := range vals {
+		switch v := val.(type) {
+		case nil:
+			b.WriteString("N")
+		case int:
+			b.WriteString("I" + strconv.Itoa(v) + ";")
+		case string:
+			b.WriteString("S" + strconv.Itoa(len(v)) + ":" + v)
+		case bool:
+			if v {
+				b.WriteString("T")
+			} else {
+				b.WriteString("F")
+			}
+		case float64:
+			// -0 equals 0
+			if v == 0 {
+				v = 0
+			}
+			b.WriteString("D" + strconv.FormatFloat(v, 'g', -1, 64) + ";")
+		case types.Decimal:
+			// equal decimals may have different scales
+			b.WriteString("M" + v.Key() + ";")
+		case types.IntervalValue:
+			// a day of hours equals a day
+			b.WriteString("V" + v.Key() + ";")
+		case types.JSONValue:
+			// equal documents may have numbers of different scales
+			key := v.Key()
+			b.WriteString("J" + strconv.Itoa(len(key)) + ":" + key)
+		default:
+			fmt.Fprintf(&b, "%T:%v;", v, v)
+		}
+	}
+	return b.String()
+}
+
+// partition picks the spill file of a group. The depth is mixed into the hash
+// so that a partition is split differently when spilled again.
+func partition(key string, depth int) int {
+	h := fnv.New32a()
+	h.Write([]byte{byte(depth)})
+	h.Write([]byte(key))
+	return int(h.Sum32() % spillPartitions)
+}
+
+func (acc *countAcc) add(entity.Value) error {
+	acc.n++
+	return nil
+}
+func (acc *countAcc) result() entity.Value {
+	return acc.n
+}
+
+func (acc *sumAcc) add(val entity.Value) error {
+	switch sum := acc.sum.(type) {
+	case nil:
+		switch val.(type) {
+		case int, float64, types.Decimal, types.IntervalValue:
+			acc.sum = val
+			return nil
+		}
+	case int:
+		if n, ok := val.(int); ok {
+			if acc.sum, ok = addInt(sum, n); !ok {
+				return errors.New("bigint out of range")
+			}
+			return nil
+		}
+	case float64:
+		if f, ok := val.(float64); ok {
+			acc.sum = sum + f
+			return nil
+		}
+	case types.Decimal:
+		if d, ok := val.(types.Decimal); ok {
+			acc.sum = sum.Add(d)
+			return nil
+		}
+	case types.IntervalValue:
+		if iv, ok := val.(types.IntervalValue); ok {
+			acc.sum = sum.Add(iv)
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot sum %v of type %T", val, val)
+}
+func (acc *sumAcc) result() entity.Value {
+	return acc.sum
+}
+
+func (acc *avgAcc) add(val entity.Value) error {
+	// integers are added up as numerics, which can't overflow
+	if n, ok := val.(int); ok {
+		val = types.NewDecimal(int64(n), 0)
+	}
+	if err := acc.sumAcc.add(val); err != nil {
+		return fmt.Errorf("cannot average %v of type %T", val, val)
+	}
+	acc.n++
+	return nil
+}
+func (acc *avgAcc) result() entity.Value {
+	switch sum := acc.sum.(type) {
+	case float64:
+		return sum / float64(acc.n)
+	case types.Decimal:
+		// n is never zero
+		avg, _ := sum.Quo(types.NewDecimal(int64(acc.n), 0))
+		return avg
+	case types.IntervalValue:
+		avg, _ := sum.Div(float64(acc.n))
+		return avg
+	}
+	return nil
+}
+
+func (acc *minMaxAcc) add(val entity.Value) error {
+	if acc.val == nil {
+		acc.val = val
+		return nil
+	}
+	cmp, err := types.Compare(val, acc.val)
+	if err != nil {
+		return err
+	}
+	if (acc.max && cmp > 0) || (!acc.max && cmp < 0) {
+		acc.val = val
+	}
+	return nil
+}
+func (acc *minMaxAcc) result() entity.Value {
+	return acc.val
+}
+
+func (acc *jsonAggAcc) add(val entity.Value) error {
+	doc, err := types.ToJSON(val, time.UTC)
+	if err != nil {
+		return err
+	}
+	acc.elems = append(acc.elems, doc)
+	return nil
+}
+func (acc *jsonAggAcc) result() entity.Value {
+	if acc.elems == nil {
+		return nil
+	}
+	return types.EncodeJSON(acc.elems)
+}
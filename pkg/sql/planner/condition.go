@@ -1,67 +1,220 @@
 package planner
 
 import (
-	"reflect"
+	"fmt"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/sql"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/types"
 )
 
-const (
-	Equal = iota + 1
+type Relation int
+
+var relMap = map[string]Relation{
+	"=":  sql.CompareEqual,
+	"<>": sql.CompareNotEqual,
+	"<":  sql.CompareLess,
+	"<=": sql.CompareEqualOrLess,
+	">":  sql.CompareGreater,
+	">=": sql.CompareEqualOrGreater,
+}
+
+var relNames = map[Relation]string{
+	sql.CompareEqual:          "=",
+	sql.CompareNotEqual:       "<>",
+	sql.CompareLess:           "<",
+	sql.CompareEqualOrLess:    "<=",
+	sql.CompareGreater:        ">",
+	sql.CompareEqualOrGreater: ">=",
+}
+
+type (
+	// CompareExpr compares two operands of the same family, or two numbers.
+	CompareExpr struct {
+		Relation Relation
+		LHS      Expression
+		RHS      Expression
+	}
+
+	// LogicExpr combines two boolean operands with AND or OR. NULL stands
+	// for an unknown value, so NULL AND false is false and NULL OR true is
+	// true.
+	LogicExpr struct {
+		Op  string
+		LHS Expression
+		RHS Expression
+	}
+
+	NotExpr struct {
+		Expr Expression
+	}
 )
 
-type Relation int
+func (c *compiler) newCompareExpr(rel Relation, lhs, rhs Expression) (Expression, error) {
+	lhs, rhs, err := c.unifyTypes(lhs, rhs)
+	if err != nil {
+		return nil, err
+	}
+	if ltyp, rtyp := lhs.Type(), rhs.Type(); !types.Comparable(ltyp, rtyp) {
+		return nil, fmt.Errorf("operator does not exist: %s %s %s", ltyp, relNames[rel], rtyp)
+	}
+	return &CompareExpr{Relation: rel, LHS: lhs, RHS: rhs}, nil
+}
 
-type Condition struct {
-	Relation Relation
-	LHS      interface{}
-	RHS      interface{}
+func (c *CompareExpr) Eval(row entity.Row) (entity.Value, error) {
+	lval, err := c.LHS.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	rval, err := c.RHS.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	if lval == nil || rval == nil {
+		return nil, nil
+	}
+	return compare(c.Relation, lval, rval)
+}
+func (c *CompareExpr) Type() types.T {
+	return types.Bool
+}
+func (c *CompareExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", c.LHS, relNames[c.Relation], c.RHS)
 }
 
-func Eval(conds []*Condition, row entity.Row, colMap map[string]int, cols []entity.Column) bool {
-	for _, cond := range conds {
-		if !cond.Eval(row, colMap, cols) {
-			return false
-		}
+func (e *LogicExpr) Eval(row entity.Row) (entity.Value, error) {
+	lval, err := e.LHS.Eval(row)
+	if err != nil {
+		return nil, err
 	}
-	return true
+	// short-circuit once the left operand decides the result
+	if e.Op == parser.OpAnd && lval == false {
+		return false, nil
+	}
+	if e.Op == parser.OpOr && lval == true {
+		return true, nil
+	}
+	rval, err := e.RHS.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	if e.Op == parser.OpAnd {
+		return and3(lval, rval), nil
+	}
+	return or3(lval, rval), nil
+}
+func (e *LogicExpr) Type() types.T {
+	return types.Bool
+}
+func (e *LogicExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.LHS, e.Op, e.RHS)
 }
 
-func (c *Condition) Eval(row entity.Row, colMap map[string]int, cols []entity.Column) bool {
-	colName, ok := c.LHS.(string)
-	if !ok {
-		return false
+func (e *NotExpr) Eval(row entity.Row) (entity.Value, error) {
+	val, err := e.Expr.Eval(row)
+	if err != nil {
+		return nil, err
 	}
-	id, ok := colMap[colName]
-	if !ok {
+	return not3(val), nil
+}
+func (e *NotExpr) Type() types.T {
+	return types.Bool
+}
+func (e *NotExpr) String() string {
+	return fmt.Sprintf("(NOT %s)", e.Expr)
+}
+
+// and3 is the AND of two booleans under three-valued logic: false if either
+// is false, else NULL if either is NULL.
+func and3(a, b entity.Value) entity.Value {
+	if a == false || b == false {
 		return false
 	}
-	col := cols[id]
-	var rval interface{}
-	switch col.Kind {
-	case reflect.String:
-		rval, ok = c.RHS.(string)
-		if !ok {
-			return false
+	if a == entity.Null || b == entity.Null {
+		return entity.Null
+	}
+	return true
+}
+
+// or3 is the OR of two booleans under three-valued logic: true if either is
+// true, else NULL if either is NULL.
+func or3(a, b entity.Value) entity.Value {
+	if a == true || b == true {
+		return true
+	}
+	if a == entity.Null || b == entity.Null {
+		return entity.Null
+	}
+	return false
+}
+
+// not3 is the NOT of a boolean, which is NULL for NULL.
+func not3(a entity.Value) entity.Value {
+	if a == entity.Null {
+		return entity.Null
+	}
+	return !a.(bool)
+}
+
+// unifyTypes converts a string constant compared against an operand of another
+// family to the type of that operand, the way Postgres treats untyped
+// literals. Dates and timestamps are converted to the wider of their types.
+func (c *compiler) unifyTypes(lhs, rhs Expression) (Expression, Expression, error) {
+	ltyp, rtyp := lhs.Type(), rhs.Type()
+	if ltyp.Family == rtyp.Family {
+		return lhs, rhs, nil
+	}
+	if lit, ok := rhs.(*ConstExpr); ok && rtyp.Family == types.TextFamily {
+		val, err := c.parseConst(lit.Value.(string), ltyp)
+		if err != nil {
+			return nil, nil, err
 		}
-	case reflect.Int:
-		rval, ok = c.RHS.(int)
-		if !ok {
-			return false
+		return lhs, val, nil
+	}
+	if lit, ok := lhs.(*ConstExpr); ok && ltyp.Family == types.TextFamily {
+		val, err := c.parseConst(lit.Value.(string), rtyp)
+		if err != nil {
+			return nil, nil, err
 		}
-	default:
-		return false
+		return val, rhs, nil
 	}
-	return compare(c.Relation, row.Values[id], rval)
+	if typ, ok := dateTimeCommon(ltyp, rtyp); ok {
+		return c.promote(lhs, typ), c.promote(rhs, typ), nil
+	}
+	return lhs, rhs, nil
+}
+
+// parseConst reads a string constant as a value of typ.
+func (c *compiler) parseConst(s string, typ types.T) (Expression, error) {
+	val, err := typ.CoerceIn(s, c.location())
+	if err != nil {
+		return nil, err
+	}
+	return &ConstExpr{Value: val}, nil
 }
 
-func compare(relation Relation, a, b interface{}) bool {
+func compare(relation Relation, a, b interface{}) (bool, error) {
 	logrus.Debugf("comparing %v %d %v", a, relation, b)
+	cmp, err := types.Compare(a, b)
+	if err != nil {
+		return false, err
+	}
 	switch relation {
-	case Equal:
-		return a == b
+	case sql.CompareEqual:
+		return cmp == 0, nil
+	case sql.CompareNotEqual:
+		return cmp != 0, nil
+	case sql.CompareLess:
+		return cmp < 0, nil
+	case sql.CompareEqualOrLess:
+		return cmp <= 0, nil
+	case sql.CompareGreater:
+		return cmp > 0, nil
+	case sql.CompareEqualOrGreater:
+		return cmp >= 0, nil
 	}
-	return false
+	return false, fmt.Errorf("invalid relation %d", relation)
 }
@@ -0,0 +1,289 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/sql"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+type (
+	// CaseExpr returns the result of the first condition that holds, or
+	// Else. The conditions of a simple CASE compare its operand to the
+	// values of the WHEN clauses.
+	CaseExpr struct {
+		Conds   []Expression
+		Results []Expression
+		Else    Expression
+		typ     types.T
+	}
+
+	// CoalesceExpr returns the first of its arguments that isn't NULL. The
+	// arguments after it aren't evaluated.
+	CoalesceExpr struct {
+		Args []Expression
+		typ  types.T
+	}
+
+	// NullIfExpr returns NULL if LHS equals RHS, and LHS otherwise.
+	NullIfExpr struct {
+		LHS Expression
+		RHS Expression
+	}
+
+	// ExtremumExpr returns the largest of its arguments for GREATEST, or the
+	// smallest for LEAST. NULL arguments are ignored.
+	ExtremumExpr struct {
+		Name string
+		Args []Expression
+		typ  types.T
+	}
+)
+
+// unifyResults resolves the type of values returned in place of each other,
+// like the results of a CASE. They must all be of the same family, except
+// that NULL fits any type, string constants are converted to the type of the
+// others, and numbers, dates and timestamps to the widest of their types.
+func (c *compiler) unifyResults(name string, exprs []Expression) ([]Expression, types.T, error) {
+	typ := types.Unknown
+	for _, expr := range exprs {
+		t := expr.Type()
+		if _, ok := expr.(*ConstExpr); ok && t.Family == types.TextFamily {
+			continue
+		}
+		common, ok := types.Common(typ, t)
+		if !ok {
+			common, ok = dateTimeCommon(typ, t)
+		}
+		if !ok {
+			return nil, types.T{}, fmt.Errorf("%s types %s and %s cannot be matched", name, typ, t)
+		}
+		typ = common
+	}
+	res := make([]Expression, len(exprs))
+	for i, expr := range exprs {
+		res[i] = c.promote(expr, typ)
+		lit, ok := expr.(*ConstExpr)
+		if !ok || expr.Type().Family != types.TextFamily {
+			continue
+		}
+		if typ == types.Unknown {
+			typ = types.Text
+		}
+		val, err := c.parseConst(lit.Value.(string), typ)
+		if err != nil {
+			return nil, types.T{}, err
+		}
+		res[i] = val
+	}
+	return res, typ, nil
+}
+
+func (c *compiler) compileCase(e *parser.CaseExpr) (Expression, error) {
+	var operand Expression
+	if e.Operand != nil {
+		var err error
+		if operand, err = c.compile(e.Operand); err != nil {
+			return nil, err
+		}
+	}
+	conds := make([]Expression, len(e.Whens))
+	results := make([]Expression, len(e.Whens), len(e.Whens)+1)
+	for i, when := range e.Whens {
+		cond, err := c.compile(when.Cond)
+		if err != nil {
+			return nil, err
+		}
+		if operand != nil {
+			if cond, err = c.newCompareExpr(sql.CompareEqual, operand, cond); err != nil {
+				return nil, err
+			}
+		} else if err := expectBool("CASE/WHEN", cond); err != nil {
+			return nil, err
+		}
+		conds[i] = cond
+		if results[i], err = c.compile(when.Result); err != nil {
+			return nil, err
+		}
+	}
+	if e.Else != nil {
+		els, err := c.compile(e.Else)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, els)
+	}
+	results, typ, err := c.unifyResults("CASE", results)
+	if err != nil {
+		return nil, err
+	}
+	expr := &CaseExpr{Conds: conds, Results: results[:len(conds)], typ: typ}
+	if e.Else != nil {
+		expr.Else = results[len(conds)]
+	}
+	return expr, nil
+}
+
+func (e *CaseExpr) Eval(row entity.Row) (entity.Value, error) {
+	for i, cond := range e.Conds {
+		ok, err := evalBool(cond, row)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return e.Results[i].Eval(row)
+		}
+	}
+	if e.Else == nil {
+		return nil, nil
+	}
+	return e.Else.Eval(row)
+}
+func (e *CaseExpr) Type() types.T {
+	return e.typ
+}
+func (e *CaseExpr) String() string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for i, cond := range e.Conds {
+		fmt.Fprintf(&b, " WHEN %s THEN %s", cond, e.Results[i])
+	}
+	if e.Else != nil {
+		fmt.Fprintf(&b, " ELSE %s", e.Else)
+	}
+	b.WriteString(" END")
+	return b.String()
+}
+
+// compileConditional compiles a call of COALESCE, NULLIF, GREATEST or LEAST.
+// Unlike other functions these only evaluate the arguments they need. It
+// returns nil for any other function.
+func (c *compiler) compileConditional(e *parser.FuncCall) (Expression, error) {
+	switch e.Name {
+	case "coalesce", "nullif", "greatest", "least":
+	default:
+		return nil, nil
+	}
+	if e.Star {
+		return nil, fmt.Errorf("function %s(*) does not exist", e.Name)
+	}
+	if e.Distinct {
+		return nil, fmt.Errorf("DISTINCT specified, but %s is not an aggregate function", e.Name)
+	}
+	args := make([]Expression, len(e.Args))
+	for i, arg := range e.Args {
+		var err error
+		if args[i], err = c.compile(arg); err != nil {
+			return nil, err
+		}
+	}
+	name := strings.ToUpper(e.Name)
+	if e.Name == "nullif" {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("function nullif takes 2 arguments, not %d", len(args))
+		}
+		cmp, err := c.newCompareExpr(sql.CompareEqual, args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		return &NullIfExpr{LHS: cmp.(*CompareExpr).LHS, RHS: cmp.(*CompareExpr).RHS}, nil
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("function %s requires at least one argument", e.Name)
+	}
+	args, typ, err := c.unifyResults(name, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.Name == "coalesce" {
+		return &CoalesceExpr{Args: args, typ: typ}, nil
+	}
+	return &ExtremumExpr{Name: name, Args: args, typ: typ}, nil
+}
+
+func (e *CoalesceExpr) Eval(row entity.Row) (entity.Value, error) {
+	for _, arg := range e.Args {
+		val, err := arg.Eval(row)
+		if err != nil || val != nil {
+			return val, err
+		}
+	}
+	return nil, nil
+}
+func (e *CoalesceExpr) Type() types.T {
+	return e.typ
+}
+func (e *CoalesceExpr) String() string {
+	return fmt.Sprintf("COALESCE(%s)", joinExprs(e.Args))
+}
+
+func (e *NullIfExpr) Eval(row entity.Row) (entity.Value, error) {
+	lval, err := e.LHS.Eval(row)
+	if err != nil || lval == nil {
+		return nil, err
+	}
+	rval, err := e.RHS.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	if rval == nil {
+		return lval, nil
+	}
+	cmp, err := types.Compare(lval, rval)
+	if err != nil {
+		return nil, err
+	}
+	if cmp == 0 {
+		return nil, nil
+	}
+	return lval, nil
+}
+func (e *NullIfExpr) Type() types.T {
+	return e.LHS.Type()
+}
+func (e *NullIfExpr) String() string {
+	return fmt.Sprintf("NULLIF(%s, %s)", e.LHS, e.RHS)
+}
+
+func (e *ExtremumExpr) Eval(row entity.Row) (entity.Value, error) {
+	var res entity.Value
+	for _, arg := range e.Args {
+		val, err := arg.Eval(row)
+		if err != nil {
+			return nil, err
+		}
+		if val == nil {
+			continue
+		}
+		if res == nil {
+			res = val
+			continue
+		}
+		cmp, err := types.Compare(val, res)
+		if err != nil {
+			return nil, err
+		}
+		if (e.Name == "GREATEST" && cmp > 0) || (e.Name == "LEAST" && cmp < 0) {
+			res = val
+		}
+	}
+	return res, nil
+}
+func (e *ExtremumExpr) Type() types.T {
+	return e.typ
+}
+func (e *ExtremumExpr) String() string {
+	return fmt.Sprintf("%s(%s)", e.Name, joinExprs(e.Args))
+}
+
+// joinExprs lists expressions separated by commas.
+func joinExprs(exprs []Expression) string {
+	strs := make([]string, len(exprs))
+	for i, expr := range exprs {
+		strs[i] = expr.String()
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,235 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+)
+
+// maxQueryDepth bounds the nesting of subqueries being planned.
+const maxQueryDepth = 64
+
+type (
+	// cteEnv lists the CTEs visible to a query, most recent first. Each
+	// entry holds one CTE and shadows those of the same name it links to.
+	cteEnv struct {
+		def    *cteDef
+		parent *cteEnv
+	}
+
+	// cteDef is a CTE of a WITH clause. Every reference to it plans its
+	// query again.
+	cteDef struct {
+		*parser.CTE
+		recursive bool
+		// scope is the scope of the query the WITH clause belongs to and
+		// env the CTEs visible to the query of the CTE.
+		scope    *scope
+		env      *cteEnv
+		planning bool
+	}
+
+	// workTable holds the rows a recursive CTE returned in its last
+	// iteration.
+	workTable struct {
+		anchor Node
+		names  []string
+		rows   []entity.Row
+		used   bool
+	}
+
+	// RecursiveUnion runs a recursive CTE. It returns the rows of Anchor,
+	// then runs Recursive over the rows it returned last until it returns
+	// no more rows. Without All the rows returned before are discarded.
+	RecursiveUnion struct {
+		Anchor    Node
+		Recursive Node
+		All       bool
+		work      *workTable
+		cols      []entity.Column
+	}
+
+	// WorkTableScan reads the working table of a recursive CTE from its
+	// recursive term.
+	WorkTableScan struct {
+		Alias string
+		work  *workTable
+	}
+
+	RecursiveUnionIter struct {
+		node    *RecursiveUnion
+		iter    index.Iterator
+		started bool
+		// next collects the rows of the running iteration
+		next []entity.Row
+		seen map[string]bool
+	}
+)
+
+// parseWith returns a scope of the query in s that sees the CTEs of a WITH
+// clause. A CTE sees those listed before it, or all of them in a recursive
+// WITH.
+func (p *Planner) parseWith(with *parser.With, s *scope) (*scope, error) {
+	env := s.ctes
+	defs := make([]*cteDef, len(with.CTEs))
+	names := make(map[string]bool, len(with.CTEs))
+	for i, cte := range with.CTEs {
+		if names[cte.Name] {
+			return nil, fmt.Errorf("WITH query name %s specified more than once", cte.Name)
+		}
+		names[cte.Name] = true
+		defs[i] = &cteDef{CTE: cte, recursive: with.Recursive, scope: s, env: env}
+		env = &cteEnv{def: defs[i], parent: env}
+	}
+	if with.Recursive {
+		for _, def := range defs {
+			def.env = env
+		}
+	}
+	return s.nest(env), nil
+}
+
+// lookupCTE finds the CTE a table name refers to, if any.
+func (s *scope) lookupCTE(name string) *cteDef {
+	for env := s.ctes; env != nil; env = env.parent {
+		if env.def.Name == name {
+			return env.def
+		}
+	}
+	return nil
+}
+
+// parseCTERef plans a reference to a CTE. Within the recursive term of the
+// CTE, it reads the working table.
+func (p *Planner) parseCTERef(def *cteDef, alias string, s *scope) (Node, error) {
+	nested := false
+	for sc := s; sc != nil; {
+		if sc.body == def {
+			if nested {
+				return nil, fmt.Errorf("recursive reference to query %s must not appear within a subquery", def.Name)
+			}
+			if sc.work == nil {
+				return nil, fmt.Errorf("recursive reference to query %s must not appear within its non-recursive term", def.Name)
+			}
+			sc.work.used = true
+			return &WorkTableScan{Alias: alias, work: sc.work}, nil
+		}
+		if sc.base != nil {
+			sc = sc.base
+		} else if sc.parent != nil {
+			sc, nested = sc.parent.scope, true
+		} else {
+			sc = nil
+		}
+	}
+	if def.planning {
+		return nil, fmt.Errorf("mutual recursion between WITH items is not implemented: %s", def.Name)
+	}
+	def.planning = true
+	defer func() { def.planning = false }()
+	node, err := p.parseCTE(def)
+	if err != nil {
+		return nil, err
+	}
+	return &DerivedTable{
+		PlanNode: PlanNode{
+			Alias: alias,
+			Child: node,
+		},
+		Names: def.Columns,
+	}, nil
+}
+
+// parseCTE plans the query of a CTE. A union whose right query refers to the
+// CTE runs as a recursive union.
+func (p *Planner) parseCTE(def *cteDef) (Node, error) {
+	s := def.scope.nest(def.env)
+	s.body = def
+	q := def.Query
+	if !def.recursive || q.SetOp == nil || q.SetOp.Op != parser.SetUnion || q.With != nil || len(q.OrderBy) > 0 || q.Limit != nil {
+		return p.parseSelectStatement(q, s)
+	}
+	left, err := p.parseSelectStatement(q.SetOp.Left, s)
+	if err != nil {
+		return nil, err
+	}
+	work := &workTable{anchor: left, names: def.Columns}
+	s = def.scope.nest(def.env)
+	s.body = def
+	s.work = work
+	right, err := p.parseSelectStatement(q.SetOp.Right, s)
+	if err != nil {
+		return nil, err
+	}
+	if work.used {
+		return &RecursiveUnion{Anchor: left, Recursive: right, All: q.SetOp.All, work: work}, nil
+	}
+	return &SetOp{Op: parser.SetUnion, All: q.SetOp.All, Left: left, Right: right}, nil
+}
+
+// RecursiveUnion Expression
+func (ru *RecursiveUnion) Iter() index.Iterator {
+	iter := &RecursiveUnionIter{node: ru}
+	if !ru.All {
+		iter.seen = make(map[string]bool)
+	}
+	return iter
+}
+func (ru *RecursiveUnion) Columns() []entity.Column {
+	return ru.cols
+}
+func (ru *RecursiveUnion) Prepare() error {
+	cols, err := setOpColumns(parser.SetUnion, ru.Anchor.Columns(), ru.Recursive.Columns())
+	if err != nil {
+		return err
+	}
+	ru.cols = cols
+	return nil
+}
+
+// WorkTableScan Expression
+func (ws *WorkTableScan) Iter() index.Iterator {
+	return &rowsIter{rows: ws.work.rows, position: -1}
+}
+func (ws *WorkTableScan) Columns() []entity.Column {
+	return qualifyColumns(ws.Alias, renameColumns(ws.work.anchor.Columns(), ws.work.names))
+}
+func (ws *WorkTableScan) Prepare() error {
+	return nil
+}
+
+func (iter *RecursiveUnionIter) Next() (entity.Row, error) {
+	for {
+		if iter.iter == nil {
+			if !iter.started {
+				iter.iter = iter.node.Anchor.Iter()
+				iter.started = true
+			} else {
+				if len(iter.next) == 0 {
+					return entity.Row{}, index.EndOfIterator
+				}
+				iter.node.work.rows = iter.next
+				iter.next = nil
+				iter.iter = iter.node.Recursive.Iter()
+			}
+		}
+		row, err := iter.iter.Next()
+		if err == index.EndOfIterator {
+			iter.iter = nil
+			continue
+		} else if err != nil {
+			return entity.Row{}, err
+		}
+		if iter.seen != nil {
+			key := hashKey(row.Values)
+			if iter.seen[key] {
+				continue
+			}
+			iter.seen[key] = true
+		}
+		iter.next = append(iter.next, row)
+		return row, nil
+	}
+}
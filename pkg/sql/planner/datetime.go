@@ -0,0 +1,329 @@
+package planner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+type (
+	// CastExpr converts a value to a type. Timestamps with and without time
+	// zone are converted on the clocks of the session.
+	CastExpr struct {
+		Expr Expression
+		To   types.T
+		loc  *time.Location
+	}
+
+	// DateTimeExpr applies an arithmetic operator to dates, times,
+	// timestamps and intervals, like a timestamp plus an interval.
+	DateTimeExpr struct {
+		Op  string
+		LHS Expression
+		RHS Expression
+		op  *dateTimeOp
+		// swap is set when the operands are passed to op the other way
+		// round, like for an interval plus a timestamp.
+		swap bool
+		loc  *time.Location
+	}
+
+	// dateTimeOp is an operator by the families of its operands. A
+	// FloatFamily operand stands for any number.
+	dateTimeOp struct {
+		op       string
+		lhs, rhs types.Family
+		res      types.T
+		eval     func(a, b entity.Value, loc *time.Location) (entity.Value, error)
+	}
+)
+
+// dateTimeOps are the operators of dates and times. Additions and
+// multiplications also take their operands the other way round.
+var dateTimeOps = []dateTimeOp{
+	{op: parser.OpAdd, lhs: types.DateFamily, rhs: types.IntFamily, res: types.Date, eval: addDays},
+	{op: parser.OpSub, lhs: types.DateFamily, rhs: types.IntFamily, res: types.Date, eval: subDays},
+	{op: parser.OpSub, lhs: types.DateFamily, rhs: types.DateFamily, res: types.Int, eval: subDates},
+	{op: parser.OpAdd, lhs: types.DateFamily, rhs: types.IntervalFamily, res: types.Timestamp, eval: addDateInterval},
+	{op: parser.OpSub, lhs: types.DateFamily, rhs: types.IntervalFamily, res: types.Timestamp, eval: minus(addDateInterval)},
+	{op: parser.OpAdd, lhs: types.DateFamily, rhs: types.TimeFamily, res: types.Timestamp, eval: addDateTime},
+	{op: parser.OpAdd, lhs: types.TimestampFamily, rhs: types.IntervalFamily, res: types.Timestamp, eval: addTimestampInterval},
+	{op: parser.OpSub, lhs: types.TimestampFamily, rhs: types.IntervalFamily, res: types.Timestamp, eval: minus(addTimestampInterval)},
+	{op: parser.OpSub, lhs: types.TimestampFamily, rhs: types.TimestampFamily, res: types.Interval, eval: subTimestamps},
+	{op: parser.OpAdd, lhs: types.TimestampTZFamily, rhs: types.IntervalFamily, res: types.TimestampTZ, eval: addTimestampTZInterval},
+	{op: parser.OpSub, lhs: types.TimestampTZFamily, rhs: types.IntervalFamily, res: types.TimestampTZ, eval: minus(addTimestampTZInterval)},
+	{op: parser.OpSub, lhs: types.TimestampTZFamily, rhs: types.TimestampTZFamily, res: types.Interval, eval: subTimestampTZs},
+	{op: parser.OpAdd, lhs: types.TimeFamily, rhs: types.IntervalFamily, res: types.Time, eval: addTimeInterval},
+	{op: parser.OpSub, lhs: types.TimeFamily, rhs: types.IntervalFamily, res: types.Time, eval: minus(addTimeInterval)},
+	{op: parser.OpSub, lhs: types.TimeFamily, rhs: types.TimeFamily, res: types.Interval, eval: subTimes},
+	{op: parser.OpAdd, lhs: types.IntervalFamily, rhs: types.IntervalFamily, res: types.Interval, eval: addIntervals},
+	{op: parser.OpSub, lhs: types.IntervalFamily, rhs: types.IntervalFamily, res: types.Interval, eval: minus(addIntervals)},
+	{op: parser.OpMul, lhs: types.IntervalFamily, rhs: types.FloatFamily, res: types.Interval, eval: mulInterval},
+	{op: parser.OpDiv, lhs: types.IntervalFamily, rhs: types.FloatFamily, res: types.Interval, eval: divInterval},
+}
+
+// temporalTypes are the types string constants are read as for the operands
+// of dateTimeOps.
+var temporalTypes = map[types.Family]types.T{
+	types.IntFamily:         types.Int,
+	types.FloatFamily:       types.Float,
+	types.DateFamily:        types.Date,
+	types.TimeFamily:        types.Time,
+	types.TimestampFamily:   types.Timestamp,
+	types.TimestampTZFamily: types.TimestampTZ,
+	types.IntervalFamily:    types.Interval,
+}
+
+// dateTimeRanks orders dates and timestamps from the narrowest type.
+var dateTimeRanks = map[types.Family]int{
+	types.DateFamily:        1,
+	types.TimestampFamily:   2,
+	types.TimestampTZFamily: 3,
+}
+
+// valueFuncs are the functions called without parentheses, like
+// CURRENT_DATE. Columns of the same name hide them.
+var valueFuncs = map[string]struct{}{
+	"current_date":      {},
+	"current_timestamp": {},
+	"localtimestamp":    {},
+	"localtime":         {},
+}
+
+// isTemporal reports whether the values of typ are dates, times or
+// intervals.
+func isTemporal(typ types.T) bool {
+	switch typ.Family {
+	case types.DateFamily, types.TimeFamily, types.TimestampFamily, types.TimestampTZFamily, types.IntervalFamily:
+		return true
+	}
+	return false
+}
+
+// dateTimeCommon returns the wider of a date or timestamp type and another.
+func dateTimeCommon(a, b types.T) (types.T, bool) {
+	ra, rb := dateTimeRanks[a.Family], dateTimeRanks[b.Family]
+	switch {
+	case ra == 0 || rb == 0:
+		return types.T{}, false
+	case ra < rb:
+		return b, true
+	}
+	return a, true
+}
+
+// promote converts a date or timestamp to typ, a wider date or timestamp
+// type, and a number to typ, a wider number type. Other expressions are
+// returned as they are.
+func (c *compiler) promote(expr Expression, typ types.T) Expression {
+	from := expr.Type()
+	if from.Family == typ.Family {
+		return expr
+	}
+	if (dateTimeRanks[from.Family] > 0 && dateTimeRanks[typ.Family] > 0) || (from.IsNumber() && typ.IsNumber()) {
+		return &CastExpr{Expr: expr, To: typ, loc: c.location()}
+	}
+	return expr
+}
+
+// compileCast converts an expression to a type. Values can be cast to the
+// types they can be stored in, and to text. Casts of constants are evaluated
+// right away.
+func (c *compiler) compileCast(e *parser.CastExpr) (Expression, error) {
+	expr, err := c.compile(e.Expr)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := types.Resolve(e.Type.Name, e.Type.Modifiers)
+	if err != nil {
+		return nil, err
+	}
+	if from := expr.Type(); !types.Assignable(from, typ) && typ.Family != types.TextFamily {
+		return nil, fmt.Errorf("cannot cast type %s to %s", from, typ)
+	}
+	cast := &CastExpr{Expr: expr, To: typ, loc: c.location()}
+	if _, ok := expr.(*ConstExpr); ok {
+		val, err := cast.Eval(entity.Row{})
+		if err != nil {
+			return nil, err
+		}
+		// a constant whose value has another type would lose the type
+		if val != nil && types.Of(val) == typ {
+			return &ConstExpr{Value: val}, nil
+		}
+	}
+	return cast, nil
+}
+
+func (e *CastExpr) Eval(row entity.Row) (entity.Value, error) {
+	val, err := e.Expr.Eval(row)
+	if err != nil || val == nil {
+		return nil, err
+	}
+	if _, ok := val.(string); !ok && e.To.Family == types.TextFamily {
+		val = e.Expr.Type().FormatIn(val, e.loc)
+	}
+	return e.To.CoerceIn(val, e.loc)
+}
+func (e *CastExpr) Type() types.T {
+	return e.To
+}
+func (e *CastExpr) String() string {
+	return fmt.Sprintf("CAST(%s AS %s)", e.Expr, e.To)
+}
+
+// newDateTimeExpr resolves an operator of dates and times by the types of its
+// operands. String constants are read as the type of the first operator
+// whose operand they can be. Dates and timestamps are converted to the
+// operands of wider types.
+func (c *compiler) newDateTimeExpr(op string, lhs, rhs Expression) (Expression, error) {
+	for _, swap := range []bool{false, true} {
+		if swap && op != parser.OpAdd && op != parser.OpMul {
+			break
+		}
+		a, b := lhs, rhs
+		if swap {
+			a, b = rhs, lhs
+		}
+		for i := range dateTimeOps {
+			o := &dateTimeOps[i]
+			if o.op != op {
+				continue
+			}
+			x, ok := c.dateTimeOperand(a, o.lhs)
+			if !ok {
+				continue
+			}
+			y, ok := c.dateTimeOperand(b, o.rhs)
+			if !ok {
+				continue
+			}
+			if swap {
+				x, y = y, x
+			}
+			return &DateTimeExpr{Op: op, LHS: x, RHS: y, op: o, swap: swap, loc: c.location()}, nil
+		}
+	}
+	return nil, fmt.Errorf("operator does not exist: %s %s %s", lhs.Type(), op, rhs.Type())
+}
+
+// dateTimeOperand converts an operand to the family of an operand of a
+// dateTimeOp, or reports false if it can't be.
+func (c *compiler) dateTimeOperand(expr Expression, family types.Family) (Expression, bool) {
+	typ := expr.Type()
+	switch {
+	case typ.Family == family || typ.Family == types.UnknownFamily:
+		return expr, true
+	case family == types.FloatFamily:
+		return expr, typ.IsNumber()
+	case dateTimeRanks[typ.Family] > 0 && dateTimeRanks[typ.Family] < dateTimeRanks[family]:
+		return c.promote(expr, temporalTypes[family]), true
+	}
+	if lit, ok := expr.(*ConstExpr); ok && typ.Family == types.TextFamily {
+		val, err := c.parseConst(lit.Value.(string), temporalTypes[family])
+		return val, err == nil
+	}
+	return nil, false
+}
+
+func (e *DateTimeExpr) Eval(row entity.Row) (entity.Value, error) {
+	lval, err := e.LHS.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	rval, err := e.RHS.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	if lval == nil || rval == nil {
+		return nil, nil
+	}
+	if e.swap {
+		lval, rval = rval, lval
+	}
+	return e.op.eval(lval, rval, e.loc)
+}
+func (e *DateTimeExpr) Type() types.T {
+	return e.op.res
+}
+func (e *DateTimeExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.LHS, e.Op, e.RHS)
+}
+
+// minus turns the addition of an interval into its subtraction.
+func minus(add func(a, b entity.Value, loc *time.Location) (entity.Value, error)) func(a, b entity.Value, loc *time.Location) (entity.Value, error) {
+	return func(a, b entity.Value, loc *time.Location) (entity.Value, error) {
+		return add(a, b.(types.IntervalValue).Neg(), loc)
+	}
+}
+
+func addDays(a, b entity.Value, _ *time.Location) (entity.Value, error) {
+	return a.(types.DateValue) + types.DateValue(b.(int)), nil
+}
+
+func subDays(a, b entity.Value, _ *time.Location) (entity.Value, error) {
+	return a.(types.DateValue) - types.DateValue(b.(int)), nil
+}
+
+func subDates(a, b entity.Value, _ *time.Location) (entity.Value, error) {
+	return int(a.(types.DateValue) - b.(types.DateValue)), nil
+}
+
+func addDateInterval(a, b entity.Value, _ *time.Location) (entity.Value, error) {
+	return a.(types.DateValue).Timestamp().AddInterval(b.(types.IntervalValue)), nil
+}
+
+func addDateTime(a, b entity.Value, _ *time.Location) (entity.Value, error) {
+	return a.(types.DateValue).Timestamp() + types.TimestampValue(b.(types.TimeValue)), nil
+}
+
+func addTimestampInterval(a, b entity.Value, _ *time.Location) (entity.Value, error) {
+	return a.(types.TimestampValue).AddInterval(b.(types.IntervalValue)), nil
+}
+
+func subTimestamps(a, b entity.Value, _ *time.Location) (entity.Value, error) {
+	return a.(types.TimestampValue).Sub(b.(types.TimestampValue)), nil
+}
+
+// addTimestampTZInterval adds the months and days of an interval on the
+// clocks of the session, so that a day later is the same time of day across
+// a change to daylight saving time.
+func addTimestampTZInterval(a, b entity.Value, loc *time.Location) (entity.Value, error) {
+	return a.(types.TimestampTZValue).AddInterval(b.(types.IntervalValue), loc), nil
+}
+
+func subTimestampTZs(a, b entity.Value, _ *time.Location) (entity.Value, error) {
+	return a.(types.TimestampTZValue).Sub(b.(types.TimestampTZValue)), nil
+}
+
+// addTimeInterval adds the time of an interval to a time of day, wrapping
+// around midnight. Its months and days are ignored.
+func addTimeInterval(a, b entity.Value, _ *time.Location) (entity.Value, error) {
+	day := int64(24 * time.Hour / time.Microsecond)
+	us := (int64(a.(types.TimeValue)) + b.(types.IntervalValue).Micros) % day
+	if us < 0 {
+		us += day
+	}
+	return types.TimeValue(us), nil
+}
+
+func subTimes(a, b entity.Value, _ *time.Location) (entity.Value, error) {
+	return types.IntervalValue{Micros: int64(a.(types.TimeValue) - b.(types.TimeValue))}, nil
+}
+
+func addIntervals(a, b entity.Value, _ *time.Location) (entity.Value, error) {
+	return a.(types.IntervalValue).Add(b.(types.IntervalValue)), nil
+}
+
+func mulInterval(a, b entity.Value, _ *time.Location) (entity.Value, error) {
+	f, _ := types.ToFloat(b)
+	return a.(types.IntervalValue).Mul(f)
+}
+
+func divInterval(a, b entity.Value, _ *time.Location) (entity.Value, error) {
+	f, _ := types.ToFloat(b)
+	return a.(types.IntervalValue).Div(f)
+}
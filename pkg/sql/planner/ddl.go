@@ -0,0 +1,167 @@
+package planner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/storage"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+// CreateTable adds a new table to the database catalog.
+type CreateTable struct {
+	Database    *storage.Database
+	TableName   string
+	IfNotExists bool
+	Defs        []*parser.ColumnDef
+	// TimeZone is the zone of the session constant defaults are checked in.
+	TimeZone *time.Location
+	cols     []entity.Column
+}
+
+func (ct *CreateTable) Prepare() error {
+	seen := make(map[string]bool)
+	cols := make([]entity.Column, len(ct.Defs))
+	for i, def := range ct.Defs {
+		if seen[def.Name] {
+			return fmt.Errorf("column %s specified more than once", def.Name)
+		}
+		seen[def.Name] = true
+		typ, err := types.Resolve(def.Type.Name, def.Type.Modifiers)
+		if err != nil {
+			return err
+		}
+		col := entity.Column{
+			Type: typ,
+			Name: def.Name,
+		}
+		null := false
+		for _, opt := range def.Options {
+			switch opt.Kind {
+			case parser.OptionNotNull, parser.OptionNull:
+				if (col.NotNull && opt.Kind == parser.OptionNull) || (null && opt.Kind == parser.OptionNotNull) {
+					return fmt.Errorf("conflicting NULL/NOT NULL declarations for column %s", def.Name)
+				}
+				col.NotNull = opt.Kind == parser.OptionNotNull
+				null = opt.Kind == parser.OptionNull
+			case parser.OptionDefault:
+				col.Default = opt.Value
+				expr, err := compileDefault(col, &compiler{loc: ct.TimeZone})
+				if err != nil {
+					return err
+				}
+				// constants are checked once and for all
+				if e, ok := expr.(*ConstExpr); ok {
+					if _, err := coerce(e.Value, col, ct.TimeZone); err != nil {
+						return err
+					}
+				}
+			default:
+				return fmt.Errorf("unsupported column option %s", opt)
+			}
+		}
+		cols[i] = col
+	}
+	ct.cols = cols
+	return nil
+}
+
+func (ct *CreateTable) Exec() (int, error) {
+	if _, err := ct.Database.CreateTable(ct.TableName, ct.cols, ct.IfNotExists); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+func (ct *CreateTable) Tag(n int) string {
+	return "CREATE TABLE"
+}
+
+// DropTable removes tables from the database catalog.
+type DropTable struct {
+	Database   *storage.Database
+	TableNames []string
+	IfExists   bool
+}
+
+func (dt *DropTable) Prepare() error {
+	return nil
+}
+
+func (dt *DropTable) Exec() (int, error) {
+	return dt.Database.DropTables(dt.TableNames, dt.IfExists)
+}
+
+func (dt *DropTable) Tag(n int) string {
+	return "DROP TABLE"
+}
+
+// CreateIndex adds a GIN index on a jsonb column of a table. The index finds
+// the documents by the keys and values they hold, so that queries testing
+// containment with @> only read the rows that can match.
+type CreateIndex struct {
+	Database    *storage.Database
+	Name        string
+	TableName   string
+	Method      string
+	Column      string
+	IfNotExists bool
+	col         int
+}
+
+func (ci *CreateIndex) Prepare() error {
+	if ci.Method != "gin" {
+		method := ci.Method
+		if method == "" {
+			method = "btree"
+		}
+		return fmt.Errorf("access method %s is not supported", method)
+	}
+	table, err := ci.Database.GetTable(ci.TableName)
+	if err != nil {
+		return err
+	}
+	col, err := lookupColumn(&parser.ColumnRef{Name: ci.Column}, table.Columns())
+	if err != nil {
+		return err
+	}
+	if col == nil {
+		return fmt.Errorf("invalid column %s", ci.Column)
+	}
+	if col.Column.Type.Oid != types.OidJSONB {
+		return fmt.Errorf("data type %s has no default operator class for access method gin", col.Column.Type)
+	}
+	if ci.Name == "" {
+		ci.Name = fmt.Sprintf("%s_%s_idx", ci.TableName, ci.Column)
+	}
+	ci.col = col.Index
+	return nil
+}
+
+func (ci *CreateIndex) Exec() (int, error) {
+	idx := index.NewInvertedIndex(ci.Name, ci.col, jsonTerms)
+	if _, err := ci.Database.CreateIndex(ci.TableName, ci.Name, idx, ci.IfNotExists); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+func (ci *CreateIndex) Tag(n int) string {
+	return "CREATE INDEX"
+}
+
+// jsonTerms returns the terms a GIN index finds a jsonb value by.
+func jsonTerms(val entity.Value) []string {
+	v, ok := val.(types.JSONValue)
+	if !ok {
+		return nil
+	}
+	doc, err := v.Doc()
+	if err != nil {
+		return nil
+	}
+	return types.JSONTerms(doc)
+}
@@ -0,0 +1,166 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+)
+
+type (
+	// Distinct removes duplicate rows. Without On the rows are hashed to
+	// find the duplicates. With On the child sorts the rows so that those
+	// equal on the DISTINCT ON expressions are adjacent, and the first of
+	// each run is kept.
+	Distinct struct {
+		On []parser.Expr
+		// sort orders the rows of DISTINCT ON. onKeys are the positions of
+		// its keys that hold the DISTINCT ON expressions.
+		sort   *Sort
+		onKeys []int
+		keys   []int
+		cols   []entity.Column
+		PlanNode
+	}
+
+	DistinctIter struct {
+		node *Distinct
+		seen map[string]bool
+		prev string
+		// started is set once a row is returned, as prev is only valid
+		// from then on
+		started bool
+		PlanIter
+	}
+)
+
+// parseDistinctOn plans a DISTINCT ON query. Its rows are sorted by the ORDER
+// BY clause, which must start with the DISTINCT ON expressions in any order,
+// and then by the DISTINCT ON expressions it leaves out.
+func (p *Planner) parseDistinctOn(sel *parser.Select, proj Node) (Node, error) {
+	on := sel.Distinct.On
+	pos := make(map[string]int, len(on))
+	for _, expr := range on {
+		pos[expr.String()] = -1
+	}
+	items := make([]*parser.OrderItem, 0, len(sel.OrderBy)+len(on))
+	covered := 0
+	for _, item := range sel.OrderBy {
+		if covered < len(pos) {
+			i, ok := pos[item.Expr.String()]
+			if !ok {
+				return nil, errors.New("SELECT DISTINCT ON expressions must match initial ORDER BY expressions")
+			}
+			if i < 0 {
+				pos[item.Expr.String()] = len(items)
+				covered++
+			}
+		}
+		items = append(items, item)
+	}
+	for _, expr := range on {
+		if pos[expr.String()] < 0 {
+			pos[expr.String()] = len(items)
+			items = append(items, &parser.OrderItem{Expr: expr})
+		}
+	}
+	onKeys := make([]int, len(on))
+	for i, expr := range on {
+		onKeys[i] = pos[expr.String()]
+	}
+	sort := &Sort{
+		Items:   items,
+		WorkMem: p.WorkMem,
+		junk:    true,
+		PlanNode: PlanNode{
+			Child: proj,
+		},
+	}
+	var root Node = &Distinct{
+		On:     on,
+		sort:   sort,
+		onKeys: onKeys,
+		PlanNode: PlanNode{
+			Child: sort,
+		},
+	}
+	if sel.Limit != nil {
+		root = &Limit{
+			Count:  sel.Limit.Count,
+			Offset: sel.Limit.Offset,
+			PlanNode: PlanNode{
+				Child: root,
+			},
+		}
+	}
+	return root, nil
+}
+
+// Distinct Expression
+func (d *Distinct) Iter() index.Iterator {
+	iter := &DistinctIter{
+		node: d,
+		PlanIter: PlanIter{
+			ChildIter: d.Child.Iter(),
+		},
+	}
+	if d.sort == nil {
+		iter.seen = make(map[string]bool)
+	}
+	return iter
+}
+func (d *Distinct) Columns() []entity.Column {
+	return d.cols
+}
+func (d *Distinct) Prepare() error {
+	if d.Child == nil {
+		return errors.New("no child node")
+	}
+	d.cols = d.Child.Columns()
+	if d.sort == nil {
+		d.keys = make([]int, len(d.cols))
+		for i := range d.keys {
+			d.keys[i] = i
+		}
+		return nil
+	}
+	d.keys = make([]int, len(d.onKeys))
+	for i, pos := range d.onKeys {
+		if pos >= len(d.sort.keys) {
+			return fmt.Errorf("invalid DISTINCT ON expression %s", d.On[i])
+		}
+		d.keys[i] = d.sort.keys[pos].index
+	}
+	return nil
+}
+
+func (iter *DistinctIter) Next() (entity.Row, error) {
+	width := len(iter.node.cols)
+	vals := make([]entity.Value, len(iter.node.keys))
+	for {
+		row, err := iter.ChildIter.Next()
+		if err != nil {
+			return entity.Row{}, err
+		}
+		for i, k := range iter.node.keys {
+			vals[i] = row.Values[k]
+		}
+		key := hashKey(vals)
+		if iter.seen != nil {
+			if iter.seen[key] {
+				continue
+			}
+			iter.seen[key] = true
+		} else {
+			if iter.started && key == iter.prev {
+				continue
+			}
+			iter.prev = key
+			iter.started = true
+		}
+		row.Values = row.Values[:width]
+		return row, nil
+	}
+}
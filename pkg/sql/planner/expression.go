@@ -0,0 +1,712 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+// Expression is a scalar expression compiled against the columns of the rows
+// it is evaluated on.
+type Expression interface {
+	Eval(row entity.Row) (entity.Value, error)
+	Type() types.T
+	String() string
+}
+
+type (
+	ColumnExpr struct {
+		Index  int
+		Column entity.Column
+	}
+
+	ConstExpr struct {
+		Value entity.Value
+	}
+
+	// ArithExpr applies an arithmetic operator to two numbers, converted to
+	// the type of the result first. Integer results must fit the wider of
+	// their types.
+	ArithExpr struct {
+		Op  string
+		LHS Expression
+		RHS Expression
+	}
+
+	NegExpr struct {
+		Expr Expression
+	}
+)
+
+func (e *ColumnExpr) Eval(row entity.Row) (entity.Value, error) {
+	return row.Values[e.Index], nil
+}
+func (e *ColumnExpr) Type() types.T {
+	return e.Column.Type
+}
+func (e *ColumnExpr) String() string {
+	return e.Column.Name
+}
+
+func (e *ConstExpr) Eval(row entity.Row) (entity.Value, error) {
+	return e.Value, nil
+}
+func (e *ConstExpr) Type() types.T {
+	return types.Of(e.Value)
+}
+func (e *ConstExpr) String() string {
+	return fmt.Sprintf("%v", e.Value)
+}
+
+func (e *ArithExpr) Eval(row entity.Row) (entity.Value, error) {
+	lval, err := e.LHS.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	rval, err := e.RHS.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	if lval == nil || rval == nil {
+		return nil, nil
+	}
+	switch typ := e.Type(); typ.Family {
+	case types.FloatFamily:
+		a, aok := types.ToFloat(lval)
+		b, bok := types.ToFloat(rval)
+		if !aok || !bok {
+			break
+		}
+		res, err := e.evalFloat(a, b)
+		if err != nil {
+			return nil, err
+		}
+		return typ.Coerce(res)
+	case types.NumericFamily:
+		a, aok := types.ToDecimal(lval)
+		b, bok := types.ToDecimal(rval)
+		if !aok || !bok {
+			break
+		}
+		return e.evalNumeric(a, b)
+	default:
+		a, aok := lval.(int)
+		b, bok := rval.(int)
+		if !aok || !bok {
+			break
+		}
+		return e.evalInt(typ, a, b)
+	}
+	return nil, fmt.Errorf("cannot apply %s to %v of type %T and %v of type %T", e.Op, lval, lval, rval, rval)
+}
+
+func (e *ArithExpr) evalInt(typ types.T, a, b int) (entity.Value, error) {
+	var res int
+	var ok bool
+	switch e.Op {
+	case parser.OpAdd:
+		res, ok = addInt(a, b)
+	case parser.OpSub:
+		res, ok = subInt(a, b)
+	case parser.OpMul:
+		res, ok = mulInt(a, b)
+	case parser.OpDiv:
+		if b == 0 {
+			return nil, errors.New("division by zero")
+		}
+		if b == -1 {
+			res, ok = negInt(a)
+		} else {
+			res, ok = a/b, true
+		}
+	case parser.OpMod:
+		if b == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return a % b, nil
+	default:
+		return nil, fmt.Errorf("invalid operator %s", e.Op)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s out of range", typ)
+	}
+	return typ.Coerce(res)
+}
+
+// addInt, subInt, mulInt and negInt return the result of an operation on
+// integers and whether it didn't overflow.
+func addInt(a, b int) (int, bool) {
+	c := a + b
+	return c, (c > a) == (b > 0)
+}
+func subInt(a, b int) (int, bool) {
+	c := a - b
+	return c, (c < a) == (b > 0)
+}
+func mulInt(a, b int) (int, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	// the smallest integer is the only one whose negation overflows
+	return c, c/b == a && !(b == -1 && c == a)
+}
+func negInt(a int) (int, bool) {
+	return -a, a == 0 || -a != a
+}
+
+func (e *ArithExpr) evalFloat(a, b float64) (float64, error) {
+	switch e.Op {
+	case parser.OpAdd:
+		return a + b, nil
+	case parser.OpSub:
+		return a - b, nil
+	case parser.OpMul:
+		return a * b, nil
+	case parser.OpDiv:
+		if b == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return a / b, nil
+	}
+	return 0, fmt.Errorf("invalid operator %s", e.Op)
+}
+
+func (e *ArithExpr) evalNumeric(a, b types.Decimal) (entity.Value, error) {
+	switch e.Op {
+	case parser.OpAdd:
+		return a.Add(b), nil
+	case parser.OpSub:
+		return a.Sub(b), nil
+	case parser.OpMul:
+		return a.Mul(b), nil
+	case parser.OpDiv:
+		return a.Quo(b)
+	case parser.OpMod:
+		return a.Rem(b)
+	}
+	return nil, fmt.Errorf("invalid operator %s", e.Op)
+}
+func (e *ArithExpr) Type() types.T {
+	return numberType(e.LHS, e.RHS)
+}
+func (e *ArithExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.LHS, e.Op, e.RHS)
+}
+
+func (e *NegExpr) Eval(row entity.Row) (entity.Value, error) {
+	val, err := e.Expr.Eval(row)
+	if err != nil || val == nil {
+		return nil, err
+	}
+	switch v := val.(type) {
+	case int:
+		n, ok := negInt(v)
+		if !ok {
+			return nil, fmt.Errorf("%s out of range", e.Type())
+		}
+		return e.Type().Coerce(n)
+	case float64:
+		return -v, nil
+	case types.Decimal:
+		return v.Neg(), nil
+	case types.IntervalValue:
+		return v.Neg(), nil
+	}
+	return nil, fmt.Errorf("cannot negate %v of type %T", val, val)
+}
+func (e *NegExpr) Type() types.T {
+	if typ := e.Expr.Type(); typ.Family == types.IntervalFamily {
+		return typ
+	}
+	return numberType(e.Expr)
+}
+func (e *NegExpr) String() string {
+	return fmt.Sprintf("(-%s)", e.Expr)
+}
+
+// compiler compiles parsed expressions against the columns of the rows they
+// are evaluated on.
+type compiler struct {
+	cols []entity.Column
+	// outer is the number of leading columns of cols that belong to an
+	// enclosing query. References only resolve to them when no other
+	// column matches.
+	outer int
+	// agg is set for expressions evaluated on the output of an aggregation.
+	// Those can only refer to its grouped expressions and aggregate calls.
+	agg *Aggregate
+	// windows are set for expressions evaluated on the output of Window
+	// nodes, whose window function calls they can refer to.
+	windows []*Window
+	// scope plans subqueries and resolves references to the columns of
+	// enclosing queries. Expressions compiled without one can't have
+	// subqueries.
+	scope *scope
+	// loc and now are the time zone of the session and the time the
+	// statement started when there is no scope to tell them.
+	loc *time.Location
+	now time.Time
+}
+
+// compileExpr resolves the column references of a parsed expression against
+// cols and checks the types of its operands.
+func compileExpr(expr parser.Expr, cols []entity.Column) (Expression, error) {
+	return (&compiler{cols: cols}).compile(expr)
+}
+
+// location returns the time zone of the session expressions are compiled
+// for.
+func (c *compiler) location() *time.Location {
+	switch {
+	case c.loc != nil:
+		return c.loc
+	case c.scope != nil:
+		return c.scope.planner.Session.TimeZone
+	}
+	return time.UTC
+}
+
+// statementTime returns the time the statement expressions are compiled for
+// started.
+func (c *compiler) statementTime() time.Time {
+	switch {
+	case !c.now.IsZero():
+		return c.now
+	case c.scope != nil:
+		return c.scope.planner.now
+	}
+	return time.Now()
+}
+
+// compilerFor returns the compiler for expressions of a query planned in s
+// and evaluated on the rows produced by node.
+func compilerFor(node Node, s *scope) *compiler {
+	c := &compiler{cols: node.Columns(), scope: s}
+	for {
+		switch n := node.(type) {
+		case *Aggregate:
+			c.agg = n
+		case *Window:
+			c.windows = append(c.windows, n)
+			node = n.Child
+			continue
+		case *Select:
+			// a HAVING clause filters the output of the aggregation
+			node = n.Child
+			continue
+		}
+		return c
+	}
+}
+
+func (c *compiler) compile(expr parser.Expr) (Expression, error) {
+	if c.agg != nil {
+		res, err := c.agg.lookup(expr)
+		if res != nil || err != nil {
+			return res, err
+		}
+	}
+	switch e := expr.(type) {
+	case *parser.ColumnRef:
+		if _, ok := valueFuncs[e.String()]; ok && !c.visible(e) {
+			return c.compileFunction(&parser.FuncCall{Name: e.Name})
+		}
+		return c.compileColumnRef(e)
+	case *parser.Star:
+		return nil, fmt.Errorf("%s is not allowed here", e)
+	case *parser.Literal:
+		return &ConstExpr{Value: e.Value}, nil
+	case *parser.UnaryExpr:
+		return c.compileUnaryExpr(e)
+	case *parser.BinaryExpr:
+		return c.compileBinaryExpr(e)
+	case *parser.FuncCall:
+		if e.Over != nil {
+			return c.lookupWindow(e)
+		}
+		if _, ok := windowFuncs[e.Name]; ok {
+			return nil, fmt.Errorf("window function %s requires an OVER clause", e.Name)
+		}
+		if res, err := c.compileConditional(e); res != nil || err != nil {
+			return res, err
+		}
+		if _, ok := aggregates[e.Name]; ok {
+			return nil, fmt.Errorf("aggregate function %s is not allowed here", e.Name)
+		}
+		return c.compileFunction(e)
+	case *parser.Subquery:
+		return c.compileSubquery(e)
+	case *parser.ExistsExpr:
+		return c.compileExists(e)
+	case *parser.InExpr:
+		if e.Subquery == nil {
+			return c.compileInList(e)
+		}
+		return c.compileIn(e)
+	case *parser.BetweenExpr:
+		return c.compileBetween(e)
+	case *parser.LikeExpr:
+		return c.compileLike(e)
+	case *parser.IsNullExpr:
+		return c.compileIsNull(e)
+	case *parser.IsDistinctExpr:
+		return c.compileIsDistinct(e)
+	case *parser.CaseExpr:
+		return c.compileCase(e)
+	case *parser.CastExpr:
+		return c.compileCast(e)
+	default:
+		return nil, fmt.Errorf("unsupported expression %s", expr)
+	}
+}
+
+// expandStar lists the columns a * or table.* stands for. Over the output of
+// an aggregation these are the grouped columns of its input.
+func (c *compiler) expandStar(star *parser.Star) ([]Expression, error) {
+	if c.agg == nil {
+		return expandStar(star, c.cols)
+	}
+	cols, err := expandStar(star, c.agg.Child.Columns())
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Expression, len(cols))
+	for i, col := range cols {
+		ref := col.(*ColumnExpr).Column
+		expr, err := c.compile(&parser.ColumnRef{Table: ref.Table, Name: ref.Name})
+		if err != nil {
+			return nil, err
+		}
+		res[i] = expr
+	}
+	return res, nil
+}
+
+// compileColumnRef resolves a column reference against the input, then
+// against the rows of enclosing queries.
+func (c *compiler) compileColumnRef(ref *parser.ColumnRef) (Expression, error) {
+	cols := c.cols
+	if c.agg != nil {
+		cols = c.agg.Child.Columns()
+	}
+	col, err := lookupColumn(ref, cols[c.outer:])
+	if err != nil {
+		return nil, err
+	}
+	if col != nil {
+		if c.agg != nil {
+			return nil, fmt.Errorf("column %s must appear in the GROUP BY clause or be used in an aggregate function", ref)
+		}
+		col.Index += c.outer
+		return col, nil
+	}
+	if col, err := lookupColumn(ref, cols[:c.outer]); err != nil {
+		return nil, err
+	} else if col != nil {
+		return col, nil
+	}
+	if c.scope != nil && c.scope.parent != nil && c.scope.parent.visible(ref) {
+		expr, err := c.scope.parent.compile(ref)
+		if err != nil {
+			return nil, err
+		}
+		q := c.scope.query()
+		q.correlated = true
+		return &OuterExpr{scope: q, Expr: expr}, nil
+	}
+	return resolveColumn(ref, cols)
+}
+
+// visible reports whether a column reference resolves against the input or
+// the rows of enclosing queries.
+func (c *compiler) visible(ref *parser.ColumnRef) bool {
+	cols := c.cols
+	if c.agg != nil {
+		cols = c.agg.Child.Columns()
+	}
+	if col, err := lookupColumn(ref, cols); col != nil || err != nil {
+		return true
+	}
+	return c.scope != nil && c.scope.parent != nil && c.scope.parent.visible(ref)
+}
+
+// resolveColumn finds the column a possibly qualified reference points to.
+func resolveColumn(ref *parser.ColumnRef, cols []entity.Column) (Expression, error) {
+	col, err := lookupColumn(ref, cols)
+	if err != nil {
+		return nil, err
+	}
+	if col != nil {
+		return col, nil
+	}
+	if ref.Table != "" {
+		found := false
+		for _, col := range cols {
+			found = found || col.Table == ref.Table
+		}
+		if !found {
+			return nil, fmt.Errorf("missing FROM-clause entry for table %s", ref.Table)
+		}
+	}
+	return nil, fmt.Errorf("invalid column %s", ref)
+}
+
+// lookupColumn finds the column a possibly qualified reference points to. It
+// returns nil when no column matches.
+func lookupColumn(ref *parser.ColumnRef, cols []entity.Column) (*ColumnExpr, error) {
+	var res *ColumnExpr
+	for i, col := range cols {
+		if ref.Table != "" {
+			if col.Table != ref.Table {
+				continue
+			}
+		} else if col.Merged {
+			continue
+		}
+		if col.Name != ref.Name {
+			continue
+		}
+		if res != nil {
+			return nil, fmt.Errorf("column reference %s is ambiguous", ref)
+		}
+		res = &ColumnExpr{Index: i, Column: col}
+	}
+	return res, nil
+}
+
+// expandStar lists the columns a * or table.* stands for.
+func expandStar(star *parser.Star, cols []entity.Column) ([]Expression, error) {
+	res := make([]Expression, 0, len(cols))
+	for i, col := range cols {
+		if (star.Table == "" && !col.Merged) || (star.Table != "" && col.Table == star.Table) {
+			res = append(res, &ColumnExpr{Index: i, Column: col})
+		}
+	}
+	if star.Table != "" && len(res) == 0 {
+		return nil, fmt.Errorf("missing FROM-clause entry for table %s", star.Table)
+	}
+	return res, nil
+}
+
+// walkExpr calls fn for expr and its subexpressions. The subexpressions of an
+// expression are skipped when fn returns false for it.
+func walkExpr(expr parser.Expr, fn func(parser.Expr) bool) {
+	if expr == nil || !fn(expr) {
+		return
+	}
+	switch e := expr.(type) {
+	case *parser.UnaryExpr:
+		walkExpr(e.Expr, fn)
+	case *parser.BinaryExpr:
+		walkExpr(e.LHS, fn)
+		walkExpr(e.RHS, fn)
+	case *parser.FuncCall:
+		for _, arg := range e.Args {
+			walkExpr(arg, fn)
+		}
+		if e.Over != nil {
+			for _, expr := range e.Over.PartitionBy {
+				walkExpr(expr, fn)
+			}
+			for _, item := range e.Over.OrderBy {
+				walkExpr(item.Expr, fn)
+			}
+		}
+	case *parser.InExpr:
+		// subqueries are walked on their own
+		walkExpr(e.Expr, fn)
+		for _, item := range e.List {
+			walkExpr(item, fn)
+		}
+	case *parser.BetweenExpr:
+		walkExpr(e.Expr, fn)
+		walkExpr(e.Low, fn)
+		walkExpr(e.High, fn)
+	case *parser.LikeExpr:
+		walkExpr(e.Expr, fn)
+		walkExpr(e.Pattern, fn)
+		walkExpr(e.Escape, fn)
+	case *parser.IsNullExpr:
+		walkExpr(e.Expr, fn)
+	case *parser.IsDistinctExpr:
+		walkExpr(e.LHS, fn)
+		walkExpr(e.RHS, fn)
+	case *parser.CastExpr:
+		walkExpr(e.Expr, fn)
+	case *parser.CaseExpr:
+		walkExpr(e.Operand, fn)
+		for _, when := range e.Whens {
+			walkExpr(when.Cond, fn)
+			walkExpr(when.Result, fn)
+		}
+		walkExpr(e.Else, fn)
+	}
+}
+
+// qualifyColumns returns the columns of a table as they are referred to by a
+// query, under the given table name.
+func qualifyColumns(table string, cols []entity.Column) []entity.Column {
+	res := make([]entity.Column, len(cols))
+	for i, col := range cols {
+		col.Table = table
+		col.Merged = false
+		res[i] = col
+	}
+	return res
+}
+
+func (c *compiler) compileUnaryExpr(e *parser.UnaryExpr) (Expression, error) {
+	operand, err := c.compile(e.Expr)
+	if err != nil {
+		return nil, err
+	}
+	switch e.Op {
+	case parser.OpNot:
+		if err := expectBool(e.Op, operand); err != nil {
+			return nil, err
+		}
+		return &NotExpr{Expr: operand}, nil
+	case parser.OpAdd, parser.OpSub:
+		if operand.Type().Family == types.IntervalFamily {
+			if e.Op == parser.OpAdd {
+				return operand, nil
+			}
+			return &NegExpr{Expr: operand}, nil
+		}
+		if err := expectNumber(e.Op, operand); err != nil {
+			return nil, err
+		}
+		if e.Op == parser.OpAdd {
+			return operand, nil
+		}
+		return &NegExpr{Expr: operand}, nil
+	default:
+		return nil, fmt.Errorf("invalid operator %s", e.Op)
+	}
+}
+
+func (c *compiler) compileBinaryExpr(e *parser.BinaryExpr) (Expression, error) {
+	lhs, err := c.compile(e.LHS)
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := c.compile(e.RHS)
+	if err != nil {
+		return nil, err
+	}
+	switch e.Op {
+	case parser.OpAnd, parser.OpOr:
+		if err := expectBool(e.Op, lhs); err != nil {
+			return nil, err
+		}
+		if err := expectBool(e.Op, rhs); err != nil {
+			return nil, err
+		}
+		return &LogicExpr{Op: e.Op, LHS: lhs, RHS: rhs}, nil
+	case parser.OpAdd, parser.OpSub, parser.OpMul, parser.OpDiv, parser.OpMod:
+		return c.newArithExpr(e.Op, lhs, rhs)
+	}
+	if rel, ok := relMap[e.Op]; ok {
+		return c.newCompareExpr(rel, lhs, rhs)
+	}
+	if match, ok := matchOps[e.Op]; ok {
+		return newPatternExpr(match.op, lhs, rhs, nil, match.not)
+	}
+	if _, ok := jsonOps[e.Op]; ok {
+		return c.newJSONExpr(e.Op, lhs, rhs)
+	}
+	return nil, fmt.Errorf("invalid operator %s", e.Op)
+}
+
+func (c *compiler) newArithExpr(op string, lhs, rhs Expression) (Expression, error) {
+	if isTemporal(lhs.Type()) || isTemporal(rhs.Type()) {
+		return c.newDateTimeExpr(op, lhs, rhs)
+	}
+	lhs, rhs, err := c.unifyTypes(lhs, rhs)
+	if err != nil {
+		return nil, err
+	}
+	ltyp, rtyp := lhs.Type(), rhs.Type()
+	// there is no modulo of floating point numbers
+	if !isNumber(ltyp) || !isNumber(rtyp) || (op == parser.OpMod && numberType(lhs, rhs).Family == types.FloatFamily) {
+		return nil, fmt.Errorf("operator does not exist: %s %s %s", ltyp, op, rtyp)
+	}
+	return &ArithExpr{Op: op, LHS: lhs, RHS: rhs}, nil
+}
+
+// numberType returns the type of the result of arithmetic on exprs. It is
+// double precision when one of them is double precision, real when one of
+// them is real, numeric when one of them is a numeric, and otherwise the widest of their
+// integer types. NULL operands count as integers.
+func numberType(exprs ...Expression) types.T {
+	var res types.T
+	for i, expr := range exprs {
+		typ := expr.Type()
+		switch {
+		case typ.Family == types.UnknownFamily:
+			typ = types.Int
+		case typ.Family == types.NumericFamily:
+			typ = types.Numeric
+		}
+		switch {
+		case i == 0:
+			res = typ
+		case res == types.Float || typ == types.Float:
+			res = types.Float
+		case res.Family == types.FloatFamily || typ.Family == types.FloatFamily:
+			res = types.Real
+		case res.Family == types.NumericFamily || typ.Family == types.NumericFamily:
+			res = types.Numeric
+		default:
+			res, _ = types.Common(res, typ)
+		}
+	}
+	return res
+}
+
+// isNumber reports whether the values of typ are numbers, or NULL.
+func isNumber(typ types.T) bool {
+	return typ.IsNumber() || typ.Family == types.UnknownFamily
+}
+
+// isInt reports whether the values of typ are integers, or NULL.
+func isInt(typ types.T) bool {
+	return typ.Family == types.IntFamily || typ.Family == types.UnknownFamily
+}
+
+func expectInt(op string, expr Expression) error {
+	if typ := expr.Type(); !isInt(typ) {
+		return fmt.Errorf("operator does not exist: %s %s", op, typ)
+	}
+	return nil
+}
+
+func expectNumber(op string, expr Expression) error {
+	if typ := expr.Type(); !isNumber(typ) {
+		return fmt.Errorf("operator does not exist: %s %s", op, typ)
+	}
+	return nil
+}
+
+func expectBool(op string, expr Expression) error {
+	if typ := expr.Type(); typ.Family != types.BoolFamily && typ.Family != types.UnknownFamily {
+		return fmt.Errorf("argument of %s must be type boolean, not %s", op, typ)
+	}
+	return nil
+}
+
+// evalBool evaluates a predicate. Anything but true, including NULL, rejects
+// the row.
+func evalBool(expr Expression, row entity.Row) (bool, error) {
+	val, err := expr.Eval(row)
+	if err != nil {
+		return false, err
+	}
+	b, ok := val.(bool)
+	return ok && b, nil
+}
@@ -0,0 +1,98 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
+	"github.com/hiepd/galedb/pkg/sql"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+)
+
+type (
+	// FunctionScan returns the rows of a set-returning function called in a
+	// FROM clause. The function is called for every row of its child, which
+	// its arguments can refer to, and each of its rows is returned after
+	// the columns of that row. Its columns are qualified by Alias, which
+	// also names the column of a function returning a single one when the
+	// query gives it.
+	FunctionScan struct {
+		Call    *parser.FuncCall
+		aliased bool
+		fn      *sql.Function
+		args    []Expression
+		cols    []entity.Column
+		loc     *time.Location
+		PlanNode
+	}
+
+	FunctionScanIter struct {
+		scan *FunctionScan
+		row  entity.Row
+		rows [][]entity.Value
+		PlanIter
+	}
+)
+
+// FunctionScan Expression
+func (fs *FunctionScan) Iter() index.Iterator {
+	return &FunctionScanIter{
+		scan: fs,
+		PlanIter: PlanIter{
+			ChildIter: fs.Child.Iter(),
+		},
+	}
+}
+func (fs *FunctionScan) Columns() []entity.Column {
+	return fs.cols
+}
+func (fs *FunctionScan) Prepare() error {
+	if fs.Child == nil {
+		return errors.New("no child node")
+	}
+	if fs.Call.Over != nil {
+		return fmt.Errorf("window function %s is not allowed in FROM", fs.Call.Name)
+	}
+	c := compilerFor(fs.Child, fs.scope)
+	fn, args, err := c.resolveFunction(sql.TableFunctions, fs.Call)
+	if err != nil {
+		return err
+	}
+	fs.fn = fn
+	fs.args = args
+	cols := fn.Columns
+	if fs.aliased && len(cols) == 1 {
+		cols = []entity.Column{{Name: fs.Alias, Type: cols[0].Type}}
+	}
+	fs.cols = append(append([]entity.Column(nil), fs.Child.Columns()...), qualifyColumns(fs.Alias, cols)...)
+	fs.loc = c.location()
+	return nil
+}
+
+func (iter *FunctionScanIter) Next() (entity.Row, error) {
+	for len(iter.rows) == 0 {
+		row, err := iter.ChildIter.Next()
+		if err != nil {
+			return entity.Row{}, err
+		}
+		args := make([]entity.Value, len(iter.scan.args))
+		for i, arg := range iter.scan.args {
+			if args[i], err = arg.Eval(row); err != nil {
+				return entity.Row{}, err
+			}
+		}
+		if iter.rows, err = iter.scan.fn.CallSet(args, iter.scan.loc); err != nil {
+			return entity.Row{}, err
+		}
+		iter.row = row
+	}
+	vals := make([]entity.Value, 0, len(iter.row.Values)+len(iter.rows[0]))
+	vals = append(vals, iter.row.Values...)
+	vals = append(vals, iter.rows[0]...)
+	iter.rows = iter.rows[1:]
+	return entity.Row{
+		Values: vals,
+	}, nil
+}
@@ -0,0 +1,159 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/sql"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+// FuncExpr calls a function of the registry. A stable function whose
+// arguments are constants is called once and its result reused.
+type FuncExpr struct {
+	Func *sql.Function
+	Args []Expression
+	// cached is set once val holds the result of a stable call
+	cached bool
+	val    entity.Value
+	loc    *time.Location
+	now    time.Time
+}
+
+// compileFunction resolves a function call against the registry by the types
+// of its arguments. Calls of immutable functions with constant arguments are
+// evaluated right away.
+func (c *compiler) compileFunction(e *parser.FuncCall) (Expression, error) {
+	fn, args, err := c.resolveFunction(sql.Functions, e)
+	if err != nil {
+		return nil, err
+	}
+	consts := true
+	for _, arg := range args {
+		if _, ok := arg.(*ConstExpr); !ok {
+			consts = false
+		}
+	}
+	expr := &FuncExpr{Func: fn, Args: args, loc: c.location(), now: c.statementTime()}
+	if consts && fn.Volatility == sql.Immutable {
+		val, err := expr.Eval(entity.Row{})
+		if err != nil {
+			return nil, err
+		}
+		// a NULL constant would lose the type of the result
+		if val != nil {
+			return &ConstExpr{Value: val}, nil
+		}
+	}
+	return expr, nil
+}
+
+// resolveFunction compiles the arguments of a function call and looks the
+// function up in r by their types.
+func (c *compiler) resolveFunction(r *sql.Registry, e *parser.FuncCall) (*sql.Function, []Expression, error) {
+	if e.Star {
+		return nil, nil, fmt.Errorf("function %s(*) does not exist", e.Name)
+	}
+	if e.Distinct {
+		return nil, nil, fmt.Errorf("DISTINCT specified, but %s is not an aggregate function", e.Name)
+	}
+	args := make([]Expression, len(e.Args))
+	typs := make([]types.T, len(e.Args))
+	for i, arg := range e.Args {
+		var err error
+		if args[i], err = c.compile(arg); err != nil {
+			return nil, nil, err
+		}
+		typs[i] = args[i].Type()
+	}
+	fn := r.Lookup(e.Name, typs)
+	if fn == nil {
+		var err error
+		if fn, err = c.lookupUntyped(r, e.Name, args, typs); err != nil {
+			return nil, nil, err
+		}
+	}
+	if fn == nil {
+		names := make([]string, len(typs))
+		for i, typ := range typs {
+			names[i] = typ.String()
+		}
+		return nil, nil, fmt.Errorf("function %s(%s) does not exist", e.Name, strings.Join(names, ", "))
+	}
+	return fn, args, nil
+}
+
+// lookupUntyped looks a function up again with its string constant arguments
+// taken as untyped, like the literals of Postgres, and reads them as values
+// of the types of its parameters.
+func (c *compiler) lookupUntyped(r *sql.Registry, name string, args []Expression, typs []types.T) (*sql.Function, error) {
+	untyped := make([]types.T, len(typs))
+	found := false
+	for i, arg := range args {
+		untyped[i] = typs[i]
+		if _, ok := arg.(*ConstExpr); ok && typs[i].Family == types.TextFamily {
+			untyped[i] = types.Unknown
+			found = true
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	fn := r.Lookup(name, untyped)
+	if fn == nil {
+		return nil, nil
+	}
+	for i, arg := range args {
+		if untyped[i].Family != types.UnknownFamily {
+			continue
+		}
+		param := fn.Args[len(fn.Args)-1]
+		if i < len(fn.Args) {
+			param = fn.Args[i]
+		}
+		if param.Family == types.TextFamily || param.Family == types.AnyFamily {
+			continue
+		}
+		val, err := c.parseConst(arg.(*ConstExpr).Value.(string), param)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = val
+	}
+	return fn, nil
+}
+
+func (e *FuncExpr) Eval(row entity.Row) (entity.Value, error) {
+	if e.cached {
+		return e.val, nil
+	}
+	args := make([]entity.Value, len(e.Args))
+	consts := true
+	for i, arg := range e.Args {
+		val, err := arg.Eval(row)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = val
+		if _, ok := arg.(*ConstExpr); !ok {
+			consts = false
+		}
+	}
+	val, err := e.Func.CallAt(args, e.now, e.loc)
+	if err != nil {
+		return nil, err
+	}
+	if consts && e.Func.Volatility == sql.Stable {
+		e.cached, e.val = true, val
+	}
+	return val, nil
+}
+func (e *FuncExpr) Type() types.T {
+	return e.Func.Return
+}
+func (e *FuncExpr) String() string {
+	return fmt.Sprintf("%s(%s)", e.Func.Name, joinExprs(e.Args))
+}
@@ -0,0 +1,174 @@
+package planner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/storage"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+// Insert adds rows built from a VALUES list to a table. Columns missing from
+// the target list are filled with their default value, evaluated for every
+// row.
+type Insert struct {
+	Ref        storage.Table
+	Attributes []string
+	Values     [][]parser.Expr
+	// TimeZone is the zone of the session timestamps are read in.
+	TimeZone *time.Location
+	// Now is the time the statement started.
+	Now  time.Time
+	rows []entity.Row
+}
+
+func (ins *Insert) Prepare() error {
+	cols := ins.Ref.Columns()
+	targets, err := ins.resolveTargets(cols)
+	if err != nil {
+		return err
+	}
+	// values can't refer to any column
+	c := &compiler{loc: ins.TimeZone, now: ins.Now}
+	defaults := make([]Expression, len(cols))
+	for i, col := range cols {
+		if defaults[i], err = compileDefault(col, c); err != nil {
+			return err
+		}
+	}
+	rows := make([]entity.Row, len(ins.Values))
+	for i, exprs := range ins.Values {
+		if len(exprs) > len(targets) {
+			return fmt.Errorf("INSERT has more expressions than target columns")
+		}
+		if len(exprs) < len(targets) && len(ins.Attributes) > 0 {
+			return fmt.Errorf("INSERT has more target columns than expressions")
+		}
+		vals := make([]entity.Value, len(cols))
+		set := make([]bool, len(cols))
+		for j, expr := range exprs {
+			if _, ok := expr.(*parser.Default); ok {
+				continue
+			}
+			col := cols[targets[j]]
+			val, err := insertValue(expr, col, c)
+			if err != nil {
+				return err
+			}
+			vals[targets[j]] = val
+			set[targets[j]] = true
+		}
+		for j, col := range cols {
+			if set[j] {
+				continue
+			}
+			val, err := defaults[j].Eval(entity.Row{})
+			if err != nil {
+				return err
+			}
+			if vals[j], err = coerce(val, col, ins.TimeZone); err != nil {
+				return err
+			}
+		}
+		if err := checkNotNull(vals, cols); err != nil {
+			return err
+		}
+		rows[i] = entity.Row{Values: vals}
+	}
+	ins.rows = rows
+	return nil
+}
+
+func (ins *Insert) Exec() (int, error) {
+	for i, row := range ins.rows {
+		if err := ins.Ref.AddRow(row); err != nil {
+			return i, err
+		}
+	}
+	return len(ins.rows), nil
+}
+
+func (ins *Insert) Tag(n int) string {
+	return fmt.Sprintf("INSERT 0 %d", n)
+}
+
+// resolveTargets maps every target column to its position in the table.
+func (ins *Insert) resolveTargets(cols []entity.Column) ([]int, error) {
+	if len(ins.Attributes) == 0 {
+		targets := make([]int, len(cols))
+		for i := range cols {
+			targets[i] = i
+		}
+		return targets, nil
+	}
+	colMap := make(map[string]int)
+	for i, col := range cols {
+		colMap[col.Name] = i
+	}
+	seen := make(map[string]bool)
+	targets := make([]int, len(ins.Attributes))
+	for i, name := range ins.Attributes {
+		id, ok := colMap[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid column %s", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("column %s specified more than once", name)
+		}
+		seen[name] = true
+		targets[i] = id
+	}
+	return targets, nil
+}
+
+// insertValue evaluates the value of col in a row added to its table, with
+// expressions compiled by c.
+func insertValue(expr parser.Expr, col entity.Column, c *compiler) (entity.Value, error) {
+	e, err := c.compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	val, err := e.Eval(entity.Row{})
+	if err != nil {
+		return nil, err
+	}
+	return coerce(val, col, c.location())
+}
+
+// compileDefault compiles the default of col with c, which can't refer to any
+// column. Columns without one default to NULL.
+func compileDefault(col entity.Column, c *compiler) (Expression, error) {
+	if col.Default == nil {
+		return &ConstExpr{}, nil
+	}
+	expr, err := c.compile(col.Default)
+	if err != nil {
+		return nil, err
+	}
+	if typ := expr.Type(); !types.Assignable(typ, col.Type) {
+		return nil, fmt.Errorf("column %s is of type %s but default expression is of type %s", col.Name, col.Type, typ)
+	}
+	return expr, nil
+}
+
+// checkNotNull checks that a row holds no NULL in a NOT NULL column.
+func checkNotNull(vals []entity.Value, cols []entity.Column) error {
+	for i, col := range cols {
+		if col.NotNull && vals[i] == entity.Null {
+			return fmt.Errorf("null value in column %s violates not-null constraint", col.Name)
+		}
+	}
+	return nil
+}
+
+// coerce converts a value to the type of the column it is stored in. Numbers
+// are converted to the numeric type of the column, and strings are read as
+// values of its type. Timestamps are converted in the time zone loc.
+func coerce(val entity.Value, col entity.Column, loc *time.Location) (entity.Value, error) {
+	if typ := types.Of(val); !types.Assignable(typ, col.Type) {
+		return nil, fmt.Errorf("column %s is of type %s but expression is of type %s", col.Name, col.Type, typ)
+	}
+	return col.Type.CoerceIn(val, loc)
+}
@@ -0,0 +1,953 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/storage"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+// JoinMethod is the algorithm used to join two inputs.
+type JoinMethod int
+
+const (
+	// JoinAuto hash joins inputs matched on equal values, unless the right
+	// input is estimated not to fit in memory in which case both inputs
+	// are sorted and merged. Other joins use nested loops.
+	JoinAuto JoinMethod = iota
+	JoinNestedLoop
+	JoinHash
+	JoinMerge
+)
+
+type (
+	// Join joins the rows of two inputs. Prepare picks the node that
+	// implements it.
+	//
+	// The rows of a join hold the columns merged by USING or NATURAL,
+	// followed by the columns of the left and the right input. The merged
+	// columns are only visible qualified by their table name.
+	//
+	// Semi and anti joins return the rows of the left input with and
+	// without a match. They run subqueries, so their condition is over
+	// the right input and refers to the left one like a subquery refers
+	// to the enclosing query. For IN subqueries, Operand over the left
+	// input must also equal Item.
+	Join struct {
+		Kind    parser.JoinKind
+		Cond    *parser.JoinCond
+		Operand parser.Expr
+		Item    parser.Expr
+		Left    Node
+		Right   Node
+		Method  JoinMethod
+		WorkMem int
+		impl    Node
+		scope   *scope
+	}
+
+	// joinNode holds what the join algorithms have in common. A pair of
+	// rows matches when their keys are equal and cond holds.
+	joinNode struct {
+		kind      parser.JoinKind
+		left      Node
+		right     Node
+		leftKeys  []Expression
+		rightKeys []Expression
+		cond      Expression
+		// merged lists the pairs of left and right columns merged into
+		// one column
+		merged [][2]int
+		cols   []entity.Column
+	}
+
+	// NestedLoopJoin scans its right input once for every row of its left
+	// input.
+	NestedLoopJoin struct {
+		joinNode
+	}
+
+	// HashJoin builds a hash table from its right input and probes it with
+	// the rows of its left input.
+	HashJoin struct {
+		joinNode
+	}
+
+	// MergeJoin sorts both inputs by their keys and merges them.
+	MergeJoin struct {
+		workMem int
+		joinNode
+	}
+
+	NestedLoopJoinIter struct {
+		join     *joinNode
+		left     index.Iterator
+		right    index.Iterator
+		lrow     []entity.Value
+		lkeys    []entity.Value
+		lnull    bool
+		lmatched bool
+		pos      int
+		rmatched []bool
+		tail     index.Iterator
+		done     bool
+	}
+
+	HashJoinIter struct {
+		join       *joinNode
+		left       index.Iterator
+		right      index.Iterator
+		rows       [][]entity.Value
+		rmatched   []bool
+		table      map[string][]int
+		lrow       []entity.Value
+		lmatched   bool
+		candidates []int
+		tail       int
+		built      bool
+		probed     bool
+	}
+
+	MergeJoinIter struct {
+		join    *joinNode
+		workMem int
+		left    index.Iterator
+		right   index.Iterator
+		lhead   *keyedRow
+		rhead   *keyedRow
+		out     []entity.Row
+		started bool
+		done    bool
+	}
+
+	keyedRow struct {
+		vals []entity.Value
+		keys []entity.Value
+	}
+
+	// keyedIter appends the values of keys to the rows of its child.
+	keyedIter struct {
+		keys []Expression
+		PlanIter
+	}
+)
+
+// Join Expression
+func (j *Join) Iter() index.Iterator {
+	return j.impl.Iter()
+}
+func (j *Join) Columns() []entity.Column {
+	if j.impl != nil {
+		return j.impl.Columns()
+	}
+	cols, _, err := j.columns()
+	if err != nil {
+		return nil
+	}
+	if j.semi() {
+		return j.Left.Columns()
+	}
+	return cols
+}
+
+// Prepare resolves the join condition and picks the algorithm of the join.
+// Equalities between an expression over the left input and one over the
+// right input become keys that hash and merge joins can match rows by.
+func (j *Join) Prepare() error {
+	if j.Left == nil || j.Right == nil {
+		return errors.New("no child node")
+	}
+	cols, merged, err := j.columns()
+	if err != nil {
+		return err
+	}
+	lcols, rcols := j.Left.Columns(), j.Right.Columns()
+	node := joinNode{
+		kind:   j.Kind,
+		left:   j.Left,
+		right:  j.Right,
+		merged: merged,
+		cols:   cols,
+	}
+	c := &compiler{cols: cols, scope: j.scope}
+	left := func(expr parser.Expr) (Expression, error) {
+		return (&compiler{cols: lcols, loc: c.location(), now: c.statementTime()}).compile(expr)
+	}
+	if j.semi() {
+		node.cols = lcols
+		c.outer = len(lcols)
+		// the right input hides the columns of the same name of the left
+		// one
+		left = func(expr parser.Expr) (Expression, error) {
+			if refersTo(expr, rcols) {
+				return nil, errors.New("expression refers to the right input")
+			}
+			return (&compiler{cols: lcols, loc: c.location(), now: c.statementTime()}).compile(expr)
+		}
+	}
+	right := func(expr parser.Expr) (Expression, error) {
+		return (&compiler{cols: rcols, loc: c.location(), now: c.statementTime()}).compile(expr)
+	}
+	offset := len(merged)
+	var preds []Expression
+	for _, m := range merged {
+		lhs := &ColumnExpr{Index: m[0], Column: lcols[m[0]]}
+		rhs := &ColumnExpr{Index: m[1], Column: rcols[m[1]]}
+		if lhs.Type().Family == rhs.Type().Family {
+			node.leftKeys = append(node.leftKeys, lhs)
+			node.rightKeys = append(node.rightKeys, rhs)
+			continue
+		}
+		pred, err := c.newCompareExpr(relMap["="], &ColumnExpr{Index: offset + m[0], Column: lcols[m[0]]}, &ColumnExpr{Index: offset + len(lcols) + m[1], Column: rcols[m[1]]})
+		if err != nil {
+			return err
+		}
+		preds = append(preds, pred)
+	}
+	if j.Operand != nil {
+		lhs, err := (&compiler{cols: lcols, scope: j.scope}).compile(j.Operand)
+		if err != nil {
+			return err
+		}
+		rhs, err := c.compile(j.Item)
+		if err != nil {
+			return err
+		}
+		pred, err := c.newCompareExpr(relMap["="], lhs, rhs)
+		if err != nil {
+			return err
+		}
+		operand := func(parser.Expr) (Expression, error) {
+			return lhs, nil
+		}
+		if lkey, rkey, ok := c.keyPair(j.Operand, j.Item, operand, right); ok {
+			node.leftKeys = append(node.leftKeys, lkey)
+			node.rightKeys = append(node.rightKeys, rkey)
+		} else {
+			preds = append(preds, pred)
+		}
+	}
+	if j.Cond != nil && j.Cond.On != nil {
+		for _, conj := range conjuncts(j.Cond.On) {
+			pred, err := c.compile(conj)
+			if err != nil {
+				return err
+			}
+			if err := expectBool("JOIN", pred); err != nil {
+				return err
+			}
+			if lhs, rhs, ok := c.joinKeys(conj, left, right); ok {
+				node.leftKeys = append(node.leftKeys, lhs)
+				node.rightKeys = append(node.rightKeys, rhs)
+				continue
+			}
+			preds = append(preds, pred)
+		}
+	}
+	for _, pred := range preds {
+		if node.cond == nil {
+			node.cond = pred
+		} else {
+			node.cond = &LogicExpr{Op: parser.OpAnd, LHS: node.cond, RHS: pred}
+		}
+	}
+	method := j.Method
+	if len(node.leftKeys) == 0 {
+		method = JoinNestedLoop
+	} else if method == JoinAuto {
+		method = JoinHash
+		if size, ok := estimateSize(j.Right); ok && size > j.WorkMem {
+			method = JoinMerge
+		}
+	}
+	switch method {
+	case JoinHash:
+		j.impl = &HashJoin{joinNode: node}
+	case JoinMerge:
+		j.impl = &MergeJoin{workMem: j.WorkMem, joinNode: node}
+	default:
+		j.impl = &NestedLoopJoin{joinNode: node}
+	}
+	return j.impl.Prepare()
+}
+
+// columns lists the columns of the join and the pairs of left and right
+// columns it merges.
+func (j *Join) columns() ([]entity.Column, [][2]int, error) {
+	lcols, rcols := j.Left.Columns(), j.Right.Columns()
+	var using []string
+	if j.Cond != nil {
+		using = j.Cond.Using
+		if j.Cond.Natural {
+			using = commonColumns(lcols, rcols)
+		}
+	}
+	lcols = append([]entity.Column(nil), lcols...)
+	rcols = append([]entity.Column(nil), rcols...)
+	merged := make([][2]int, 0, len(using))
+	cols := make([]entity.Column, 0, len(using)+len(lcols)+len(rcols))
+	for _, name := range using {
+		li, err := usingColumn(name, lcols, "left")
+		if err != nil {
+			return nil, nil, err
+		}
+		ri, err := usingColumn(name, rcols, "right")
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, m := range merged {
+			if m[0] == li {
+				return nil, nil, fmt.Errorf("column name %s appears more than once in USING clause", name)
+			}
+		}
+		merged = append(merged, [2]int{li, ri})
+		typ, ok := types.Common(lcols[li].Type, rcols[ri].Type)
+		if !ok {
+			typ = lcols[li].Type
+		}
+		cols = append(cols, entity.Column{
+			Type: typ,
+			Name: name,
+		})
+		lcols[li].Merged = true
+		rcols[ri].Merged = true
+	}
+	cols = append(cols, lcols...)
+	cols = append(cols, rcols...)
+	return cols, merged, nil
+}
+
+// commonColumns lists the names of the visible columns two inputs share, in
+// the order of the left input.
+func commonColumns(lcols, rcols []entity.Column) []string {
+	res := make([]string, 0)
+	for _, lcol := range lcols {
+		if lcol.Merged {
+			continue
+		}
+		for _, rcol := range rcols {
+			if !rcol.Merged && rcol.Name == lcol.Name {
+				res = append(res, lcol.Name)
+				break
+			}
+		}
+	}
+	return res
+}
+
+func usingColumn(name string, cols []entity.Column, side string) (int, error) {
+	expr, err := resolveColumn(&parser.ColumnRef{Name: name}, cols)
+	if err != nil {
+		return 0, fmt.Errorf("column %s specified in USING clause does not exist in %s table", name, side)
+	}
+	return expr.(*ColumnExpr).Index, nil
+}
+
+// conjuncts splits a condition into the operands of its top level ANDs.
+func conjuncts(expr parser.Expr) []parser.Expr {
+	if e, ok := expr.(*parser.BinaryExpr); ok && e.Op == parser.OpAnd {
+		return append(conjuncts(e.LHS), conjuncts(e.RHS)...)
+	}
+	return []parser.Expr{expr}
+}
+
+// joinKeys compiles an equality between an expression over the left input
+// and one over the right input into a pair of keys. The left and right
+// functions compile expressions over either input.
+func (c *compiler) joinKeys(expr parser.Expr, left, right func(parser.Expr) (Expression, error)) (Expression, Expression, bool) {
+	e, ok := expr.(*parser.BinaryExpr)
+	if !ok || e.Op != "=" || !usesColumns(e.LHS) || !usesColumns(e.RHS) {
+		return nil, nil, false
+	}
+	if lhs, rhs, ok := c.keyPair(e.LHS, e.RHS, left, right); ok {
+		return lhs, rhs, true
+	}
+	rhs, lhs, ok := c.keyPair(e.LHS, e.RHS, right, left)
+	return lhs, rhs, ok
+}
+
+func (c *compiler) keyPair(a, b parser.Expr, compileA, compileB func(parser.Expr) (Expression, error)) (Expression, Expression, bool) {
+	lhs, err := compileA(a)
+	if err != nil {
+		return nil, nil, false
+	}
+	rhs, err := compileB(b)
+	if err != nil {
+		return nil, nil, false
+	}
+	// keys only match when their values are equal, so both sides must
+	// be of the same family
+	lhs, rhs, err = c.unifyTypes(lhs, rhs)
+	if err != nil || lhs.Type().Family != rhs.Type().Family {
+		return nil, nil, false
+	}
+	return lhs, rhs, true
+}
+
+// refersTo reports whether an expression refers to any of cols.
+func refersTo(expr parser.Expr, cols []entity.Column) bool {
+	res := false
+	walkExpr(expr, func(e parser.Expr) bool {
+		if ref, ok := e.(*parser.ColumnRef); ok {
+			col, err := lookupColumn(ref, cols)
+			res = res || col != nil || err != nil
+		}
+		return !res
+	})
+	return res
+}
+
+func usesColumns(expr parser.Expr) bool {
+	res := false
+	walkExpr(expr, func(e parser.Expr) bool {
+		if _, ok := e.(*parser.ColumnRef); ok {
+			res = true
+		}
+		return !res
+	})
+	return res
+}
+
+// estimateSize estimates the memory taken by the rows of a node, when known.
+func estimateSize(node Node) (int, bool) {
+	switch n := node.(type) {
+	case *Table:
+		ref, ok := n.Ref.(*storage.PersistentTable)
+		if !ok {
+			return 0, false
+		}
+		return ref.Indexes[0].Size() * (24 + 32*len(ref.Columns())), true
+	case *Select:
+		return estimateSize(n.Child)
+	}
+	return 0, false
+}
+
+func (j *joinNode) Columns() []entity.Column {
+	return j.cols
+}
+func (j *joinNode) Prepare() error {
+	return nil
+}
+
+// joined builds the joined row of a left and a right row. Either can be nil
+// for the rows of an outer join without a match.
+func (j *joinNode) joined(lrow, rrow []entity.Value) entity.Row {
+	lwidth, rwidth := len(j.left.Columns()), len(j.right.Columns())
+	vals := make([]entity.Value, 0, len(j.merged)+lwidth+rwidth)
+	for i, m := range j.merged {
+		var val entity.Value
+		if lrow != nil {
+			val = lrow[m[0]]
+		}
+		if val == nil && rrow != nil {
+			val = rrow[m[1]]
+		}
+		if val != nil && j.cols[i].Type.IsNumber() {
+			// numbers take the common type of both columns, which can't fail
+			val, _ = j.cols[i].Type.Coerce(val)
+		}
+		vals = append(vals, val)
+	}
+	if lrow == nil {
+		lrow = make([]entity.Value, lwidth)
+	}
+	if rrow == nil {
+		rrow = make([]entity.Value, rwidth)
+	}
+	vals = append(vals, lrow...)
+	vals = append(vals, rrow...)
+	return entity.Row{
+		Values: vals,
+	}
+}
+
+func (j *Join) semi() bool {
+	return j.Kind == parser.JoinSemi || j.Kind == parser.JoinAnti
+}
+
+// match reports whether a pair of rows with equal keys satisfies the
+// condition, returning their joined row.
+func (j *joinNode) match(lrow, rrow []entity.Value) (entity.Row, bool, error) {
+	row := j.joined(lrow, rrow)
+	if j.cond == nil {
+		return row, true, nil
+	}
+	ok, err := evalBool(j.cond, row)
+	return row, ok, err
+}
+
+// unmatched returns the row of the join for a left row without a match, when
+// the join returns one.
+func (j *joinNode) unmatched(lrow []entity.Value) (entity.Row, bool) {
+	switch j.kind {
+	case parser.JoinLeft, parser.JoinFull:
+		return j.joined(lrow, nil), true
+	case parser.JoinAnti:
+		return entity.Row{Values: lrow}, true
+	}
+	return entity.Row{}, false
+}
+
+// semi reports whether the join returns left rows with or without a match
+// rather than joined rows.
+func (j *joinNode) semi() bool {
+	return j.kind == parser.JoinSemi || j.kind == parser.JoinAnti
+}
+
+func (j *joinNode) rightOuter() bool {
+	return j.kind == parser.JoinRight || j.kind == parser.JoinFull
+}
+
+// evalKeys evaluates keys on a row. It reports false when a key is NULL, as
+// such a row can't match any other.
+func evalKeys(keys []Expression, row entity.Row) ([]entity.Value, bool, error) {
+	vals := make([]entity.Value, len(keys))
+	for i, key := range keys {
+		val, err := key.Eval(row)
+		if err != nil {
+			return nil, false, err
+		}
+		if val == nil {
+			return nil, false, nil
+		}
+		vals[i] = val
+	}
+	return vals, true, nil
+}
+
+// NestedLoopJoin Expression
+func (j *NestedLoopJoin) Iter() index.Iterator {
+	return &NestedLoopJoinIter{
+		join: &j.joinNode,
+		left: j.left.Iter(),
+	}
+}
+
+func (iter *NestedLoopJoinIter) Next() (entity.Row, error) {
+	for !iter.done {
+		if iter.tail != nil {
+			return iter.nextUnmatched()
+		}
+		if iter.lrow == nil {
+			row, err := iter.left.Next()
+			if err == index.EndOfIterator {
+				if !iter.join.rightOuter() {
+					iter.done = true
+					break
+				}
+				iter.tail = iter.join.right.Iter()
+				iter.pos = -1
+				continue
+			} else if err != nil {
+				return entity.Row{}, err
+			}
+			keys, ok, err := evalKeys(iter.join.leftKeys, row)
+			if err != nil {
+				return entity.Row{}, err
+			}
+			iter.lrow = row.Values
+			iter.lkeys = keys
+			iter.lnull = !ok
+			iter.lmatched = false
+			iter.right = iter.join.right.Iter()
+			iter.pos = -1
+		}
+		rrow, err := iter.right.Next()
+		if err == index.EndOfIterator {
+			lrow := iter.lrow
+			iter.lrow = nil
+			if !iter.lmatched {
+				if row, ok := iter.join.unmatched(lrow); ok {
+					return row, nil
+				}
+			}
+			continue
+		} else if err != nil {
+			return entity.Row{}, err
+		}
+		iter.pos++
+		ok, err := iter.keysMatch(rrow)
+		if err != nil {
+			return entity.Row{}, err
+		}
+		if !ok {
+			continue
+		}
+		row, ok, err := iter.join.match(iter.lrow, rrow.Values)
+		if err != nil {
+			return entity.Row{}, err
+		}
+		if !ok {
+			continue
+		}
+		iter.lmatched = true
+		if iter.join.semi() {
+			// the rest of the right input can't change the result
+			lrow := iter.lrow
+			iter.lrow = nil
+			if iter.join.kind == parser.JoinSemi {
+				return entity.Row{Values: lrow}, nil
+			}
+			continue
+		}
+		if iter.join.rightOuter() {
+			for len(iter.rmatched) <= iter.pos {
+				iter.rmatched = append(iter.rmatched, false)
+			}
+			iter.rmatched[iter.pos] = true
+		}
+		return row, nil
+	}
+	return entity.Row{}, index.EndOfIterator
+}
+
+// keysMatch compares the keys of the current left row to those of a right
+// row.
+func (iter *NestedLoopJoinIter) keysMatch(rrow entity.Row) (bool, error) {
+	if len(iter.join.rightKeys) == 0 {
+		return true, nil
+	}
+	if iter.lnull {
+		return false, nil
+	}
+	keys, ok, err := evalKeys(iter.join.rightKeys, rrow)
+	if err != nil || !ok {
+		return false, err
+	}
+	cmp, err := compareKeys(iter.lkeys, keys)
+	return cmp == 0, err
+}
+
+// nextUnmatched returns the next right row that didn't match any left row.
+// The right input is scanned in the same order every time, so its rows are
+// identified by their position in the scan.
+func (iter *NestedLoopJoinIter) nextUnmatched() (entity.Row, error) {
+	for {
+		rrow, err := iter.tail.Next()
+		if err == index.EndOfIterator {
+			iter.done = true
+			return entity.Row{}, err
+		} else if err != nil {
+			return entity.Row{}, err
+		}
+		iter.pos++
+		if iter.pos >= len(iter.rmatched) || !iter.rmatched[iter.pos] {
+			return iter.join.joined(nil, rrow.Values), nil
+		}
+	}
+}
+
+// HashJoin Expression
+func (j *HashJoin) Iter() index.Iterator {
+	return &HashJoinIter{
+		join:  &j.joinNode,
+		left:  j.left.Iter(),
+		right: j.right.Iter(),
+	}
+}
+
+func (iter *HashJoinIter) Next() (entity.Row, error) {
+	if !iter.built {
+		if err := iter.build(); err != nil {
+			return entity.Row{}, err
+		}
+	}
+	for !iter.probed {
+		for len(iter.candidates) > 0 {
+			i := iter.candidates[0]
+			iter.candidates = iter.candidates[1:]
+			row, ok, err := iter.join.match(iter.lrow, iter.rows[i])
+			if err != nil {
+				return entity.Row{}, err
+			}
+			if !ok {
+				continue
+			}
+			iter.lmatched = true
+			iter.rmatched[i] = true
+			if !iter.join.semi() {
+				return row, nil
+			}
+			iter.candidates = nil
+			lrow := iter.lrow
+			iter.lrow = nil
+			if iter.join.kind == parser.JoinSemi {
+				return entity.Row{Values: lrow}, nil
+			}
+		}
+		if iter.lrow != nil {
+			lrow := iter.lrow
+			iter.lrow = nil
+			if !iter.lmatched {
+				if row, ok := iter.join.unmatched(lrow); ok {
+					return row, nil
+				}
+			}
+		}
+		row, err := iter.left.Next()
+		if err == index.EndOfIterator {
+			iter.probed = true
+			break
+		} else if err != nil {
+			return entity.Row{}, err
+		}
+		iter.lrow = row.Values
+		iter.lmatched = false
+		keys, ok, err := evalKeys(iter.join.leftKeys, row)
+		if err != nil {
+			return entity.Row{}, err
+		}
+		if ok {
+			iter.candidates = iter.table[hashKey(keys)]
+		}
+	}
+	if iter.join.rightOuter() {
+		for iter.tail < len(iter.rows) {
+			i := iter.tail
+			iter.tail++
+			if !iter.rmatched[i] {
+				return iter.join.joined(nil, iter.rows[i]), nil
+			}
+		}
+	}
+	return entity.Row{}, index.EndOfIterator
+}
+
+// build reads the right input into a hash table by its keys. Rows with NULL
+// keys aren't added to the table but are kept for outer joins.
+func (iter *HashJoinIter) build() error {
+	iter.built = true
+	iter.table = make(map[string][]int)
+	for {
+		row, err := iter.right.Next()
+		if err == index.EndOfIterator {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		keys, ok, err := evalKeys(iter.join.rightKeys, row)
+		if err != nil {
+			return err
+		}
+		if !ok && !iter.join.rightOuter() {
+			continue
+		}
+		iter.rows = append(iter.rows, row.Values)
+		iter.rmatched = append(iter.rmatched, false)
+		if ok {
+			key := hashKey(keys)
+			iter.table[key] = append(iter.table[key], len(iter.rows)-1)
+		}
+	}
+}
+
+// MergeJoin Expression
+func (j *MergeJoin) Iter() index.Iterator {
+	return &MergeJoinIter{
+		join:    &j.joinNode,
+		workMem: j.workMem,
+	}
+}
+
+func (iter *MergeJoinIter) Next() (entity.Row, error) {
+	if !iter.started {
+		iter.started = true
+		iter.left = iter.sorted(iter.join.left, iter.join.leftKeys)
+		iter.right = iter.sorted(iter.join.right, iter.join.rightKeys)
+		var err error
+		if iter.lhead, err = iter.advance(iter.left); err != nil {
+			return entity.Row{}, err
+		}
+		if iter.rhead, err = iter.advance(iter.right); err != nil {
+			return entity.Row{}, err
+		}
+	}
+	for len(iter.out) == 0 && !iter.done {
+		if err := iter.merge(); err != nil {
+			return entity.Row{}, err
+		}
+	}
+	if len(iter.out) == 0 {
+		return entity.Row{}, index.EndOfIterator
+	}
+	row := iter.out[0]
+	iter.out = iter.out[1:]
+	return row, nil
+}
+
+// sorted sorts the rows of a node by keys, which are appended to each row.
+// NULL keys sort last.
+func (iter *MergeJoinIter) sorted(node Node, keys []Expression) index.Iterator {
+	width := len(node.Columns())
+	sortKeys := make([]sortKey, len(keys))
+	for i := range keys {
+		sortKeys[i] = sortKey{index: width + i}
+	}
+	return &SortIter{
+		keys:    sortKeys,
+		workMem: iter.workMem,
+		width:   width + len(keys),
+		PlanIter: PlanIter{
+			ChildIter: &keyedIter{
+				keys: keys,
+				PlanIter: PlanIter{
+					ChildIter: node.Iter(),
+				},
+			},
+		},
+	}
+}
+
+func (iter *MergeJoinIter) advance(input index.Iterator) (*keyedRow, error) {
+	row, err := input.Next()
+	if err == index.EndOfIterator {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	n := len(row.Values) - len(iter.join.leftKeys)
+	return &keyedRow{vals: row.Values[:n], keys: row.Values[n:]}, nil
+}
+
+// merge produces the joined rows of the next key, or the next row without a
+// match.
+func (iter *MergeJoinIter) merge() error {
+	l, r := iter.lhead, iter.rhead
+	var err error
+	switch {
+	case l == nil && r == nil:
+		iter.done = true
+		return nil
+	case l != nil && (r == nil || hasNull(l.keys)):
+		if row, ok := iter.join.unmatched(l.vals); ok {
+			iter.out = append(iter.out, row)
+		}
+		iter.lhead, err = iter.advance(iter.left)
+		return err
+	case r != nil && (l == nil || hasNull(r.keys)):
+		if iter.join.rightOuter() {
+			iter.out = append(iter.out, iter.join.joined(nil, r.vals))
+		}
+		iter.rhead, err = iter.advance(iter.right)
+		return err
+	}
+	cmp, err := compareKeys(l.keys, r.keys)
+	if err != nil {
+		return err
+	}
+	if cmp < 0 {
+		if row, ok := iter.join.unmatched(l.vals); ok {
+			iter.out = append(iter.out, row)
+		}
+		iter.lhead, err = iter.advance(iter.left)
+		return err
+	}
+	if cmp > 0 {
+		if iter.join.rightOuter() {
+			iter.out = append(iter.out, iter.join.joined(nil, r.vals))
+		}
+		iter.rhead, err = iter.advance(iter.right)
+		return err
+	}
+	// join every left row with the key to the right rows with the key
+	key := l.keys
+	group := make([]*keyedRow, 0)
+	for iter.rhead != nil && !hasNull(iter.rhead.keys) {
+		if cmp, err := compareKeys(iter.rhead.keys, key); err != nil {
+			return err
+		} else if cmp != 0 {
+			break
+		}
+		group = append(group, iter.rhead)
+		if iter.rhead, err = iter.advance(iter.right); err != nil {
+			return err
+		}
+	}
+	rmatched := make([]bool, len(group))
+	for iter.lhead != nil && !hasNull(iter.lhead.keys) {
+		if cmp, err := compareKeys(iter.lhead.keys, key); err != nil {
+			return err
+		} else if cmp != 0 {
+			break
+		}
+		lmatched := false
+		for i, r := range group {
+			row, ok, err := iter.join.match(iter.lhead.vals, r.vals)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			lmatched = true
+			rmatched[i] = true
+			if iter.join.semi() {
+				break
+			}
+			iter.out = append(iter.out, row)
+		}
+		if lmatched && iter.join.kind == parser.JoinSemi {
+			iter.out = append(iter.out, entity.Row{Values: iter.lhead.vals})
+		}
+		if !lmatched {
+			if row, ok := iter.join.unmatched(iter.lhead.vals); ok {
+				iter.out = append(iter.out, row)
+			}
+		}
+		if iter.lhead, err = iter.advance(iter.left); err != nil {
+			return err
+		}
+	}
+	if iter.join.rightOuter() {
+		for i, r := range group {
+			if !rmatched[i] {
+				iter.out = append(iter.out, iter.join.joined(nil, r.vals))
+			}
+		}
+	}
+	return nil
+}
+
+func hasNull(vals []entity.Value) bool {
+	for _, val := range vals {
+		if val == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func compareKeys(a, b []entity.Value) (int, error) {
+	for i := range a {
+		cmp, err := types.Compare(a[i], b[i])
+		if err != nil || cmp != 0 {
+			return cmp, err
+		}
+	}
+	return 0, nil
+}
+
+func (iter *keyedIter) Next() (entity.Row, error) {
+	row, err := iter.ChildIter.Next()
+	if err != nil {
+		return entity.Row{}, err
+	}
+	vals := make([]entity.Value, 0, len(row.Values)+len(iter.keys))
+	vals = append(vals, row.Values...)
+	for _, key := range iter.keys {
+		val, err := key.Eval(row)
+		if err != nil {
+			return entity.Row{}, err
+		}
+		vals = append(vals, val)
+	}
+	return entity.Row{
+		Key:    row.Key,
+		Values: vals,
+	}, nil
+}
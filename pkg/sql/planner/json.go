@@ -0,0 +1,194 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+// JSONExpr applies an operator to a JSON document, like getting one of its
+// fields or testing whether it contains another document.
+type JSONExpr struct {
+	Op  string
+	LHS Expression
+	RHS Expression
+	typ types.T
+}
+
+// jsonOps are the operators of JSON documents by the families their right
+// operand can have.
+var jsonOps = map[string][]types.Family{
+	parser.OpJSONField:     {types.TextFamily, types.IntFamily},
+	parser.OpJSONFieldText: {types.TextFamily, types.IntFamily},
+	parser.OpJSONPath:      {types.TextFamily},
+	parser.OpJSONPathText:  {types.TextFamily},
+	parser.OpContains:      {types.JSONFamily},
+	parser.OpContainedBy:   {types.JSONFamily},
+	parser.OpHasKey:        {types.TextFamily},
+}
+
+// newJSONExpr resolves an operator of JSON documents. String constants are
+// read as jsonb where a document is expected. Containment and the ? operator
+// are only defined for jsonb.
+func (c *compiler) newJSONExpr(op string, lhs, rhs Expression) (Expression, error) {
+	lhs, err := c.jsonOperand(lhs)
+	if err != nil {
+		return nil, err
+	}
+	families := jsonOps[op]
+	if families[0] == types.JSONFamily {
+		if rhs, err = c.jsonOperand(rhs); err != nil {
+			return nil, err
+		}
+	}
+	ltyp, rtyp := lhs.Type(), rhs.Type()
+	ok := ltyp.Family == types.JSONFamily
+	if families[0] == types.JSONFamily || op == parser.OpHasKey {
+		ok = ltyp.Oid == types.OidJSONB
+	}
+	if rtyp.Family == types.JSONFamily {
+		ok = ok && rtyp.Oid == types.OidJSONB
+	}
+	if rtyp.Family != types.UnknownFamily && !hasFamily(families, rtyp.Family) {
+		ok = false
+	}
+	if !ok {
+		return nil, fmt.Errorf("operator does not exist: %s %s %s", ltyp, op, rtyp)
+	}
+	if path, ok := rhs.(*ConstExpr); ok && (op == parser.OpJSONPath || op == parser.OpJSONPathText) {
+		if _, err := parsePath(path.Value.(string)); err != nil {
+			return nil, err
+		}
+	}
+	typ := types.Bool
+	switch op {
+	case parser.OpJSONField, parser.OpJSONPath:
+		typ = types.JSONB
+		if ltyp.Family == types.JSONFamily {
+			typ = ltyp
+		}
+	case parser.OpJSONFieldText, parser.OpJSONPathText:
+		typ = types.Text
+	}
+	return &JSONExpr{Op: op, LHS: lhs, RHS: rhs, typ: typ}, nil
+}
+
+func hasFamily(families []types.Family, family types.Family) bool {
+	for _, f := range families {
+		if f == family {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonOperand reads a string constant as jsonb.
+func (c *compiler) jsonOperand(expr Expression) (Expression, error) {
+	if lit, ok := expr.(*ConstExpr); ok && expr.Type().Family == types.TextFamily {
+		return c.parseConst(lit.Value.(string), types.JSONB)
+	}
+	return expr, nil
+}
+
+// parsePath reads the steps of a path written as an array literal, like
+// '{a,0,"b c"}'.
+func parsePath(s string) ([]string, error) {
+	t := strings.TrimSpace(s)
+	if len(t) < 2 || t[0] != '{' || t[len(t)-1] != '}' {
+		return nil, fmt.Errorf("malformed array literal: %q", s)
+	}
+	t = strings.TrimSpace(t[1 : len(t)-1])
+	if t == "" {
+		return nil, nil
+	}
+	var steps []string
+	for _, step := range strings.Split(t, ",") {
+		step = strings.TrimSpace(step)
+		if len(step) >= 2 && step[0] == '"' && step[len(step)-1] == '"' {
+			step = step[1 : len(step)-1]
+		} else if step == "" || strings.ContainsAny(step, `"{}`) {
+			return nil, fmt.Errorf("malformed array literal: %q", s)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func (e *JSONExpr) Eval(row entity.Row) (entity.Value, error) {
+	lval, err := e.LHS.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	rval, err := e.RHS.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	if lval == nil || rval == nil {
+		return nil, nil
+	}
+	doc, err := lval.(types.JSONValue).Doc()
+	if err != nil {
+		return nil, err
+	}
+	switch e.Op {
+	case parser.OpJSONField, parser.OpJSONFieldText:
+		var elem interface{}
+		var ok bool
+		// fields are only found by name and elements by position
+		switch step := rval.(type) {
+		case string:
+			if obj, isObj := doc.(map[string]interface{}); isObj {
+				elem, ok = obj[step]
+			}
+		case int:
+			if arr, isArr := doc.([]interface{}); isArr {
+				elem, ok = types.JSONIndex(arr, step)
+			}
+		}
+		if !ok {
+			return nil, nil
+		}
+		return e.result(elem), nil
+	case parser.OpJSONPath, parser.OpJSONPathText:
+		steps, err := parsePath(rval.(string))
+		if err != nil {
+			return nil, err
+		}
+		for _, step := range steps {
+			var ok bool
+			if doc, ok = types.JSONGet(doc, step); !ok {
+				return nil, nil
+			}
+		}
+		return e.result(doc), nil
+	case parser.OpHasKey:
+		return types.JSONHasKey(doc, rval.(string)), nil
+	}
+	other, err := rval.(types.JSONValue).Doc()
+	if err != nil {
+		return nil, err
+	}
+	if e.Op == parser.OpContainedBy {
+		return types.JSONContains(other, doc), nil
+	}
+	return types.JSONContains(doc, other), nil
+}
+
+// result returns an element of a document as a document, or as text for the
+// operators returning text.
+func (e *JSONExpr) result(elem interface{}) entity.Value {
+	if e.typ.Family == types.TextFamily {
+		return types.JSONText(elem)
+	}
+	return types.EncodeJSON(elem)
+}
+
+func (e *JSONExpr) Type() types.T {
+	return e.typ
+}
+func (e *JSONExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.LHS, e.Op, e.RHS)
+}
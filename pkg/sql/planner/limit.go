@@ -0,0 +1,112 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+)
+
+type (
+	// Limit skips the first Offset rows of its child and returns at most
+	// Count of the rest. It stops pulling rows from its child as soon as the
+	// count is reached.
+	Limit struct {
+		Count  parser.Expr
+		Offset parser.Expr
+		count  int
+		offset int
+		PlanNode
+	}
+
+	LimitIter struct {
+		count  int
+		offset int
+		seen   int
+		PlanIter
+	}
+)
+
+// Limit Expression
+func (lim *Limit) Iter() index.Iterator {
+	return &LimitIter{
+		count:  lim.count,
+		offset: lim.offset,
+		PlanIter: PlanIter{
+			ChildIter: lim.Child.Iter(),
+		},
+	}
+}
+func (lim *Limit) Columns() []entity.Column {
+	return lim.Child.Columns()
+}
+
+// Prepare evaluates the count and offset, which can't refer to columns. A
+// NULL count returns every row. When the child is a sort only the first
+// offset + count rows have to be sorted.
+func (lim *Limit) Prepare() error {
+	if lim.Child == nil {
+		return errors.New("no child node")
+	}
+	count, err := limitValue("LIMIT", lim.Count, -1)
+	if err != nil {
+		return err
+	}
+	offset, err := limitValue("OFFSET", lim.Offset, 0)
+	if err != nil {
+		return err
+	}
+	lim.count = count
+	lim.offset = offset
+	if sort, ok := lim.Child.(*Sort); ok && count >= 0 {
+		sort.Bound = offset + count
+	}
+	return nil
+}
+
+// limitValue evaluates the argument of a LIMIT or OFFSET clause, returning
+// def when it is missing or NULL.
+func limitValue(clause string, expr parser.Expr, def int) (int, error) {
+	if expr == nil {
+		return def, nil
+	}
+	compiled, err := compileExpr(expr, nil)
+	if err != nil {
+		return 0, err
+	}
+	if typ := compiled.Type(); !isInt(typ) {
+		return 0, fmt.Errorf("argument of %s must be type integer, not %s", clause, typ)
+	}
+	val, err := compiled.Eval(entity.Row{})
+	if err != nil {
+		return 0, err
+	}
+	if val == nil {
+		return def, nil
+	}
+	n := val.(int)
+	if n < 0 {
+		return 0, fmt.Errorf("%s must not be negative", clause)
+	}
+	return n, nil
+}
+
+func (iter *LimitIter) Next() (entity.Row, error) {
+	for iter.offset > 0 {
+		if _, err := iter.ChildIter.Next(); err != nil {
+			return entity.Row{}, err
+		}
+		iter.offset--
+	}
+	if iter.count >= 0 && iter.seen >= iter.count {
+		return entity.Row{}, index.EndOfIterator
+	}
+	row, err := iter.ChildIter.Next()
+	if err != nil {
+		return entity.Row{}, err
+	}
+	iter.seen++
+	return row, nil
+}
@@ -3,17 +3,15 @@ package planner
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/hiepd/galedb/pkg/entity"
 	"github.com/hiepd/galedb/pkg/index"
 	"github.com/hiepd/galedb/pkg/sql/parser"
 	"github.com/hiepd/galedb/pkg/storage"
+	"github.com/hiepd/galedb/pkg/types"
 )
 
-var relMap = map[string]Relation{
-	"=": Equal,
-}
-
 type (
 	Node interface {
 		Iter() index.Iterator
@@ -21,65 +19,70 @@ type (
 		Prepare() error
 	}
 
+	// Command is a statement that changes the database instead of
+	// producing rows. Exec returns the number of affected rows.
+	Command interface {
+		Prepare() error
+		Exec() (int, error)
+		Tag(n int) string
+	}
+
 	PlanNode struct {
 		Alias string
 		Child Node
+		// scope is the scope of the query the node belongs to, which
+		// plans the subqueries of its expressions.
+		scope *scope
 	}
 
 	Select struct {
-		Conditions []*Condition
+		Predicate parser.Expr
+		cond      Expression
 		PlanNode
 	}
 
 	Projection struct {
-		Attributes []string
-		Cols       []entity.Column
+		Items []*parser.SelectItem
+		Cols  []entity.Column
+		exprs []Expression
 		PlanNode
 	}
 
 	Table struct {
-		Ref     storage.Table
-		RefIter index.Iterator
+		Ref   storage.Table
+		index index.Index
+		// terms are looked up in index when it is an inverted index.
+		terms []string
 		PlanNode
 	}
 
+	// SingleRow produces one row without columns. It is the input of a
+	// SELECT without FROM.
+	SingleRow struct{}
+
 	PlanIter struct {
 		ChildIter index.Iterator
 	}
 
 	SelectIter struct {
-		cols   []entity.Column
-		conds  []*Condition
-		colMap map[string]int
+		cond Expression
 		PlanIter
 	}
 
 	ProjectionIter struct {
-		colNum   int
-		colOrder map[int]int
+		exprs []Expression
 		PlanIter
 	}
+
+	SingleRowIter struct {
+		done bool
+	}
 )
 
 // Projection Expression
 func (proj *Projection) Iter() index.Iterator {
-	childColOrder := make(map[string]int)
-	childCols := proj.Child.Columns()
-	for i, col := range childCols {
-		childColOrder[col.Name] = i
-	}
-	colOrder := make(map[int]int)
-	for i, col := range proj.Cols {
-		source, ok := childColOrder[col.Name]
-		// TODO: handle this better
-		if !ok {
-			panic("col doesn't exist")
-		}
-		colOrder[source] = i
-	}
 	return &ProjectionIter{
-		colOrder: colOrder,
-		colNum:   len(proj.Cols),
+		exprs: proj.exprs,
 		PlanIter: PlanIter{
 			ChildIter: proj.Child.Iter(),
 		},
@@ -95,34 +98,53 @@ func (proj *Projection) resolveColumns() error {
 	if proj.Child == nil {
 		return errors.New("no child node")
 	}
-	colMap := make(map[string]entity.Column)
-	childCols := proj.Child.Columns()
-	for _, col := range childCols {
-		colMap[col.Name] = col
-	}
-	cols := make([]entity.Column, len(proj.Attributes))
-	for i, colName := range proj.Attributes {
-		col, ok := colMap[colName]
-		if !ok {
-			return fmt.Errorf("invalid column %s", colName)
+	c := compilerFor(proj.Child, proj.scope)
+	cols := make([]entity.Column, 0, len(proj.Items))
+	exprs := make([]Expression, 0, len(proj.Items))
+	for _, item := range proj.Items {
+		if star, ok := item.Expr.(*parser.Star); ok {
+			expanded, err := c.expandStar(star)
+			if err != nil {
+				return err
+			}
+			for _, expr := range expanded {
+				exprs = append(exprs, expr)
+				cols = append(cols, expr.(*ColumnExpr).Column)
+			}
+			continue
+		}
+		expr, err := c.compile(item.Expr)
+		if err != nil {
+			return err
 		}
-		cols[i] = col
+		exprs = append(exprs, expr)
+		cols = append(cols, outputColumn(item, expr))
 	}
 	proj.Cols = cols
+	proj.exprs = exprs
 	return nil
 }
 
+// outputColumn describes the column produced by a select item. Like Postgres,
+// expressions without an alias are named ?column?.
+func outputColumn(item *parser.SelectItem, expr Expression) entity.Column {
+	if col, ok := expr.(*ColumnExpr); ok && item.Alias == "" {
+		return col.Column
+	}
+	name := item.Alias
+	if name == "" {
+		name = "?column?"
+	}
+	return entity.Column{
+		Type: expr.Type(),
+		Name: name,
+	}
+}
+
 // Select Expression
 func (sel *Select) Iter() index.Iterator {
-	childCols := sel.Child.Columns()
-	colMap := make(map[string]int)
-	for i, col := range childCols {
-		colMap[col.Name] = i
-	}
 	return &SelectIter{
-		cols:   childCols,
-		colMap: colMap,
-		conds:  sel.Conditions,
+		cond: sel.cond,
 		PlanIter: PlanIter{
 			ChildIter: sel.Child.Iter(),
 		},
@@ -132,73 +154,180 @@ func (sel *Select) Columns() []entity.Column {
 	return sel.Child.Columns()
 }
 func (sel *Select) Prepare() error {
+	if sel.Child == nil {
+		return errors.New("no child node")
+	}
+	cond, err := compilerFor(sel.Child, sel.scope).compile(sel.Predicate)
+	if err != nil {
+		return err
+	}
+	clause := "WHERE"
+	if _, ok := sel.Child.(*Aggregate); ok {
+		clause = "HAVING"
+	}
+	if err := expectBool(clause, cond); err != nil {
+		return err
+	}
+	sel.cond = cond
+	if tb, ok := sel.Child.(*Table); ok {
+		tb.useIndex(cond)
+	}
 	return nil
 }
 
 // Table Expression
 func (tb *Table) Iter() index.Iterator {
-	return tb.RefIter
+	if inv, ok := tb.index.(*index.InvertedIndex); ok {
+		return inv.Lookup(tb.terms)
+	}
+	return tb.index.Iterator()
 }
 func (tb *Table) Columns() []entity.Column {
-	return tb.Ref.Columns()
+	return qualifyColumns(tb.Alias, tb.Ref.Columns())
 }
 func (tb *Table) Prepare() error {
 	switch ref := tb.Ref.(type) {
 	case *storage.PersistentTable:
-		tb.RefIter = ref.Indexes[0].Iterator()
+		tb.index, tb.terms = ref.Indexes[0], nil
 	default:
 		return errors.New("table is not persistent")
 	}
 	return nil
 }
 
+// useIndex makes the table read only the rows an inverted index finds for the
+// documents a jsonb column must contain by cond. The condition is still
+// checked on every row.
+func (tb *Table) useIndex(cond Expression) {
+	ref, ok := tb.Ref.(*storage.PersistentTable)
+	if !ok {
+		return
+	}
+	for _, conj := range andedExprs(cond) {
+		e, ok := conj.(*JSONExpr)
+		if !ok || e.Op != parser.OpContains {
+			continue
+		}
+		col, ok := e.LHS.(*ColumnExpr)
+		lit, isConst := e.RHS.(*ConstExpr)
+		if !ok || !isConst {
+			continue
+		}
+		terms := jsonTerms(lit.Value)
+		if len(terms) == 0 {
+			continue
+		}
+		for _, idx := range ref.Indexes[1:] {
+			inv, ok := idx.(*index.InvertedIndex)
+			if !ok || inv.Column != col.Index {
+				continue
+			}
+			// a single index is read, with the terms of every document
+			if other, ok := tb.index.(*index.InvertedIndex); ok && other != inv {
+				break
+			}
+			tb.index = inv
+			tb.terms = append(tb.terms, terms...)
+			break
+		}
+	}
+}
+
+// andedExprs splits a compiled condition into the conditions it ANDs.
+func andedExprs(cond Expression) []Expression {
+	if e, ok := cond.(*LogicExpr); ok && e.Op == parser.OpAnd {
+		return append(andedExprs(e.LHS), andedExprs(e.RHS)...)
+	}
+	return []Expression{cond}
+}
+
 func (iter *SelectIter) Next() (entity.Row, error) {
 	for {
 		row, err := iter.ChildIter.Next()
 		if err != nil {
 			return entity.Row{}, err
 		}
-		if Eval(iter.conds, row, iter.colMap, iter.cols) {
+		ok, err := evalBool(iter.cond, row)
+		if err != nil {
+			return entity.Row{}, err
+		}
+		if ok {
 			return row, nil
 		}
 	}
 }
 
 func (iter *ProjectionIter) Next() (entity.Row, error) {
-	for {
-		row, err := iter.ChildIter.Next()
-		if err != nil {
-			return entity.Row{}, err
-		}
-		return iter.project(row, iter.colNum), nil
+	row, err := iter.ChildIter.Next()
+	if err != nil {
+		return entity.Row{}, err
 	}
+	return iter.project(row)
 }
 
-func (iter *ProjectionIter) project(row entity.Row, n int) entity.Row {
-	vals := make([]entity.Value, n)
-	for k, v := range iter.colOrder {
-		vals[v] = row.Values[k]
+func (iter *ProjectionIter) project(row entity.Row) (entity.Row, error) {
+	vals := make([]entity.Value, len(iter.exprs))
+	for i, expr := range iter.exprs {
+		val, err := expr.Eval(row)
+		if err != nil {
+			return entity.Row{}, err
+		}
+		vals[i] = val
 	}
 	return entity.Row{
 		Values: vals,
+	}, nil
+}
+
+// SingleRow Expression
+func (sr *SingleRow) Iter() index.Iterator {
+	return &SingleRowIter{}
+}
+func (sr *SingleRow) Columns() []entity.Column {
+	return nil
+}
+func (sr *SingleRow) Prepare() error {
+	return nil
+}
+
+func (iter *SingleRowIter) Next() (entity.Row, error) {
+	if iter.done {
+		return entity.Row{}, index.EndOfIterator
 	}
+	iter.done = true
+	return entity.Row{}, nil
 }
 
 type Planner struct {
 	Database *storage.Database
+	// WorkMem is the memory budget in bytes of operators that can spill
+	// to disk.
+	WorkMem int
+	// JoinMethod forces the algorithm of joins on equal values.
+	JoinMethod JoinMethod
+	// Session holds the settings of the connection.
+	Session *Session
+	// depth counts the subqueries being planned
+	depth int
+	// now is the time the statement being planned started
+	now time.Time
 }
 
 type QueryPlan struct {
-	Root Node
+	Root    Node
+	Command Command
 }
 
 func New(db *storage.Database) *Planner {
 	return &Planner{
 		Database: db,
+		WorkMem:  DefaultWorkMem,
+		Session:  NewSession(),
 	}
 }
 
 func (p *Planner) Prepare(statement parser.Statement) (*QueryPlan, error) {
+	p.now = time.Now()
 	switch stmt := statement.(type) {
 	case *parser.Select:
 		plan, err := p.buildQueryPlan(stmt)
@@ -207,13 +336,91 @@ func (p *Planner) Prepare(statement parser.Statement) (*QueryPlan, error) {
 		}
 		err = plan.prepare(plan.Root)
 		return plan, err
+	case *parser.Insert:
+		table, err := p.Database.GetTable(stmt.TableName)
+		if err != nil {
+			return nil, err
+		}
+		cmd := &Insert{
+			Ref:        table,
+			Attributes: stmt.Cols,
+			Values:     stmt.Rows,
+			TimeZone:   p.Session.TimeZone,
+			Now:        p.now,
+		}
+		if err := cmd.Prepare(); err != nil {
+			return nil, err
+		}
+		return &QueryPlan{Command: cmd}, nil
+	case *parser.Update:
+		s := &scope{planner: p}
+		table, child, err := p.parseScanStatement(stmt.TableName, stmt.Where, s)
+		if err != nil {
+			return nil, err
+		}
+		cmd := &Update{
+			Ref:         table.Ref,
+			Assignments: stmt.Set,
+			PlanNode: PlanNode{
+				Child: child,
+				scope: s,
+			},
+		}
+		return p.prepareCommand(cmd, child)
+	case *parser.Delete:
+		table, child, err := p.parseScanStatement(stmt.TableName, stmt.Where, &scope{planner: p})
+		if err != nil {
+			return nil, err
+		}
+		cmd := &Delete{
+			Ref: table.Ref,
+			PlanNode: PlanNode{
+				Child: child,
+			},
+		}
+		return p.prepareCommand(cmd, child)
+	case *parser.CreateTable:
+		cmd := &CreateTable{
+			Database:    p.Database,
+			TableName:   stmt.TableName,
+			IfNotExists: stmt.IfNotExists,
+			Defs:        stmt.Columns,
+			TimeZone:    p.Session.TimeZone,
+		}
+		return p.prepareCommand(cmd, nil)
+	case *parser.CreateIndex:
+		cmd := &CreateIndex{
+			Database:    p.Database,
+			Name:        stmt.Name,
+			TableName:   stmt.TableName,
+			Method:      stmt.Method,
+			Column:      stmt.Column,
+			IfNotExists: stmt.IfNotExists,
+		}
+		return p.prepareCommand(cmd, nil)
+	case *parser.DropTable:
+		cmd := &DropTable{
+			Database:   p.Database,
+			TableNames: stmt.TableNames,
+			IfExists:   stmt.IfExists,
+		}
+		return p.prepareCommand(cmd, nil)
+	case *parser.SetVar:
+		cmd := &SetVariable{
+			Session: p.Session,
+			Name:    stmt.Name,
+			Value:   stmt.Value,
+		}
+		return p.prepareCommand(cmd, nil)
+	case *parser.ShowVar:
+		return p.showVariable(stmt)
 	default:
 		return nil, errors.New("unsupported statement")
 	}
 }
 
 func (p *Planner) buildQueryPlan(sel *parser.Select) (*QueryPlan, error) {
-	root, err := p.parseSelectStatement(sel)
+	root, err := p.parseSelectStatement(sel, &scope{planner: p})
 	if err != nil {
 		return nil, err
 	}
@@ -223,60 +430,329 @@ func (p *Planner) buildQueryPlan(sel *parser.Select) (*QueryPlan, error) {
 	return plan, nil
 }
 
-func (p *Planner) parseSelectStatement(sel *parser.Select) (Node, error) {
-	gChild, err := p.parseFromStatement(sel.From)
+// parseSelectStatement plans a query in scope s, which is nested in the scope
+// of the enclosing query for subqueries.
+func (p *Planner) parseSelectStatement(sel *parser.Select, s *scope) (Node, error) {
+	if sel.With != nil {
+		nested, err := p.parseWith(sel.With, s)
+		if err != nil {
+			return nil, err
+		}
+		s = nested
+	}
+	if sel.SetOp != nil {
+		root, err := p.parseSetOp(sel.SetOp, s)
+		if err != nil {
+			return nil, err
+		}
+		return p.parseOrderAndLimit(sel, root), nil
+	}
+	var gChild Node = &SingleRow{}
+	if sel.From != nil {
+		from, err := p.parseFromStatement(sel.From, s)
+		if err != nil {
+			return nil, err
+		}
+		gChild = from
+	}
+	if sel.Where != nil {
+		where := sel.Where
+		if sel.From != nil {
+			child, rest, err := p.parseSemiJoins(where.Expr, gChild, s)
+			if err != nil {
+				return nil, err
+			}
+			gChild = child
+			where = nil
+			if rest != nil {
+				where = &parser.Where{Expr: rest}
+			}
+		}
+		if where != nil {
+			child, err := p.parseWhereStatement(where, s)
+			if err != nil {
+				return nil, err
+			}
+			child.Child = gChild
+			gChild = child
+		}
+	}
+	if node := p.parseAggregate(sel, gChild, s); node != nil {
+		gChild = node
+	}
+	gChild = p.parseWindows(sel, gChild, s)
+	var root Node = &Projection{
+		Items: sel.Cols,
+		PlanNode: PlanNode{
+			Child: gChild,
+			scope: s,
+		},
+	}
+	if sel.Distinct != nil && len(sel.Distinct.On) > 0 {
+		return p.parseDistinctOn(sel, root)
+	}
+	if sel.Distinct != nil {
+		root = &Distinct{
+			PlanNode: PlanNode{
+				Child: root,
+			},
+		}
+	}
+	return p.parseOrderAndLimit(sel, root), nil
+}
+
+// parseOrderAndLimit sorts and limits the rows of a query.
+func (p *Planner) parseOrderAndLimit(sel *parser.Select, root Node) Node {
+	if len(sel.OrderBy) > 0 {
+		root = &Sort{
+			Items:   sel.OrderBy,
+			WorkMem: p.WorkMem,
+			PlanNode: PlanNode{
+				Child: root,
+			},
+		}
+	}
+	if sel.Limit != nil {
+		root = &Limit{
+			Count:  sel.Limit.Count,
+			Offset: sel.Limit.Offset,
+			PlanNode: PlanNode{
+				Child: root,
+			},
+		}
+	}
+	return root
+}
+
+// parseAggregate groups the input of a query that uses GROUP BY, HAVING or
+// aggregate functions. It returns nil for other queries.
+func (p *Planner) parseAggregate(sel *parser.Select, child Node, s *scope) Node {
+	exprs := make([]parser.Expr, 0, len(sel.Cols)+len(sel.OrderBy)+1)
+	for _, item := range sel.Cols {
+		exprs = append(exprs, item.Expr)
+	}
+	exprs = append(exprs, sel.Having)
+	for _, item := range sel.OrderBy {
+		exprs = append(exprs, item.Expr)
+	}
+	if sel.Distinct != nil {
+		exprs = append(exprs, sel.Distinct.On...)
+	}
+	calls := collectAggregates(exprs)
+	if len(sel.GroupBy) == 0 && sel.Having == nil && len(calls) == 0 {
+		return nil
+	}
+	var node Node = &Aggregate{
+		GroupBy: sel.GroupBy,
+		Calls:   calls,
+		Items:   sel.Cols,
+		WorkMem: p.WorkMem,
+		PlanNode: PlanNode{
+			Child: child,
+			scope: s,
+		},
+	}
+	if sel.Having != nil {
+		node = &Select{
+			Predicate: sel.Having,
+			PlanNode: PlanNode{
+				Child: node,
+				scope: s,
+			},
+		}
+	}
+	return node
+}
+
+// parseScanStatement builds the scan used by commands to locate the rows of a
+// table matching a WHERE clause.
+func (p *Planner) parseScanStatement(tableName string, where *parser.Where, s *scope) (*Table, Node, error) {
+	table, err := p.parseTableRef(tableName, tableName)
 	if err != nil {
+		return nil, nil, err
+	}
+	if where == nil {
+		return table, table, nil
+	}
+	sel, err := p.parseWhereStatement(where, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	sel.Child = table
+	return table, sel, nil
+}
+
+func (p *Planner) prepareCommand(cmd Command, child Node) (*QueryPlan, error) {
+	plan := &QueryPlan{
+		Command: cmd,
+	}
+	if err := plan.prepare(child); err != nil {
 		return nil, err
 	}
-	if sel.Where != nil {
-		child, err := p.parseWhereStatement(sel.Where)
+	if err := cmd.Prepare(); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// parseFromStatement joins the tables of a FROM clause. Comma separated tables
+// are cross joined.
+func (p *Planner) parseFromStatement(from *parser.From, s *scope) (Node, error) {
+	aliases := make(map[string]bool)
+	var res Node
+	for _, expr := range from.Tables {
+		// a function can refer to the tables listed before it
+		if fn, ok := expr.(*parser.FuncTable); ok && res != nil {
+			scan, err := p.parseFuncTable(fn, res, aliases, s)
+			if err != nil {
+				return nil, err
+			}
+			res = scan
+			continue
+		}
+		node, err := p.parseTableExpr(expr, aliases, s)
+		if err != nil {
+			return nil, err
+		}
+		if res == nil {
+			res = node
+			continue
+		}
+		res = &Join{
+			Kind:    parser.JoinCross,
+			Left:    res,
+			Right:   node,
+			Method:  p.JoinMethod,
+			WorkMem: p.WorkMem,
+			scope:   s,
+		}
+	}
+	return res, nil
+}
+
+// parseTableExpr builds the node of a table expression. Every table must be
+// referred to by a different name.
+func (p *Planner) parseTableExpr(expr parser.TableExpr, aliases map[string]bool, s *scope) (Node, error) {
+	switch e := expr.(type) {
+	case *parser.TableRef:
+		alias := e.Alias
+		if alias == "" {
+			alias = e.Name
+		}
+		if aliases[alias] {
+			return nil, fmt.Errorf("table name %s specified more than once", alias)
+		}
+		aliases[alias] = true
+		if def := s.lookupCTE(e.Name); def != nil {
+			return p.parseCTERef(def, alias, s)
+		}
+		return p.parseTableRef(e.Name, alias)
+	case *parser.DerivedTable:
+		if e.Alias == "" {
+			return nil, errors.New("subquery in FROM must have an alias")
+		}
+		if aliases[e.Alias] {
+			return nil, fmt.Errorf("table name %s specified more than once", e.Alias)
+		}
+		aliases[e.Alias] = true
+		// a subquery in FROM can't refer to the other tables of the FROM
+		// clause, only to enclosing queries
+		child, err := p.parseSelectStatement(e.Select, s)
 		if err != nil {
 			return nil, err
 		}
-		child.Child = gChild
-		return &Projection{
-			Attributes: sel.Cols,
+		return &DerivedTable{
 			PlanNode: PlanNode{
+				Alias: e.Alias,
 				Child: child,
 			},
 		}, nil
+	case *parser.FuncTable:
+		return p.parseFuncTable(e, &SingleRow{}, aliases, s)
+	case *parser.Join:
+		left, err := p.parseTableExpr(e.Left, aliases, s)
+		if err != nil {
+			return nil, err
+		}
+		right, err := p.parseTableExpr(e.Right, aliases, s)
+		if err != nil {
+			return nil, err
+		}
+		return &Join{
+			Kind:    e.Kind,
+			Cond:    e.Cond,
+			Left:    left,
+			Right:   right,
+			Method:  p.JoinMethod,
+			WorkMem: p.WorkMem,
+			scope:   s,
+		}, nil
 	}
-	return &Projection{
-		Attributes: sel.Cols,
+	return nil, fmt.Errorf("unsupported table expression %s", expr)
+}
+
+// parseFuncTable builds the scan of a set-returning function called for every
+// row of child.
+func (p *Planner) parseFuncTable(fn *parser.FuncTable, child Node, aliases map[string]bool, s *scope) (Node, error) {
+	alias := fn.Alias
+	if alias == "" {
+		alias = fn.Call.Name
+	}
+	if aliases[alias] {
+		return nil, fmt.Errorf("table name %s specified more than once", alias)
+	}
+	aliases[alias] = true
+	return &FunctionScan{
+		Call:    fn.Call,
+		aliased: fn.Alias != "",
 		PlanNode: PlanNode{
-			Child: gChild,
+			Alias: alias,
+			Child: child,
+			scope: s,
 		},
 	}, nil
 }
 
-func (p *Planner) parseFromStatement(from *parser.From) (Node, error) {
-	table, err := p.Database.GetTable(from.TableName)
+func (p *Planner) parseTableRef(name, alias string) (*Table, error) {
+	table, err := p.Database.GetTable(name)
 	if err != nil {
 		return nil, err
 	}
 	return &Table{
 		Ref: table,
+		PlanNode: PlanNode{
+			Alias: alias,
+		},
 	}, nil
 }
 
-func (p *Planner) parseWhereStatement(where *parser.Where) (*Select, error) {
-	conds := make([]*Condition, len(where.Conditions))
-	for i, pcond := range where.Conditions {
-		rel, ok := relMap[pcond.Relation]
-		if !ok {
-			return nil, fmt.Errorf("invalid relation %s", pcond.Relation)
-		}
-		conds[i] = &Condition{
-			Relation: rel,
-			LHS:      pcond.LHS,
-			RHS:      pcond.RHS,
-		}
-	}
+func (p *Planner) parseWhereStatement(where *parser.Where, s *scope) (*Select, error) {
 	return &Select{
-		Conditions: conds,
+		Predicate: where.Expr,
+		PlanNode: PlanNode{
+			scope: s,
+		},
 	}, nil
 }
 
+// IsQuery reports whether the plan produces rows rather than executing a
+// command.
+func (plan *QueryPlan) IsQuery() bool {
+	return plan.Command == nil
+}
+
+// Exec runs a command plan and returns its command tag.
+func (plan *QueryPlan) Exec() (string, error) {
+	if plan.Command == nil {
+		return "", errors.New("plan is not a command")
+	}
+	n, err := plan.Command.Exec()
+	if err != nil {
+		return "", err
+	}
+	return plan.Command.Tag(n), nil
+}
+
 func (plan *QueryPlan) Iter() index.Iterator {
 	return plan.Root.Iter()
 }
@@ -290,6 +766,16 @@ func (plan *QueryPlan) Columns() []string {
 	return res
 }
 
+// Types returns the types of the columns of a query plan.
+func (plan *QueryPlan) Types() []types.T {
+	cols := plan.Root.Columns()
+	res := make([]types.T, len(cols))
+	for i, col := range cols {
+		res[i] = col.Type
+	}
+	return res
+}
+
 func (plan *QueryPlan) prepare(node Node) error {
 	if node == nil {
 		return nil
@@ -303,6 +789,57 @@ func (plan *QueryPlan) prepare(node Node) error {
 		if err := plan.prepare(n.Child); err != nil {
 			return err
 		}
+	case *Sort:
+		if err := plan.prepare(n.Child); err != nil {
+			return err
+		}
+	case *Limit:
+		if err := plan.prepare(n.Child); err != nil {
+			return err
+		}
+	case *Distinct:
+		if err := plan.prepare(n.Child); err != nil {
+			return err
+		}
+	case *Aggregate:
+		if err := plan.prepare(n.Child); err != nil {
+			return err
+		}
+	case *Window:
+		if err := plan.prepare(n.Child); err != nil {
+			return err
+		}
+	case *DerivedTable:
+		if err := plan.prepare(n.Child); err != nil {
+			return err
+		}
+	case *FunctionScan:
+		if err := plan.prepare(n.Child); err != nil {
+			return err
+		}
+	case *SetOp:
+		if err := plan.prepare(n.Left); err != nil {
+			return err
+		}
+		if err := plan.prepare(n.Right); err != nil {
+			return err
+		}
+	case *RecursiveUnion:
+		// the recursive term reads rows shaped like those of the
+		// non-recursive term
+		if err := plan.prepare(n.Anchor); err != nil {
+			return err
+		}
+		if err := plan.prepare(n.Recursive); err != nil {
+			return err
+		}
+	case *Join:
+		if err := plan.prepare(n.Left); err != nil {
+			return err
+		}
+		if err := plan.prepare(n.Right); err != nil {
+			return err
+		}
 	default:
 	}
 	if err := node.Prepare(); err != nil {
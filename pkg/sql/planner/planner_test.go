@@ -0,0 +1,3107 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
+	"github.com/hiepd/galedb/pkg/sql"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/storage"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+func testDb() *storage.Database {
+	cols := []entity.Column{
+		{Type: types.Int, Name: "id", NotNull: true},
+		{Type: types.Text, Name: "user_type", Default: &parser.Literal{Value: "customer"}},
+		{Type: types.Text, Name: "email"},
+		{Type: types.Int, Name: "age"},
+	}
+	tbl := storage.NewPersisentTable(cols)
+	tbl.AddRow(entity.Row{Values: []entity.Value{1, "customer", "customer1@example.com", 24}})
+	tbl.AddRow(entity.Row{Values: []entity.Value{2, "driver", "driver2@example.com", 30}})
+	return &storage.Database{
+		Catalog: map[string]*storage.PersistentTable{
+			"users": tbl.(*storage.PersistentTable),
+		},
+	}
+}
+
+func tableRows(t *testing.T, db *storage.Database, name string) [][]entity.Value {
+	tbl, err := db.GetTable(name)
+	require.NoError(t, err)
+	iter := tbl.Indexes[0].Iterator()
+	res := make([][]entity.Value, 0)
+	for {
+		row, err := iter.Next()
+		if err == index.EndOfIterator {
+			break
+		}
+		require.NoError(t, err)
+		res = append(res, row.Values)
+	}
+	return res
+}
+
+func queryRows(t *testing.T, db *storage.Database, sql string) ([][]entity.Value, error) {
+	return plannerRows(t, New(db), sql)
+}
+
+func plannerRows(t *testing.T, p *Planner, sql string) ([][]entity.Value, error) {
+	stmt, err := parser.Parse(sql)
+	require.NoError(t, err)
+	plan, err := p.Prepare(stmt)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := collectRows(plan.Iter())
+	if err != nil {
+		return nil, err
+	}
+	res := make([][]entity.Value, len(rows))
+	for i, row := range rows {
+		res[i] = row.Values
+	}
+	return res, nil
+}
+
+func TestPlanner_Select(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name: "string literal",
+			sql:  "select id from users where user_type = 'driver'",
+			want: [][]entity.Value{{2}},
+		},
+		{
+			name: "or",
+			sql:  "select id from users where id = 1 or user_type = 'driver'",
+			want: [][]entity.Value{{1}, {2}},
+		},
+		{
+			name: "not",
+			sql:  "select id from users where not id = 1",
+			want: [][]entity.Value{{2}},
+		},
+		{
+			name: "nested",
+			sql:  "select id from users where id = 3 or (age = 30 and not (user_type = 'customer' or id = 1))",
+			want: [][]entity.Value{{2}},
+		},
+		{
+			name: "column to column",
+			sql:  "select id from users where user_type = email",
+			want: [][]entity.Value{},
+		},
+		{
+			name: "greater",
+			sql:  "select id from users where age > 24",
+			want: [][]entity.Value{{2}},
+		},
+		{
+			name: "greater or equal",
+			sql:  "select id from users where age >= 24",
+			want: [][]entity.Value{{1}, {2}},
+		},
+		{
+			name: "less",
+			sql:  "select id from users where 25 > age",
+			want: [][]entity.Value{{1}},
+		},
+		{
+			name: "less or equal",
+			sql:  "select id from users where age <= 30 and age < 31",
+			want: [][]entity.Value{{1}, {2}},
+		},
+		{
+			name: "not equal",
+			sql:  "select id from users where user_type <> 'driver'",
+			want: [][]entity.Value{{1}},
+		},
+		{
+			name: "not equal alias",
+			sql:  "select id from users where id != 1",
+			want: [][]entity.Value{{2}},
+		},
+		{
+			name: "string ordering",
+			sql:  "select id from users where email < 'd'",
+			want: [][]entity.Value{{1}},
+		},
+		{
+			name: "string literal as integer",
+			sql:  "select id from users where age > '25'",
+			want: [][]entity.Value{{2}},
+		},
+		{
+			name: "arithmetic",
+			sql:  "select age + 1, id * 2 as doubled, -age, age / 7, age % 7 from users where age - 5 > 20",
+			want: [][]entity.Value{{31, 4, -30, 4, 2}},
+		},
+		{
+			name: "arithmetic precedence",
+			sql:  "select 1 + 2 * 3, (1 + 2) * 3, 2 - 3 - 4, -2 * -3",
+			want: [][]entity.Value{{7, 9, -5, 6}},
+		},
+		{
+			name: "repeated column",
+			sql:  "select id, id from users where id = 1",
+			want: [][]entity.Value{{1, 1}},
+		},
+		{
+			name: "boolean expression",
+			sql:  "select id = 1 from users",
+			want: [][]entity.Value{{true}, {false}},
+		},
+		{
+			name: "star",
+			sql:  "select * from users where id = 1",
+			want: [][]entity.Value{{1, "customer", "customer1@example.com", 24}},
+		},
+		{
+			name: "qualified star",
+			sql:  "select users.*, id from users where id = 2",
+			want: [][]entity.Value{{2, "driver", "driver2@example.com", 30, 2}},
+		},
+		{
+			name: "qualified column",
+			sql:  "select users.id from users where users.age > 24",
+			want: [][]entity.Value{{2}},
+		},
+		{
+			name:    "unknown table qualifier",
+			sql:     "select orders.id from users",
+			wantErr: true,
+		},
+		{
+			name:    "unknown star qualifier",
+			sql:     "select orders.* from users",
+			wantErr: true,
+		},
+		{
+			name:    "division by zero",
+			sql:     "select id / 0 from users",
+			wantErr: true,
+		},
+		{
+			name:    "arithmetic on text",
+			sql:     "select email + 1 from users",
+			wantErr: true,
+		},
+		{
+			name:    "incomparable types",
+			sql:     "select id from users where age > email",
+			wantErr: true,
+		},
+		{
+			name:    "invalid integer literal",
+			sql:     "select id from users where age > 'old'",
+			wantErr: true,
+		},
+		{
+			name:    "unknown column",
+			sql:     "select id from users where user_type = driver",
+			wantErr: true,
+		},
+		{
+			name:    "non boolean operand",
+			sql:     "select id from users where id and age = 1",
+			wantErr: true,
+		},
+		{
+			name:    "non boolean predicate",
+			sql:     "select id from users where id",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryRows(t, testDb(), tt.sql)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func sortDb() *storage.Database {
+	db := testDb()
+	tbl := db.Catalog["users"]
+	tbl.AddRow(entity.Row{Values: []entity.Value{3, "driver", "driver3@example.com", nil}})
+	tbl.AddRow(entity.Row{Values: []entity.Value{4, "customer", "customer4@example.com", 30}})
+	tbl.AddRow(entity.Row{Values: []entity.Value{5, "customer", "customer5@example.com", 18}})
+	return db
+}
+
+func TestPlanner_Predicate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name: "in list",
+			sql:  "select id from users where age in (18, 30)",
+			want: [][]entity.Value{{2}, {4}, {5}},
+		},
+		{
+			name: "not in list",
+			sql:  "select id from users where age not in (18, 24)",
+			want: [][]entity.Value{{2}, {4}},
+		},
+		{
+			name: "in list of expressions",
+			sql:  "select id from users where 30 in (age, id * 10)",
+			want: [][]entity.Value{{2}, {3}, {4}},
+		},
+		{
+			name: "in list of strings",
+			sql:  "select id from users where user_type in ('driver', 'admin')",
+			want: [][]entity.Value{{2}, {3}},
+		},
+		{
+			name: "between",
+			sql:  "select id from users where age between 20 and 30",
+			want: [][]entity.Value{{1}, {2}, {4}},
+		},
+		{
+			name: "not between",
+			sql:  "select id from users where age not between 20 and 30",
+			want: [][]entity.Value{{5}},
+		},
+		{
+			name: "null operands",
+			sql:  "select id, age between 20 and 30, age in (30), age not in (30) from users where id > 2",
+			want: [][]entity.Value{{3, nil, nil, nil}, {4, true, true, false}, {5, false, false, true}},
+		},
+		{
+			name: "like",
+			sql:  "select id from users where email like 'customer_@%'",
+			want: [][]entity.Value{{1}, {4}, {5}},
+		},
+		{
+			name: "not like",
+			sql:  "select id from users where email not like '%4%'",
+			want: [][]entity.Value{{1}, {2}, {3}, {5}},
+		},
+		{
+			name: "ilike",
+			sql:  "select id from users where email ilike 'DRIVER%.COM'",
+			want: [][]entity.Value{{2}, {3}},
+		},
+		{
+			name: "like escape",
+			sql:  "select id, email like '%!%%' escape '!', email like '%.com' escape '' from users where id = 1",
+			want: [][]entity.Value{{1, false, true}},
+		},
+		{
+			name: "similar to",
+			sql:  "select id from users where email similar to '(driver|customer)[14]@%.com'",
+			want: [][]entity.Value{{1}, {4}},
+		},
+		{
+			name: "similar to dot",
+			sql:  "select id from users where email similar to 'customer1@example_com' and email not similar to 'customer1@example.xcom'",
+			want: [][]entity.Value{{1}},
+		},
+		{
+			name: "regex match",
+			sql:  "select id from users where email ~ '^c.*[45]@'",
+			want: [][]entity.Value{{4}, {5}},
+		},
+		{
+			name: "regex not match case insensitive",
+			sql:  "select id from users where email !~* '^D'",
+			want: [][]entity.Value{{1}, {4}, {5}},
+		},
+		{
+			name: "is null",
+			sql:  "select id from users where age is null",
+			want: [][]entity.Value{{3}},
+		},
+		{
+			name: "is not null",
+			sql:  "select id from users where age is not null and age < 25",
+			want: [][]entity.Value{{1}, {5}},
+		},
+		{
+			name: "is distinct from",
+			sql:  "select id from users where age is distinct from 30",
+			want: [][]entity.Value{{1}, {3}, {5}},
+		},
+		{
+			name: "is not distinct from",
+			sql:  "select id, age is not distinct from 30 from users where id > 3 or age is null",
+			want: [][]entity.Value{{3, false}, {4, true}, {5, false}},
+		},
+		{
+			name: "three-valued logic",
+			sql:  "select id, age > 25 and id > 3, age > 25 or id > 3, not age > 25 from users where id > 2",
+			want: [][]entity.Value{{3, false, nil, nil}, {4, true, true, false}, {5, false, true, true}},
+		},
+		{
+			name: "null literal",
+			sql:  "select null, null is null, null and 1 = 2, null or 1 = 1, 1 + null from users where id = 1",
+			want: [][]entity.Value{{nil, true, false, true, nil}},
+		},
+		{
+			name: "null never equals",
+			sql:  "select id from users where age = null or not (age <> null) or null",
+			want: [][]entity.Value{},
+		},
+		{
+			name:    "in list type mismatch",
+			sql:     "select id from users where id in (1, 'a')",
+			wantErr: true,
+		},
+		{
+			name:    "like non string",
+			sql:     "select id from users where id like '1'",
+			wantErr: true,
+		},
+		{
+			name:    "like pattern ending with escape",
+			sql:     "select id from users where email like 'a!' escape '!'",
+			wantErr: true,
+		},
+		{
+			name:    "invalid escape string",
+			sql:     "select id from users where email like 'a' escape 'ab'",
+			wantErr: true,
+		},
+		{
+			name:    "invalid regular expression",
+			sql:     "select id from users where email ~ '('",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryRows(t, sortDb(), tt.sql)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlanner_Conditional(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name: "searched case",
+			sql:  "select id, case when age < 20 then 'young' when age < 30 then 'adult' else 'senior' end from users",
+			want: [][]entity.Value{{1, "adult"}, {2, "senior"}, {3, "senior"}, {4, "senior"}, {5, "young"}},
+		},
+		{
+			name: "simple case without else",
+			sql:  "select id, case user_type when 'driver' then 1 when 'admin' then 2 end from users where id < 4",
+			want: [][]entity.Value{{1, nil}, {2, 1}, {3, 1}},
+		},
+		{
+			name: "case results converted",
+			sql:  "select case when id = 1 then '10' else id end from users where id < 3",
+			want: [][]entity.Value{{10}, {2}},
+		},
+		{
+			name: "case in where",
+			sql:  "select id from users where case user_type when 'driver' then age is null else age > 25 end",
+			want: [][]entity.Value{{3}, {4}},
+		},
+		{
+			name: "case in order by",
+			sql:  "select id from users order by case when age is null then 0 else age end desc, id",
+			want: [][]entity.Value{{2}, {4}, {1}, {5}, {3}},
+		},
+		{
+			name: "case in group by",
+			sql:  "select case when age >= 25 then 'old' else 'young' end, count(*) from users group by case when age >= 25 then 'old' else 'young' end order by 1",
+			want: [][]entity.Value{{"old", 2}, {"young", 3}},
+		},
+		{
+			name: "case in aggregate",
+			sql:  "select sum(case user_type when 'driver' then 1 else 0 end) from users",
+			want: [][]entity.Value{{2}},
+		},
+		{
+			name: "coalesce",
+			sql:  "select id, coalesce(age, -1) from users where id > 2",
+			want: [][]entity.Value{{3, -1}, {4, 30}, {5, 18}},
+		},
+		{
+			name: "coalesce stops at first value",
+			sql:  "select coalesce(id, 1 / 0) from users where id = 1",
+			want: [][]entity.Value{{1}},
+		},
+		{
+			name: "nullif",
+			sql:  "select id, nullif(age, 30) from users where id < 5",
+			want: [][]entity.Value{{1, 24}, {2, nil}, {3, nil}, {4, nil}},
+		},
+		{
+			name: "greatest and least",
+			sql:  "select greatest(id * 10, age), least(id * 10, age, null) from users where id > 2",
+			want: [][]entity.Value{{30, 30}, {40, 30}, {50, 18}},
+		},
+		{
+			name:    "condition not boolean",
+			sql:     "select case when age then 1 end from users",
+			wantErr: true,
+		},
+		{
+			name:    "case types mismatch",
+			sql:     "select case when age > 1 then id else email end from users",
+			wantErr: true,
+		},
+		{
+			name:    "case result not integer",
+			sql:     "select case when age > 1 then 1 else 'x' end from users",
+			wantErr: true,
+		},
+		{
+			name:    "coalesce types mismatch",
+			sql:     "select coalesce(age, email) from users",
+			wantErr: true,
+		},
+		{
+			name:    "nullif argument count",
+			sql:     "select nullif(age) from users",
+			wantErr: true,
+		},
+		{
+			name:    "greatest without arguments",
+			sql:     "select greatest() from users",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryRows(t, sortDb(), tt.sql)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlanner_Functions(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name: "string functions",
+			sql:  "select upper(user_type), length(email) from users where id < 3",
+			want: [][]entity.Value{{"CUSTOMER", 21}, {"DRIVER", 19}},
+		},
+		{
+			name: "in where",
+			sql:  "select id from users where split_part(email, '@', 1) <> concat(user_type, id)",
+			want: [][]entity.Value{},
+		},
+		{
+			name: "in order by",
+			sql:  "select id from users order by mod(id, 3), id",
+			want: [][]entity.Value{{3}, {1}, {4}, {2}, {5}},
+		},
+		{
+			name: "in group by",
+			sql:  "select substring(email, 1, 1), count(*) from users group by substring(email, 1, 1) order by 1",
+			want: [][]entity.Value{{"c", 3}, {"d", 2}},
+		},
+		{
+			name: "null argument",
+			sql:  "select id, abs(age), concat(age, '!') from users where id = 3",
+			want: [][]entity.Value{{3, nil, "!"}},
+		},
+		{
+			name: "integer converted to float",
+			sql:  "select power(id, 2), round(age) from users where id = 2",
+			want: [][]entity.Value{{4.0, 30.0}},
+		},
+		{
+			name:    "wrong argument type",
+			sql:     "select lower(id) from users",
+			wantErr: true,
+		},
+		{
+			name:    "unknown function",
+			sql:     "select nosuch(id) from users",
+			wantErr: true,
+		},
+		{
+			name:    "star argument",
+			sql:     "select lower(*) from users",
+			wantErr: true,
+		},
+		{
+			name:    "distinct argument",
+			sql:     "select lower(distinct email) from users",
+			wantErr: true,
+		},
+		{
+			name:    "constant call fails when planned",
+			sql:     "select id from users where id > 10 and mod(1, 0) > 0",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryRows(t, sortDb(), tt.sql)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlanner_RegisterFunction(t *testing.T) {
+	calls := 0
+	require.NoError(t, sql.RegisterFunction(&sql.Function{
+		Name:       "test_double",
+		Args:       []types.T{types.Int},
+		Return:     types.Int,
+		Volatility: sql.Immutable,
+		Strict:     true,
+		Impl: func(args []entity.Value) (entity.Value, error) {
+			calls++
+			return args[0].(int) * 2, nil
+		},
+	}))
+	got, err := queryRows(t, sortDb(), "select test_double(id) from users where test_double(id) > 6")
+	require.NoError(t, err)
+	assert.Equal(t, [][]entity.Value{{8}, {10}}, got)
+	assert.Equal(t, 7, calls)
+
+	// called once when the statement is planned
+	calls = 0
+	got, err = queryRows(t, sortDb(), "select test_double(21) from users")
+	require.NoError(t, err)
+	assert.Len(t, got, 5)
+	assert.Equal(t, []entity.Value{42}, got[0])
+	assert.Equal(t, 1, calls)
+}
+
+func TestPlanner_Sort(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name: "ascending",
+			sql:  "select id from users order by age",
+			want: [][]entity.Value{{5}, {1}, {2}, {4}, {3}},
+		},
+		{
+			name: "descending",
+			sql:  "select id from users order by age desc",
+			want: [][]entity.Value{{3}, {2}, {4}, {1}, {5}},
+		},
+		{
+			name: "nulls first",
+			sql:  "select id from users order by age asc nulls first",
+			want: [][]entity.Value{{3}, {5}, {1}, {2}, {4}},
+		},
+		{
+			name: "nulls last",
+			sql:  "select id from users order by age desc nulls last",
+			want: [][]entity.Value{{2}, {4}, {1}, {5}, {3}},
+		},
+		{
+			name: "multiple keys",
+			sql:  "select id, user_type from users order by user_type desc, id desc",
+			want: [][]entity.Value{{3, "driver"}, {2, "driver"}, {5, "customer"}, {4, "customer"}, {1, "customer"}},
+		},
+		{
+			name: "ordinal",
+			sql:  "select email, id from users where age > 20 order by 2 desc",
+			want: [][]entity.Value{{"customer4@example.com", 4}, {"driver2@example.com", 2}, {"customer1@example.com", 1}},
+		},
+		{
+			name: "output alias",
+			sql:  "select id, -age as n from users where age > 20 order by n, id",
+			want: [][]entity.Value{{2, -30}, {4, -30}, {1, -24}},
+		},
+		{
+			name: "expression not in select list",
+			sql:  "select id from users where age > 0 order by age % 7, id",
+			want: [][]entity.Value{{2}, {4}, {1}, {5}},
+		},
+		{
+			name: "qualified column",
+			sql:  "select id from users order by users.email",
+			want: [][]entity.Value{{1}, {4}, {5}, {2}, {3}},
+		},
+		{
+			name:    "ordinal out of range",
+			sql:     "select id from users order by 2",
+			wantErr: true,
+		},
+		{
+			name:    "unknown column",
+			sql:     "select id from users order by name",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryRows(t, sortDb(), tt.sql)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlanner_SortSpill(t *testing.T) {
+	db := sortDb()
+	tbl := db.Catalog["users"]
+	for i := 6; i <= 100; i++ {
+		tbl.AddRow(entity.Row{Values: []entity.Value{i, "customer", "", i % 10}})
+	}
+	want, err := queryRows(t, db, "select id, age from users order by age desc, id")
+	require.NoError(t, err)
+	p := New(db)
+	p.WorkMem = 200
+	got, err := plannerRows(t, p, "select id, age from users order by age desc, id")
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Len(t, got, 100)
+	assert.Equal(t, []entity.Value{3, nil}, got[0])
+	assert.Equal(t, []entity.Value{2, 30}, got[1])
+}
+
+func TestPlanner_Limit(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name: "limit",
+			sql:  "select id from users limit 2",
+			want: [][]entity.Value{{1}, {2}},
+		},
+		{
+			name: "limit and offset",
+			sql:  "select id from users limit 2 offset 3",
+			want: [][]entity.Value{{4}, {5}},
+		},
+		{
+			name: "offset before limit",
+			sql:  "select id from users offset 4 limit 2",
+			want: [][]entity.Value{{5}},
+		},
+		{
+			name: "offset only",
+			sql:  "select id from users offset 3 rows",
+			want: [][]entity.Value{{4}, {5}},
+		},
+		{
+			name: "limit zero",
+			sql:  "select id from users limit 0",
+			want: [][]entity.Value{},
+		},
+		{
+			name: "limit all",
+			sql:  "select id from users limit all offset 4",
+			want: [][]entity.Value{{5}},
+		},
+		{
+			name: "limit expression",
+			sql:  "select id from users limit 1 + 1",
+			want: [][]entity.Value{{1}, {2}},
+		},
+		{
+			name: "fetch first",
+			sql:  "select id from users offset 1 row fetch first 2 rows only",
+			want: [][]entity.Value{{2}, {3}},
+		},
+		{
+			name: "fetch next without count",
+			sql:  "select id from users fetch next row only",
+			want: [][]entity.Value{{1}},
+		},
+		{
+			name: "top n",
+			sql:  "select id, age from users order by age desc nulls last limit 3",
+			want: [][]entity.Value{{2, 30}, {4, 30}, {1, 24}},
+		},
+		{
+			name: "top n with offset",
+			sql:  "select id from users order by id desc limit 2 offset 1",
+			want: [][]entity.Value{{4}, {3}},
+		},
+		{
+			name: "top n larger than input",
+			sql:  "select id from users order by id desc limit 10",
+			want: [][]entity.Value{{5}, {4}, {3}, {2}, {1}},
+		},
+		{
+			name:    "negative limit",
+			sql:     "select id from users limit -1",
+			wantErr: true,
+		},
+		{
+			name:    "negative offset",
+			sql:     "select id from users offset -1",
+			wantErr: true,
+		},
+		{
+			name:    "column in limit",
+			sql:     "select id from users limit id",
+			wantErr: true,
+		},
+		{
+			name:    "text limit",
+			sql:     "select id from users limit 'a'",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryRows(t, sortDb(), tt.sql)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// failingNode produces n rows and then fails.
+type failingNode struct {
+	n int
+}
+
+type failingIter struct {
+	n int
+}
+
+func (f *failingNode) Iter() index.Iterator {
+	return &failingIter{n: f.n}
+}
+func (f *failingNode) Columns() []entity.Column {
+	return []entity.Column{{Type: types.Int, Name: "n"}}
+}
+func (f *failingNode) Prepare() error {
+	return nil
+}
+
+func (iter *failingIter) Next() (entity.Row, error) {
+	if iter.n == 0 {
+		return entity.Row{}, errors.New("read past limit")
+	}
+	iter.n--
+	return entity.Row{Values: []entity.Value{iter.n}}, nil
+}
+
+func TestLimit_EarlyTermination(t *testing.T) {
+	lim := &Limit{
+		Count:  &parser.Literal{Value: 2},
+		Offset: &parser.Literal{Value: 1},
+		PlanNode: PlanNode{
+			Child: &failingNode{n: 3},
+		},
+	}
+	require.NoError(t, lim.Prepare())
+	rows, err := collectRows(lim.Iter())
+	require.NoError(t, err)
+	assert.Len(t, rows, 2)
+}
+
+func TestPlanner_TopNSpill(t *testing.T) {
+	db := sortDb()
+	tbl := db.Catalog["users"]
+	for i := 6; i <= 100; i++ {
+		tbl.AddRow(entity.Row{Values: []entity.Value{i, "customer", "", i % 10}})
+	}
+	sql := "select id, age from users order by age, id desc limit 20 offset 5"
+	want, err := queryRows(t, db, sql)
+	require.NoError(t, err)
+	assert.Len(t, want, 20)
+	p := New(db)
+	p.WorkMem = 200
+	got, err := plannerRows(t, p, sql)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, []entity.Value{10, 0}, want[4])
+	assert.Equal(t, []entity.Value{50, 0}, want[0])
+}
+
+func TestPlanner_Distinct(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name: "distinct",
+			sql:  "select distinct user_type from users order by user_type",
+			want: [][]entity.Value{{"customer"}, {"driver"}},
+		},
+		{
+			name: "distinct with nulls",
+			sql:  "select distinct age from users order by age",
+			want: [][]entity.Value{{18}, {24}, {30}, {nil}},
+		},
+		{
+			name: "distinct rows",
+			sql:  "select distinct user_type, age from users where age > 20 order by 1, 2",
+			want: [][]entity.Value{{"customer", 24}, {"customer", 30}, {"driver", 30}},
+		},
+		{
+			name: "distinct with limit",
+			sql:  "select count(*) from (select distinct user_type from users limit 5) t",
+			want: [][]entity.Value{{2}},
+		},
+		{
+			name: "distinct on",
+			sql:  "select distinct on (user_type) user_type, id from users order by user_type, age desc",
+			want: [][]entity.Value{{"customer", 4}, {"driver", 3}},
+		},
+		{
+			name: "distinct on expression outside select list",
+			sql:  "select distinct on (age / 10) id from users order by age / 10, id",
+			want: [][]entity.Value{{5}, {1}, {2}, {3}},
+		},
+		{
+			name: "distinct on without order by",
+			sql:  "select distinct on (user_type) user_type from users",
+			want: [][]entity.Value{{"customer"}, {"driver"}},
+		},
+		{
+			name: "distinct on in other order",
+			sql:  "select distinct on (user_type, age) age, user_type from users order by age, user_type",
+			want: [][]entity.Value{{18, "customer"}, {24, "customer"}, {30, "customer"}, {30, "driver"}, {nil, "driver"}},
+		},
+		{
+			name: "distinct on with limit",
+			sql:  "select distinct on (user_type) id from users order by user_type, id desc limit 1",
+			want: [][]entity.Value{{5}},
+		},
+		{
+			name: "distinct aggregate groups",
+			sql:  "select distinct count(*) from users group by age order by 1",
+			want: [][]entity.Value{{1}, {2}},
+		},
+		{
+			name:    "distinct order by outside select list",
+			sql:     "select distinct user_type from users order by age",
+			wantErr: true,
+		},
+		{
+			name:    "distinct on not leading order by",
+			sql:     "select distinct on (user_type) id from users order by id",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryRows(t, sortDb(), tt.sql)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlanner_DistinctOnSpill(t *testing.T) {
+	db := sortDb()
+	tbl := db.Catalog["users"]
+	for i := 6; i <= 100; i++ {
+		tbl.AddRow(entity.Row{Values: []entity.Value{i, "customer", "", i % 10}})
+	}
+	sql := "select distinct on (age) id from users order by age, id desc"
+	want, err := queryRows(t, db, sql)
+	require.NoError(t, err)
+	p := New(db)
+	p.WorkMem = 200
+	got, err := plannerRows(t, p, sql)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Len(t, got, 14)
+	assert.Equal(t, []entity.Value{100}, got[0])
+}
+
+func TestPlanner_Aggregate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name: "aggregates without group by",
+			sql:  "select count(*), count(age), sum(age), min(age), max(email), avg(age) from users",
+			want: [][]entity.Value{{5, 4, 102, 18, "driver3@example.com", types.NewDecimal(255000000000000000, 16)}},
+		},
+		{
+			name: "empty input",
+			sql:  "select count(*), sum(age), avg(age), max(age) from users where id > 10",
+			want: [][]entity.Value{{0, nil, nil, nil}},
+		},
+		{
+			name: "group by",
+			sql:  "select user_type, count(*), sum(age) from users group by user_type",
+			want: [][]entity.Value{{"customer", 3, 72}, {"driver", 2, 30}},
+		},
+		{
+			name: "group by with empty input",
+			sql:  "select user_type, count(*) from users where id > 10 group by user_type",
+			want: [][]entity.Value{},
+		},
+		{
+			name: "count distinct",
+			sql:  "select count(distinct age), count(distinct user_type), sum(distinct age) from users",
+			want: [][]entity.Value{{3, 2, 72}},
+		},
+		{
+			name: "null group",
+			sql:  "select age, count(*) from users group by age order by age",
+			want: [][]entity.Value{{18, 1}, {24, 1}, {30, 2}, {nil, 1}},
+		},
+		{
+			name: "having",
+			sql:  "select user_type from users group by user_type having count(*) > 2",
+			want: [][]entity.Value{{"customer"}},
+		},
+		{
+			name: "having on average",
+			sql:  "select user_type, avg(age) from users group by user_type having avg(age) > 24",
+			want: [][]entity.Value{{"driver", types.NewDecimal(300000000000000000, 16)}},
+		},
+		{
+			name: "having without group by",
+			sql:  "select count(*) from users having max(age) > 100",
+			want: [][]entity.Value{},
+		},
+		{
+			name: "expressions over aggregates",
+			sql:  "select user_type, sum(age) - count(age) * 10 as n from users group by user_type order by sum(age) desc",
+			want: [][]entity.Value{{"customer", 42}, {"driver", 20}},
+		},
+		{
+			name: "group by expression",
+			sql:  "select age / 10, count(*) from users where age > 0 group by age / 10 order by 1",
+			want: [][]entity.Value{{1, 1}, {2, 1}, {3, 2}},
+		},
+		{
+			name: "group by position and alias",
+			sql:  "select user_type as t, age % 2 as parity, count(*) from users group by t, 2 order by t, parity",
+			want: [][]entity.Value{{"customer", 0, 3}, {"driver", 0, 1}, {"driver", nil, 1}},
+		},
+		{
+			name: "qualified group column",
+			sql:  "select users.user_type, count(*) from users group by user_type order by 1",
+			want: [][]entity.Value{{"customer", 3}, {"driver", 2}},
+		},
+		{
+			name: "star over grouped columns",
+			sql:  "select * from users group by id, user_type, email, age order by id limit 1",
+			want: [][]entity.Value{{1, "customer", "customer1@example.com", 24}},
+		},
+		{
+			name:    "ungrouped column",
+			sql:     "select user_type, age from users group by user_type",
+			wantErr: true,
+		},
+		{
+			name:    "ungrouped column in order by",
+			sql:     "select user_type from users group by user_type order by age",
+			wantErr: true,
+		},
+		{
+			name:    "aggregate in where",
+			sql:     "select id from users where count(*) > 1",
+			wantErr: true,
+		},
+		{
+			name:    "nested aggregate",
+			sql:     "select sum(count(*)) from users",
+			wantErr: true,
+		},
+		{
+			name:    "sum of text",
+			sql:     "select sum(email) from users",
+			wantErr: true,
+		},
+		{
+			name:    "group by position out of range",
+			sql:     "select user_type from users group by 2",
+			wantErr: true,
+		},
+		{
+			name:    "unknown function",
+			sql:     "select median(age) from users",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryRows(t, sortDb(), tt.sql)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlanner_AggregateSpill(t *testing.T) {
+	db := sortDb()
+	tbl := db.Catalog["users"]
+	for i := 6; i <= 500; i++ {
+		tbl.AddRow(entity.Row{Values: []entity.Value{i, "customer", fmt.Sprintf("user%d", i%97), i % 7}})
+	}
+	sql := "select email, count(*), sum(age), count(distinct age) from users group by email order by email"
+	want, err := queryRows(t, db, sql)
+	require.NoError(t, err)
+	assert.Len(t, want, 102)
+	p := New(db)
+	p.WorkMem = 500
+	got, err := plannerRows(t, p, sql)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestPlanner_NumericSpill(t *testing.T) {
+	db := typesDb(t)
+	tbl := db.Catalog["prices"]
+	for i := 0; i < 300; i++ {
+		tbl.AddRow(entity.Row{Values: []entity.Value{"n", types.NewDecimal(int64(i*37%1000), 2), nil, i % 7, i, true}})
+	}
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{name: "order by", sql: "select amount, total from prices order by amount, total"},
+		{name: "group by", sql: "select amount, count(*) from prices group by amount order by amount"},
+		{name: "sum", sql: "select qty, sum(amount), max(amount) from prices group by qty order by qty"},
+		{name: "window", sql: "select total, sum(amount) over (partition by qty order by total) from prices order by total"},
+		{name: "join", sql: "select a.total, b.total from prices a join prices b on a.amount = b.amount order by a.total, b.total"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := queryRows(t, db, tt.sql)
+			require.NoError(t, err)
+			p := New(db)
+			p.WorkMem = 200
+			got, err := plannerRows(t, p, tt.sql)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func joinDb() *storage.Database {
+	db := sortDb()
+	cols := []entity.Column{
+		{Type: types.Int, Name: "id"},
+		{Type: types.Int, Name: "user_id"},
+		{Type: types.Text, Name: "item"},
+	}
+	tbl := storage.NewPersisentTable(cols)
+	tbl.AddRow(entity.Row{Values: []entity.Value{1, 1, "book"}})
+	tbl.AddRow(entity.Row{Values: []entity.Value{2, 1, "pen"}})
+	tbl.AddRow(entity.Row{Values: []entity.Value{3, 2, "cup"}})
+	tbl.AddRow(entity.Row{Values: []entity.Value{4, 9, "hat"}})
+	tbl.AddRow(entity.Row{Values: []entity.Value{5, nil, "map"}})
+	tbl.AddRow(entity.Row{Values: []entity.Value{6, 2, "ink"}})
+	db.Catalog["orders"] = tbl.(*storage.PersistentTable)
+	return db
+}
+
+// joinMethods are the settings join tests run with.
+var joinMethods = map[string]struct {
+	method  JoinMethod
+	workMem int
+}{
+	"auto":        {JoinAuto, DefaultWorkMem},
+	"nested loop": {JoinNestedLoop, DefaultWorkMem},
+	"hash":        {JoinHash, DefaultWorkMem},
+	"merge":       {JoinMerge, DefaultWorkMem},
+	"merge spill": {JoinMerge, 64},
+}
+
+func TestPlanner_Join(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name: "inner",
+			sql:  "select u.id, o.item from users u join orders o on u.id = o.user_id order by o.item",
+			want: [][]entity.Value{{1, "book"}, {2, "cup"}, {2, "ink"}, {1, "pen"}},
+		},
+		{
+			name: "left",
+			sql:  "select u.id, o.item from users as u left outer join orders as o on u.id = o.user_id order by u.id, o.item",
+			want: [][]entity.Value{{1, "book"}, {1, "pen"}, {2, "cup"}, {2, "ink"}, {3, nil}, {4, nil}, {5, nil}},
+		},
+		{
+			name: "right",
+			sql:  "select u.id, o.item from users u right join orders o on o.user_id = u.id order by o.item",
+			want: [][]entity.Value{{1, "book"}, {2, "cup"}, {nil, "hat"}, {2, "ink"}, {nil, "map"}, {1, "pen"}},
+		},
+		{
+			name: "full",
+			sql:  "select u.id, o.item from users u full join orders o on u.id = o.user_id order by u.id, o.item",
+			want: [][]entity.Value{{1, "book"}, {1, "pen"}, {2, "cup"}, {2, "ink"}, {3, nil}, {4, nil}, {5, nil}, {nil, "hat"}, {nil, "map"}},
+		},
+		{
+			name: "comma",
+			sql:  "select count(*) from users, orders",
+			want: [][]entity.Value{{30}},
+		},
+		{
+			name: "cross",
+			sql:  "select count(*) from users cross join orders",
+			want: [][]entity.Value{{30}},
+		},
+		{
+			name: "comma with where",
+			sql:  "select users.id, orders.item from users, orders where users.id = orders.user_id and orders.item <> 'pen' order by 2",
+			want: [][]entity.Value{{1, "book"}, {2, "cup"}, {2, "ink"}},
+		},
+		{
+			name: "using",
+			sql:  "select * from users join orders using (id) order by id limit 2",
+			want: [][]entity.Value{
+				{1, "customer", "customer1@example.com", 24, 1, "book"},
+				{2, "driver", "driver2@example.com", 30, 1, "pen"},
+			},
+		},
+		{
+			name: "natural",
+			sql:  "select id, item from users natural join orders where id > 3 order by id",
+			want: [][]entity.Value{{4, "hat"}, {5, "map"}},
+		},
+		{
+			name: "full using",
+			sql:  "select id, u.id, o.id from users u full join orders o using (id) order by 1",
+			want: [][]entity.Value{{1, 1, 1}, {2, 2, 2}, {3, 3, 3}, {4, 4, 4}, {5, 5, 5}, {6, nil, 6}},
+		},
+		{
+			name: "qualified star over using",
+			sql:  "select o.* from users u join orders o using (id) where u.id = 3",
+			want: [][]entity.Value{{3, 2, "cup"}},
+		},
+		{
+			name: "residual condition",
+			sql:  "select u.id, o.item from users u join orders o on u.id = o.user_id and o.item > u.user_type order by o.item",
+			want: [][]entity.Value{{2, "ink"}, {1, "pen"}},
+		},
+		{
+			name: "left join with residual condition",
+			sql:  "select u.id, o.item from users u left join orders o on u.id = o.user_id and o.item = 'pen' where u.id < 3 order by u.id",
+			want: [][]entity.Value{{1, "pen"}, {2, nil}},
+		},
+		{
+			name: "non equi join",
+			sql:  "select u.id, o.id from users u join orders o on u.id > o.id and o.id > 3 order by 1, 2",
+			want: [][]entity.Value{{5, 4}},
+		},
+		{
+			name: "three tables",
+			sql:  "select u.id, o.item, p.item from users u join orders o on u.id = o.user_id join orders p on p.id = o.id order by o.item",
+			want: [][]entity.Value{{1, "book", "book"}, {2, "cup", "cup"}, {2, "ink", "ink"}, {1, "pen", "pen"}},
+		},
+		{
+			name: "parenthesized join",
+			sql:  "select count(*) from orders p, (users u join orders o on u.id = o.user_id)",
+			want: [][]entity.Value{{24}},
+		},
+		{
+			name: "grouped join",
+			sql:  "select u.user_type, count(o.id) from users u left join orders o on u.id = o.user_id group by u.user_type order by 1",
+			want: [][]entity.Value{{"customer", 2}, {"driver", 2}},
+		},
+		{
+			name:    "ambiguous column",
+			sql:     "select id from users join orders on users.id = orders.id",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate table name",
+			sql:     "select * from users, users",
+			wantErr: true,
+		},
+		{
+			name:    "unknown using column",
+			sql:     "select * from users join orders using (email)",
+			wantErr: true,
+		},
+		{
+			name:    "table name hidden by alias",
+			sql:     "select users.id from users u",
+			wantErr: true,
+		},
+		{
+			name:    "non boolean condition",
+			sql:     "select * from users u join orders o on u.id",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		for name, m := range joinMethods {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				p := New(joinDb())
+				p.JoinMethod = m.method
+				p.WorkMem = m.workMem
+				got, err := plannerRows(t, p, tt.sql)
+				if tt.wantErr {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			})
+		}
+	}
+}
+
+func TestPlanner_Subquery(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name: "in",
+			sql:  "select id from users where id in (select user_id from orders) order by id",
+			want: [][]entity.Value{{1}, {2}},
+		},
+		{
+			name: "in with expression",
+			sql:  "select id from users where id + 1 in (select user_id from orders)",
+			want: [][]entity.Value{{1}},
+		},
+		{
+			name: "not in",
+			sql:  "select id from users where id not in (select user_id from orders where user_id > 0) order by id",
+			want: [][]entity.Value{{3}, {4}, {5}},
+		},
+		{
+			name: "not in with null",
+			sql:  "select id from users where id not in (select user_id from orders)",
+			want: [][]entity.Value{},
+		},
+		{
+			name: "not in empty subquery",
+			sql:  "select count(*) from users where id not in (select user_id from orders where item = 'none')",
+			want: [][]entity.Value{{5}},
+		},
+		{
+			name: "exists",
+			sql:  "select id from users u where exists (select 1 from orders o where o.user_id = u.id) order by id",
+			want: [][]entity.Value{{1}, {2}},
+		},
+		{
+			name: "not exists",
+			sql:  "select id from users u where not exists (select * from orders o where o.user_id = u.id and o.item <> 'pen') order by id",
+			want: [][]entity.Value{{3}, {4}, {5}},
+		},
+		{
+			name: "exists with condition on outer row",
+			sql:  "select id from users u where exists (select 1 from orders o where o.user_id = u.id and u.age > 25)",
+			want: [][]entity.Value{{2}},
+		},
+		{
+			name: "inner columns hide outer ones",
+			sql:  "select count(*) from users where exists (select 1 from orders where user_id = id and item = 'book')",
+			want: [][]entity.Value{{5}},
+		},
+		{
+			name: "exists under or",
+			sql:  "select id from users u where u.id = 5 or exists (select 1 from orders o where o.user_id = u.id) order by id",
+			want: [][]entity.Value{{1}, {2}, {5}},
+		},
+		{
+			name: "correlated scalar subquery",
+			sql:  "select u.id, (select count(*) from orders o where o.user_id = u.id) from users u order by 1",
+			want: [][]entity.Value{{1, 2}, {2, 2}, {3, 0}, {4, 0}, {5, 0}},
+		},
+		{
+			name: "scalar subquery in where",
+			sql:  "select id from users where id = (select min(user_id) from orders)",
+			want: [][]entity.Value{{1}},
+		},
+		{
+			name: "scalar subquery without rows",
+			sql:  "select (select id from orders where id > 10) from users where id = 1",
+			want: [][]entity.Value{{nil}},
+		},
+		{
+			name: "subquery without from",
+			sql:  "select id, (select u.age + 1) from users u where id < 3 order by id",
+			want: [][]entity.Value{{1, 25}, {2, 31}},
+		},
+		{
+			name: "grouped outer query",
+			sql:  "select u.user_type, (select count(*) from orders o where o.user_id in (select id from users v where v.user_type = u.user_type)) from users u group by u.user_type order by 1",
+			want: [][]entity.Value{{"customer", 2}, {"driver", 2}},
+		},
+		{
+			name: "derived table",
+			sql:  "select t.n, count(*) from (select user_id as n from orders) t group by t.n order by 1",
+			want: [][]entity.Value{{1, 2}, {2, 2}, {9, 1}, {nil, 1}},
+		},
+		{
+			name: "joined derived table",
+			sql:  "select u.id, t.total from users u join (select user_id, count(*) as total from orders group by user_id) as t on t.user_id = u.id order by 1",
+			want: [][]entity.Value{{1, 2}, {2, 2}},
+		},
+		{
+			name:    "more than one row",
+			sql:     "select (select id from orders) from users",
+			wantErr: true,
+		},
+		{
+			name:    "more than one column",
+			sql:     "select (select id, item from orders) from users",
+			wantErr: true,
+		},
+		{
+			name:    "in with mismatched kinds",
+			sql:     "select id from users where id in (select item from orders)",
+			wantErr: true,
+		},
+		{
+			name:    "derived table without alias",
+			sql:     "select * from (select 1)",
+			wantErr: true,
+		},
+		{
+			name:    "derived table referring to sibling",
+			sql:     "select * from users u, (select u.id) t",
+			wantErr: true,
+		},
+		{
+			name:    "ungrouped outer column",
+			sql:     "select (select u.age) from users u group by u.user_type",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		for name, m := range joinMethods {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				p := New(joinDb())
+				p.JoinMethod = m.method
+				p.WorkMem = m.workMem
+				got, err := plannerRows(t, p, tt.sql)
+				if tt.wantErr {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			})
+		}
+	}
+}
+
+func TestPlanner_SemiJoin(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want parser.JoinKind
+		// joined is unset when the subquery runs for every row
+		joined bool
+	}{
+		{
+			name:   "exists",
+			sql:    "select id from users u where exists (select 1 from orders o where o.user_id = u.id)",
+			want:   parser.JoinSemi,
+			joined: true,
+		},
+		{
+			name:   "not exists",
+			sql:    "select id from users u where not exists (select 1 from orders o where o.user_id = u.id)",
+			want:   parser.JoinAnti,
+			joined: true,
+		},
+		{
+			name:   "in",
+			sql:    "select id from users where id in (select user_id from orders)",
+			want:   parser.JoinSemi,
+			joined: true,
+		},
+		{
+			name: "not in",
+			sql:  "select id from users where id not in (select user_id from orders)",
+		},
+		{
+			name: "aggregated subquery",
+			sql:  "select id from users u where exists (select count(*) from orders o where o.user_id = u.id)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := parser.Parse(tt.sql)
+			require.NoError(t, err)
+			plan, err := New(joinDb()).Prepare(stmt)
+			require.NoError(t, err)
+			join, ok := plan.Root.(*Projection).Child.(*Join)
+			require.Equal(t, tt.joined, ok)
+			if ok {
+				assert.Equal(t, tt.want, join.Kind)
+			}
+		})
+	}
+}
+
+func TestPlanner_JoinMethod(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		workMem int
+		want    Node
+	}{
+		{
+			name:    "equality",
+			sql:     "select * from users u join orders o on u.id = o.user_id",
+			workMem: DefaultWorkMem,
+			want:    &HashJoin{},
+		},
+		{
+			name:    "right input too large",
+			sql:     "select * from users u join orders o on u.id = o.user_id",
+			workMem: 100,
+			want:    &MergeJoin{},
+		},
+		{
+			name:    "no equality",
+			sql:     "select * from users u join orders o on u.id < o.user_id",
+			workMem: DefaultWorkMem,
+			want:    &NestedLoopJoin{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := parser.Parse(tt.sql)
+			require.NoError(t, err)
+			p := New(joinDb())
+			p.WorkMem = tt.workMem
+			plan, err := p.Prepare(stmt)
+			require.NoError(t, err)
+			join := plan.Root.(*Projection).Child.(*Join)
+			assert.IsType(t, tt.want, join.impl)
+		})
+	}
+}
+
+func TestPlanner_SetOp(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name: "union",
+			sql:  "select id from users where id < 3 union select user_id from orders order by 1",
+			want: [][]entity.Value{{1}, {2}, {9}, {nil}},
+		},
+		{
+			name: "union all",
+			sql:  "select user_id from orders where item < 'd' union all select id from users where id = 1 order by 1",
+			want: [][]entity.Value{{1}, {1}, {2}},
+		},
+		{
+			name: "intersect",
+			sql:  "select id from users intersect select user_id from orders order by 1",
+			want: [][]entity.Value{{1}, {2}},
+		},
+		{
+			name: "intersect all",
+			sql:  "select user_id from orders intersect all select user_id from orders where item <> 'pen' order by 1",
+			want: [][]entity.Value{{1}, {2}, {2}, {9}, {nil}},
+		},
+		{
+			name: "except",
+			sql:  "select age from users except select age from users where id > 3 order by 1",
+			want: [][]entity.Value{{24}, {nil}},
+		},
+		{
+			name: "except all",
+			sql:  "select user_id from orders except all select id from users order by 1",
+			want: [][]entity.Value{{1}, {2}, {9}, {nil}},
+		},
+		{
+			name: "intersect binds tighter than union",
+			sql:  "select 5 union select id from users intersect select user_id from orders order by 1",
+			want: [][]entity.Value{{1}, {2}, {5}},
+		},
+		{
+			name: "order by name and limit",
+			sql:  "select id, age from users union all select user_id, id from orders order by age desc, id limit 2 offset 1",
+			want: [][]entity.Value{{2, 30}, {4, 30}},
+		},
+		{
+			name: "in subquery",
+			sql:  "select id from users where id in (select user_id from orders except select 2) order by id",
+			want: [][]entity.Value{{1}},
+		},
+		{
+			name:    "column count mismatch",
+			sql:     "select id from users union select id, age from users",
+			wantErr: true,
+		},
+		{
+			name:    "type mismatch",
+			sql:     "select id from users except select email from users",
+			wantErr: true,
+		},
+		{
+			name:    "order by expression",
+			sql:     "select id from users union select user_id from orders order by id + 1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryRows(t, joinDb(), tt.sql)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// cteDb adds an org chart to joinDb, where boss is the id of the employee's
+// manager.
+func cteDb() *storage.Database {
+	db := joinDb()
+	cols := []entity.Column{
+		{Type: types.Int, Name: "id"},
+		{Type: types.Text, Name: "name"},
+		{Type: types.Int, Name: "boss"},
+	}
+	tbl := storage.NewPersisentTable(cols)
+	tbl.AddRow(entity.Row{Values: []entity.Value{1, "ann", nil}})
+	tbl.AddRow(entity.Row{Values: []entity.Value{2, "bob", 1}})
+	tbl.AddRow(entity.Row{Values: []entity.Value{3, "cat", 1}})
+	tbl.AddRow(entity.Row{Values: []entity.Value{4, "dan", 2}})
+	tbl.AddRow(entity.Row{Values: []entity.Value{5, "eve", 4}})
+	db.Catalog["emp"] = tbl.(*storage.PersistentTable)
+	return db
+}
+
+func TestPlanner_CTE(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name: "referenced twice",
+			sql:  "with o as (select user_id, count(*) as n from orders group by user_id) select a.user_id, b.n from o a join o b on a.user_id = b.user_id where a.n > 1 order by 1",
+			want: [][]entity.Value{{1, 2}, {2, 2}},
+		},
+		{
+			name: "column names",
+			sql:  "with t(x) as (select id, age from users where id < 3) select x, age from t order by x",
+			want: [][]entity.Value{{1, 24}, {2, 30}},
+		},
+		{
+			name: "refers to earlier cte",
+			sql:  "with a as (select id from users where age >= 30), b as (select id from a where id > 2) select * from b",
+			want: [][]entity.Value{{4}},
+		},
+		{
+			name: "shadows table",
+			sql:  "with users as (select 1 as id) select id from users",
+			want: [][]entity.Value{{1}},
+		},
+		{
+			name: "in subquery",
+			sql:  "with big as (select id from users where age > 25) select count(*) from orders where user_id in (select id from big)",
+			want: [][]entity.Value{{2}},
+		},
+		{
+			name: "in derived table",
+			sql:  "select * from (with t as (select 7 as n) select n from t) as d",
+			want: [][]entity.Value{{7}},
+		},
+		{
+			name: "union",
+			sql:  "with t as (select user_id from orders where item = 'book' union select user_id from orders where user_id = 1) select * from t",
+			want: [][]entity.Value{{1}},
+		},
+		{
+			name: "hierarchy",
+			sql:  "with recursive chain(id, name, depth) as (select id, name, 0 from emp where id = 1 union all select e.id, e.name, c.depth + 1 from emp e join chain c on e.boss = c.id) select name, depth from chain order by depth, name",
+			want: [][]entity.Value{{"ann", 0}, {"bob", 1}, {"cat", 1}, {"dan", 2}, {"eve", 3}},
+		},
+		{
+			name: "ancestors of every row",
+			sql:  "select e.name, (with recursive up(id, boss) as (select id, boss from emp where id = e.id union all select emp.id, emp.boss from emp join up on emp.id = up.boss) select count(*) from up) from emp e order by 1",
+			want: [][]entity.Value{{"ann", 1}, {"bob", 2}, {"cat", 2}, {"dan", 3}, {"eve", 4}},
+		},
+		{
+			name: "limit stops recursion",
+			sql:  "with recursive t(n) as (select 1 union all select n + 1 from t) select n from t limit 3",
+			want: [][]entity.Value{{1}, {2}, {3}},
+		},
+		{
+			name: "union discards repeated rows",
+			sql:  "with recursive t(n) as (select 1 union select (n + 1) % 3 from t) select n from t order by n",
+			want: [][]entity.Value{{0}, {1}, {2}},
+		},
+		{
+			name:    "duplicate name",
+			sql:     "with t as (select 1), t as (select 2) select * from t",
+			wantErr: true,
+		},
+		{
+			name:    "too many column names",
+			sql:     "with t(a, b) as (select 1) select * from t",
+			wantErr: true,
+		},
+		{
+			name:    "not visible before its definition",
+			sql:     "with a as (select * from b), b as (select 1) select * from a",
+			wantErr: true,
+		},
+		{
+			name:    "recursive reference in non-recursive term",
+			sql:     "with recursive t(n) as (select n from t union all select 1) select * from t",
+			wantErr: true,
+		},
+		{
+			name:    "recursive reference in subquery",
+			sql:     "with recursive t(n) as (select 1 union all select n + 1 from t where n < (select max(n) from t)) select * from t",
+			wantErr: true,
+		},
+		{
+			name:    "mismatched union types",
+			sql:     "with recursive t(n) as (select 1 union all select 'a' from t) select * from t",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryRows(t, cteDb(), tt.sql)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlanner_Columns(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "expressions",
+			sql:  "select id, age + 1, age + 1 as next_age from users",
+			want: []string{"id", "?column?", "next_age"},
+		},
+		{
+			name: "star",
+			sql:  "select *, users.id as key from users",
+			want: []string{"id", "user_type", "email", "age", "key"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := parser.Parse(tt.sql)
+			require.NoError(t, err)
+			plan, err := New(testDb()).Prepare(stmt)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, plan.Columns())
+		})
+	}
+}
+
+func typesDb(t *testing.T) *storage.Database {
+	db := testDb()
+	for _, sql := range []string{
+		"create table prices (code char(3), amount numeric(10, 2), ratio real, qty smallint, total bigint, active boolean)",
+		"insert into prices values ('ab', '12.345', '0.1', 7, 5000000000, 'yes'), ('xyz', 3, '2', null, -1, 'f')",
+	} {
+		stmt, err := parser.Parse(sql)
+		require.NoError(t, err)
+		plan, err := New(db).Prepare(stmt)
+		require.NoError(t, err)
+		_, err = plan.Exec()
+		require.NoError(t, err)
+	}
+	return db
+}
+
+func TestPlanner_Types(t *testing.T) {
+	tests := []struct {
+		name      string
+		sql       string
+		want      [][]entity.Value
+		wantTypes []types.T
+		wantErr   bool
+	}{
+		{
+			name: "stored values",
+			sql:  "select * from prices",
+			want: [][]entity.Value{
+				{"ab ", types.NewDecimal(1235, 2), float64(float32(0.1)), 7, 5000000000, true},
+				{"xyz", types.NewDecimal(300, 2), 2.0, nil, -1, false},
+			},
+			wantTypes: []types.T{types.MakeChar(3), types.MakeNumeric(10, 2), types.Real, types.SmallInt, types.BigInt, types.Bool},
+		},
+		{
+			name:      "numbers compare across types",
+			sql:       "select code from prices where amount > 4 and total > qty and ratio < amount",
+			want:      [][]entity.Value{{"ab "}},
+			wantTypes: []types.T{types.MakeChar(3)},
+		},
+		{
+			name:      "string constants take the column type",
+			sql:       "select code from prices where amount = '3' or active = 'true'",
+			want:      [][]entity.Value{{"ab "}, {"xyz"}},
+			wantTypes: []types.T{types.MakeChar(3)},
+		},
+		{
+			name:      "result types",
+			sql:       "select qty + 1, total * 2, count(*), max(amount) from prices group by qty, total order by 1",
+			want:      [][]entity.Value{{8, 10000000000, 1, types.NewDecimal(1235, 2)}, {nil, -2, 1, types.NewDecimal(300, 2)}},
+			wantTypes: []types.T{types.Int, types.BigInt, types.BigInt, types.MakeNumeric(10, 2)},
+		},
+		{
+			name:      "average of integers",
+			sql:       "select avg(qty), avg(total) from prices",
+			want:      [][]entity.Value{{types.NewDecimal(70000000000000000, 16), types.NewDecimal(249999999950000000, 8)}},
+			wantTypes: []types.T{types.Numeric, types.Numeric},
+		},
+		{
+			name:      "union widens types",
+			sql:       "select qty from prices union all select total from prices order by 1",
+			want:      [][]entity.Value{{-1}, {7}, {5000000000}, {nil}},
+			wantTypes: []types.T{types.BigInt},
+		},
+		{
+			name: "numeric literals",
+			sql:  "select 1.10 + 2.205, 2.50 * 1.5, 10 / 4.0, 7.5 % 2, -1.50, 1e3",
+			want: [][]entity.Value{{
+				types.NewDecimal(3305, 3), types.NewDecimal(3750, 3), types.NewDecimal(25000000000000000, 16),
+				types.NewDecimal(15, 1), types.NewDecimal(-150, 2), types.NewDecimal(1000, 0),
+			}},
+			wantTypes: []types.T{types.Numeric, types.Numeric, types.Numeric, types.Numeric, types.Numeric, types.Numeric},
+		},
+		{
+			name:      "numeric columns",
+			sql:       "select amount + 1, amount * qty, -amount from prices where amount > 3.5",
+			want:      [][]entity.Value{{types.NewDecimal(1335, 2), types.NewDecimal(8645, 2), types.NewDecimal(-1235, 2)}},
+			wantTypes: []types.T{types.Numeric, types.Numeric, types.Numeric},
+		},
+		{
+			name:      "float arithmetic",
+			sql:       "select ratio * 2, ratio + 0.5, ratio * ratio, 1.5 / ratio, ratio + 1::double precision from prices where code = 'xyz'",
+			want:      [][]entity.Value{{4.0, 2.5, 4.0, 0.75, 3.0}},
+			wantTypes: []types.T{types.Real, types.Real, types.Real, types.Real, types.Float},
+		},
+		{
+			name:      "real arithmetic rounds to real",
+			sql:       "select 1.1::real + 1, ratio + 0.1 from prices where code = 'xyz'",
+			want:      [][]entity.Value{{float64(float32(float64(float32(1.1)) + 1)), float64(float32(2.1))}},
+			wantTypes: []types.T{types.Real, types.Real},
+		},
+		{
+			name:      "numeric aggregates",
+			sql:       "select sum(amount), avg(amount), max(amount) from prices",
+			want:      [][]entity.Value{{types.NewDecimal(1535, 2), types.NewDecimal(76750000000000000, 16), types.NewDecimal(1235, 2)}},
+			wantTypes: []types.T{types.Numeric, types.Numeric, types.MakeNumeric(10, 2)},
+		},
+		{
+			name:      "numeric functions",
+			sql:       "select round(amount, 1), ceil(amount), mod(amount, 5), round(amount) from prices where active",
+			want:      [][]entity.Value{{types.NewDecimal(124, 1), types.NewDecimal(13, 0), types.NewDecimal(235, 2), types.NewDecimal(12, 0)}},
+			wantTypes: []types.T{types.Numeric, types.Numeric, types.Numeric, types.Numeric},
+		},
+		{
+			name:      "integer functions keep the width of their arguments",
+			sql:       "select abs(qty), abs(-total), mod(total, 3), mod(qty, 2::smallint), abs(-qty::integer) from prices where active",
+			want:      [][]entity.Value{{7, 5000000000, 2, 1, 7}},
+			wantTypes: []types.T{types.SmallInt, types.BigInt, types.BigInt, types.SmallInt, types.Int},
+		},
+		{
+			name:    "absolute value out of range",
+			sql:     "select abs(-2147483647 - 1)",
+			wantErr: true,
+		},
+		{
+			name:      "boolean column as predicate",
+			sql:       "select code from prices where active",
+			want:      [][]entity.Value{{"ab "}},
+			wantTypes: []types.T{types.MakeChar(3)},
+		},
+		{
+			name: "coalesce converts integers",
+			sql:  "select coalesce(amount, 0), coalesce(ratio, 0), coalesce(qty, 1.5) from prices order by code",
+			want: [][]entity.Value{
+				{types.NewDecimal(1235, 2), float64(float32(0.1)), types.NewDecimal(7, 0)},
+				{types.NewDecimal(300, 2), 2.0, types.NewDecimal(15, 1)},
+			},
+			wantTypes: []types.T{types.Numeric, types.Real, types.Numeric},
+		},
+		{
+			name:      "case converts integers",
+			sql:       "select case when active then amount else 0 end from prices order by code",
+			want:      [][]entity.Value{{types.NewDecimal(1235, 2)}, {types.NewDecimal(0, 0)}},
+			wantTypes: []types.T{types.Numeric},
+		},
+		{
+			name:      "greatest and least convert integers",
+			sql:       "select greatest(1, 2.5), least(qty, amount), greatest(1, 2.5, ratio) from prices order by code",
+			want:      [][]entity.Value{{types.NewDecimal(25, 1), types.NewDecimal(7, 0), 2.5}, {types.NewDecimal(25, 1), types.NewDecimal(300, 2), 2.5}},
+			wantTypes: []types.T{types.Numeric, types.Numeric, types.Real},
+		},
+		{
+			name:      "union converts integers",
+			sql:       "select 1 union select 2.5 union select 1.0 order by 1",
+			want:      [][]entity.Value{{types.NewDecimal(1, 0)}, {types.NewDecimal(25, 1)}},
+			wantTypes: []types.T{types.Numeric},
+		},
+		{
+			name:      "union converts numerics to floats",
+			sql:       "select amount from prices union all select ratio from prices order by 1",
+			want:      [][]entity.Value{{float64(float32(0.1))}, {2.0}, {3.0}, {float64(float32(12.35))}},
+			wantTypes: []types.T{types.Real},
+		},
+		{
+			name:      "cast to double precision",
+			sql:       "select 1.5::double precision, qty::double precision from prices order by code",
+			want:      [][]entity.Value{{1.5, 7.0}, {1.5, nil}},
+			wantTypes: []types.T{types.Float, types.Float},
+		},
+		{
+			name:      "negated boolean column",
+			sql:       "select code from prices where not active and amount < 5.5",
+			want:      [][]entity.Value{{"xyz"}},
+			wantTypes: []types.T{types.MakeChar(3)},
+		},
+		{
+			name:      "boolean literals",
+			sql:       "select true, active = false from prices where active = true or false",
+			want:      [][]entity.Value{{true, false}},
+			wantTypes: []types.T{types.Bool, types.Bool},
+		},
+		{
+			name:    "modulo of floats",
+			sql:     "select ratio % 2 from prices",
+			wantErr: true,
+		},
+		{
+			name:    "numeric division by zero",
+			sql:     "select amount / 0.0 from prices",
+			wantErr: true,
+		},
+		{
+			name:    "arithmetic on boolean",
+			sql:     "select active + 1 from prices",
+			wantErr: true,
+		},
+		{
+			name:    "boolean column compared with number",
+			sql:     "select code from prices where active = 1.5",
+			wantErr: true,
+		},
+		{
+			name:    "integer overflow",
+			sql:     "select qty * 2147483647 from prices",
+			wantErr: true,
+		},
+		{
+			name:    "boolean compared with number",
+			sql:     "select code from prices where active = qty",
+			wantErr: true,
+		},
+		{
+			name:    "json has no equality",
+			sql:     "select '1'::json = '1'::json",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := parser.Parse(tt.sql)
+			require.NoError(t, err)
+			plan, err := New(typesDb(t)).Prepare(stmt)
+			if err == nil {
+				var rows []entity.Row
+				rows, err = collectRows(plan.Iter())
+				if err == nil {
+					got := make([][]entity.Value, len(rows))
+					for i, row := range rows {
+						got[i] = row.Values
+					}
+					assert.Equal(t, tt.want, got)
+					assert.Equal(t, tt.wantTypes, plan.Types())
+				}
+			}
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPlanner_IntegerOverflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    [][]entity.Value
+		wantErr string
+	}{
+		{
+			name: "largest and smallest bigint",
+			sql:  "select 9223372036854775806 + 1, -9223372036854775807 - 1, (-9223372036854775807 - 1) / 1",
+			want: [][]entity.Value{{math.MaxInt64, math.MinInt64, math.MinInt64}},
+		},
+		{
+			name:    "addition",
+			sql:     "select total + 9223372036854775807 from prices where total > 0",
+			wantErr: "bigint out of range",
+		},
+		{
+			name:    "subtraction",
+			sql:     "select -9223372036854775807 - total from prices where total > 0",
+			wantErr: "bigint out of range",
+		},
+		{
+			name:    "multiplication",
+			sql:     "select total * total * total from prices",
+			wantErr: "bigint out of range",
+		},
+		{
+			name:    "division",
+			sql:     "select (-9223372036854775807 - 1) / -1",
+			wantErr: "bigint out of range",
+		},
+		{
+			name:    "negation",
+			sql:     "select -(-9223372036854775807 - 1)",
+			wantErr: "bigint out of range",
+		},
+		{
+			name: "sum",
+			sql:  "select sum(total) from prices",
+			want: [][]entity.Value{{4999999999}},
+		},
+		{
+			name:    "sum overflow",
+			sql:     "select sum(prices.total * 1000000000) from prices, prices p",
+			wantErr: "bigint out of range",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryRows(t, typesDb(t), tt.sql)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlanner_InsertTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr bool
+	}{
+		{name: "trailing spaces cut", sql: "insert into prices (code) values ('ab   ')"},
+		{name: "number to numeric", sql: "insert into prices (amount) values (-12345678)"},
+		{name: "text too long", sql: "insert into prices (code) values ('abcd')", wantErr: true},
+		{name: "smallint out of range", sql: "insert into prices (qty) values (40000)", wantErr: true},
+		{name: "integer out of range", sql: "insert into users (id) values (5000000000)", wantErr: true},
+		{name: "numeric overflow", sql: "insert into prices (amount) values ('123456789.1')", wantErr: true},
+		{name: "invalid boolean", sql: "insert into prices (active) values ('maybe')", wantErr: true},
+		{name: "number to boolean", sql: "insert into prices (active) values (1)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := parser.Parse(tt.sql)
+			require.NoError(t, err)
+			plan, err := New(typesDb(t)).Prepare(stmt)
+			if err == nil {
+				_, err = plan.Exec()
+			}
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func dateTimeDb(t *testing.T) *storage.Database {
+	db := testDb()
+	for _, sql := range []string{
+		"create table events (id int, day date, stamp timestamp, seen timestamptz, took interval, starts time)",
+		"insert into events values (1, '2024-03-15', '2024-03-15 10:20:30', '2024-03-15 10:20:30+02', '1 day 2 hours', '10:00')",
+		"insert into events values (2, '2024-01-31', '2024-01-31 23:59:59.5', '2024-01-31 23:00:00-05', '90 minutes', '23:30')",
+	} {
+		stmt, err := parser.Parse(sql)
+		require.NoError(t, err)
+		plan, err := New(db).Prepare(stmt)
+		require.NoError(t, err)
+		_, err = plan.Exec()
+		require.NoError(t, err)
+	}
+	return db
+}
+
+func TestPlanner_DateTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		sql       string
+		want      [][]entity.Value
+		wantTypes []types.T
+		wantErr   bool
+	}{
+		{
+			name: "stored values",
+			sql:  "select day::text, stamp::text, seen::text, took::text, starts::text from events order by id",
+			want: [][]entity.Value{
+				{"2024-03-15", "2024-03-15 10:20:30", "2024-03-15 08:20:30+00", "1 day 02:00:00", "10:00:00"},
+				{"2024-01-31", "2024-01-31 23:59:59.5", "2024-02-01 04:00:00+00", "01:30:00", "23:30:00"},
+			},
+			wantTypes: []types.T{types.Text, types.Text, types.Text, types.Text, types.Text},
+		},
+		{
+			name: "date arithmetic",
+			sql:  "select day + 1, day - date '2024-01-01', day + interval '1 month', day + starts from events where id = 2",
+			want: [][]entity.Value{{
+				mustValue(types.Date, "2024-02-01"), 30, mustValue(types.Timestamp, "2024-02-29"), mustValue(types.Timestamp, "2024-01-31 23:30"),
+			}},
+			wantTypes: []types.T{types.Date, types.Int, types.Timestamp, types.Timestamp},
+		},
+		{
+			name: "timestamp arithmetic",
+			sql:  "select stamp - interval '1 month', interval '1 hour' + stamp, seen - stamp, stamp - day from events where id = 1",
+			want: [][]entity.Value{{
+				mustValue(types.Timestamp, "2024-02-15 10:20:30"), mustValue(types.Timestamp, "2024-03-15 11:20:30"),
+				mustValue(types.Interval, "-2 hours"), mustValue(types.Interval, "10:20:30"),
+			}},
+			wantTypes: []types.T{types.Timestamp, types.Timestamp, types.Interval, types.Interval},
+		},
+		{
+			name: "time and interval arithmetic",
+			sql:  "select starts + interval '1 hour', took * 2, took / 3, -took from events where id = 2",
+			want: [][]entity.Value{{
+				mustValue(types.Time, "00:30"), mustValue(types.Interval, "3 hours"),
+				mustValue(types.Interval, "30 minutes"), mustValue(types.Interval, "-90 minutes"),
+			}},
+			wantTypes: []types.T{types.Time, types.Interval, types.Interval, types.Interval},
+		},
+		{
+			name:      "dates compare with timestamps and strings",
+			sql:       "select id from events where day < stamp and day > '2024-02-01' and seen >= '2024-03-15'",
+			want:      [][]entity.Value{{1}},
+			wantTypes: []types.T{types.Int},
+		},
+		{
+			name: "extract and date_trunc",
+			sql:  "select extract(month from day), date_part('hour', stamp), date_trunc('month', stamp) from events order by id",
+			want: [][]entity.Value{
+				{types.NewDecimal(3, 0), 10.0, mustValue(types.Timestamp, "2024-03-01")},
+				{types.NewDecimal(1, 0), 23.0, mustValue(types.Timestamp, "2024-01-01")},
+			},
+			wantTypes: []types.T{types.Numeric, types.Float, types.Timestamp},
+		},
+		{
+			name:      "string arguments read as timestamps",
+			sql:       "select date_trunc('year', '2024-03-15 10:00')",
+			want:      [][]entity.Value{{mustValue(types.Timestamp, "2024-01-01")}},
+			wantTypes: []types.T{types.Timestamp},
+		},
+		{
+			name:      "aggregates",
+			sql:       "select sum(took), avg(took), min(day), max(seen) from events",
+			want:      [][]entity.Value{{mustValue(types.Interval, "1 day 03:30:00"), mustValue(types.Interval, "13:45:00"), mustValue(types.Date, "2024-01-31"), mustValue(types.TimestampTZ, "2024-03-15 08:20:30+00")}},
+			wantTypes: []types.T{types.Interval, types.Interval, types.Date, types.TimestampTZ},
+		},
+		{
+			name:      "casts",
+			sql:       "select cast(stamp as date), '12:30'::time, stamp::timestamptz::timestamp = stamp from events where id = 1",
+			want:      [][]entity.Value{{mustValue(types.Date, "2024-03-15"), mustValue(types.Time, "12:30"), true}},
+			wantTypes: []types.T{types.Date, types.Time, types.Bool},
+		},
+		{
+			name:      "at time zone",
+			sql:       "select seen at time zone 'Asia/Tokyo', stamp at time zone 'UTC' from events where id = 1",
+			want:      [][]entity.Value{{mustValue(types.Timestamp, "2024-03-15 17:20:30"), mustValue(types.TimestampTZ, "2024-03-15 10:20:30+00")}},
+			wantTypes: []types.T{types.Timestamp, types.TimestampTZ},
+		},
+		{
+			name:      "current date",
+			sql:       "select current_date = current_date, localtimestamp <= now()",
+			want:      [][]entity.Value{{true, true}},
+			wantTypes: []types.T{types.Bool, types.Bool},
+		},
+		{
+			name:      "time of the statement",
+			sql:       "select now() = now(), now() = current_timestamp, localtimestamp = now()::timestamp, clock_timestamp() >= now() from events",
+			want:      [][]entity.Value{{true, true, true, true}, {true, true, true, true}},
+			wantTypes: []types.T{types.Bool, types.Bool, types.Bool, types.Bool},
+		},
+		{
+			name:    "invalid date",
+			sql:     "select date '2024-02-30'",
+			wantErr: true,
+		},
+		{
+			name:    "adding dates",
+			sql:     "select day + day from events",
+			wantErr: true,
+		},
+		{
+			name:    "cast of boolean to date",
+			sql:     "select cast(true as date)",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := parser.Parse(tt.sql)
+			require.NoError(t, err)
+			plan, err := New(dateTimeDb(t)).Prepare(stmt)
+			if err == nil {
+				var rows []entity.Row
+				rows, err = collectRows(plan.Iter())
+				if err == nil {
+					got := make([][]entity.Value, len(rows))
+					for i, row := range rows {
+						got[i] = row.Values
+					}
+					assert.Equal(t, tt.want, got)
+					assert.Equal(t, tt.wantTypes, plan.Types())
+				}
+			}
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPlanner_SetTimeZone(t *testing.T) {
+	db := dateTimeDb(t)
+	pl := New(db)
+	query := func(sql string) []entity.Value {
+		stmt, err := parser.Parse(sql)
+		require.NoError(t, err)
+		plan, err := pl.Prepare(stmt)
+		require.NoError(t, err)
+		if !plan.IsQuery() {
+			_, err = plan.Exec()
+			require.NoError(t, err)
+			return nil
+		}
+		rows, err := collectRows(plan.Iter())
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		return rows[0].Values
+	}
+	query("set time zone 'America/New_York'")
+	assert.Equal(t, []entity.Value{"America/New_York"}, query("show time zone"))
+	assert.Equal(t, []entity.Value{
+		"2024-03-15 04:20:30-04", mustValue(types.Timestamp, "2024-03-15 04:20:30"), mustValue(types.TimestampTZ, "2024-03-15 04:00:00+00"),
+	}, query("select seen::text, seen::timestamp, date_trunc('day', seen) from events where id = 1"))
+	query("insert into events (id, seen) values (3, '2024-07-01 12:00')")
+	assert.Equal(t, []entity.Value{mustValue(types.TimestampTZ, "2024-07-01 16:00:00+00")}, query("select seen from events where id = 3"))
+	query("set timezone to default")
+	assert.Equal(t, []entity.Value{"UTC"}, query("show timezone"))
+
+	for _, sql := range []string{"set time zone 'Mars/Olympus'", "set datestyle to 'ISO'", "show datestyle"} {
+		stmt, err := parser.Parse(sql)
+		require.NoError(t, err)
+		_, err = pl.Prepare(stmt)
+		assert.Error(t, err, sql)
+	}
+}
+
+func mustValue(typ types.T, s string) entity.Value {
+	val, err := typ.Coerce(s)
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+func jsonDb(t *testing.T) *storage.Database {
+	db := testDb()
+	for _, sql := range []string{
+		"create table docs (id int, data jsonb, raw json)",
+		`insert into docs values (1, '{"a": {"b": "x"}, "tags": ["red", "blue"], "n": 1.50}', '{"z": 1, "a": 2}')`,
+		`insert into docs values (2, '{"a": {"b": "y"}, "tags": ["green"], "n": 2}', '[1,2]')`,
+		`insert into docs values (3, '[1, 2, "k"]', null)`,
+	} {
+		stmt, err := parser.Parse(sql)
+		require.NoError(t, err)
+		plan, err := New(db).Prepare(stmt)
+		require.NoError(t, err)
+		_, err = plan.Exec()
+		require.NoError(t, err)
+	}
+	return db
+}
+
+func TestPlanner_JSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		sql       string
+		want      [][]entity.Value
+		wantTypes []types.T
+		wantErr   bool
+	}{
+		{
+			name: "field and path operators",
+			sql:  "select data->'a'->>'b', data#>'{tags,0}', data#>>'{a,b}', data->'tags'->-1, raw->'a' from docs order by id",
+			want: [][]entity.Value{
+				{"x", types.JSONValue(`"red"`), "x", types.JSONValue(`"blue"`), types.JSONValue("2")},
+				{"y", types.JSONValue(`"green"`), "y", types.JSONValue(`"green"`), nil},
+				{nil, nil, nil, nil, nil},
+			},
+			wantTypes: []types.T{types.Text, types.JSONB, types.Text, types.JSONB, types.JSON},
+		},
+		{
+			name:      "stored values",
+			sql:       "select data, raw from docs where id = 1",
+			want:      [][]entity.Value{{types.JSONValue(`{"a": {"b": "x"}, "n": 1.50, "tags": ["red", "blue"]}`), types.JSONValue(`{"z": 1, "a": 2}`)}},
+			wantTypes: []types.T{types.JSONB, types.JSON},
+		},
+		{
+			name:      "containment and keys",
+			sql:       `select id, data @> '{"tags": ["red"]}', '{"n": 2}' <@ data, data ? 'tags', data ? 'k' from docs order by id`,
+			want:      [][]entity.Value{{1, true, false, true, false}, {2, false, true, true, false}, {3, false, false, false, true}},
+			wantTypes: []types.T{types.Int, types.Bool, types.Bool, types.Bool, types.Bool},
+		},
+		{
+			name:      "equal documents",
+			sql:       `select id from docs where data->'n' = '1.5'`,
+			want:      [][]entity.Value{{1}},
+			wantTypes: []types.T{types.Int},
+		},
+		{
+			name: "build object",
+			sql:  "select jsonb_build_object('id', id, 'b', data->'a'->'b') from docs where id < 3 order by id",
+			want: [][]entity.Value{
+				{types.JSONValue(`{"b": "x", "id": 1}`)},
+				{types.JSONValue(`{"b": "y", "id": 2}`)},
+			},
+			wantTypes: []types.T{types.JSONB},
+		},
+		{
+			name:      "aggregate with nulls",
+			sql:       "select jsonb_agg(data->'n'), count(data->'n') from docs",
+			want:      [][]entity.Value{{types.JSONValue(`[1.50, 2, null]`), 2}},
+			wantTypes: []types.T{types.JSONB, types.BigInt},
+		},
+		{
+			name: "array elements of every row",
+			sql:  "select d.id, e from docs d, jsonb_array_elements(d.data->'tags') e",
+			want: [][]entity.Value{
+				{1, types.JSONValue(`"red"`)},
+				{1, types.JSONValue(`"blue"`)},
+				{2, types.JSONValue(`"green"`)},
+			},
+			wantTypes: []types.T{types.Int, types.JSONB},
+		},
+		{
+			name:      "alias names the column of a function",
+			sql:       "select v from docs, jsonb_array_elements(docs.data->'tags') as v where docs.id = 2",
+			want:      [][]entity.Value{{types.JSONValue(`"green"`)}},
+			wantTypes: []types.T{types.JSONB},
+		},
+		{
+			name:      "column of a function without alias",
+			sql:       "select value from jsonb_array_elements('[1, 2]') where value = '2'",
+			want:      [][]entity.Value{{types.JSONValue("2")}},
+			wantTypes: []types.T{types.JSONB},
+		},
+		{
+			name: "function without other tables",
+			sql:  `select key, value from jsonb_each('{"b": 1, "a": [true]}') order by key desc`,
+			want: [][]entity.Value{
+				{"b", types.JSONValue("1")},
+				{"a", types.JSONValue("[true]")},
+			},
+			wantTypes: []types.T{types.Text, types.JSONB},
+		},
+		{
+			name:      "function in a join",
+			sql:       `select d.id, t.t from jsonb_array_elements_text('["2", "3"]') t join docs d on d.id::text = t`,
+			want:      [][]entity.Value{{2, "2"}, {3, "3"}},
+			wantTypes: []types.T{types.Int, types.Text},
+		},
+		{
+			name:    "elements of a scalar",
+			sql:     "select * from docs, jsonb_array_elements(docs.data->'n')",
+			wantErr: true,
+		},
+		{
+			name:    "scalar function in from",
+			sql:     "select * from lower('A')",
+			wantErr: true,
+		},
+		{
+			name:    "containment of json",
+			sql:     "select raw @> '{}' from docs",
+			wantErr: true,
+		},
+		{
+			name:    "invalid document",
+			sql:     `select data @> '{"a"' from docs`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid path",
+			sql:     "select data #> 'a,b' from docs",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := parser.Parse(tt.sql)
+			require.NoError(t, err)
+			plan, err := New(jsonDb(t)).Prepare(stmt)
+			if err == nil {
+				var rows []entity.Row
+				rows, err = collectRows(plan.Iter())
+				if err == nil {
+					got := make([][]entity.Value, len(rows))
+					for i, row := range rows {
+						got[i] = row.Values
+					}
+					assert.Equal(t, tt.want, got)
+					assert.Equal(t, tt.wantTypes, plan.Types())
+				}
+			}
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPlanner_InsertJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    entity.Value
+		wantErr bool
+	}{
+		{name: "jsonb normalized", sql: `insert into docs (id, data) values (9, '{"b": 1, "a" : [ 1e1 ]}')`, want: types.JSONValue(`{"a": [10], "b": 1}`)},
+		{name: "document of a function", sql: "insert into docs (id, data) values (9, jsonb_build_array(1, 'a'))", want: types.JSONValue(`[1, "a"]`)},
+		{name: "invalid document", sql: `insert into docs (id, data) values (9, '{"a": }')`, wantErr: true},
+		{name: "invalid update", sql: `update docs set data = 'nul' where id = 1`, wantErr: true},
+		{name: "number to jsonb", sql: "insert into docs (id, data) values (9, 1)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := jsonDb(t)
+			stmt, err := parser.Parse(tt.sql)
+			require.NoError(t, err)
+			plan, err := New(db).Prepare(stmt)
+			if err == nil {
+				_, err = plan.Exec()
+			}
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			got, err := queryRows(t, db, "select data from docs where id = 9")
+			require.NoError(t, err)
+			assert.Equal(t, [][]entity.Value{{tt.want}}, got)
+		})
+	}
+}
+
+func TestPlanner_JSONIndex(t *testing.T) {
+	db := jsonDb(t)
+	pl := New(db)
+	exec := func(sql string) error {
+		stmt, err := parser.Parse(sql)
+		require.NoError(t, err)
+		plan, err := pl.Prepare(stmt)
+		if err != nil {
+			return err
+		}
+		_, err = plan.Exec()
+		return err
+	}
+	query := `select id from docs where data @> '{"tags": ["red"]}' and id > 0`
+	want, err := plannerRows(t, pl, query)
+	require.NoError(t, err)
+	assert.Equal(t, [][]entity.Value{{1}}, want)
+
+	require.NoError(t, exec("create index on docs using gin (data)"))
+	assert.Error(t, exec("create index docs_data_idx on docs using gin (data)"))
+	require.NoError(t, exec("create index if not exists docs_data_idx on docs using gin (data)"))
+	assert.Error(t, exec("create index on docs (data)"))
+	assert.Error(t, exec("create index on docs using gin (raw)"))
+	assert.Error(t, exec("create index on docs using gin (missing)"))
+
+	stmt, err := parser.Parse(query)
+	require.NoError(t, err)
+	plan, err := pl.Prepare(stmt)
+	require.NoError(t, err)
+	var table *Table
+	for node := plan.Root; table == nil; {
+		switch n := node.(type) {
+		case *Table:
+			table = n
+		case *Projection:
+			node = n.Child
+		case *Select:
+			node = n.Child
+		default:
+			t.Fatalf("unexpected node %T", node)
+		}
+	}
+	assert.IsType(t, &index.InvertedIndex{}, table.index)
+	got, err := plannerRows(t, pl, query)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	// the index follows changes to the table
+	require.NoError(t, exec(`insert into docs values (4, '{"tags": ["red", "green"]}', null)`))
+	require.NoError(t, exec(`update docs set data = '{"tags": []}' where id = 1`))
+	got, err = plannerRows(t, pl, query)
+	require.NoError(t, err)
+	assert.Equal(t, [][]entity.Value{{4}}, got)
+	require.NoError(t, exec("delete from docs where id = 4"))
+	got, err = plannerRows(t, pl, query)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestPlanner_Insert(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		wantTag string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name:    "all columns",
+			sql:     "insert into users values (3, 'driver', 'driver3@example.com', 41)",
+			wantTag: "INSERT 0 1",
+			want: [][]entity.Value{
+				{3, "driver", "driver3@example.com", 41},
+			},
+		},
+		{
+			name:    "multiple rows with defaults",
+			sql:     "insert into users (email, id) values ('a@example.com', 3), ('b@example.com', '4')",
+			wantTag: "INSERT 0 2",
+			want: [][]entity.Value{
+				{3, "customer", "a@example.com", nil},
+				{4, "customer", "b@example.com", nil},
+			},
+		},
+		{
+			name:    "explicit default and null",
+			sql:     "insert into users (id, user_type, age) values (3, default, null)",
+			wantTag: "INSERT 0 1",
+			want: [][]entity.Value{
+				{3, "customer", nil, nil},
+			},
+		},
+		{
+			name:    "type mismatch",
+			sql:     "insert into users (id, email) values (3, 4)",
+			wantErr: true,
+		},
+		{
+			name:    "null in not null column",
+			sql:     "insert into users (id) values (3), (null)",
+			wantErr: true,
+		},
+		{
+			name:    "not null column left out",
+			sql:     "insert into users (email) values ('a@example.com')",
+			wantErr: true,
+		},
+		{
+			name:    "invalid integer",
+			sql:     "insert into users (id) values ('three')",
+			wantErr: true,
+		},
+		{
+			name:    "unknown column",
+			sql:     "insert into users (id, name) values (3, 'name')",
+			wantErr: true,
+		},
+		{
+			name:    "too many values",
+			sql:     "insert into users (id) values (3, 'driver')",
+			wantErr: true,
+		},
+		{
+			name:    "unknown table",
+			sql:     "insert into orders values (1)",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDb()
+			before := tableRows(t, db, "users")
+			stmt, err := parser.Parse(tt.sql)
+			require.NoError(t, err)
+			plan, err := New(db).Prepare(stmt)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Equal(t, before, tableRows(t, db, "users"))
+				return
+			}
+			require.NoError(t, err)
+			tag, err := plan.Exec()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTag, tag)
+			assert.Equal(t, append(before, tt.want...), tableRows(t, db, "users"))
+		})
+	}
+}
+
+func TestPlanner_Update(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		wantTag string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name:    "with where",
+			sql:     "update users set age = 25, email = 'new@example.com' where id = 1",
+			wantTag: "UPDATE 1",
+			want: [][]entity.Value{
+				{1, "customer", "new@example.com", 25},
+				{2, "driver", "driver2@example.com", 30},
+			},
+		},
+		{
+			name:    "without where",
+			sql:     "update users set user_type = default",
+			wantTag: "UPDATE 2",
+			want: [][]entity.Value{
+				{1, "customer", "customer1@example.com", 24},
+				{2, "customer", "driver2@example.com", 30},
+			},
+		},
+		{
+			name:    "from column",
+			sql:     "update users set email = user_type where user_type = 'driver'",
+			wantTag: "UPDATE 1",
+			want: [][]entity.Value{
+				{1, "customer", "customer1@example.com", 24},
+				{2, "driver", "driver", 30},
+			},
+		},
+		{
+			name:    "to null",
+			sql:     "update users set age = null where id = 2",
+			wantTag: "UPDATE 1",
+			want: [][]entity.Value{
+				{1, "customer", "customer1@example.com", 24},
+				{2, "driver", "driver2@example.com", nil},
+			},
+		},
+		{
+			name:    "no match",
+			sql:     "update users set age = 1 where id = 3",
+			wantTag: "UPDATE 0",
+			want: [][]entity.Value{
+				{1, "customer", "customer1@example.com", 24},
+				{2, "driver", "driver2@example.com", 30},
+			},
+		},
+		{
+			name:    "unknown column",
+			sql:     "update users set name = 'name'",
+			wantErr: true,
+		},
+		{
+			name:    "type mismatch",
+			sql:     "update users set age = 'old'",
+			wantErr: true,
+		},
+		{
+			name:    "null in not null column",
+			sql:     "update users set id = null where id = 2",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDb()
+			before := tableRows(t, db, "users")
+			stmt, err := parser.Parse(tt.sql)
+			require.NoError(t, err)
+			plan, err := New(db).Prepare(stmt)
+			if err == nil {
+				var tag string
+				tag, err = plan.Exec()
+				assert.Equal(t, tt.wantTag, tag)
+			}
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Equal(t, before, tableRows(t, db, "users"))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tableRows(t, db, "users"))
+		})
+	}
+}
+
+func TestPlanner_Delete(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		wantTag string
+		want    [][]entity.Value
+	}{
+		{
+			name:    "with where",
+			sql:     "delete from users where user_type = 'driver'",
+			wantTag: "DELETE 1",
+			want: [][]entity.Value{
+				{1, "customer", "customer1@example.com", 24},
+			},
+		},
+		{
+			name:    "with subquery",
+			sql:     "delete from users where age > (select min(age) from users)",
+			wantTag: "DELETE 1",
+			want: [][]entity.Value{
+				{1, "customer", "customer1@example.com", 24},
+			},
+		},
+		{
+			name:    "without where",
+			sql:     "delete from users",
+			wantTag: "DELETE 2",
+			want:    [][]entity.Value{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDb()
+			stmt, err := parser.Parse(tt.sql)
+			require.NoError(t, err)
+			plan, err := New(db).Prepare(stmt)
+			require.NoError(t, err)
+			tag, err := plan.Exec()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTag, tag)
+			assert.Equal(t, tt.want, tableRows(t, db, "users"))
+		})
+	}
+}
+
+func TestPlanner_CreateTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    []entity.Column
+		wantErr bool
+	}{
+		{
+			name: "typed columns",
+			sql:  "create table orders (id integer, user_id bigint default 0, item varchar(255) null, note text default 'none')",
+			want: []entity.Column{
+				{Type: types.Int, Name: "id"},
+				{Type: types.BigInt, Name: "user_id", Default: &parser.Literal{Value: 0}},
+				{Type: types.MakeVarChar(255), Name: "item"},
+				{Type: types.Text, Name: "note", Default: &parser.Literal{Value: "none"}},
+			},
+		},
+		{
+			name: "type modifiers",
+			sql:  "create table prices (code char(3) default 'x', amount numeric(10, 2) default '1.005', ratio double precision, qty smallint, active boolean default 'yes')",
+			want: []entity.Column{
+				{Type: types.MakeChar(3), Name: "code", Default: &parser.Literal{Value: "x"}},
+				{Type: types.MakeNumeric(10, 2), Name: "amount", Default: &parser.Literal{Value: "1.005"}},
+				{Type: types.Float, Name: "ratio"},
+				{Type: types.SmallInt, Name: "qty"},
+				{Type: types.Bool, Name: "active", Default: &parser.Literal{Value: "yes"}},
+			},
+		},
+		{
+			name:    "modifier not allowed",
+			sql:     "create table orders (id integer(4))",
+			wantErr: true,
+		},
+		{
+			name:    "numeric scale above precision",
+			sql:     "create table orders (price numeric(2, 3))",
+			wantErr: true,
+		},
+		{
+			name:    "default too long",
+			sql:     "create table orders (code varchar(2) default 'abc')",
+			wantErr: true,
+		},
+		{
+			name: "if not exists",
+			sql:  "create table if not exists users (id integer)",
+			want: testDb().Catalog["users"].Columns(),
+		},
+		{
+			name:    "already exists",
+			sql:     "create table users (id integer)",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate column",
+			sql:     "create table orders (id integer, id text)",
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			sql:     "create table orders (id blob)",
+			wantErr: true,
+		},
+		{
+			name: "not null",
+			sql:  "create table orders (id integer not null, item text null default 'none')",
+			want: []entity.Column{
+				{Type: types.Int, Name: "id", NotNull: true},
+				{Type: types.Text, Name: "item", Default: &parser.Literal{Value: "none"}},
+			},
+		},
+		{
+			name:    "invalid default",
+			sql:     "create table orders (id integer default 'one')",
+			wantErr: true,
+		},
+		{
+			name:    "conflicting null declarations",
+			sql:     "create table orders (id integer not null null)",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDb()
+			stmt, err := parser.Parse(tt.sql)
+			require.NoError(t, err)
+			plan, err := New(db).Prepare(stmt)
+			if err == nil {
+				_, err = plan.Exec()
+			}
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			tbl, err := db.GetTable(stmt.(*parser.CreateTable).TableName)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tbl.Columns())
+		})
+	}
+}
+
+func TestPlanner_DefaultExpressions(t *testing.T) {
+	db := testDb()
+	p := New(db)
+	exec := func(sql string) error {
+		stmt, err := parser.Parse(sql)
+		require.NoError(t, err)
+		plan, err := p.Prepare(stmt)
+		if err == nil {
+			_, err = plan.Exec()
+		}
+		return err
+	}
+	require.NoError(t, exec("create table events (id integer, at timestamptz default now(), day date default current_date, code char(3) default 'x', amount numeric(10, 2) default '1.005')"))
+	require.NoError(t, exec("insert into events (id) values (1), (2)"))
+	require.NoError(t, exec("insert into events values (3, default, null, default, 2)"))
+	rows, err := plannerRows(t, p, "select count(distinct at), count(day), min(code), min(amount) from events where id < 3 and day = current_date")
+	require.NoError(t, err)
+	assert.Equal(t, [][]entity.Value{{1, 2, "x  ", types.NewDecimal(101, 2)}}, rows)
+	rows, err = plannerRows(t, p, "select at is null, day, code, amount from events where id = 3")
+	require.NoError(t, err)
+	assert.Equal(t, [][]entity.Value{{false, nil, "x  ", types.NewDecimal(200, 2)}}, rows)
+
+	require.NoError(t, exec("insert into events (id, at, day) values (4, null, null)"))
+	require.NoError(t, exec("update events set at = default, day = default where id = 4"))
+	rows, err = plannerRows(t, p, "select at is null, day = current_date from events where id = 4")
+	require.NoError(t, err)
+	assert.Equal(t, [][]entity.Value{{false, true}}, rows)
+
+	for _, sql := range []string{
+		"create table bad (id integer default id)",
+		"create table bad (id integer default true)",
+		"create table bad (id integer default 'one')",
+		"create table bad (code varchar(2) default 'abc')",
+	} {
+		assert.Error(t, exec(sql), sql)
+	}
+}
+
+func TestPlanner_DropTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr bool
+	}{
+		{
+			name: "existing",
+			sql:  "drop table users",
+		},
+		{
+			name: "if exists",
+			sql:  "drop table if exists users, orders",
+		},
+		{
+			name:    "missing",
+			sql:     "drop table users, orders",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDb()
+			stmt, err := parser.Parse(tt.sql)
+			require.NoError(t, err)
+			plan, err := New(db).Prepare(stmt)
+			require.NoError(t, err)
+			tag, err := plan.Exec()
+			_, lookupErr := db.GetTable("users")
+			if tt.wantErr {
+				require.Error(t, err)
+				require.NoError(t, lookupErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "DROP TABLE", tag)
+			require.Error(t, lookupErr)
+		})
+	}
+}
+
+func TestPlanner_Window(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    [][]entity.Value
+		wantErr bool
+	}{
+		{
+			name: "row number",
+			sql:  "select id, row_number() over (order by id desc) from users order by id",
+			want: [][]entity.Value{{1, 5}, {2, 4}, {3, 3}, {4, 2}, {5, 1}},
+		},
+		{
+			name: "rank and dense rank",
+			sql:  "select id, rank() over (order by age), dense_rank() over (order by age) from users order by id",
+			want: [][]entity.Value{{1, 2, 2}, {2, 3, 3}, {3, 5, 4}, {4, 3, 3}, {5, 1, 1}},
+		},
+		{
+			name: "partition by",
+			sql:  "select id, row_number() over (partition by user_type order by id) from users order by id",
+			want: [][]entity.Value{{1, 1}, {2, 1}, {3, 2}, {4, 2}, {5, 3}},
+		},
+		{
+			name: "aggregate over partition",
+			sql:  "select id, count(*) over (partition by user_type) from users order by id",
+			want: [][]entity.Value{{1, 3}, {2, 2}, {3, 2}, {4, 3}, {5, 3}},
+		},
+		{
+			name: "running sum",
+			sql:  "select id, sum(id) over (order by id) from users order by id",
+			want: [][]entity.Value{{1, 1}, {2, 3}, {3, 6}, {4, 10}, {5, 15}},
+		},
+		{
+			name: "range frame includes peers",
+			sql:  "select id, sum(id) over (order by age) from users order by id",
+			want: [][]entity.Value{{1, 6}, {2, 12}, {3, 15}, {4, 12}, {5, 5}},
+		},
+		{
+			name: "rows frame excludes peers",
+			sql:  "select id, sum(id) over (order by age rows between unbounded preceding and current row) from users order by id",
+			want: [][]entity.Value{{1, 6}, {2, 8}, {3, 15}, {4, 12}, {5, 5}},
+		},
+		{
+			name: "sliding frame",
+			sql:  "select id, sum(id) over (order by id rows between 1 preceding and 1 following) from users order by id",
+			want: [][]entity.Value{{1, 3}, {2, 6}, {3, 9}, {4, 12}, {5, 9}},
+		},
+		{
+			name: "empty frame",
+			sql:  "select id, count(id) over (order by id rows between 1 following and 2 following), max(id) over (order by id rows between 1 following and 2 following) from users order by id",
+			want: [][]entity.Value{{1, 2, 3}, {2, 2, 4}, {3, 2, 5}, {4, 1, 5}, {5, 0, nil}},
+		},
+		{
+			name: "lag and lead",
+			sql:  "select id, lag(id) over (order by id), lead(id, 2, 0) over (order by id) from users order by id",
+			want: [][]entity.Value{{1, nil, 3}, {2, 1, 4}, {3, 2, 5}, {4, 3, 0}, {5, 4, 0}},
+		},
+		{
+			name: "first and last value",
+			sql:  "select id, first_value(email) over (partition by user_type order by id), last_value(id) over (partition by user_type order by id rows between current row and unbounded following) from users order by id",
+			want: [][]entity.Value{
+				{1, "customer1@example.com", 5},
+				{2, "driver2@example.com", 3},
+				{3, "driver2@example.com", 3},
+				{4, "customer1@example.com", 5},
+				{5, "customer1@example.com", 5},
+			},
+		},
+		{
+			name: "ntile",
+			sql:  "select id, ntile(2) over (order by id), ntile(3) over (order by id) from users order by id",
+			want: [][]entity.Value{{1, 1, 1}, {2, 1, 1}, {3, 1, 2}, {4, 2, 2}, {5, 2, 3}},
+		},
+		{
+			name: "window over aggregate",
+			sql:  "select user_type, count(*), rank() over (order by count(*) desc) from users group by user_type order by user_type",
+			want: [][]entity.Value{{"customer", 3, 1}, {"driver", 2, 2}},
+		},
+		{
+			name: "order by window function",
+			sql:  "select id from users order by row_number() over (order by id desc)",
+			want: [][]entity.Value{{5}, {4}, {3}, {2}, {1}},
+		},
+		{
+			name: "order by alias",
+			sql:  "select id, row_number() over (order by email desc) as rn from users order by rn",
+			want: [][]entity.Value{{3, 1}, {2, 2}, {5, 3}, {4, 4}, {1, 5}},
+		},
+		{
+			name:    "missing over",
+			sql:     "select rank() from users",
+			wantErr: true,
+		},
+		{
+			name:    "window function in where",
+			sql:     "select id from users where row_number() over () > 1",
+			wantErr: true,
+		},
+		{
+			name:    "not a window function",
+			sql:     "select upper(email) over () from users",
+			wantErr: true,
+		},
+		{
+			name:    "nested window functions",
+			sql:     "select sum(row_number() over ()) over () from users",
+			wantErr: true,
+		},
+		{
+			name:    "frame ends before start",
+			sql:     "select sum(id) over (order by id rows between current row and 1 preceding) from users",
+			wantErr: true,
+		},
+		{
+			name:    "frame starts with unbounded following",
+			sql:     "select sum(id) over (order by id rows between unbounded following and current row) from users",
+			wantErr: true,
+		},
+		{
+			name:    "range with offset",
+			sql:     "select sum(id) over (order by id range 1 preceding) from users",
+			wantErr: true,
+		},
+		{
+			name:    "ntile of zero",
+			sql:     "select ntile(0) over () from users",
+			wantErr: true,
+		},
+		{
+			name:    "distinct window aggregate",
+			sql:     "select count(distinct id) over () from users",
+			wantErr: true,
+		},
+		{
+			name:    "lag offset not integer",
+			sql:     "select lag(id, 'x') over () from users",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryRows(t, sortDb(), tt.sql)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,485 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/sql"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+type (
+	// InListExpr tests whether the value of Expr is among the values of
+	// List, or with Not that it isn't. Like in Postgres the result is NULL
+	// rather than false when the value isn't found and either it or a value
+	// of the list is NULL.
+	InListExpr struct {
+		Expr Expression
+		List []Expression
+		Not  bool
+		// set holds the hashed values of a list of constants
+		set     map[string]bool
+		setNull bool
+	}
+
+	// BetweenExpr tests whether the value of Expr lies between two bounds,
+	// which Low and High compare it to.
+	BetweenExpr struct {
+		Low  Expression
+		High Expression
+		Not  bool
+		expr *parser.BetweenExpr
+	}
+
+	// PatternExpr matches a string against a LIKE, ILIKE or SIMILAR TO
+	// pattern, or against a regular expression with ~ and ~*. The pattern
+	// is compiled to a regular expression, once when it is constant.
+	PatternExpr struct {
+		Op      string
+		Expr    Expression
+		Pattern Expression
+		Escape  Expression
+		Not     bool
+		// re is the compiled pattern, and key the pattern and escape it
+		// was compiled from
+		re  *regexp.Regexp
+		key string
+	}
+
+	// IsNullExpr tests whether the value of Expr is NULL.
+	IsNullExpr struct {
+		Expr Expression
+		Not  bool
+	}
+
+	// IsDistinctExpr tests whether two values differ, taking NULL to be a
+	// value equal to itself. It is never NULL.
+	IsDistinctExpr struct {
+		LHS Expression
+		RHS Expression
+		Not bool
+	}
+)
+
+// matchOps are the regular expression operators. !~ and !~* negate ~ and ~*.
+var matchOps = map[string]struct {
+	op  string
+	not bool
+}{
+	parser.OpMatch:     {parser.OpMatch, false},
+	parser.OpIMatch:    {parser.OpIMatch, false},
+	parser.OpNotMatch:  {parser.OpMatch, true},
+	parser.OpNotIMatch: {parser.OpIMatch, true},
+}
+
+// likeOps are the names Postgres gives the operators of pattern matches.
+var likeOps = map[string]string{
+	parser.OpLike:    "~~",
+	parser.OpILike:   "~~*",
+	parser.OpSimilar: "~",
+	parser.OpMatch:   "~",
+	parser.OpIMatch:  "~*",
+}
+
+func (c *compiler) compileInList(e *parser.InExpr) (Expression, error) {
+	lhs, err := c.compile(e.Expr)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Expression, len(e.List))
+	consts := true
+	for i, item := range e.List {
+		rhs, err := c.compile(item)
+		if err != nil {
+			return nil, err
+		}
+		cmp, err := c.newCompareExpr(sql.CompareEqual, lhs, rhs)
+		if err != nil {
+			return nil, err
+		}
+		lhs, list[i] = cmp.(*CompareExpr).LHS, cmp.(*CompareExpr).RHS
+		typ := list[i].Type()
+		if _, ok := list[i].(*ConstExpr); !ok || (typ.Family != lhs.Type().Family && typ.Family != types.UnknownFamily) {
+			consts = false
+		}
+	}
+	in := &InListExpr{Expr: lhs, List: list, Not: e.Not}
+	if consts {
+		in.set = make(map[string]bool, len(list))
+		for _, item := range list {
+			val, err := item.Eval(entity.Row{})
+			if err != nil {
+				return nil, err
+			}
+			if val == nil {
+				in.setNull = true
+				continue
+			}
+			in.set[hashKey([]entity.Value{val})] = true
+		}
+	}
+	return in, nil
+}
+
+func (e *InListExpr) Eval(row entity.Row) (entity.Value, error) {
+	val, err := e.Expr.Eval(row)
+	if err != nil || val == nil {
+		return nil, err
+	}
+	found, null, err := e.find(val, row)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case found:
+		return !e.Not, nil
+	case null:
+		return nil, nil
+	}
+	return e.Not, nil
+}
+
+// find looks for a value in the list, and reports whether the list holds a
+// NULL.
+func (e *InListExpr) find(val entity.Value, row entity.Row) (bool, bool, error) {
+	if e.set != nil {
+		return e.set[hashKey([]entity.Value{val})], e.setNull, nil
+	}
+	null := false
+	for _, item := range e.List {
+		v, err := item.Eval(row)
+		if err != nil {
+			return false, false, err
+		}
+		if v == nil {
+			null = true
+			continue
+		}
+		cmp, err := types.Compare(val, v)
+		if err != nil {
+			return false, false, err
+		}
+		if cmp == 0 {
+			return true, null, nil
+		}
+	}
+	return false, null, nil
+}
+func (e *InListExpr) Type() types.T {
+	return types.Bool
+}
+func (e *InListExpr) String() string {
+	items := make([]string, len(e.List))
+	for i, item := range e.List {
+		items[i] = item.String()
+	}
+	op := "IN"
+	if e.Not {
+		op = "NOT IN"
+	}
+	return fmt.Sprintf("(%s %s (%s))", e.Expr, op, strings.Join(items, ", "))
+}
+
+func (c *compiler) compileBetween(e *parser.BetweenExpr) (Expression, error) {
+	expr, err := c.compile(e.Expr)
+	if err != nil {
+		return nil, err
+	}
+	low, err := c.compile(e.Low)
+	if err != nil {
+		return nil, err
+	}
+	high, err := c.compile(e.High)
+	if err != nil {
+		return nil, err
+	}
+	lcmp, err := c.newCompareExpr(sql.CompareEqualOrGreater, expr, low)
+	if err != nil {
+		return nil, err
+	}
+	hcmp, err := c.newCompareExpr(sql.CompareEqualOrLess, expr, high)
+	if err != nil {
+		return nil, err
+	}
+	return &BetweenExpr{Low: lcmp, High: hcmp, Not: e.Not, expr: e}, nil
+}
+
+func (e *BetweenExpr) Eval(row entity.Row) (entity.Value, error) {
+	low, err := e.Low.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	high, err := e.High.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	res := and3(low, high)
+	if e.Not {
+		return not3(res), nil
+	}
+	return res, nil
+}
+func (e *BetweenExpr) Type() types.T {
+	return types.Bool
+}
+func (e *BetweenExpr) String() string {
+	return e.expr.String()
+}
+
+func (c *compiler) compileLike(e *parser.LikeExpr) (Expression, error) {
+	expr, err := c.compile(e.Expr)
+	if err != nil {
+		return nil, err
+	}
+	pattern, err := c.compile(e.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	var escape Expression
+	if e.Escape != nil {
+		if escape, err = c.compile(e.Escape); err != nil {
+			return nil, err
+		}
+	}
+	return newPatternExpr(e.Op, expr, pattern, escape, e.Not)
+}
+
+func newPatternExpr(op string, expr, pattern, escape Expression, not bool) (Expression, error) {
+	for _, operand := range []Expression{expr, pattern, escape} {
+		if operand == nil {
+			continue
+		}
+		if typ := operand.Type(); typ.Family != types.TextFamily && typ.Family != types.UnknownFamily {
+			return nil, fmt.Errorf("operator does not exist: %s %s %s", expr.Type(), likeOps[op], pattern.Type())
+		}
+	}
+	return &PatternExpr{Op: op, Expr: expr, Pattern: pattern, Escape: escape, Not: not}, nil
+}
+
+func (e *PatternExpr) Eval(row entity.Row) (entity.Value, error) {
+	val, err := e.Expr.Eval(row)
+	if err != nil || val == nil {
+		return nil, err
+	}
+	pattern, err := e.Pattern.Eval(row)
+	if err != nil || pattern == nil {
+		return nil, err
+	}
+	escape := entity.Value(`\`)
+	if e.Escape != nil {
+		if escape, err = e.Escape.Eval(row); err != nil || escape == nil {
+			return nil, err
+		}
+	}
+	re, err := e.compile(pattern.(string), escape.(string))
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString(val.(string)) != e.Not, nil
+}
+
+// compile returns the regular expression of a pattern, reusing the last one
+// when the pattern is the same.
+func (e *PatternExpr) compile(pattern, escape string) (*regexp.Regexp, error) {
+	key := escape + ":" + pattern
+	if e.re != nil && e.key == key {
+		return e.re, nil
+	}
+	var expr string
+	switch e.Op {
+	case parser.OpLike, parser.OpILike, parser.OpSimilar:
+		if len([]rune(escape)) > 1 {
+			return nil, errors.New("invalid escape string: escape string must be empty or one character")
+		}
+		var err error
+		if e.Op == parser.OpSimilar {
+			expr, err = similarToRegexp(pattern, escape)
+		} else {
+			expr, err = likeToRegexp(pattern, escape)
+		}
+		if err != nil {
+			return nil, err
+		}
+		expr = "^(?s:" + expr + ")$"
+	default:
+		expr = pattern
+	}
+	if e.Op == parser.OpILike || e.Op == parser.OpIMatch {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %s", err)
+	}
+	e.re, e.key = re, key
+	return re, nil
+}
+
+// likeToRegexp translates a LIKE pattern to a regular expression. The escape
+// character makes the following one match itself.
+func likeToRegexp(pattern, escape string) (string, error) {
+	var b strings.Builder
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escape != "" && string(r) == escape:
+			i++
+			if i == len(runes) {
+				return "", errors.New("LIKE pattern must not end with escape character")
+			}
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String(), nil
+}
+
+// similarToRegexp translates a SIMILAR TO pattern to a regular expression.
+// Besides % and _, it uses the |, *, +, ?, {}, () and [] of regular
+// expressions, while . stands for itself.
+func similarToRegexp(pattern, escape string) (string, error) {
+	var b strings.Builder
+	runes := []rune(pattern)
+	bracket := false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escape != "" && string(r) == escape:
+			i++
+			if i == len(runes) {
+				return "", errors.New("SIMILAR TO pattern must not end with escape character")
+			}
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case bracket:
+			if r == ']' {
+				bracket = false
+			}
+			if r == '\\' {
+				b.WriteString(`\\`)
+			} else {
+				b.WriteRune(r)
+			}
+		case r == '[':
+			bracket = true
+			b.WriteRune(r)
+			// a leading ^ negates the class and a leading ] is literal
+			if i+1 < len(runes) && runes[i+1] == '^' {
+				i++
+				b.WriteRune('^')
+			}
+			if i+1 < len(runes) && runes[i+1] == ']' {
+				i++
+				b.WriteString(`\]`)
+			}
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		case strings.ContainsRune("|*+?{}()", r):
+			b.WriteRune(r)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String(), nil
+}
+func (e *PatternExpr) Type() types.T {
+	return types.Bool
+}
+func (e *PatternExpr) String() string {
+	if _, ok := matchOps[e.Op]; ok {
+		op := e.Op
+		if e.Not {
+			op = "!" + op
+		}
+		return fmt.Sprintf("(%s %s %s)", e.Expr, op, e.Pattern)
+	}
+	op := e.Op
+	if e.Not {
+		op = "NOT " + op
+	}
+	if e.Escape != nil {
+		return fmt.Sprintf("(%s %s %s ESCAPE %s)", e.Expr, op, e.Pattern, e.Escape)
+	}
+	return fmt.Sprintf("(%s %s %s)", e.Expr, op, e.Pattern)
+}
+
+func (c *compiler) compileIsNull(e *parser.IsNullExpr) (Expression, error) {
+	expr, err := c.compile(e.Expr)
+	if err != nil {
+		return nil, err
+	}
+	return &IsNullExpr{Expr: expr, Not: e.Not}, nil
+}
+
+func (e *IsNullExpr) Eval(row entity.Row) (entity.Value, error) {
+	val, err := e.Expr.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	return (val == nil) != e.Not, nil
+}
+func (e *IsNullExpr) Type() types.T {
+	return types.Bool
+}
+func (e *IsNullExpr) String() string {
+	if e.Not {
+		return fmt.Sprintf("(%s IS NOT NULL)", e.Expr)
+	}
+	return fmt.Sprintf("(%s IS NULL)", e.Expr)
+}
+
+func (c *compiler) compileIsDistinct(e *parser.IsDistinctExpr) (Expression, error) {
+	lhs, err := c.compile(e.LHS)
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := c.compile(e.RHS)
+	if err != nil {
+		return nil, err
+	}
+	// check that the operands compare the way = does
+	cmp, err := c.newCompareExpr(sql.CompareEqual, lhs, rhs)
+	if err != nil {
+		return nil, err
+	}
+	return &IsDistinctExpr{LHS: cmp.(*CompareExpr).LHS, RHS: cmp.(*CompareExpr).RHS, Not: e.Not}, nil
+}
+
+func (e *IsDistinctExpr) Eval(row entity.Row) (entity.Value, error) {
+	lval, err := e.LHS.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	rval, err := e.RHS.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	if lval == nil || rval == nil {
+		return (lval == nil) != (rval == nil) != e.Not, nil
+	}
+	cmp, err := types.Compare(lval, rval)
+	if err != nil {
+		return nil, err
+	}
+	return (cmp != 0) != e.Not, nil
+}
+func (e *IsDistinctExpr) Type() types.T {
+	return types.Bool
+}
+func (e *IsDistinctExpr) String() string {
+	if e.Not {
+		return fmt.Sprintf("(%s IS NOT DISTINCT FROM %s)", e.LHS, e.RHS)
+	}
+	return fmt.Sprintf("(%s IS DISTINCT FROM %s)", e.LHS, e.RHS)
+}
@@ -0,0 +1,68 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hiepd/galedb/pkg/sql/parser"
+)
+
+// Session holds the settings of a connection, which SET changes for the
+// statements that follow.
+type Session struct {
+	// TimeZone is the zone timestamps with time zone are read and shown in.
+	TimeZone *time.Location
+}
+
+func NewSession() *Session {
+	return &Session{TimeZone: time.UTC}
+}
+
+// SetVariable changes a setting of a session. TimeZone is the only setting.
+type SetVariable struct {
+	Session *Session
+	Name    string
+	// Value is empty for the default value.
+	Value string
+	loc   *time.Location
+}
+
+func (sv *SetVariable) Prepare() error {
+	if sv.Name != "timezone" {
+		return fmt.Errorf("unrecognized configuration parameter %q", sv.Name)
+	}
+	if sv.Value == "" || strings.EqualFold(sv.Value, "local") {
+		sv.loc = time.UTC
+		return nil
+	}
+	loc, err := time.LoadLocation(sv.Value)
+	if err != nil {
+		return fmt.Errorf("invalid value for parameter \"TimeZone\": %q", sv.Value)
+	}
+	sv.loc = loc
+	return nil
+}
+
+func (sv *SetVariable) Exec() (int, error) {
+	sv.Session.TimeZone = sv.loc
+	return 0, nil
+}
+
+func (sv *SetVariable) Tag(n int) string {
+	return "SET"
+}
+
+// showVariable plans SHOW as a query of one row holding the setting.
+func (p *Planner) showVariable(stmt *parser.ShowVar) (*QueryPlan, error) {
+	if stmt.Name != "timezone" {
+		return nil, fmt.Errorf("unrecognized configuration parameter %q", stmt.Name)
+	}
+	item := parser.NewSelectItem(parser.NewLiteral(p.Session.TimeZone.String()), "TimeZone")
+	sel := parser.NewSelect(nil, []*parser.SelectItem{item}, nil, nil, nil, nil).(*parser.Select)
+	plan, err := p.buildQueryPlan(sel)
+	if err != nil {
+		return nil, err
+	}
+	return plan, plan.prepare(plan.Root)
+}
@@ -0,0 +1,205 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+type (
+	// SetOp combines the rows of two queries. UNION returns the rows of
+	// either, INTERSECT those of both and EXCEPT those of Left missing from
+	// Right. Rows are told apart by hashing their values, so NULLs match.
+	// Without All every distinct row is returned once, with All a row
+	// returned n times by Left and m times by Right is returned n+m,
+	// min(n, m) or max(n-m, 0) times.
+	SetOp struct {
+		Op    parser.SetOpKind
+		All   bool
+		Left  Node
+		Right Node
+		cols  []entity.Column
+	}
+
+	SetOpIter struct {
+		node  *SetOp
+		iter  index.Iterator
+		right bool
+		// counts holds how many times each row of Right is left to match
+		counts map[string]int
+		seen   map[string]bool
+	}
+)
+
+// parseSetOp plans the queries of a compound query.
+func (p *Planner) parseSetOp(op *parser.SetOp, s *scope) (Node, error) {
+	left, err := p.parseSelectStatement(op.Left, s)
+	if err != nil {
+		return nil, err
+	}
+	right, err := p.parseSelectStatement(op.Right, s)
+	if err != nil {
+		return nil, err
+	}
+	return &SetOp{Op: op.Op, All: op.All, Left: left, Right: right}, nil
+}
+
+// setOpColumns checks that the rows of two queries can be combined and
+// returns the columns of the result, named after those of left. Their types
+// are the common types of both sides, so a column that is NULL in left takes
+// the type of right.
+func setOpColumns(op parser.SetOpKind, left, right []entity.Column) ([]entity.Column, error) {
+	if len(left) != len(right) {
+		return nil, fmt.Errorf("each %s query must have the same number of columns", op)
+	}
+	cols := make([]entity.Column, len(left))
+	for i := range left {
+		col := left[i]
+		col.Table = ""
+		col.Merged = false
+		typ, ok := types.Common(left[i].Type, right[i].Type)
+		if !ok {
+			return nil, fmt.Errorf("%s types %s and %s cannot be matched", op, left[i].Type, right[i].Type)
+		}
+		col.Type = typ
+		cols[i] = col
+	}
+	return cols, nil
+}
+
+// SetOp Expression
+func (so *SetOp) Iter() index.Iterator {
+	iter := &SetOpIter{node: so}
+	if !so.All {
+		iter.seen = make(map[string]bool)
+	}
+	return iter
+}
+func (so *SetOp) Columns() []entity.Column {
+	return so.cols
+}
+func (so *SetOp) Prepare() error {
+	if so.Left == nil || so.Right == nil {
+		return errors.New("no child node")
+	}
+	cols, err := setOpColumns(so.Op, so.Left.Columns(), so.Right.Columns())
+	if err != nil {
+		return err
+	}
+	so.cols = cols
+	return nil
+}
+
+// convert converts the values of a row of one of the queries to the types of
+// the result, like integers returned in place of numerics.
+func (so *SetOp) convert(row entity.Row, from Node) (entity.Row, error) {
+	var vals []entity.Value
+	for i, col := range from.Columns() {
+		typ := so.cols[i].Type
+		if row.Values[i] == nil || col.Type.Family == typ.Family {
+			continue
+		}
+		if vals == nil {
+			vals = append([]entity.Value(nil), row.Values...)
+		}
+		val, err := typ.Coerce(row.Values[i])
+		if err != nil {
+			return entity.Row{}, err
+		}
+		vals[i] = val
+	}
+	if vals != nil {
+		row.Values = vals
+	}
+	return row, nil
+}
+
+func (iter *SetOpIter) Next() (entity.Row, error) {
+	if iter.node.Op == parser.SetUnion {
+		return iter.union()
+	}
+	if iter.counts == nil {
+		if err := iter.count(); err != nil {
+			return entity.Row{}, err
+		}
+		iter.iter = iter.node.Left.Iter()
+	}
+	for {
+		row, err := iter.iter.Next()
+		if err != nil {
+			return entity.Row{}, err
+		}
+		if row, err = iter.node.convert(row, iter.node.Left); err != nil {
+			return entity.Row{}, err
+		}
+		key := hashKey(row.Values)
+		if iter.seen != nil && iter.seen[key] {
+			continue
+		}
+		n := iter.counts[key]
+		if iter.node.All && n > 0 {
+			iter.counts[key] = n - 1
+		}
+		if (iter.node.Op == parser.SetIntersect) != (n > 0) {
+			continue
+		}
+		if iter.seen != nil {
+			iter.seen[key] = true
+		}
+		return row, nil
+	}
+}
+
+// union returns the rows of the left query, then those of the right one.
+func (iter *SetOpIter) union() (entity.Row, error) {
+	for {
+		if iter.iter == nil {
+			iter.iter = iter.node.Left.Iter()
+		}
+		row, err := iter.iter.Next()
+		if err == index.EndOfIterator && !iter.right {
+			iter.iter = iter.node.Right.Iter()
+			iter.right = true
+			continue
+		} else if err != nil {
+			return entity.Row{}, err
+		}
+		from := iter.node.Left
+		if iter.right {
+			from = iter.node.Right
+		}
+		if row, err = iter.node.convert(row, from); err != nil {
+			return entity.Row{}, err
+		}
+		if iter.seen != nil {
+			key := hashKey(row.Values)
+			if iter.seen[key] {
+				continue
+			}
+			iter.seen[key] = true
+		}
+		return row, nil
+	}
+}
+
+// count hashes the rows of the right query.
+func (iter *SetOpIter) count() error {
+	iter.counts = make(map[string]int)
+	right := iter.node.Right.Iter()
+	for {
+		row, err := right.Next()
+		if err == index.EndOfIterator {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if row, err = iter.node.convert(row, iter.node.Right); err != nil {
+			return err
+		}
+		iter.counts[hashKey(row.Values)]++
+	}
+}
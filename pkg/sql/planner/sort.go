@@ -0,0 +1,508 @@
+package planner
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+type (
+	// Sort orders the rows of its child. Rows are sorted in memory until
+	// they take more than WorkMem bytes, after which sorted runs are written
+	// to temporary files and merged. When Bound is positive only the first
+	// Bound rows are produced, which are kept in a heap instead.
+	Sort struct {
+		Items   []*parser.OrderItem
+		WorkMem int
+		Bound   int
+		keys    []sortKey
+		cols    []entity.Column
+		// junk keeps the columns added for sort keys in the rows, for a
+		// parent that reads them
+		junk bool
+		PlanNode
+	}
+
+	sortKey struct {
+		index      int
+		desc       bool
+		nullsFirst bool
+	}
+
+	SortIter struct {
+		keys    []sortKey
+		workMem int
+		bound   int
+		width   int
+		out     index.Iterator
+		PlanIter
+	}
+
+	// rowsIter iterates over rows held in memory.
+	rowsIter struct {
+		rows     []entity.Row
+		position int
+	}
+)
+
+// Sort Expression
+func (s *Sort) Iter() index.Iterator {
+	width := len(s.cols)
+	if s.junk {
+		width = len(s.Child.Columns())
+	}
+	return &SortIter{
+		keys:    s.keys,
+		workMem: s.WorkMem,
+		bound:   s.Bound,
+		width:   width,
+		PlanIter: PlanIter{
+			ChildIter: s.Child.Iter(),
+		},
+	}
+}
+func (s *Sort) Columns() []entity.Column {
+	return s.cols
+}
+
+// Prepare resolves the sort keys against the output of the child. A key is
+// either the position of an output column, the name of an output column, or
+// an expression over the input of the child projection. Expressions are
+// added to the projection as extra columns which the sort drops again.
+func (s *Sort) Prepare() error {
+	if s.Child == nil {
+		return errors.New("no child node")
+	}
+	cols := s.Child.Columns()
+	proj, _ := s.Child.(*Projection)
+	width := len(cols)
+	keys := make([]sortKey, len(s.Items))
+	var junk []*parser.SelectItem
+	for i, item := range s.Items {
+		key := sortKey{
+			desc:       item.Desc,
+			nullsFirst: item.Desc,
+		}
+		switch item.Nulls {
+		case parser.NullsFirst:
+			key.nullsFirst = true
+		case parser.NullsLast:
+			key.nullsFirst = false
+		}
+		id, err := outputIndex(item.Expr, cols[:width])
+		if err != nil {
+			return err
+		}
+		if id < 0 {
+			if _, ok := s.Child.(*Distinct); ok {
+				return errors.New("for SELECT DISTINCT, ORDER BY expressions must appear in select list")
+			}
+			if proj == nil {
+				return fmt.Errorf("invalid ORDER BY expression %s", item.Expr)
+			}
+			id = width + len(junk)
+			junk = append(junk, &parser.SelectItem{Expr: item.Expr})
+		}
+		key.index = id
+		keys[i] = key
+	}
+	if len(junk) > 0 {
+		proj.Items = append(proj.Items, junk...)
+		if err := proj.Prepare(); err != nil {
+			return err
+		}
+	}
+	s.keys = keys
+	s.cols = cols[:width]
+	if s.WorkMem <= 0 {
+		s.WorkMem = DefaultWorkMem
+	}
+	return nil
+}
+
+// outputIndex finds the output column an ORDER BY item refers to, either by
+// position or by name. It returns -1 when the item is an expression over the
+// input instead.
+func outputIndex(expr parser.Expr, cols []entity.Column) (int, error) {
+	switch e := expr.(type) {
+	case *parser.Literal:
+		n, ok := e.Value.(int)
+		if !ok {
+			return -1, nil
+		}
+		if n < 1 || n > len(cols) {
+			return 0, fmt.Errorf("ORDER BY position %d is not in select list", n)
+		}
+		return n - 1, nil
+	case *parser.ColumnRef:
+		if e.Table != "" {
+			return -1, nil
+		}
+		id := -1
+		for i, col := range cols {
+			if col.Name != e.Name {
+				continue
+			}
+			if id >= 0 {
+				return 0, fmt.Errorf("ORDER BY %s is ambiguous", e.Name)
+			}
+			id = i
+		}
+		return id, nil
+	}
+	return -1, nil
+}
+
+func (iter *SortIter) Next() (entity.Row, error) {
+	if iter.out == nil {
+		out, err := iter.sort()
+		if err != nil {
+			return entity.Row{}, err
+		}
+		iter.out = out
+	}
+	row, err := iter.out.Next()
+	if err != nil {
+		return entity.Row{}, err
+	}
+	row.Values = row.Values[:iter.width]
+	return row, nil
+}
+
+// sort reads the whole input and returns an iterator over the sorted rows.
+func (iter *SortIter) sort() (index.Iterator, error) {
+	rows := make([]entity.Row, 0)
+	runs := make([]*spillFile, 0)
+	cleanup := func() {
+		for _, run := range runs {
+			run.close()
+		}
+	}
+	var top *topN
+	if iter.bound > 0 {
+		top = newTopN(iter.bound, iter.keys)
+	}
+	size := 0
+	for {
+		row, err := iter.ChildIter.Next()
+		if err == index.EndOfIterator {
+			break
+		} else if err != nil {
+			cleanup()
+			return nil, err
+		}
+		if top != nil {
+			if err := top.push(row); err != nil {
+				return nil, err
+			}
+			if top.size <= iter.workMem {
+				continue
+			}
+			// not even the bounded rows fit in memory, fall back to
+			// sorting everything
+			rows, size = top.rows(), top.size
+			top = nil
+		} else {
+			rows = append(rows, row)
+			size += rowSize(row)
+		}
+		if size <= iter.workMem {
+			continue
+		}
+		run, err := iter.spill(rows)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		runs = append(runs, run)
+		rows = make([]entity.Row, 0)
+		size = 0
+	}
+	if top != nil {
+		rows, err := top.sorted()
+		if err != nil {
+			return nil, err
+		}
+		return &rowsIter{rows: rows, position: -1}, nil
+	}
+	if err := sortRows(rows, iter.keys); err != nil {
+		cleanup()
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return &rowsIter{rows: rows, position: -1}, nil
+	}
+	iters := make([]index.Iterator, 0, len(runs)+1)
+	for i, run := range runs {
+		it, err := run.iterator()
+		if err != nil {
+			for _, rest := range runs[i+1:] {
+				rest.close()
+			}
+			return nil, err
+		}
+		iters = append(iters, it)
+	}
+	iters = append(iters, &rowsIter{rows: rows, position: -1})
+	return newMergeIter(iters, iter.keys)
+}
+
+// spill sorts rows and writes them to a temporary file as one run.
+func (iter *SortIter) spill(rows []entity.Row) (*spillFile, error) {
+	if err := sortRows(rows, iter.keys); err != nil {
+		return nil, err
+	}
+	run, err := newSpillFile()
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if err := run.write(row); err != nil {
+			run.close()
+			return nil, err
+		}
+	}
+	return run, nil
+}
+
+func sortRows(rows []entity.Row, keys []sortKey) error {
+	var err error
+	sort.SliceStable(rows, func(i, j int) bool {
+		cmp, cerr := compareRows(rows[i], rows[j], keys)
+		if cerr != nil && err == nil {
+			err = cerr
+		}
+		return cmp < 0
+	})
+	return err
+}
+
+// compareRows orders two rows by the given keys.
+func compareRows(a, b entity.Row, keys []sortKey) (int, error) {
+	for _, key := range keys {
+		aval, bval := a.Values[key.index], b.Values[key.index]
+		if aval == nil || bval == nil {
+			if aval == nil && bval == nil {
+				continue
+			}
+			// a NULL goes first exactly when it is a's value and NULLs
+			// go first, or it is b's value and NULLs go last
+			if (aval == nil) == key.nullsFirst {
+				return -1, nil
+			}
+			return 1, nil
+		}
+		cmp, err := types.Compare(aval, bval)
+		if err != nil {
+			return 0, err
+		}
+		if key.desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp, nil
+		}
+	}
+	return 0, nil
+}
+
+// topN keeps the first n rows of its input in sort order. The rows are held in
+// a heap with the last of them on top so that it can be replaced by a row
+// sorting before it. Rows comparing equal keep their input order.
+type topN struct {
+	n     int
+	keys  []sortKey
+	items []topNItem
+	seq   int
+	size  int
+	err   error
+}
+
+type topNItem struct {
+	row entity.Row
+	seq int
+}
+
+func newTopN(n int, keys []sortKey) *topN {
+	return &topN{
+		n:     n,
+		keys:  keys,
+		items: make([]topNItem, 0),
+	}
+}
+
+func (t *topN) push(row entity.Row) error {
+	item := topNItem{row: row, seq: t.seq}
+	t.seq++
+	if len(t.items) < t.n {
+		heap.Push(t, item)
+		t.size += rowSize(row)
+		return t.err
+	}
+	cmp, err := compareRows(row, t.items[0].row, t.keys)
+	if err != nil {
+		return err
+	}
+	if cmp < 0 {
+		t.size += rowSize(row) - rowSize(t.items[0].row)
+		t.items[0] = item
+		heap.Fix(t, 0)
+	}
+	return t.err
+}
+
+// rows returns the rows kept so far in input order.
+func (t *topN) rows() []entity.Row {
+	sort.Slice(t.items, func(i, j int) bool {
+		return t.items[i].seq < t.items[j].seq
+	})
+	rows := make([]entity.Row, len(t.items))
+	for i, item := range t.items {
+		rows[i] = item.row
+	}
+	return rows
+}
+
+func (t *topN) sorted() ([]entity.Row, error) {
+	rows := t.rows()
+	if err := sortRows(rows, t.keys); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (t *topN) Len() int {
+	return len(t.items)
+}
+
+// Less orders the heap by descending sort order, putting the row that
+// comes last on top.
+func (t *topN) Less(i, j int) bool {
+	cmp, err := compareRows(t.items[i].row, t.items[j].row, t.keys)
+	if err != nil && t.err == nil {
+		t.err = err
+	}
+	if cmp == 0 {
+		return t.items[i].seq > t.items[j].seq
+	}
+	return cmp > 0
+}
+func (t *topN) Swap(i, j int) {
+	t.items[i], t.items[j] = t.items[j], t.items[i]
+}
+func (t *topN) Push(x interface{}) {
+	t.items = append(t.items, x.(topNItem))
+}
+func (t *topN) Pop() interface{} {
+	n := len(t.items)
+	item := t.items[n-1]
+	t.items = t.items[:n-1]
+	return item
+}
+
+// mergeIter merges sorted iterators. Rows comparing equal come out in the
+// order of their iterators so that the merge is stable.
+type mergeIter struct {
+	keys  []sortKey
+	iters []index.Iterator
+	heads []mergeHead
+	err   error
+}
+
+type mergeHead struct {
+	row entity.Row
+	src int
+}
+
+func newMergeIter(iters []index.Iterator, keys []sortKey) (*mergeIter, error) {
+	m := &mergeIter{
+		keys:  keys,
+		iters: iters,
+		heads: make([]mergeHead, 0, len(iters)),
+	}
+	for i := range iters {
+		if err := m.advance(i); err != nil {
+			return nil, err
+		}
+	}
+	heap.Init(m)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m, nil
+}
+
+// advance pushes the next row of iterator src onto the heap.
+func (m *mergeIter) advance(src int) error {
+	row, err := m.iters[src].Next()
+	if err == index.EndOfIterator {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	m.heads = append(m.heads, mergeHead{row: row, src: src})
+	return nil
+}
+
+func (m *mergeIter) Next() (entity.Row, error) {
+	if m.err != nil {
+		return entity.Row{}, m.err
+	}
+	if len(m.heads) == 0 {
+		return entity.Row{}, index.EndOfIterator
+	}
+	head := m.heads[0]
+	row, err := m.iters[head.src].Next()
+	if err == index.EndOfIterator {
+		heap.Pop(m)
+	} else if err != nil {
+		return entity.Row{}, err
+	} else {
+		m.heads[0] = mergeHead{row: row, src: head.src}
+		heap.Fix(m, 0)
+	}
+	if m.err != nil {
+		return entity.Row{}, m.err
+	}
+	return head.row, nil
+}
+
+func (m *mergeIter) Len() int {
+	return len(m.heads)
+}
+func (m *mergeIter) Less(i, j int) bool {
+	cmp, err := compareRows(m.heads[i].row, m.heads[j].row, m.keys)
+	if err != nil && m.err == nil {
+		m.err = err
+	}
+	if cmp == 0 {
+		return m.heads[i].src < m.heads[j].src
+	}
+	return cmp < 0
+}
+func (m *mergeIter) Swap(i, j int) {
+	m.heads[i], m.heads[j] = m.heads[j], m.heads[i]
+}
+func (m *mergeIter) Push(x interface{}) {
+	m.heads = append(m.heads, x.(mergeHead))
+}
+func (m *mergeIter) Pop() interface{} {
+	n := len(m.heads)
+	head := m.heads[n-1]
+	m.heads = m.heads[:n-1]
+	return head
+}
+
+func (iter *rowsIter) Next() (entity.Row, error) {
+	iter.position++
+	if iter.position >= len(iter.rows) {
+		return entity.Row{}, index.EndOfIterator
+	}
+	return iter.rows[iter.position], nil
+}
@@ -0,0 +1,115 @@
+package planner
+
+import (
+	"bufio"
+	"encoding/gob"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+func init() {
+	// rows hold their values as interfaces, so gob needs to know their
+	// concrete types. Decimals encode themselves with GobEncode.
+	gob.Register(types.Decimal{})
+	gob.Register(types.DateValue(0))
+	gob.Register(types.TimeValue(0))
+	gob.Register(types.TimestampValue(0))
+	gob.Register(types.TimestampTZValue(0))
+	gob.Register(types.IntervalValue{})
+	gob.Register(types.JSONValue(""))
+}
+
+// DefaultWorkMem is the amount of memory, in bytes, an operator may use for
+// its rows before spilling them to temporary files.
+const DefaultWorkMem = 4 << 20
+
+// spillFile holds rows that didn't fit in memory. The file is unlinked as soon
+// as it is created so that it disappears once closed, even if its reader is
+// abandoned.
+type spillFile struct {
+	file   *os.File
+	writer *bufio.Writer
+	enc    *gob.Encoder
+	size   int
+}
+
+func newSpillFile() (*spillFile, error) {
+	f, err := ioutil.TempFile("", "galedb-spill-")
+	if err != nil {
+		return nil, err
+	}
+	// ignore failures on platforms that can't remove open files
+	_ = os.Remove(f.Name())
+	w := bufio.NewWriter(f)
+	return &spillFile{
+		file:   f,
+		writer: w,
+		enc:    gob.NewEncoder(w),
+	}, nil
+}
+
+func (sf *spillFile) write(row entity.Row) error {
+	sf.size++
+	return sf.enc.Encode(&row)
+}
+
+// iterator flushes the file and returns an iterator reading its rows back in
+// the order they were written. The file is closed once the iterator is
+// exhausted.
+func (sf *spillFile) iterator() (index.Iterator, error) {
+	if err := sf.writer.Flush(); err != nil {
+		sf.file.Close()
+		return nil, err
+	}
+	if _, err := sf.file.Seek(0, io.SeekStart); err != nil {
+		sf.file.Close()
+		return nil, err
+	}
+	return &spillIter{
+		file: sf.file,
+		dec:  gob.NewDecoder(bufio.NewReader(sf.file)),
+	}, nil
+}
+
+func (sf *spillFile) close() error {
+	return sf.file.Close()
+}
+
+type spillIter struct {
+	file *os.File
+	dec  *gob.Decoder
+	done bool
+}
+
+func (iter *spillIter) Next() (entity.Row, error) {
+	if iter.done {
+		return entity.Row{}, index.EndOfIterator
+	}
+	var row entity.Row
+	if err := iter.dec.Decode(&row); err != nil {
+		iter.done = true
+		iter.file.Close()
+		if err == io.EOF {
+			return entity.Row{}, index.EndOfIterator
+		}
+		return entity.Row{}, err
+	}
+	return row, nil
+}
+
+// rowSize estimates the memory used by a row.
+func rowSize(row entity.Row) int {
+	size := 24
+	for _, val := range row.Values {
+		size += 16
+		if s, ok := val.(string); ok {
+			size += len(s)
+		}
+	}
+	return size
+}
@@ -0,0 +1,420 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+type (
+	// scope is the context a query is planned in. The scope of a subquery
+	// compiles its references to the columns of the enclosing query, which
+	// are evaluated on the row the subquery runs for.
+	scope struct {
+		planner *Planner
+		// parent compiles the expression of the enclosing query the
+		// subquery appears in. It is nil for top level queries.
+		parent *compiler
+		row    entity.Row
+		// correlated is set once the query refers to an enclosing query.
+		// Other subqueries return the same rows every time they run.
+		correlated bool
+		// ctes are the common table expressions the query can refer to.
+		ctes *cteEnv
+		// base is the scope of the query a nested scope belongs to. Nested
+		// scopes plan the parts of a query that see other CTEs: derived
+		// tables with a WITH clause and the CTEs themselves.
+		base *scope
+		// body and work are set when planning the query of a CTE. The
+		// recursive term of a recursive CTE reads itself from work.
+		body *cteDef
+		work *workTable
+	}
+
+	// subplan runs a subquery for the rows of the enclosing query.
+	subplan struct {
+		node  Node
+		scope *scope
+		sel   *parser.Select
+		// rows caches the rows of an uncorrelated subquery
+		rows   [][]entity.Value
+		cached bool
+	}
+
+	// OuterExpr evaluates an expression of an enclosing query on the row a
+	// correlated subquery runs for.
+	OuterExpr struct {
+		scope *scope
+		Expr  Expression
+	}
+
+	// SubqueryExpr is the value of a subquery returning a single column. It
+	// is NULL when the subquery returns no rows.
+	SubqueryExpr struct {
+		subplan
+	}
+
+	// ExistsExpr is true when a subquery returns any rows.
+	ExistsExpr struct {
+		subplan
+	}
+
+	// InSubqueryExpr tests whether the value of Expr is among the rows of a
+	// subquery, or with Not that it isn't. Like in Postgres the result is
+	// NULL rather than false when the value isn't found and either it or a
+	// row of the subquery is NULL.
+	InSubqueryExpr struct {
+		Expr Expression
+		Not  bool
+		// set holds the hashed rows of an uncorrelated subquery
+		set     map[string]bool
+		setNull bool
+		subplan
+	}
+
+	// DerivedTable is a subquery in a FROM clause. Its columns are
+	// qualified by the alias of the subquery.
+	DerivedTable struct {
+		PlanNode
+		// Names renames the leading columns of the subquery.
+		Names []string
+	}
+)
+
+// plan plans and prepares a subquery of an expression compiled by c.
+func (c *compiler) plan(sel *parser.Select) (subplan, error) {
+	if c.scope == nil {
+		return subplan{}, errors.New("cannot use subquery here")
+	}
+	s := &scope{planner: c.scope.planner, parent: c, ctes: c.scope.ctes}
+	// a CTE referred to from a subquery of its own query would be planned
+	// over and over
+	if s.planner.depth >= maxQueryDepth {
+		return subplan{}, errors.New("subqueries are nested too deeply")
+	}
+	s.planner.depth++
+	defer func() { s.planner.depth-- }()
+	node, err := s.planner.parseSelectStatement(sel, s)
+	if err != nil {
+		return subplan{}, err
+	}
+	if err := (&QueryPlan{Root: node}).prepare(node); err != nil {
+		return subplan{}, err
+	}
+	return subplan{node: node, scope: s, sel: sel}, nil
+}
+
+// query returns the scope of the query a nested scope belongs to.
+func (s *scope) query() *scope {
+	for s.base != nil {
+		s = s.base
+	}
+	return s
+}
+
+// nest returns a scope of the same query that sees the CTEs of env.
+func (s *scope) nest(env *cteEnv) *scope {
+	return &scope{
+		planner: s.planner,
+		parent:  s.parent,
+		ctes:    env,
+		base:    s,
+	}
+}
+
+func (c *compiler) compileSubquery(e *parser.Subquery) (Expression, error) {
+	sp, err := c.plan(e.Select)
+	if err != nil {
+		return nil, err
+	}
+	if len(sp.node.Columns()) != 1 {
+		return nil, errors.New("subquery must return only one column")
+	}
+	return &SubqueryExpr{subplan: sp}, nil
+}
+
+func (c *compiler) compileExists(e *parser.ExistsExpr) (Expression, error) {
+	sp, err := c.plan(e.Subquery)
+	if err != nil {
+		return nil, err
+	}
+	return &ExistsExpr{subplan: sp}, nil
+}
+
+func (c *compiler) compileIn(e *parser.InExpr) (Expression, error) {
+	lhs, err := c.compile(e.Expr)
+	if err != nil {
+		return nil, err
+	}
+	sp, err := c.plan(e.Subquery)
+	if err != nil {
+		return nil, err
+	}
+	cols := sp.node.Columns()
+	if len(cols) != 1 {
+		return nil, errors.New("subquery has too many columns")
+	}
+	// check that the operand compares to the rows of the subquery the
+	// way = does
+	cmp, err := c.newCompareExpr(relMap["="], lhs, &ColumnExpr{Column: cols[0]})
+	if err != nil {
+		return nil, err
+	}
+	return &InSubqueryExpr{
+		Expr:    cmp.(*CompareExpr).LHS,
+		Not:     e.Not,
+		subplan: sp,
+	}, nil
+}
+
+// fetch runs the subquery for a row of the enclosing query and returns up to
+// limit of its rows, or all of them when limit is negative.
+func (sp *subplan) fetch(row entity.Row, limit int) ([][]entity.Value, error) {
+	if sp.cached {
+		return sp.rows, nil
+	}
+	sp.scope.row = row
+	iter := sp.node.Iter()
+	rows := make([][]entity.Value, 0)
+	for limit < 0 || len(rows) < limit {
+		r, err := iter.Next()
+		if err == index.EndOfIterator {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		rows = append(rows, r.Values)
+	}
+	if !sp.scope.correlated {
+		sp.rows = rows
+		sp.cached = true
+	}
+	return rows, nil
+}
+
+func (e *OuterExpr) Eval(row entity.Row) (entity.Value, error) {
+	return e.Expr.Eval(e.scope.row)
+}
+func (e *OuterExpr) Type() types.T {
+	return e.Expr.Type()
+}
+func (e *OuterExpr) String() string {
+	return e.Expr.String()
+}
+
+func (e *SubqueryExpr) Eval(row entity.Row) (entity.Value, error) {
+	rows, err := e.fetch(row, 2)
+	if err != nil {
+		return nil, err
+	}
+	switch len(rows) {
+	case 0:
+		return nil, nil
+	case 1:
+		return rows[0][0], nil
+	}
+	return nil, errors.New("more than one row returned by a subquery used as an expression")
+}
+func (e *SubqueryExpr) Type() types.T {
+	return e.node.Columns()[0].Type
+}
+func (e *SubqueryExpr) String() string {
+	return fmt.Sprintf("(%s)", e.sel)
+}
+
+func (e *ExistsExpr) Eval(row entity.Row) (entity.Value, error) {
+	rows, err := e.fetch(row, 1)
+	if err != nil {
+		return nil, err
+	}
+	return len(rows) > 0, nil
+}
+func (e *ExistsExpr) Type() types.T {
+	return types.Bool
+}
+func (e *ExistsExpr) String() string {
+	return fmt.Sprintf("EXISTS (%s)", e.sel)
+}
+
+func (e *InSubqueryExpr) Eval(row entity.Row) (entity.Value, error) {
+	val, err := e.Expr.Eval(row)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := e.fetch(row, -1)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return e.Not, nil
+	}
+	if val == nil {
+		return nil, nil
+	}
+	found, null, err := e.find(val, rows)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case found:
+		return !e.Not, nil
+	case null:
+		return nil, nil
+	}
+	return e.Not, nil
+}
+
+// find looks for a value among the rows of the subquery, and reports whether
+// any of them is NULL.
+func (e *InSubqueryExpr) find(val entity.Value, rows [][]entity.Value) (bool, bool, error) {
+	if e.cached && e.Expr.Type().Family == e.node.Columns()[0].Type.Family {
+		if e.set == nil {
+			e.set = make(map[string]bool, len(rows))
+			for _, r := range rows {
+				if r[0] == nil {
+					e.setNull = true
+					continue
+				}
+				e.set[hashKey(r)] = true
+			}
+		}
+		return e.set[hashKey([]entity.Value{val})], e.setNull, nil
+	}
+	null := false
+	for _, r := range rows {
+		if r[0] == nil {
+			null = true
+			continue
+		}
+		cmp, err := types.Compare(val, r[0])
+		if err != nil {
+			return false, false, err
+		}
+		if cmp == 0 {
+			return true, null, nil
+		}
+	}
+	return false, null, nil
+}
+func (e *InSubqueryExpr) Type() types.T {
+	return types.Bool
+}
+func (e *InSubqueryExpr) String() string {
+	op := "IN"
+	if e.Not {
+		op = "NOT IN"
+	}
+	return fmt.Sprintf("(%s %s (%s))", e.Expr, op, e.sel)
+}
+
+// DerivedTable Expression
+func (dt *DerivedTable) Iter() index.Iterator {
+	return dt.Child.Iter()
+}
+func (dt *DerivedTable) Columns() []entity.Column {
+	return qualifyColumns(dt.Alias, renameColumns(dt.Child.Columns(), dt.Names))
+}
+func (dt *DerivedTable) Prepare() error {
+	if dt.Child == nil {
+		return errors.New("no child node")
+	}
+	if n := len(dt.Child.Columns()); len(dt.Names) > n {
+		return fmt.Errorf("table %s has %d columns available but %d columns specified", dt.Alias, n, len(dt.Names))
+	}
+	return nil
+}
+
+// renameColumns gives the leading columns the names listed.
+func renameColumns(cols []entity.Column, names []string) []entity.Column {
+	if len(names) == 0 {
+		return cols
+	}
+	res := make([]entity.Column, len(cols))
+	copy(res, cols)
+	for i := 0; i < len(names) && i < len(res); i++ {
+		res[i].Name = names[i]
+	}
+	return res
+}
+
+// parseSemiJoins runs the EXISTS, NOT EXISTS and IN subqueries among the top
+// level conjuncts of a WHERE clause as semi and anti joins of the input with
+// the FROM clause of the subquery, rather than running the subquery for every
+// row. It returns the joined input and the remaining conjuncts.
+func (p *Planner) parseSemiJoins(where parser.Expr, child Node, s *scope) (Node, parser.Expr, error) {
+	var rest parser.Expr
+	for _, conj := range conjuncts(where) {
+		kind, sub, operand := semiJoin(conj)
+		if sub == nil {
+			if rest == nil {
+				rest = conj
+			} else {
+				rest = parser.NewBinaryExpr(parser.OpAnd, rest, conj)
+			}
+			continue
+		}
+		right, err := p.parseFromStatement(sub.From, s)
+		if err != nil {
+			return nil, nil, err
+		}
+		join := &Join{
+			Kind:    kind,
+			Left:    child,
+			Right:   right,
+			Method:  p.JoinMethod,
+			WorkMem: p.WorkMem,
+			scope:   s,
+		}
+		if sub.Where != nil {
+			join.Cond = &parser.JoinCond{On: sub.Where.Expr}
+		}
+		if operand != nil {
+			join.Operand = operand
+			join.Item = sub.Cols[0].Expr
+		}
+		child = join
+	}
+	return child, rest, nil
+}
+
+// semiJoin returns the kind of join a WHERE conjunct can run as, its subquery
+// and the operand of IN. The subquery is nil for other conjuncts.
+func semiJoin(expr parser.Expr) (parser.JoinKind, *parser.Select, parser.Expr) {
+	switch e := expr.(type) {
+	case *parser.ExistsExpr:
+		if flatSubquery(e.Subquery) {
+			return parser.JoinSemi, e.Subquery, nil
+		}
+	case *parser.UnaryExpr:
+		if exists, ok := e.Expr.(*parser.ExistsExpr); ok && e.Op == parser.OpNot && flatSubquery(exists.Subquery) {
+			return parser.JoinAnti, exists.Subquery, nil
+		}
+	case *parser.InExpr:
+		// NOT IN isn't run as an anti join, as it is NULL rather than
+		// true when the subquery returns NULL
+		if e.Subquery == nil || e.Not || !flatSubquery(e.Subquery) || len(e.Subquery.Cols) != 1 {
+			break
+		}
+		if _, ok := e.Subquery.Cols[0].Expr.(*parser.Star); !ok {
+			return parser.JoinSemi, e.Subquery, e.Expr
+		}
+	}
+	return 0, nil, nil
+}
+
+// flatSubquery reports whether a subquery only filters the rows of its FROM
+// clause, so that each of them can be matched on its own.
+func flatSubquery(sel *parser.Select) bool {
+	if sel.With != nil || sel.From == nil || len(sel.GroupBy) > 0 || sel.Having != nil || sel.Limit != nil {
+		return false
+	}
+	exprs := make([]parser.Expr, len(sel.Cols))
+	for i, item := range sel.Cols {
+		exprs[i] = item.Expr
+	}
+	return len(collectAggregates(exprs)) == 0 && len(collectWindows(exprs)) == 0
+}
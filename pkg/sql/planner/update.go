@@ -0,0 +1,136 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/storage"
+)
+
+// Update rewrites every row produced by its child. The child is a scan of
+// Ref, optionally filtered by a Select, so rows still carry their key.
+type Update struct {
+	Ref         storage.Table
+	Assignments []*parser.Assignment
+	targets     []int
+	values      []Expression
+	PlanNode
+}
+
+func (upd *Update) Prepare() error {
+	cols := upd.Ref.Columns()
+	colMap := make(map[string]int)
+	for i, col := range cols {
+		colMap[col.Name] = i
+	}
+	seen := make(map[string]bool)
+	upd.targets = make([]int, len(upd.Assignments))
+	upd.values = make([]Expression, len(upd.Assignments))
+	for i, a := range upd.Assignments {
+		id, ok := colMap[a.Column]
+		if !ok {
+			return fmt.Errorf("invalid column %s", a.Column)
+		}
+		if seen[a.Column] {
+			return fmt.Errorf("multiple assignments to same column %s", a.Column)
+		}
+		seen[a.Column] = true
+		c := &compiler{cols: upd.Child.Columns(), scope: upd.scope}
+		var expr Expression
+		var err error
+		if _, ok := a.Value.(*parser.Default); ok {
+			// the default can't refer to the columns of the row
+			expr, err = compileDefault(cols[id], &compiler{loc: c.location(), now: c.statementTime()})
+		} else {
+			expr, err = c.compile(a.Value)
+		}
+		if err != nil {
+			return err
+		}
+		upd.values[i] = expr
+		upd.targets[i] = id
+	}
+	return nil
+}
+
+func (upd *Update) Exec() (int, error) {
+	rows, err := collectRows(upd.Child.Iter())
+	if err != nil {
+		return 0, err
+	}
+	cols := upd.Ref.Columns()
+	// compute every new row before touching the table so that a bad value
+	// leaves it unchanged
+	updated := make([]entity.Row, len(rows))
+	for i, row := range rows {
+		vals := make([]entity.Value, len(row.Values))
+		copy(vals, row.Values)
+		for j, id := range upd.targets {
+			val, err := upd.values[j].Eval(row)
+			if err != nil {
+				return 0, err
+			}
+			if vals[id], err = coerce(val, cols[id], upd.scope.planner.Session.TimeZone); err != nil {
+				return 0, err
+			}
+		}
+		if err := checkNotNull(vals, cols); err != nil {
+			return 0, err
+		}
+		updated[i] = entity.Row{Key: row.Key, Values: vals}
+	}
+	for i, row := range updated {
+		if err := upd.Ref.UpdateRow(row); err != nil {
+			return i, err
+		}
+	}
+	return len(updated), nil
+}
+
+func (upd *Update) Tag(n int) string {
+	return fmt.Sprintf("UPDATE %d", n)
+}
+
+// Delete removes every row produced by its child from Ref.
+type Delete struct {
+	Ref storage.Table
+	PlanNode
+}
+
+func (del *Delete) Prepare() error {
+	return nil
+}
+
+func (del *Delete) Exec() (int, error) {
+	rows, err := collectRows(del.Child.Iter())
+	if err != nil {
+		return 0, err
+	}
+	for i, row := range rows {
+		if err := del.Ref.RemoveRow(row.Key); err != nil {
+			return i, err
+		}
+	}
+	return len(rows), nil
+}
+
+func (del *Delete) Tag(n int) string {
+	return fmt.Sprintf("DELETE %d", n)
+}
+
+// collectRows drains an iterator. Commands read all affected rows before
+// modifying the table so that their changes don't feed back into the scan.
+func collectRows(iter index.Iterator) ([]entity.Row, error) {
+	rows := make([]entity.Row, 0)
+	for {
+		row, err := iter.Next()
+		if err == index.EndOfIterator {
+			return rows, nil
+		} else if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+}
@@ -0,0 +1,629 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
+	"github.com/hiepd/galedb/pkg/sql/parser"
+	"github.com/hiepd/galedb/pkg/types"
+)
+
+type (
+	// Window evaluates the window function calls that share a window. Its
+	// rows are those of its child followed by the results of the Calls.
+	//
+	// The rows of the child are extended with the PARTITION BY and ORDER BY
+	// values of the window and the arguments of the calls, and sorted by
+	// them. The rows of each partition are then held in memory while the
+	// calls are evaluated over them.
+	Window struct {
+		Spec    *parser.WindowSpec
+		Calls   []*parser.FuncCall
+		WorkMem int
+		// inputs are the expressions the rows are extended with, and keys
+		// sort the extended rows. The first parts keys hold the partition.
+		inputs []Expression
+		keys   []sortKey
+		parts  int
+		funcs  []*windowCall
+		frame  windowFrame
+		cols   []entity.Column
+		PlanNode
+	}
+
+	// windowCall is a window function call. Its arguments are read from
+	// the extended rows at the positions in args.
+	windowCall struct {
+		name string
+		args []int
+		// agg is set for aggregate functions, where star counts rows
+		agg  *aggregateFunc
+		star bool
+	}
+
+	// windowFrame holds the bounds of the frame of a window, with their
+	// offsets evaluated.
+	windowFrame struct {
+		mode       parser.FrameMode
+		start, end parser.BoundKind
+		startOff   int
+		endOff     int
+	}
+
+	// windowPartition holds the rows of a partition. The peers of row i,
+	// the rows equal to it on the ORDER BY values, are the rows from
+	// peerStart[i] up to peerEnd[i].
+	windowPartition struct {
+		rows      []entity.Row
+		peerStart []int
+		peerEnd   []int
+	}
+
+	WindowIter struct {
+		node  *Window
+		input index.Iterator
+		width int
+		part  *windowPartition
+		// results holds the results of each call for the rows of part
+		results [][]entity.Value
+		pos     int
+		// next is the first row of the following partition
+		next *entity.Row
+		done bool
+	}
+)
+
+// windowFuncs are the functions that can only be called over a window, with
+// the number of arguments they take.
+var windowFuncs = map[string]struct{ min, max int }{
+	"row_number":  {0, 0},
+	"rank":        {0, 0},
+	"dense_rank":  {0, 0},
+	"ntile":       {1, 1},
+	"lag":         {1, 3},
+	"lead":        {1, 3},
+	"first_value": {1, 1},
+	"last_value":  {1, 1},
+}
+
+// collectWindows lists the distinct window function calls in exprs.
+func collectWindows(exprs []parser.Expr) []*parser.FuncCall {
+	res := make([]*parser.FuncCall, 0)
+	seen := make(map[string]bool)
+	for _, expr := range exprs {
+		walkExpr(expr, func(e parser.Expr) bool {
+			call, ok := e.(*parser.FuncCall)
+			if !ok || call.Over == nil {
+				return true
+			}
+			if !seen[call.String()] {
+				seen[call.String()] = true
+				res = append(res, call)
+			}
+			return false
+		})
+	}
+	return res
+}
+
+// parseWindows adds a Window node over child for each distinct window used by
+// the window function calls of a query. It returns child when there are none.
+func (p *Planner) parseWindows(sel *parser.Select, child Node, s *scope) Node {
+	exprs := make([]parser.Expr, 0, len(sel.Cols)+len(sel.OrderBy))
+	for _, item := range sel.Cols {
+		exprs = append(exprs, item.Expr)
+	}
+	for _, item := range sel.OrderBy {
+		exprs = append(exprs, item.Expr)
+	}
+	if sel.Distinct != nil {
+		exprs = append(exprs, sel.Distinct.On...)
+	}
+	windows := make([]*Window, 0)
+	bySpec := make(map[string]*Window)
+	for _, call := range collectWindows(exprs) {
+		spec := call.Over.String()
+		w, ok := bySpec[spec]
+		if !ok {
+			w = &Window{
+				Spec:    call.Over,
+				WorkMem: p.WorkMem,
+				PlanNode: PlanNode{
+					scope: s,
+				},
+			}
+			bySpec[spec] = w
+			windows = append(windows, w)
+		}
+		w.Calls = append(w.Calls, call)
+	}
+	for _, w := range windows {
+		w.Child = child
+		child = w
+	}
+	return child
+}
+
+// lookupWindow finds the column holding the result of a window function call.
+func (c *compiler) lookupWindow(call *parser.FuncCall) (Expression, error) {
+	for _, w := range c.windows {
+		for i, other := range w.Calls {
+			if other.String() == call.String() {
+				pos := len(w.Child.Columns()) + i
+				return &ColumnExpr{Index: pos, Column: w.cols[pos]}, nil
+			}
+		}
+	}
+	return nil, errors.New("window functions are not allowed here")
+}
+
+// Window Expression
+func (w *Window) Iter() index.Iterator {
+	exprs := make([]Expression, 0, len(w.Child.Columns())+len(w.inputs))
+	for i, col := range w.Child.Columns() {
+		exprs = append(exprs, &ColumnExpr{Index: i, Column: col})
+	}
+	exprs = append(exprs, w.inputs...)
+	var input index.Iterator = &ProjectionIter{
+		exprs: exprs,
+		PlanIter: PlanIter{
+			ChildIter: w.Child.Iter(),
+		},
+	}
+	if len(w.keys) > 0 {
+		input = &SortIter{
+			keys:    w.keys,
+			workMem: w.WorkMem,
+			width:   len(exprs),
+			PlanIter: PlanIter{
+				ChildIter: input,
+			},
+		}
+	}
+	return &WindowIter{
+		node:  w,
+		input: input,
+		width: len(w.Child.Columns()),
+	}
+}
+func (w *Window) Columns() []entity.Column {
+	return w.cols
+}
+func (w *Window) Prepare() error {
+	if w.Child == nil {
+		return errors.New("no child node")
+	}
+	c := compilerFor(w.Child, w.scope)
+	c.windows = nil
+	width := len(w.Child.Columns())
+	w.inputs = make([]Expression, 0)
+	w.keys = make([]sortKey, 0, len(w.Spec.PartitionBy)+len(w.Spec.OrderBy))
+	add := func(expr parser.Expr) (int, error) {
+		input, err := c.compile(expr)
+		if err != nil {
+			return 0, err
+		}
+		w.inputs = append(w.inputs, input)
+		return width + len(w.inputs) - 1, nil
+	}
+	for _, expr := range w.Spec.PartitionBy {
+		pos, err := add(expr)
+		if err != nil {
+			return err
+		}
+		w.keys = append(w.keys, sortKey{index: pos})
+	}
+	w.parts = len(w.keys)
+	for _, item := range w.Spec.OrderBy {
+		pos, err := add(item.Expr)
+		if err != nil {
+			return err
+		}
+		key := sortKey{index: pos, desc: item.Desc, nullsFirst: item.Desc}
+		switch item.Nulls {
+		case parser.NullsFirst:
+			key.nullsFirst = true
+		case parser.NullsLast:
+			key.nullsFirst = false
+		}
+		w.keys = append(w.keys, key)
+	}
+	frame, err := newWindowFrame(w.Spec.Frame)
+	if err != nil {
+		return err
+	}
+	w.frame = frame
+	cols := make([]entity.Column, width, width+len(w.Calls))
+	copy(cols, w.Child.Columns())
+	w.funcs = make([]*windowCall, len(w.Calls))
+	for i, call := range w.Calls {
+		fn, typ, err := w.compileCall(call, add)
+		if err != nil {
+			return err
+		}
+		w.funcs[i] = fn
+		// the results can only be referred to by the calls
+		cols = append(cols, entity.Column{Type: typ, Name: call.Name, Merged: true})
+	}
+	w.cols = cols
+	if w.WorkMem <= 0 {
+		w.WorkMem = DefaultWorkMem
+	}
+	return nil
+}
+
+// compileCall checks a window function call and adds its arguments to the
+// inputs with add. It returns the type of the results.
+func (w *Window) compileCall(call *parser.FuncCall, add func(parser.Expr) (int, error)) (*windowCall, types.T, error) {
+	fn := &windowCall{name: call.Name, star: call.Star}
+	if call.Distinct {
+		return nil, types.T{}, errors.New("DISTINCT is not implemented for window functions")
+	}
+	arity, isWindow := windowFuncs[call.Name]
+	agg, isAgg := aggregates[call.Name]
+	switch {
+	case isAgg:
+		fn.agg = &agg
+		if call.Star && call.Name != "count" {
+			return nil, types.T{}, fmt.Errorf("function %s(*) does not exist", call.Name)
+		}
+		if !call.Star && len(call.Args) != 1 {
+			return nil, types.T{}, fmt.Errorf("function %s takes exactly one argument", call.Name)
+		}
+	case !isWindow:
+		return nil, types.T{}, fmt.Errorf("OVER specified, but %s is not a window function nor an aggregate function", call.Name)
+	case call.Star || len(call.Args) < arity.min || len(call.Args) > arity.max:
+		return nil, types.T{}, fmt.Errorf("function %s takes %d to %d arguments", call.Name, arity.min, arity.max)
+	}
+	typs := make([]types.T, len(call.Args))
+	for i, arg := range call.Args {
+		pos, err := add(arg)
+		if err != nil {
+			return nil, types.T{}, err
+		}
+		fn.args = append(fn.args, pos)
+		typs[i] = w.inputs[pos-len(w.Child.Columns())].Type()
+	}
+	switch call.Name {
+	case "row_number", "rank", "dense_rank":
+		return fn, types.BigInt, nil
+	case "ntile":
+		if err := expectInt(call.Name, w.input(fn.args[0])); err != nil {
+			return nil, types.T{}, err
+		}
+		return fn, types.Int, nil
+	case "lag", "lead":
+		if len(typs) > 1 {
+			if err := expectInt(call.Name, w.input(fn.args[1])); err != nil {
+				return nil, types.T{}, err
+			}
+		}
+		if len(typs) > 2 {
+			// the default takes the place of the value
+			args, typ, err := (&compiler{scope: w.scope}).unifyResults(call.Name, []Expression{w.input(fn.args[0]), w.input(fn.args[2])})
+			if err != nil {
+				return nil, types.T{}, err
+			}
+			w.inputs[fn.args[2]-len(w.Child.Columns())] = args[1]
+			return fn, typ, nil
+		}
+		return fn, typs[0], nil
+	case "first_value", "last_value":
+		return fn, typs[0], nil
+	}
+	typ := types.Unknown
+	if !call.Star {
+		typ = typs[0]
+	}
+	res, ok := agg.typ(typ)
+	if !ok {
+		return nil, types.T{}, fmt.Errorf("function %s(%s) does not exist", call.Name, typ)
+	}
+	return fn, res, nil
+}
+
+// input returns the input expression at a position of the extended rows.
+func (w *Window) input(pos int) Expression {
+	return w.inputs[pos-len(w.Child.Columns())]
+}
+
+// newWindowFrame checks the bounds of a frame and evaluates their offsets.
+// The default frame ends with the last peer of the current row.
+func newWindowFrame(frame *parser.Frame) (windowFrame, error) {
+	if frame == nil {
+		return windowFrame{mode: parser.FrameRange, start: parser.UnboundedPreceding, end: parser.CurrentRow}, nil
+	}
+	res := windowFrame{mode: frame.Mode, start: frame.Start.Kind, end: frame.End.Kind}
+	switch {
+	case res.start == parser.UnboundedFollowing:
+		return res, errors.New("frame start cannot be UNBOUNDED FOLLOWING")
+	case res.end == parser.UnboundedPreceding:
+		return res, errors.New("frame end cannot be UNBOUNDED PRECEDING")
+	case res.start > res.end:
+		return res, fmt.Errorf("frame starting from %s cannot end with %s", frame.Start, frame.End)
+	}
+	var err error
+	if res.startOff, err = frameOffset(frame.Mode, frame.Start, "starting"); err != nil {
+		return res, err
+	}
+	if res.endOff, err = frameOffset(frame.Mode, frame.End, "ending"); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// frameOffset evaluates the offset of a PRECEDING or FOLLOWING bound, which
+// can't refer to any column.
+func frameOffset(mode parser.FrameMode, bound *parser.FrameBound, which string) (int, error) {
+	if bound.Offset == nil {
+		return 0, nil
+	}
+	if mode == parser.FrameRange {
+		return 0, errors.New("RANGE with offset PRECEDING/FOLLOWING is not supported")
+	}
+	expr, err := compileExpr(bound.Offset, nil)
+	if err != nil {
+		return 0, err
+	}
+	val, err := expr.Eval(entity.Row{})
+	if err != nil {
+		return 0, err
+	}
+	n, ok := val.(int)
+	switch {
+	case val == nil:
+		return 0, fmt.Errorf("frame %s offset must not be null", which)
+	case !ok:
+		return 0, fmt.Errorf("argument of ROWS must be type integer, not %s", expr.Type())
+	case n < 0:
+		return 0, fmt.Errorf("frame %s offset must not be negative", which)
+	}
+	return n, nil
+}
+
+// bounds returns the first and last row of the frame of row i. The frame is
+// empty when the first comes after the last.
+func (f *windowFrame) bounds(p *windowPartition, i int) (int, int) {
+	var start, end int
+	switch f.start {
+	case parser.UnboundedPreceding:
+		start = 0
+	case parser.Preceding:
+		start = i - f.startOff
+	case parser.CurrentRow:
+		start = i
+		if f.mode == parser.FrameRange {
+			start = p.peerStart[i]
+		}
+	case parser.Following:
+		start = i + f.startOff
+	}
+	switch f.end {
+	case parser.Preceding:
+		end = i - f.endOff
+	case parser.CurrentRow:
+		end = i
+		if f.mode == parser.FrameRange {
+			end = p.peerEnd[i] - 1
+		}
+	case parser.Following:
+		end = i + f.endOff
+	case parser.UnboundedFollowing:
+		end = len(p.rows) - 1
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > len(p.rows)-1 {
+		end = len(p.rows) - 1
+	}
+	return start, end
+}
+
+func (iter *WindowIter) Next() (entity.Row, error) {
+	for iter.part == nil || iter.pos >= len(iter.part.rows) {
+		if err := iter.nextPartition(); err != nil {
+			return entity.Row{}, err
+		}
+	}
+	row := iter.part.rows[iter.pos]
+	vals := make([]entity.Value, iter.width, iter.width+len(iter.results))
+	copy(vals, row.Values[:iter.width])
+	for _, res := range iter.results {
+		vals = append(vals, res[iter.pos])
+	}
+	iter.pos++
+	return entity.Row{Key: row.Key, Values: vals}, nil
+}
+
+// nextPartition reads the rows of the next partition and evaluates the calls
+// over them.
+func (iter *WindowIter) nextPartition() error {
+	if iter.done {
+		return index.EndOfIterator
+	}
+	node := iter.node
+	p := &windowPartition{}
+	var key string
+	if iter.next != nil {
+		p.rows = append(p.rows, *iter.next)
+		key = hashKey(partitionValues(*iter.next, node.keys[:node.parts]))
+		iter.next = nil
+	}
+	for {
+		row, err := iter.input.Next()
+		if err == index.EndOfIterator {
+			iter.done = true
+			break
+		} else if err != nil {
+			return err
+		}
+		k := hashKey(partitionValues(row, node.keys[:node.parts]))
+		if len(p.rows) > 0 && k != key {
+			iter.next = &row
+			break
+		}
+		key = k
+		p.rows = append(p.rows, row)
+	}
+	if len(p.rows) == 0 {
+		return index.EndOfIterator
+	}
+	if err := p.findPeers(node.keys[node.parts:]); err != nil {
+		return err
+	}
+	iter.results = make([][]entity.Value, len(node.funcs))
+	for i, fn := range node.funcs {
+		res, err := fn.evaluate(p, &node.frame)
+		if err != nil {
+			return err
+		}
+		iter.results[i] = res
+	}
+	iter.part = p
+	iter.pos = 0
+	return nil
+}
+
+func partitionValues(row entity.Row, keys []sortKey) []entity.Value {
+	vals := make([]entity.Value, len(keys))
+	for i, key := range keys {
+		vals[i] = row.Values[key.index]
+	}
+	return vals
+}
+
+// findPeers groups the rows that are equal on the ORDER BY values. Without
+// ORDER BY all rows of the partition are peers.
+func (p *windowPartition) findPeers(keys []sortKey) error {
+	n := len(p.rows)
+	p.peerStart = make([]int, n)
+	p.peerEnd = make([]int, n)
+	start := 0
+	for i := 1; i <= n; i++ {
+		if i < n {
+			cmp, err := compareRows(p.rows[start], p.rows[i], keys)
+			if err != nil {
+				return err
+			}
+			if cmp == 0 {
+				continue
+			}
+		}
+		for j := start; j < i; j++ {
+			p.peerStart[j], p.peerEnd[j] = start, i
+		}
+		start = i
+	}
+	return nil
+}
+
+// evaluate returns the results of a call for the rows of a partition.
+func (fn *windowCall) evaluate(p *windowPartition, frame *windowFrame) ([]entity.Value, error) {
+	n := len(p.rows)
+	res := make([]entity.Value, n)
+	arg := func(i, j int) entity.Value {
+		return p.rows[i].Values[fn.args[j]]
+	}
+	if fn.agg != nil {
+		return fn.aggregate(p, frame)
+	}
+	rank := 0
+	for i := 0; i < n; i++ {
+		switch fn.name {
+		case "row_number":
+			res[i] = i + 1
+		case "rank":
+			res[i] = p.peerStart[i] + 1
+		case "dense_rank":
+			if p.peerStart[i] == i {
+				rank++
+			}
+			res[i] = rank
+		case "ntile":
+			buckets := arg(i, 0)
+			if buckets == nil {
+				continue
+			}
+			if buckets.(int) <= 0 {
+				return nil, errors.New("argument of ntile must be greater than zero")
+			}
+			res[i] = ntile(i, n, buckets.(int))
+		case "lag", "lead":
+			offset := entity.Value(1)
+			if len(fn.args) > 1 {
+				offset = arg(i, 1)
+			}
+			if offset == nil {
+				continue
+			}
+			j := i - offset.(int)
+			if fn.name == "lead" {
+				j = i + offset.(int)
+			}
+			if j >= 0 && j < n {
+				res[i] = arg(j, 0)
+			} else if len(fn.args) > 2 {
+				res[i] = arg(i, 2)
+			}
+		case "first_value", "last_value":
+			start, end := frame.bounds(p, i)
+			if start > end {
+				continue
+			}
+			if fn.name == "first_value" {
+				res[i] = arg(start, 0)
+			} else {
+				res[i] = arg(end, 0)
+			}
+		}
+	}
+	return res, nil
+}
+
+// ntile returns the bucket of row i out of n rows split into buckets of
+// sizes differing by at most one, the larger ones first.
+func ntile(i, n, buckets int) int {
+	size, rest := n/buckets, n%buckets
+	if size == 0 {
+		return i + 1
+	}
+	if big := rest * (size + 1); i >= big {
+		return (i-big)/size + rest + 1
+	}
+	return i/(size+1) + 1
+}
+
+// aggregate computes an aggregate over the frame of each row. When frames all
+// start at the first row, each frame extends the last one and the rows are
+// added to a single accumulator as the frame grows.
+func (fn *windowCall) aggregate(p *windowPartition, frame *windowFrame) ([]entity.Value, error) {
+	res := make([]entity.Value, len(p.rows))
+	add := func(acc accumulator, j int) error {
+		var val entity.Value = 1
+		if !fn.star {
+			val = p.rows[j].Values[fn.args[0]]
+		}
+		if val == nil && !fn.agg.nulls {
+			return nil
+		}
+		return acc.add(val)
+	}
+	var acc accumulator
+	added := 0
+	for i := range p.rows {
+		start, end := frame.bounds(p, i)
+		if frame.start != parser.UnboundedPreceding || acc == nil {
+			acc, added = fn.agg.new(), start
+		}
+		for ; added <= end; added++ {
+			if err := add(acc, added); err != nil {
+				return nil, err
+			}
+		}
+		res[i] = acc.result()
+	}
+	return res, nil
+}
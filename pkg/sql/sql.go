@@ -6,6 +6,7 @@ const (
 	CompareEqualOrLess
 	CompareLess
 	CompareGreater
+	CompareNotEqual
 )
 
 type Result struct{}
@@ -2,17 +2,95 @@ package storage
 
 import (
 	"fmt"
+	"sync"
+
+	"github.com/hiepd/galedb/pkg/entity"
+	"github.com/hiepd/galedb/pkg/index"
 )
 
 type Database struct {
 	Name    string
 	Catalog map[string]*PersistentTable
+	// indexes maps the names of the indexes to the names of their tables.
+	indexes map[string]string
+	mu      sync.RWMutex
 }
 
 func (db *Database) GetTable(tableName string) (*PersistentTable, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
 	t, ok := db.Catalog[tableName]
 	if !ok {
 		return nil, fmt.Errorf("cannot find table %s in database %s", tableName, db.Name)
 	}
 	return t, nil
 }
+
+// CreateTable adds an empty table to the catalog. It returns false without an
+// error when the table already exists and ifNotExists is set.
+func (db *Database) CreateTable(tableName string, columns []entity.Column, ifNotExists bool) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.Catalog[tableName]; ok {
+		if ifNotExists {
+			return false, nil
+		}
+		return false, fmt.Errorf("table %s already exists in database %s", tableName, db.Name)
+	}
+	if db.Catalog == nil {
+		db.Catalog = make(map[string]*PersistentTable)
+	}
+	db.Catalog[tableName] = NewPersisentTable(columns).(*PersistentTable)
+	return true, nil
+}
+
+// DropTables removes tables from the catalog. Either all of them are removed
+// or none are. Missing tables are skipped when ifExists is set.
+func (db *Database) DropTables(tableNames []string, ifExists bool) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, tableName := range tableNames {
+		if _, ok := db.Catalog[tableName]; !ok && !ifExists {
+			return 0, fmt.Errorf("cannot find table %s in database %s", tableName, db.Name)
+		}
+	}
+	n := 0
+	for _, tableName := range tableNames {
+		if _, ok := db.Catalog[tableName]; ok {
+			delete(db.Catalog, tableName)
+			n++
+		}
+	}
+	for name, tableName := range db.indexes {
+		if _, ok := db.Catalog[tableName]; !ok {
+			delete(db.indexes, name)
+		}
+	}
+	return n, nil
+}
+
+// CreateIndex adds an index to a table and fills it with the rows of the
+// table. Index names are unique in the database. It returns false without an
+// error when the index already exists and ifNotExists is set.
+func (db *Database) CreateIndex(tableName, indexName string, idx index.Index, ifNotExists bool) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.indexes[indexName]; ok {
+		if ifNotExists {
+			return false, nil
+		}
+		return false, fmt.Errorf("relation %s already exists", indexName)
+	}
+	t, ok := db.Catalog[tableName]
+	if !ok {
+		return false, fmt.Errorf("cannot find table %s in database %s", tableName, db.Name)
+	}
+	if err := t.AddIndex(idx); err != nil {
+		return false, err
+	}
+	if db.indexes == nil {
+		db.indexes = make(map[string]string)
+	}
+	db.indexes[indexName] = tableName
+	return true, nil
+}
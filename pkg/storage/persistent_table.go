@@ -37,6 +37,48 @@ func (pt *PersistentTable) AddRow(row entity.Row) error {
 	return nil
 }
 
+// UpdateRow replaces the values of the row identified by row.Key in every
+// index.
+func (pt *PersistentTable) UpdateRow(row entity.Row) error {
+	for _, idx := range pt.Indexes {
+		// TODO: Need to rollback everything if one index failed
+		if err := idx.Update(row.Key, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveRow deletes the row identified by key from every index.
+func (pt *PersistentTable) RemoveRow(key entity.Key) error {
+	for _, idx := range pt.Indexes {
+		// TODO: Need to rollback everything if one index failed
+		if err := idx.Remove(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddIndex adds a secondary index holding the rows of the table.
+func (pt *PersistentTable) AddIndex(idx index.Index) error {
+	iter := pt.Indexes[0].Iterator()
+	for {
+		row, err := iter.Next()
+		if err == index.EndOfIterator {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := idx.Add(row); err != nil {
+			return err
+		}
+	}
+	pt.Indexes = append(pt.Indexes, idx)
+	return nil
+}
+
 func (pt *PersistentTable) Columns() []entity.Column {
 	return pt.columns
 }
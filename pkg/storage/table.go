@@ -5,5 +5,7 @@ import "github.com/hiepd/galedb/pkg/entity"
 type Table interface {
 	IsPersistent() bool
 	AddRow(row entity.Row) error
+	UpdateRow(row entity.Row) error
+	RemoveRow(key entity.Key) error
 	Columns() []entity.Column
 }
@@ -0,0 +1,550 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// DateValue is the value of a DATE, the number of days since
+	// 1970-01-01.
+	DateValue int64
+
+	// TimeValue is the value of a TIME, the number of microseconds since
+	// midnight.
+	TimeValue int64
+
+	// TimestampValue is the value of a TIMESTAMP, the number of
+	// microseconds since 1970-01-01 00:00:00 on a clock of no particular
+	// time zone.
+	TimestampValue int64
+
+	// TimestampTZValue is the value of a TIMESTAMPTZ, the number of
+	// microseconds since the Unix epoch. It is shown in the time zone of
+	// the session.
+	TimestampTZValue int64
+
+	// IntervalValue is the value of an INTERVAL. Months and days are kept
+	// apart from the rest since their length varies.
+	IntervalValue struct {
+		Months int
+		Days   int
+		Micros int64
+	}
+)
+
+const (
+	usPerSecond = int64(time.Second / time.Microsecond)
+	usPerMinute = 60 * usPerSecond
+	usPerHour   = 60 * usPerMinute
+	usPerDay    = 24 * usPerHour
+	// daysPerMonth is the length of a month when intervals are compared, or
+	// when fractions of months are turned into days.
+	daysPerMonth = 30
+)
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// micros returns the microseconds since the Unix epoch of t.
+func micros(t time.Time) int64 {
+	return t.Unix()*usPerSecond + int64(t.Nanosecond())/int64(time.Microsecond)
+}
+
+// fromMicros returns the UTC time of microseconds since the Unix epoch.
+func fromMicros(us int64) time.Time {
+	sec := floorDiv(us, usPerSecond)
+	return time.Unix(sec, (us-sec*usPerSecond)*int64(time.Microsecond)).UTC()
+}
+
+// wallClock returns the time with the same date and clock as t, in loc.
+func wallClock(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.Date()
+	h, min, s := t.Clock()
+	return time.Date(y, m, d, h, min, s, t.Nanosecond(), loc)
+}
+
+// DateOf returns the date of t in its location.
+func DateOf(t time.Time) DateValue {
+	return DateValue(floorDiv(micros(wallClock(t, time.UTC)), usPerDay))
+}
+
+// Time returns midnight UTC of the date.
+func (d DateValue) Time() time.Time {
+	return fromMicros(int64(d) * usPerDay)
+}
+
+// Timestamp returns midnight of the date.
+func (d DateValue) Timestamp() TimestampValue {
+	return TimestampValue(int64(d) * usPerDay)
+}
+
+func (d DateValue) String() string {
+	return d.Time().Format("2006-01-02")
+}
+
+func (t TimeValue) String() string {
+	return fromMicros(int64(t)).Format("15:04:05.999999")
+}
+
+// TimestampOf returns the timestamp of the date and clock of t in its
+// location.
+func TimestampOf(t time.Time) TimestampValue {
+	return TimestampValue(micros(wallClock(t, time.UTC)))
+}
+
+// Time returns the timestamp as a UTC time.
+func (ts TimestampValue) Time() time.Time {
+	return fromMicros(int64(ts))
+}
+
+// Zoned returns the instant the clocks of loc show the timestamp.
+func (ts TimestampValue) Zoned(loc *time.Location) TimestampTZValue {
+	return TimestampTZValue(micros(wallClock(ts.Time(), loc)))
+}
+
+// Date returns the date of the timestamp.
+func (ts TimestampValue) Date() DateValue {
+	return DateValue(floorDiv(int64(ts), usPerDay))
+}
+
+// Clock returns the time of day of the timestamp.
+func (ts TimestampValue) Clock() TimeValue {
+	return TimeValue(int64(ts) - floorDiv(int64(ts), usPerDay)*usPerDay)
+}
+
+func (ts TimestampValue) String() string {
+	return ts.Time().Format("2006-01-02 15:04:05.999999")
+}
+
+// AddInterval adds the months, the days and then the rest of an interval.
+// Adding months keeps the day of the month, unless the month is shorter.
+func (ts TimestampValue) AddInterval(iv IntervalValue) TimestampValue {
+	t := addMonthsDays(ts.Time(), iv.Months, iv.Days)
+	return TimestampValue(micros(t) + iv.Micros)
+}
+
+// Sub returns the interval from u to ts, in days and a time of less than a
+// day.
+func (ts TimestampValue) Sub(u TimestampValue) IntervalValue {
+	return justifyHours(int64(ts) - int64(u))
+}
+
+// TimestampTZOf returns the instant of t.
+func TimestampTZOf(t time.Time) TimestampTZValue {
+	return TimestampTZValue(micros(t))
+}
+
+// Time returns the instant as a UTC time.
+func (ts TimestampTZValue) Time() time.Time {
+	return fromMicros(int64(ts))
+}
+
+// Local returns the timestamp the clocks of loc show at the instant.
+func (ts TimestampTZValue) Local(loc *time.Location) TimestampValue {
+	return TimestampOf(ts.Time().In(loc))
+}
+
+func (ts TimestampTZValue) String() string {
+	return formatTimestampTZ(ts, time.UTC)
+}
+
+// AddInterval adds an interval like TimestampValue.AddInterval, counting
+// months and days on the clocks of loc.
+func (ts TimestampTZValue) AddInterval(iv IntervalValue, loc *time.Location) TimestampTZValue {
+	t := addMonthsDays(ts.Time().In(loc), iv.Months, iv.Days)
+	return TimestampTZValue(micros(t) + iv.Micros)
+}
+
+// Sub returns the interval from u to ts, in days and a time of less than a
+// day.
+func (ts TimestampTZValue) Sub(u TimestampTZValue) IntervalValue {
+	return justifyHours(int64(ts) - int64(u))
+}
+
+func addMonthsDays(t time.Time, months, days int) time.Time {
+	y, m, d := t.Date()
+	if months != 0 {
+		n := int64(m) - 1 + int64(months)
+		y += int(floorDiv(n, 12))
+		m = time.Month(n-floorDiv(n, 12)*12) + 1
+		// the first of the next month, less a day
+		if last := time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day(); d > last {
+			d = last
+		}
+	}
+	h, min, s := t.Clock()
+	return time.Date(y, m, d+days, h, min, s, t.Nanosecond(), t.Location())
+}
+
+func justifyHours(us int64) IntervalValue {
+	return IntervalValue{Days: int(us / usPerDay), Micros: us % usPerDay}
+}
+
+// Add returns the sum of two intervals.
+func (v IntervalValue) Add(w IntervalValue) IntervalValue {
+	return IntervalValue{Months: v.Months + w.Months, Days: v.Days + w.Days, Micros: v.Micros + w.Micros}
+}
+
+// Neg returns -v.
+func (v IntervalValue) Neg() IntervalValue {
+	return IntervalValue{Months: -v.Months, Days: -v.Days, Micros: -v.Micros}
+}
+
+// Mul multiplies an interval by a number. Fractions of months are carried
+// over to days, and fractions of days to the rest.
+func (v IntervalValue) Mul(f float64) (IntervalValue, error) {
+	months := float64(v.Months) * f
+	days := float64(v.Days)*f + (months-math.Trunc(months))*daysPerMonth
+	us := math.Round(float64(v.Micros)*f + (days-math.Trunc(days))*float64(usPerDay))
+	for _, x := range []float64{months, days, us} {
+		if math.IsNaN(x) || math.Abs(x) >= math.MaxInt64 {
+			return IntervalValue{}, fmt.Errorf("interval out of range")
+		}
+	}
+	return IntervalValue{Months: int(months), Days: int(days), Micros: int64(us)}, nil
+}
+
+// Div divides an interval by a number.
+func (v IntervalValue) Div(f float64) (IntervalValue, error) {
+	if f == 0 {
+		return IntervalValue{}, fmt.Errorf("division by zero")
+	}
+	return v.Mul(1 / f)
+}
+
+// span returns the length of the interval in days and microseconds of less
+// than a day, counting 30 days in a month. Intervals of the same span are
+// equal.
+func (v IntervalValue) span() (int64, int64) {
+	days := int64(v.Months)*daysPerMonth + int64(v.Days) + floorDiv(v.Micros, usPerDay)
+	return days, v.Micros - floorDiv(v.Micros, usPerDay)*usPerDay
+}
+
+// Cmp compares two intervals by their length, counting 30 days in a month.
+func (v IntervalValue) Cmp(w IntervalValue) int {
+	vd, vus := v.span()
+	wd, wus := w.span()
+	switch {
+	case vd < wd || (vd == wd && vus < wus):
+		return -1
+	case vd > wd || (vd == wd && vus > wus):
+		return 1
+	}
+	return 0
+}
+
+// Key returns a string that is the same for equal intervals.
+func (v IntervalValue) Key() string {
+	days, us := v.span()
+	return strconv.FormatInt(days, 10) + ":" + strconv.FormatInt(us, 10)
+}
+
+// String formats the interval the way Postgres does by default, like
+// "1 year 2 mons -3 days +04:05:06.5".
+func (v IntervalValue) String() string {
+	var b strings.Builder
+	zero, before := true, false
+	part := func(n int, unit string) {
+		if n == 0 {
+			return
+		}
+		if !zero {
+			b.WriteByte(' ')
+		}
+		if before && n > 0 {
+			b.WriteByte('+')
+		}
+		fmt.Fprintf(&b, "%d %s", n, unit)
+		if n != 1 {
+			b.WriteByte('s')
+		}
+		zero, before = false, n < 0
+	}
+	part(v.Months/12, "year")
+	part(v.Months%12, "mon")
+	part(v.Days, "day")
+	if zero || v.Micros != 0 {
+		if !zero {
+			b.WriteByte(' ')
+		}
+		us := v.Micros
+		if us < 0 {
+			b.WriteByte('-')
+			us = -us
+		} else if before {
+			b.WriteByte('+')
+		}
+		fmt.Fprintf(&b, "%02d:%02d:%02d", us/usPerHour, us/usPerMinute%60, us/usPerSecond%60)
+		if frac := us % usPerSecond; frac != 0 {
+			b.WriteString(strings.TrimRight(fmt.Sprintf(".%06d", frac), "0"))
+		}
+	}
+	return b.String()
+}
+
+// timestampLayouts are the accepted formats of timestamps, and whether they
+// have a time zone. Fractions of seconds are accepted after the seconds
+// of any of them.
+var timestampLayouts = []struct {
+	layout string
+	zone   bool
+}{
+	{"2006-01-02 15:04:05Z07", true},
+	{"2006-01-02 15:04:05Z07:00", true},
+	{"2006-01-02T15:04:05Z07:00", true},
+	{"2006-01-02 15:04Z07", true},
+	{"2006-01-02 15:04:05", false},
+	{"2006-01-02T15:04:05", false},
+	{"2006-01-02 15:04", false},
+	{"2006-01-02", false},
+}
+
+// parseTimestamp reads a date and a time of day, with an optional offset
+// from UTC. Without one, the time is returned in UTC.
+func parseTimestamp(s string) (time.Time, bool, bool) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "epoch") {
+		return time.Unix(0, 0).UTC(), true, true
+	}
+	for _, l := range timestampLayouts {
+		if t, err := time.Parse(l.layout, s); err == nil {
+			return t.Round(time.Microsecond), l.zone, true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+func parseTime(s string) (TimeValue, bool) {
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return TimeValue(micros(t.Round(time.Microsecond)) - micros(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC))), true
+		}
+	}
+	return 0, false
+}
+
+// intervalUnits are the units of intervals with their length in months,
+// days or microseconds.
+var intervalUnits = map[string]struct {
+	months, days int
+	us           int64
+}{
+	"microsecond": {us: 1},
+	"millisecond": {us: 1000},
+	"second":      {us: usPerSecond},
+	"minute":      {us: usPerMinute},
+	"hour":        {us: usPerHour},
+	"day":         {days: 1},
+	"week":        {days: 7},
+	"month":       {months: 1},
+	"year":        {months: 12},
+	"decade":      {months: 120},
+	"century":     {months: 1200},
+	"millennium":  {months: 12000},
+}
+
+// intervalAbbrevs are the other names of intervalUnits.
+var intervalAbbrevs = map[string]string{
+	"us": "microsecond", "usec": "microsecond", "usecs": "microsecond", "microseconds": "microsecond",
+	"ms": "millisecond", "msec": "millisecond", "msecs": "millisecond", "milliseconds": "millisecond",
+	"s": "second", "sec": "second", "secs": "second", "seconds": "second",
+	"m": "minute", "min": "minute", "mins": "minute", "minutes": "minute",
+	"h": "hour", "hr": "hour", "hrs": "hour", "hours": "hour",
+	"d": "day", "days": "day",
+	"w": "week", "weeks": "week",
+	"mon": "month", "mons": "month", "months": "month",
+	"y": "year", "yr": "year", "yrs": "year", "years": "year",
+	"decades": "decade", "centuries": "century", "millennia": "millennium", "millenniums": "millennium",
+}
+
+// intervalUnit returns the name of an interval unit in intervalUnits, or ""
+// if there is no such unit.
+func intervalUnit(name string) string {
+	if long, ok := intervalAbbrevs[name]; ok {
+		return long
+	}
+	if _, ok := intervalUnits[name]; ok {
+		return name
+	}
+	return ""
+}
+
+// ParseInterval reads an interval written as numbers followed by units, like
+// "1 year 2 months", optionally followed by a time like "-04:05:06.5".
+func ParseInterval(s string) (IntervalValue, error) {
+	invalid := fmt.Errorf("invalid input syntax for type interval: %q", s)
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 {
+		return IntervalValue{}, invalid
+	}
+	var months, days float64
+	var us float64
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if strings.Contains(field, ":") {
+			t, ok := parseClock(field)
+			if !ok {
+				return IntervalValue{}, invalid
+			}
+			us += float64(t)
+			continue
+		}
+		end := strings.LastIndexAny(field, "0123456789.") + 1
+		num, unit := field[:end], field[end:]
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return IntervalValue{}, invalid
+		}
+		if unit == "" && i+1 < len(fields) && intervalUnit(fields[i+1]) != "" {
+			i++
+			unit = fields[i]
+		}
+		unit = intervalUnit(unit)
+		if unit == "" {
+			if field[end:] != "" {
+				return IntervalValue{}, invalid
+			}
+			// a bare number is a number of seconds
+			unit = "second"
+		}
+		u := intervalUnits[unit]
+		months += n * float64(u.months)
+		days += n * float64(u.days)
+		us += n * float64(u.us)
+	}
+	// fractions of months and days carry over to the smaller units
+	months = math.Round(months*1e6) / 1e6
+	days += (months - math.Trunc(months)) * daysPerMonth
+	us += (days - math.Trunc(days)) * float64(usPerDay)
+	if math.Abs(months) >= math.MaxInt32 || math.Abs(days) >= math.MaxInt32 || math.Abs(us) >= math.MaxInt64 {
+		return IntervalValue{}, fmt.Errorf("interval field value out of range: %q", s)
+	}
+	return IntervalValue{Months: int(months), Days: int(days), Micros: int64(math.Round(us))}, nil
+}
+
+// parseClock reads a signed time of hours, minutes and optionally seconds.
+// Hours may exceed a day.
+func parseClock(s string) (int64, bool) {
+	sign := int64(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	var res int64
+	for i, unit := range []int64{usPerHour, usPerMinute, usPerSecond}[:len(parts)] {
+		n, err := strconv.ParseFloat(parts[i], 64)
+		if err != nil || n < 0 || (i < 2 && n != math.Trunc(n)) {
+			return 0, false
+		}
+		res += int64(math.Round(n * float64(unit)))
+	}
+	return sign * res, true
+}
+
+// formatTimestampTZ formats an instant on the clocks of loc, followed by the
+// offset of loc from UTC.
+func formatTimestampTZ(ts TimestampTZValue, loc *time.Location) string {
+	t := ts.Time().In(loc)
+	_, off := t.Zone()
+	sign := byte('+')
+	if off < 0 {
+		sign, off = '-', -off
+	}
+	res := t.Format("2006-01-02 15:04:05.999999") + fmt.Sprintf("%c%02d", sign, off/3600)
+	if off%3600 != 0 {
+		res += fmt.Sprintf(":%02d", off/60%60)
+	}
+	if off%60 != 0 {
+		res += fmt.Sprintf(":%02d", off%60)
+	}
+	return res
+}
+
+// parseDateTime reads a value of a date or time type.
+func (t T) parseDateTime(s string, loc *time.Location) (interface{}, bool) {
+	switch t.Family {
+	case DateFamily:
+		if ts, _, ok := parseTimestamp(s); ok {
+			return DateOf(ts), true
+		}
+	case TimeFamily:
+		return parseTime(s)
+	case TimestampFamily:
+		// Postgres ignores the offset of timestamps without time zone
+		if ts, _, ok := parseTimestamp(s); ok {
+			return TimestampOf(ts), true
+		}
+	case TimestampTZFamily:
+		if ts, zone, ok := parseTimestamp(s); ok {
+			if !zone {
+				ts = wallClock(ts, loc)
+			}
+			return TimestampTZOf(ts), true
+		}
+	case IntervalFamily:
+		iv, err := ParseInterval(s)
+		return iv, err == nil
+	}
+	return nil, false
+}
+
+// coerceDateTime converts between date and time types. Timestamps with time
+// zone are converted on the clocks of loc.
+func (t T) coerceDateTime(v interface{}, loc *time.Location) (interface{}, bool) {
+	// convert to a timestamp first
+	var ts TimestampValue
+	switch v := v.(type) {
+	case DateValue:
+		ts = v.Timestamp()
+	case TimestampValue:
+		ts = v
+	case TimestampTZValue:
+		if t.Family == TimestampTZFamily {
+			return v, true
+		}
+		ts = v.Local(loc)
+	case TimeValue:
+		switch t.Family {
+		case TimeFamily:
+			return v, true
+		case IntervalFamily:
+			return IntervalValue{Micros: int64(v)}, true
+		}
+		return nil, false
+	case IntervalValue:
+		if t.Family == IntervalFamily {
+			return v, true
+		}
+		return nil, false
+	default:
+		return nil, false
+	}
+	switch t.Family {
+	case DateFamily:
+		return ts.Date(), true
+	case TimeFamily:
+		if _, ok := v.(DateValue); !ok {
+			return ts.Clock(), true
+		}
+	case TimestampFamily:
+		return ts, true
+	case TimestampTZFamily:
+		return ts.Zoned(loc), true
+	}
+	return nil, false
+}
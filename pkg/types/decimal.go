@@ -0,0 +1,340 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number, the value of a NUMERIC. It is an
+// integer coefficient scaled down by a number of decimal places. The zero
+// Decimal is 0.
+type Decimal struct {
+	coef  *big.Int
+	scale int
+}
+
+var bigTen = big.NewInt(10)
+
+// NewDecimal returns the decimal coef * 10^-scale.
+func NewDecimal(coef int64, scale int) Decimal {
+	return Decimal{coef: big.NewInt(coef), scale: scale}.normalize()
+}
+
+// ParseDecimal parses a number in decimal notation, with an optional
+// exponent.
+func ParseDecimal(s string) (Decimal, error) {
+	invalid := fmt.Errorf("invalid input syntax for type numeric: %q", s)
+	str := strings.TrimSpace(s)
+	exp := 0
+	if i := strings.IndexAny(str, "eE"); i >= 0 {
+		n, err := strconv.Atoi(str[i+1:])
+		if err != nil {
+			return Decimal{}, invalid
+		}
+		// a larger exponent would take unbounded memory to normalize
+		if n > maxNumericPrecision || n < -maxNumericPrecision {
+			return Decimal{}, errors.New("value overflows numeric format")
+		}
+		exp = n
+		str = str[:i]
+	}
+	digits := str
+	if len(digits) > 0 && (digits[0] == '+' || digits[0] == '-') {
+		digits = digits[1:]
+	}
+	scale := 0
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		scale = len(digits) - i - 1
+		digits = digits[:i] + digits[i+1:]
+	}
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Decimal{}, invalid
+	}
+	coef, _ := new(big.Int).SetString(digits, 10)
+	if str[0] == '-' {
+		coef.Neg(coef)
+	}
+	return Decimal{coef: coef, scale: scale - exp}.normalize(), nil
+}
+
+// DecimalFromFloat returns the decimal with the shortest representation that
+// converts back to f.
+func DecimalFromFloat(f float64) (Decimal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}, fmt.Errorf("cannot convert %v to numeric", f)
+	}
+	return ParseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// normalize gets rid of negative scales, which ParseDecimal gives for
+// numbers written with a positive exponent.
+func (d Decimal) normalize() Decimal {
+	if d.coef == nil {
+		d.coef = new(big.Int)
+	}
+	if d.scale < 0 {
+		d.coef = new(big.Int).Mul(d.coef, pow10(-d.scale))
+		d.scale = 0
+	}
+	return d
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+func (d Decimal) bigCoef() *big.Int {
+	if d.coef == nil {
+		return new(big.Int)
+	}
+	return d.coef
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Sign returns -1, 0 or 1 as d is negative, zero or positive.
+func (d Decimal) Sign() int {
+	return d.bigCoef().Sign()
+}
+
+// rescale returns d with scale digits after the decimal point. Digits that
+// don't fit are rounded half away from zero.
+func (d Decimal) rescale(scale int) Decimal {
+	coef := d.bigCoef()
+	switch {
+	case scale > d.scale:
+		return Decimal{coef: new(big.Int).Mul(coef, pow10(scale-d.scale)), scale: scale}
+	case scale == d.scale:
+		return d.normalize()
+	}
+	div := pow10(d.scale - scale)
+	q, r := new(big.Int).QuoRem(coef, div, new(big.Int))
+	// round half away from zero
+	if r.Abs(r).Lsh(r, 1).Cmp(div) >= 0 {
+		if coef.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Decimal{coef: q, scale: scale}
+}
+
+// Round returns d rounded to scale digits after the decimal point, or to tens,
+// hundreds and so on when scale is negative.
+func (d Decimal) Round(scale int) Decimal {
+	return d.rescale(scale).normalize()
+}
+
+// Floor returns the greatest integer not greater than d.
+func (d Decimal) Floor() Decimal {
+	return d.toInt(-1)
+}
+
+// Ceil returns the least integer not less than d.
+func (d Decimal) Ceil() Decimal {
+	return d.toInt(1)
+}
+
+// toInt drops the fraction of d, moving away from zero in the direction of
+// sign when there was one.
+func (d Decimal) toInt(sign int) Decimal {
+	if d.scale == 0 {
+		return d.normalize()
+	}
+	q, r := new(big.Int).QuoRem(d.bigCoef(), pow10(d.scale), new(big.Int))
+	if r.Sign() == sign {
+		q.Add(q, big.NewInt(int64(sign)))
+	}
+	return Decimal{coef: q}
+}
+
+// Abs returns the absolute value of d.
+func (d Decimal) Abs() Decimal {
+	if d.Sign() < 0 {
+		return d.Neg()
+	}
+	return d.normalize()
+}
+
+// IntDigits returns the number of digits before the decimal point, not
+// counting leading zeros.
+func (d Decimal) IntDigits() int {
+	abs := new(big.Int).Abs(d.bigCoef())
+	n := len(abs.String()) - d.scale
+	if abs.Sign() == 0 || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// Cmp compares d and e, returning -1, 0 or 1 as d is less than, equal to or
+// greater than e.
+func (d Decimal) Cmp(e Decimal) int {
+	scale := d.scale
+	if e.scale > scale {
+		scale = e.scale
+	}
+	return d.rescale(scale).coef.Cmp(e.rescale(scale).coef)
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{coef: new(big.Int).Neg(d.bigCoef()), scale: d.scale}
+}
+
+// align returns the coefficients of d and e at the larger of their scales.
+func (d Decimal) align(e Decimal) (*big.Int, *big.Int, int) {
+	scale := d.scale
+	if e.scale > scale {
+		scale = e.scale
+	}
+	return d.rescale(scale).coef, e.rescale(scale).coef, scale
+}
+
+// Add returns d + e, with the larger of their scales.
+func (d Decimal) Add(e Decimal) Decimal {
+	a, b, scale := d.align(e)
+	return Decimal{coef: new(big.Int).Add(a, b), scale: scale}
+}
+
+// Sub returns d - e, with the larger of their scales.
+func (d Decimal) Sub(e Decimal) Decimal {
+	a, b, scale := d.align(e)
+	return Decimal{coef: new(big.Int).Sub(a, b), scale: scale}
+}
+
+// Mul returns d * e, with the sum of their scales.
+func (d Decimal) Mul(e Decimal) Decimal {
+	return Decimal{coef: new(big.Int).Mul(d.bigCoef(), e.bigCoef()), scale: d.scale + e.scale}
+}
+
+const (
+	// minSignificantDigits is the least number of significant digits of a
+	// quotient.
+	minSignificantDigits = 16
+	// maxDisplayScale bounds the scale of a quotient.
+	maxDisplayScale = 1000
+)
+
+// Quo returns d / e rounded to a scale that keeps at least 16 significant
+// digits, and no fewer digits after the decimal point than either operand, as
+// Postgres does.
+func (d Decimal) Quo(e Decimal) (Decimal, error) {
+	if e.Sign() == 0 {
+		return Decimal{}, errors.New("division by zero")
+	}
+	w1, f1 := d.weight()
+	w2, f2 := e.weight()
+	// the weight of the quotient in base 10000 digits
+	qweight := w1 - w2
+	if f1 <= f2 {
+		qweight--
+	}
+	scale := minSignificantDigits - qweight*4
+	for _, s := range []int{d.scale, e.scale, 0} {
+		if s > scale {
+			scale = s
+		}
+	}
+	if scale > maxDisplayScale {
+		scale = maxDisplayScale
+	}
+	// shift one more digit than needed, to round the last one
+	num := new(big.Int).Mul(d.bigCoef(), pow10(scale+1+e.scale-d.scale))
+	q := Decimal{coef: num.Quo(num, e.bigCoef()), scale: scale + 1}
+	return q.rescale(scale), nil
+}
+
+// Rem returns the remainder of d / e truncated to an integer, with the sign of
+// d and the larger of their scales.
+func (d Decimal) Rem(e Decimal) (Decimal, error) {
+	if e.Sign() == 0 {
+		return Decimal{}, errors.New("division by zero")
+	}
+	a, b, scale := d.align(e)
+	return Decimal{coef: new(big.Int).Rem(a, b), scale: scale}, nil
+}
+
+// weight returns the position of the first non-zero base 10000 digit of d,
+// counting from the units, and that digit. Postgres stores numerics in base
+// 10000 and picks the scale of quotients from it.
+func (d Decimal) weight() (int, int64) {
+	coef := new(big.Int).Abs(d.bigCoef())
+	if coef.Sign() == 0 {
+		return 0, 0
+	}
+	scale := (d.scale + 3) / 4 * 4
+	digits := coef.Mul(coef, pow10(scale-d.scale)).String()
+	lead := len(digits) % 4
+	if lead == 0 {
+		lead = 4
+	}
+	first, _ := strconv.ParseInt(digits[:lead], 10, 64)
+	return (len(digits)-lead)/4 - scale/4, first
+}
+
+// Int64 returns d rounded to an integer, and reports whether it fits in an
+// int64.
+func (d Decimal) Int64() (int64, bool) {
+	n := d.rescale(0).coef
+	return n.Int64(), n.IsInt64()
+}
+
+// Float64 returns the float64 nearest to d.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// String formats d with all the digits of its scale.
+func (d Decimal) String() string {
+	coef := d.bigCoef()
+	digits := new(big.Int).Abs(coef).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+	if coef.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// Key returns a string that is the same for equal decimals, whatever their
+// scales.
+func (d Decimal) Key() string {
+	s := d.String()
+	if strings.IndexByte(s, '.') >= 0 {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// GobEncode returns the digits of d. Its fields are unexported, so gob can't
+// encode decimals on its own, like those of rows spilled to disk.
+func (d Decimal) GobEncode() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// GobDecode parses a decimal written by GobEncode.
+func (d *Decimal) GobDecode(b []byte) error {
+	v, err := ParseDecimal(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
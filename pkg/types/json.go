@@ -0,0 +1,472 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSONValue is a JSON document, held as its text. The text of jsonb values is
+// normalized: objects have their keys sorted and no duplicates, numbers
+// have no exponent, and tokens are separated the way Postgres prints them.
+type JSONValue string
+
+// ParseJSON decodes a JSON document. Objects are decoded as
+// map[string]interface{}, arrays as []interface{} and numbers as Decimals.
+func ParseJSON(s string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid input syntax for type json: %q", s)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid input syntax for type json: %q", s)
+	}
+	return decodeNumbers(doc)
+}
+
+// decodeNumbers replaces the json.Numbers of a document with Decimals.
+func decodeNumbers(doc interface{}) (interface{}, error) {
+	switch v := doc.(type) {
+	case json.Number:
+		return ParseDecimal(string(v))
+	case []interface{}:
+		for i, elem := range v {
+			var err error
+			if v[i], err = decodeNumbers(elem); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]interface{}:
+		for key, elem := range v {
+			var err error
+			if v[key], err = decodeNumbers(elem); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return doc, nil
+}
+
+// Doc decodes the document.
+func (v JSONValue) Doc() (interface{}, error) {
+	return ParseJSON(string(v))
+}
+
+// Key returns a string that is the same for equal documents, whose numbers
+// may have different scales.
+func (v JSONValue) Key() string {
+	doc, err := v.Doc()
+	if err != nil {
+		return string(v)
+	}
+	return string(EncodeJSON(keyNumbers(doc)))
+}
+
+// keyNumbers gives the numbers of a document their smallest scale.
+func keyNumbers(doc interface{}) interface{} {
+	switch v := doc.(type) {
+	case Decimal:
+		if d, err := ParseDecimal(v.Key()); err == nil {
+			return d
+		}
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = keyNumbers(elem)
+		}
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = keyNumbers(elem)
+		}
+	}
+	return doc
+}
+
+// EncodeJSON returns the normalized text of a document decoded by ParseJSON.
+func EncodeJSON(doc interface{}) JSONValue {
+	var b strings.Builder
+	writeJSON(&b, doc)
+	return JSONValue(b.String())
+}
+
+func writeJSON(b *strings.Builder, doc interface{}) {
+	switch v := doc.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case Decimal:
+		b.WriteString(v.String())
+	case string:
+		writeJSONString(b, v)
+	case []interface{}:
+		b.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeJSON(b, elem)
+		}
+		b.WriteByte(']')
+	case map[string]interface{}:
+		b.WriteByte('{')
+		for i, key := range JSONKeys(v) {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeJSONString(b, key)
+			b.WriteString(": ")
+			writeJSON(b, v[key])
+		}
+		b.WriteByte('}')
+	}
+}
+
+// writeJSONString quotes a string, escaping only what JSON requires.
+func writeJSONString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
+
+// JSONKeys returns the keys of an object in the order of jsonb: shorter keys
+// first, then by their bytes.
+func JSONKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// coerceJSON reads the text of a JSON document, which is normalized for
+// jsonb. json keeps the text as it was written.
+func (t T) coerceJSON(s string) (interface{}, error) {
+	doc, err := ParseJSON(s)
+	if err != nil {
+		return nil, err
+	}
+	if t.Oid == OidJSONB {
+		return EncodeJSON(doc), nil
+	}
+	return JSONValue(s), nil
+}
+
+// ToJSON converts a SQL value to a JSON document. Dates and times become
+// strings in ISO 8601 format, and timestamps with time zone are shown on the
+// clocks of loc.
+func ToJSON(v interface{}, loc *time.Location) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, bool, string, Decimal:
+		return v, nil
+	case int:
+		return NewDecimal(int64(v), 0), nil
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "NaN", nil
+		case math.IsInf(v, 1):
+			return "Infinity", nil
+		case math.IsInf(v, -1):
+			return "-Infinity", nil
+		}
+		return DecimalFromFloat(v)
+	case JSONValue:
+		return v.Doc()
+	case DateValue, TimeValue, IntervalValue:
+		return fmt.Sprint(v), nil
+	case TimestampValue:
+		return v.Time().Format("2006-01-02T15:04:05.999999"), nil
+	case TimestampTZValue:
+		return v.Time().In(loc).Format("2006-01-02T15:04:05.999999-07:00"), nil
+	}
+	return nil, fmt.Errorf("cannot convert %v to json", v)
+}
+
+// JSONTypeOf returns the name of the JSON type of a document, like object or
+// number.
+func JSONTypeOf(doc interface{}) string {
+	switch doc.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case Decimal:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
+
+// JSONText returns the text of a document as the ->> operator does: strings
+// unquoted and NULL for null.
+func JSONText(doc interface{}) interface{} {
+	switch v := doc.(type) {
+	case nil:
+		return nil
+	case string:
+		return v
+	}
+	return string(EncodeJSON(doc))
+}
+
+// JSONGet returns the field of an object, or the element of an array at a
+// position given as a string, like the steps of a #> path. Negative positions
+// count from the end. It reports false when there is no such field or
+// element.
+func JSONGet(doc interface{}, step string) (interface{}, bool) {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		elem, ok := v[step]
+		return elem, ok
+	case []interface{}:
+		i, err := strconv.Atoi(step)
+		if err != nil {
+			return nil, false
+		}
+		return JSONIndex(v, i)
+	}
+	return nil, false
+}
+
+// JSONIndex returns the element of an array at position i, counted from the
+// end when negative.
+func JSONIndex(arr []interface{}, i int) (interface{}, bool) {
+	if i < 0 {
+		i += len(arr)
+	}
+	if i < 0 || i >= len(arr) {
+		return nil, false
+	}
+	return arr[i], true
+}
+
+// JSONContains reports whether document a contains b, as the @> operator of
+// jsonb: objects contain the fields of the objects they contain, arrays
+// contain the elements of arrays, and scalars are equal. An array also
+// contains a scalar at the top level.
+func JSONContains(a, b interface{}) bool {
+	if arr, ok := a.([]interface{}); ok {
+		switch b.(type) {
+		case []interface{}, map[string]interface{}:
+		default:
+			for _, elem := range arr {
+				if jsonContains(elem, b) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return jsonContains(a, b)
+}
+
+func jsonContains(a, b interface{}) bool {
+	switch bv := b.(type) {
+	case map[string]interface{}:
+		av, ok := a.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, elem := range bv {
+			if x, ok := av[key]; !ok || !jsonContains(x, elem) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		av, ok := a.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, elem := range bv {
+			found := false
+			for _, x := range av {
+				if jsonContains(x, elem) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	return compareJSON(a, b) == 0
+}
+
+// JSONHasKey reports whether a key is a field of an object, a string element
+// of an array or the string itself, as the ? operator of jsonb.
+func JSONHasKey(doc interface{}, key string) bool {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		_, ok := v[key]
+		return ok
+	case []interface{}:
+		for _, elem := range v {
+			if s, ok := elem.(string); ok && s == key {
+				return true
+			}
+		}
+	case string:
+		return v == key
+	}
+	return false
+}
+
+// JSONTerms returns the terms an inverted index finds a document by: one for
+// every scalar, made of the object keys leading to it and its value. A
+// document contains another only if it has all of its terms, so they narrow
+// down the documents that can contain a given one.
+func JSONTerms(doc interface{}) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	var walk func(path string, doc interface{})
+	walk = func(path string, doc interface{}) {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			for key, elem := range v {
+				var b strings.Builder
+				b.WriteString(path)
+				writeJSONString(&b, key)
+				b.WriteByte('.')
+				walk(b.String(), elem)
+			}
+		case []interface{}:
+			// containment ignores the position of array elements
+			for _, elem := range v {
+				walk(path, elem)
+			}
+		default:
+			term := path + string(EncodeJSON(v))
+			// equal numbers may have different scales
+			if d, ok := v.(Decimal); ok {
+				term = path + d.Key()
+			}
+			if !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	walk("", doc)
+	sort.Strings(terms)
+	return terms
+}
+
+// jsonRank orders the JSON types as jsonb does.
+func jsonRank(doc interface{}) int {
+	switch doc.(type) {
+	case nil:
+		return 1
+	case string:
+		return 2
+	case Decimal:
+		return 3
+	case bool:
+		return 4
+	case []interface{}:
+		return 5
+	}
+	return 6
+}
+
+// compareJSON orders documents as jsonb does: by type, then arrays and
+// objects by their number of elements before their elements.
+func compareJSON(a, b interface{}) int {
+	if ra, rb := jsonRank(a), jsonRank(b); ra != rb {
+		return compareInts(int64(ra), int64(rb))
+	}
+	switch av := a.(type) {
+	case string:
+		return strings.Compare(av, b.(string))
+	case Decimal:
+		return av.Cmp(b.(Decimal))
+	case bool:
+		cmp, _ := Compare(av, b.(bool))
+		return cmp
+	case []interface{}:
+		bv := b.([]interface{})
+		if len(av) != len(bv) {
+			return compareInts(int64(len(av)), int64(len(bv)))
+		}
+		for i := range av {
+			if cmp := compareJSON(av[i], bv[i]); cmp != 0 {
+				return cmp
+			}
+		}
+	case map[string]interface{}:
+		bv := b.(map[string]interface{})
+		if len(av) != len(bv) {
+			return compareInts(int64(len(av)), int64(len(bv)))
+		}
+		akeys, bkeys := JSONKeys(av), JSONKeys(bv)
+		for i := range akeys {
+			if akeys[i] != bkeys[i] {
+				if len(akeys[i]) != len(bkeys[i]) {
+					return compareInts(int64(len(akeys[i])), int64(len(bkeys[i])))
+				}
+				return strings.Compare(akeys[i], bkeys[i])
+			}
+			if cmp := compareJSON(av[akeys[i]], bv[bkeys[i]]); cmp != 0 {
+				return cmp
+			}
+		}
+	}
+	return 0
+}
+
+// CompareJSON orders two JSON documents as jsonb does.
+func CompareJSON(a, b JSONValue) (int, error) {
+	if a == b {
+		return 0, nil
+	}
+	da, err := a.Doc()
+	if err != nil {
+		return 0, err
+	}
+	db, err := b.Doc()
+	if err != nil {
+		return 0, err
+	}
+	return compareJSON(da, db), nil
+}
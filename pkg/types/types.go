@@ -0,0 +1,396 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Family groups the types whose values have the same representation and can
+// be compared with each other.
+type Family int
+
+const (
+	// UnknownFamily is the family of NULL literals, which fit any type.
+	UnknownFamily Family = iota
+	BoolFamily
+	// IntFamily values are ints.
+	IntFamily
+	// FloatFamily values are float64s.
+	FloatFamily
+	// NumericFamily values are Decimals.
+	NumericFamily
+	// TextFamily values are strings.
+	TextFamily
+	// DateFamily values are DateValues.
+	DateFamily
+	// TimeFamily values are TimeValues.
+	TimeFamily
+	// TimestampFamily values are TimestampValues.
+	TimestampFamily
+	// TimestampTZFamily values are TimestampTZValues.
+	TimestampTZFamily
+	// IntervalFamily values are IntervalValues.
+	IntervalFamily
+	// JSONFamily values are JSONValues.
+	JSONFamily
+	// AnyFamily stands for arguments of any type in function signatures.
+	AnyFamily
+)
+
+var familyNames = map[Family]string{
+	UnknownFamily:     "unknown",
+	BoolFamily:        "bool",
+	IntFamily:         "int",
+	FloatFamily:       "float",
+	NumericFamily:     "numeric",
+	TextFamily:        "text",
+	DateFamily:        "date",
+	TimeFamily:        "time",
+	TimestampFamily:   "timestamp",
+	TimestampTZFamily: "timestamptz",
+	IntervalFamily:    "interval",
+	JSONFamily:        "json",
+	AnyFamily:         "any",
+}
+
+func (f Family) String() string {
+	return familyNames[f]
+}
+
+// Oid identifies a type in the Postgres protocol.
+type Oid uint32
+
+const (
+	OidBool        Oid = 16
+	OidInt8        Oid = 20
+	OidInt2        Oid = 21
+	OidInt4        Oid = 23
+	OidText        Oid = 25
+	OidJSON        Oid = 114
+	OidFloat4      Oid = 700
+	OidFloat8      Oid = 701
+	OidUnknown     Oid = 705
+	OidBpchar      Oid = 1042
+	OidVarchar     Oid = 1043
+	OidDate        Oid = 1082
+	OidTime        Oid = 1083
+	OidTimestamp   Oid = 1114
+	OidTimestampTZ Oid = 1184
+	OidInterval    Oid = 1186
+	OidNumeric     Oid = 1700
+	OidAny         Oid = 2276
+	OidJSONB       Oid = 3802
+)
+
+// T is a SQL type. Types are compared with ==.
+type T struct {
+	Family Family
+	Oid    Oid
+	// Width is the maximum number of characters of a character type. Zero
+	// means unlimited.
+	Width int
+	// Precision is the maximum number of digits of a NUMERIC and Scale the
+	// number of digits after its decimal point. A zero Precision leaves
+	// both unconstrained.
+	Precision int
+	Scale     int
+}
+
+var (
+	Unknown     = T{Family: UnknownFamily, Oid: OidUnknown}
+	Bool        = T{Family: BoolFamily, Oid: OidBool}
+	SmallInt    = T{Family: IntFamily, Oid: OidInt2}
+	Int         = T{Family: IntFamily, Oid: OidInt4}
+	BigInt      = T{Family: IntFamily, Oid: OidInt8}
+	Real        = T{Family: FloatFamily, Oid: OidFloat4}
+	Float       = T{Family: FloatFamily, Oid: OidFloat8}
+	Numeric     = T{Family: NumericFamily, Oid: OidNumeric}
+	Text        = T{Family: TextFamily, Oid: OidText}
+	VarChar     = T{Family: TextFamily, Oid: OidVarchar}
+	Date        = T{Family: DateFamily, Oid: OidDate}
+	Time        = T{Family: TimeFamily, Oid: OidTime}
+	Timestamp   = T{Family: TimestampFamily, Oid: OidTimestamp}
+	TimestampTZ = T{Family: TimestampTZFamily, Oid: OidTimestampTZ}
+	Interval    = T{Family: IntervalFamily, Oid: OidInterval}
+	JSON        = T{Family: JSONFamily, Oid: OidJSON}
+	JSONB       = T{Family: JSONFamily, Oid: OidJSONB}
+	Any         = T{Family: AnyFamily, Oid: OidAny}
+)
+
+// MakeVarChar returns the type of strings of at most width characters.
+func MakeVarChar(width int) T {
+	return T{Family: TextFamily, Oid: OidVarchar, Width: width}
+}
+
+// MakeChar returns the type of strings of width characters, padded with
+// spaces.
+func MakeChar(width int) T {
+	return T{Family: TextFamily, Oid: OidBpchar, Width: width}
+}
+
+// MakeNumeric returns the type of decimals with at most precision digits, of
+// which scale are after the decimal point.
+func MakeNumeric(precision, scale int) T {
+	return T{Family: NumericFamily, Oid: OidNumeric, Precision: precision, Scale: scale}
+}
+
+// maxNumericPrecision is the largest precision a NUMERIC can be declared
+// with.
+const maxNumericPrecision = 1000
+
+// Resolve returns the type of a name used in a column definition, with the
+// modifiers given in parentheses after it.
+func Resolve(name string, mods []int) (T, error) {
+	name = strings.ToLower(name)
+	noMods := func(t T) (T, error) {
+		if len(mods) > 0 {
+			return T{}, fmt.Errorf("type modifier is not allowed for type %s", name)
+		}
+		return t, nil
+	}
+	width := func(make func(int) T, def int) (T, error) {
+		switch {
+		case len(mods) == 0:
+			return make(def), nil
+		case len(mods) > 1:
+			return T{}, fmt.Errorf("invalid type modifier for type %s", name)
+		case mods[0] < 1:
+			return T{}, fmt.Errorf("length for type %s must be at least 1", name)
+		}
+		return make(mods[0]), nil
+	}
+	switch name {
+	case "bool", "boolean":
+		return noMods(Bool)
+	case "smallint", "int2":
+		return noMods(SmallInt)
+	case "int", "integer", "int4":
+		return noMods(Int)
+	case "bigint", "int8":
+		return noMods(BigInt)
+	case "real", "float4":
+		return noMods(Real)
+	case "double precision", "float8":
+		return noMods(Float)
+	case "float":
+		// the precision is in binary digits
+		switch {
+		case len(mods) == 0:
+			return Float, nil
+		case len(mods) > 1 || mods[0] < 1 || mods[0] > 53:
+			return T{}, fmt.Errorf("precision for type float must be between 1 and 53 bits")
+		case mods[0] <= 24:
+			return Real, nil
+		}
+		return Float, nil
+	case "numeric", "decimal":
+		switch len(mods) {
+		case 0:
+			return Numeric, nil
+		case 1:
+			mods = append(mods, 0)
+		case 2:
+		default:
+			return T{}, fmt.Errorf("invalid NUMERIC type modifier")
+		}
+		if mods[0] < 1 || mods[0] > maxNumericPrecision {
+			return T{}, fmt.Errorf("NUMERIC precision %d must be between 1 and %d", mods[0], maxNumericPrecision)
+		}
+		if mods[1] < 0 || mods[1] > mods[0] {
+			return T{}, fmt.Errorf("NUMERIC scale %d must be between 0 and precision %d", mods[1], mods[0])
+		}
+		return MakeNumeric(mods[0], mods[1]), nil
+	case "text":
+		return noMods(Text)
+	case "varchar", "character varying":
+		return width(MakeVarChar, 0)
+	case "char", "character", "bpchar":
+		return width(MakeChar, 1)
+	case "date":
+		return noMods(Date)
+	case "time", "time without time zone":
+		return noMods(Time)
+	case "timestamp", "timestamp without time zone":
+		return noMods(Timestamp)
+	case "timestamptz", "timestamp with time zone":
+		return noMods(TimestampTZ)
+	case "interval":
+		return noMods(Interval)
+	case "json":
+		return noMods(JSON)
+	case "jsonb":
+		return noMods(JSONB)
+	}
+	return T{}, fmt.Errorf("type %s does not exist", name)
+}
+
+// String returns the SQL name of the type.
+func (t T) String() string {
+	switch t.Oid {
+	case OidBool:
+		return "boolean"
+	case OidInt2:
+		return "smallint"
+	case OidInt4:
+		return "integer"
+	case OidInt8:
+		return "bigint"
+	case OidFloat4:
+		return "real"
+	case OidFloat8:
+		return "double precision"
+	case OidNumeric:
+		if t.Precision > 0 {
+			return fmt.Sprintf("numeric(%d,%d)", t.Precision, t.Scale)
+		}
+		return "numeric"
+	case OidText:
+		return "text"
+	case OidVarchar:
+		if t.Width > 0 {
+			return fmt.Sprintf("character varying(%d)", t.Width)
+		}
+		return "character varying"
+	case OidBpchar:
+		return fmt.Sprintf("character(%d)", t.Width)
+	case OidDate:
+		return "date"
+	case OidTime:
+		return "time without time zone"
+	case OidTimestamp:
+		return "timestamp without time zone"
+	case OidTimestampTZ:
+		return "timestamp with time zone"
+	case OidInterval:
+		return "interval"
+	case OidJSON:
+		return "json"
+	case OidJSONB:
+		return "jsonb"
+	case OidAny:
+		return "any"
+	}
+	return "unknown"
+}
+
+// Size returns the number of bytes of the binary representation of the type's
+// values, or -1 when they vary in length.
+func (t T) Size() int {
+	switch t.Oid {
+	case OidBool:
+		return 1
+	case OidInt2:
+		return 2
+	case OidInt4, OidFloat4, OidDate:
+		return 4
+	case OidInt8, OidFloat8, OidTime, OidTimestamp, OidTimestampTZ:
+		return 8
+	case OidInterval:
+		return 16
+	}
+	return -1
+}
+
+// Modifier returns the type modifier of the type in the Postgres protocol, or
+// -1 when it has none.
+func (t T) Modifier() int {
+	switch {
+	case t.Family == TextFamily && t.Width > 0:
+		return t.Width + 4
+	case t.Family == NumericFamily && t.Precision > 0:
+		return (t.Precision<<16 | t.Scale) + 4
+	}
+	return -1
+}
+
+// IsNumber reports whether values of the type are numbers.
+func (t T) IsNumber() bool {
+	return t.Family == IntFamily || t.Family == FloatFamily || t.Family == NumericFamily
+}
+
+// intRanks orders the integer types from the narrowest.
+var intRanks = map[Oid]int{
+	OidInt2: 1,
+	OidInt4: 2,
+	OidInt8: 3,
+}
+
+// Common returns the type values of a and b are returned as when they take
+// each other's place, like the results of a CASE. Both must be of the same
+// family, except that Unknown fits any type and that numbers of different
+// families are converted to the widest: integers to numeric, and both to
+// floats. It reports false when there is no such type.
+func Common(a, b T) (T, bool) {
+	switch {
+	case a == b || b.Family == UnknownFamily:
+		return a, true
+	case a.Family == UnknownFamily:
+		return b, true
+	case a.Family != b.Family && a.IsNumber() && b.IsNumber():
+		switch {
+		case a.Family == FloatFamily:
+			return a, true
+		case b.Family == FloatFamily:
+			return b, true
+		}
+		return Numeric, true
+	case a.Family != b.Family:
+		return T{}, false
+	}
+	switch a.Family {
+	case IntFamily:
+		if intRanks[a.Oid] < intRanks[b.Oid] {
+			return b, true
+		}
+		return a, true
+	case FloatFamily:
+		if a.Oid == OidFloat8 || b.Oid == OidFloat8 {
+			return Float, true
+		}
+		return Real, true
+	case NumericFamily:
+		return Numeric, true
+	case TextFamily:
+		if a.Oid == b.Oid && a.Oid == OidVarchar {
+			return VarChar, true
+		}
+		return Text, true
+	case JSONFamily:
+		return JSONB, true
+	}
+	return a, true
+}
+
+// Comparable reports whether values of a and b can be compared: they are of
+// the same family, or both numbers. Unknown is comparable with any type but
+// json, which has no comparison operators.
+func Comparable(a, b T) bool {
+	if a.Oid == OidJSON || b.Oid == OidJSON {
+		return false
+	}
+	if a.Family == UnknownFamily || b.Family == UnknownFamily {
+		return true
+	}
+	return a.Family == b.Family || (a.IsNumber() && b.IsNumber())
+}
+
+// IsDateTime reports whether values of the type are dates or timestamps.
+func (t T) IsDateTime() bool {
+	return t.Family == DateFamily || t.Family == TimestampFamily || t.Family == TimestampTZFamily
+}
+
+// Assignable reports whether values of type from can be stored in a column of
+// type to. Numbers convert to other numbers, dates and timestamps to each
+// other, and text to any type as long as it has the syntax of its values.
+// Timestamps also convert to their time of day, and times to intervals.
+func Assignable(from, to T) bool {
+	switch {
+	case from.Family == UnknownFamily || from.Family == to.Family || from.Family == TextFamily:
+		return true
+	case from.IsNumber():
+		return to.IsNumber()
+	case from.IsDateTime():
+		return to.IsDateTime() || (to.Family == TimeFamily && from.Family != DateFamily)
+	}
+	return from.Family == TimeFamily && to.Family == IntervalFamily
+}
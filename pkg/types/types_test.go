@@ -0,0 +1,431 @@
+package types
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		mods    []int
+		want    T
+		wantErr bool
+	}{
+		{name: "INTEGER", want: Int},
+		{name: "int8", want: BigInt},
+		{name: "double precision", want: Float},
+		{name: "float", mods: []int{10}, want: Real},
+		{name: "float", mods: []int{53}, want: Float},
+		{name: "numeric", want: Numeric},
+		{name: "decimal", mods: []int{5}, want: MakeNumeric(5, 0)},
+		{name: "numeric", mods: []int{10, 2}, want: MakeNumeric(10, 2)},
+		{name: "varchar", want: VarChar},
+		{name: "character varying", mods: []int{20}, want: MakeVarChar(20)},
+		{name: "char", want: MakeChar(1)},
+		{name: "boolean", want: Bool},
+		{name: "timestamp with time zone", want: TimestampTZ},
+		{name: "time without time zone", want: Time},
+		{name: "interval", want: Interval},
+		{name: "jsonb", want: JSONB},
+		{name: "int", mods: []int{4}, wantErr: true},
+		{name: "float", mods: []int{54}, wantErr: true},
+		{name: "numeric", mods: []int{2, 3}, wantErr: true},
+		{name: "numeric", mods: []int{0}, wantErr: true},
+		{name: "varchar", mods: []int{0}, wantErr: true},
+		{name: "blob", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(tt.name, tt.mods)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestT_String(t *testing.T) {
+	tests := []struct {
+		typ  T
+		want string
+	}{
+		{typ: SmallInt, want: "smallint"},
+		{typ: Float, want: "double precision"},
+		{typ: MakeNumeric(10, 2), want: "numeric(10,2)"},
+		{typ: MakeVarChar(255), want: "character varying(255)"},
+		{typ: MakeChar(3), want: "character(3)"},
+		{typ: Unknown, want: "unknown"},
+		{typ: Timestamp, want: "timestamp without time zone"},
+		{typ: TimestampTZ, want: "timestamp with time zone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.typ.String())
+		})
+	}
+}
+
+func TestCommon(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   T
+		want   T
+		wantOk bool
+	}{
+		{name: "same type", a: Int, b: Int, want: Int, wantOk: true},
+		{name: "wider integer", a: SmallInt, b: BigInt, want: BigInt, wantOk: true},
+		{name: "unknown", a: Unknown, b: Text, want: Text, wantOk: true},
+		{name: "varchars", a: MakeVarChar(2), b: MakeVarChar(3), want: VarChar, wantOk: true},
+		{name: "char and text", a: MakeChar(2), b: Text, want: Text, wantOk: true},
+		{name: "numerics", a: MakeNumeric(4, 2), b: Numeric, want: Numeric, wantOk: true},
+		{name: "different families", a: Int, b: Text},
+		{name: "int and numeric", a: Int, b: MakeNumeric(4, 2), want: Numeric, wantOk: true},
+		{name: "numeric and float", a: Numeric, b: Float, want: Float, wantOk: true},
+		{name: "int and real", a: BigInt, b: Real, want: Real, wantOk: true},
+		{name: "reals", a: Real, b: Real, want: Real, wantOk: true},
+		{name: "real and double", a: Real, b: Float, want: Float, wantOk: true},
+		{name: "int and boolean", a: Int, b: Bool},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Common(tt.a, tt.b)
+			assert.Equal(t, tt.wantOk, ok)
+			if tt.wantOk {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestT_Coerce(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     T
+		val     interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "null", typ: Int, val: nil, want: nil},
+		{name: "text to int", typ: Int, val: " 42 ", want: 42},
+		{name: "float to int", typ: Int, val: 2.5, want: 3},
+		{name: "decimal to int", typ: BigInt, val: NewDecimal(-25, 1), want: -3},
+		{name: "smallint range", typ: SmallInt, val: 32768, wantErr: true},
+		{name: "int range", typ: Int, val: math.MaxInt32 + 1, wantErr: true},
+		{name: "bigint text range", typ: BigInt, val: "9223372036854775808", wantErr: true},
+		{name: "invalid int", typ: Int, val: "4x", wantErr: true},
+		{name: "int to real", typ: Real, val: 3, want: 3.0},
+		{name: "real precision", typ: Real, val: 0.1, want: float64(float32(0.1))},
+		{name: "text to float", typ: Float, val: "1e3", want: 1000.0},
+		{name: "float to numeric", typ: MakeNumeric(5, 2), val: 1.005, want: NewDecimal(101, 2)},
+		{name: "numeric scale", typ: MakeNumeric(5, 2), val: "-1.005", want: NewDecimal(-101, 2)},
+		{name: "numeric overflow", typ: MakeNumeric(5, 2), val: 1000, wantErr: true},
+		{name: "unconstrained numeric", typ: Numeric, val: "1.50", want: NewDecimal(150, 2)},
+		{name: "text to bool", typ: Bool, val: "off", want: false},
+		{name: "invalid bool", typ: Bool, val: "maybe", wantErr: true},
+		{name: "int to bool", typ: Bool, val: 1, wantErr: true},
+		{name: "char padding", typ: MakeChar(4), val: "ab", want: "ab  "},
+		{name: "trailing spaces", typ: MakeVarChar(2), val: "ab  ", want: "ab"},
+		{name: "too long", typ: MakeVarChar(2), val: "abc", wantErr: true},
+		{name: "int to text", typ: Text, val: 1, wantErr: true},
+		{name: "text to date", typ: Date, val: "2024-02-29", want: DateValue(19782)},
+		{name: "invalid date", typ: Date, val: "2023-02-29", wantErr: true},
+		{name: "timestamp to date", typ: Date, val: TimestampValue(19782*86400e6 + 1), want: DateValue(19782)},
+		{name: "date to timestamptz", typ: TimestampTZ, val: DateValue(1), want: TimestampTZValue(86400e6)},
+		{name: "text to timestamptz", typ: TimestampTZ, val: "1970-01-01 02:00:00+02", want: TimestampTZValue(0)},
+		{name: "epoch", typ: Timestamp, val: "epoch", want: TimestampValue(0)},
+		{name: "text to time", typ: Time, val: "01:02:03.5", want: TimeValue(3723500000)},
+		{name: "timestamp to time", typ: Time, val: TimestampValue(86400e6 + 60e6), want: TimeValue(60e6)},
+		{name: "text to interval", typ: Interval, val: "1 year -2 days 03:00", want: IntervalValue{Months: 12, Days: -2, Micros: 3 * 3600e6}},
+		{name: "date to time", typ: Time, val: DateValue(1), wantErr: true},
+		{name: "text to jsonb", typ: JSONB, val: ` {"b":[1e2, "\u0041"],"a":null,"b":true} `, want: JSONValue(`{"a": null, "b": true}`)},
+		{name: "text to json", typ: JSON, val: `{"b":1,"a":2}`, want: JSONValue(`{"b":1,"a":2}`)},
+		{name: "json to jsonb", typ: JSONB, val: JSONValue(`[1.0,{}]`), want: JSONValue(`[1.0, {}]`)},
+		{name: "invalid json", typ: JSON, val: `{"a":}`, wantErr: true},
+		{name: "trailing json", typ: JSONB, val: `1 2`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.typ.Coerce(tt.val)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    interface{}
+		want    int
+		wantErr bool
+	}{
+		{name: "ints", a: 1, b: 2, want: -1},
+		{name: "int and float", a: 2, b: 1.5, want: 1},
+		{name: "int and decimal", a: 2, b: NewDecimal(200, 2), want: 0},
+		{name: "decimals of different scales", a: NewDecimal(15, 1), b: NewDecimal(149, 2), want: 1},
+		{name: "float and decimal", a: 0.5, b: NewDecimal(5, 1), want: 0},
+		{name: "strings", a: "a", b: "b", want: -1},
+		{name: "bools", a: false, b: true, want: -1},
+		{name: "int and string", a: 1, b: "1", wantErr: true},
+		{name: "bool and int", a: true, b: 1, wantErr: true},
+		{name: "dates", a: DateValue(2), b: DateValue(1), want: 1},
+		{name: "equal intervals", a: IntervalValue{Days: 1}, b: IntervalValue{Micros: 86400e6}, want: 0},
+		{name: "intervals", a: IntervalValue{Months: 1}, b: IntervalValue{Days: 31}, want: -1},
+		{name: "date and timestamp", a: DateValue(0), b: TimestampValue(0), wantErr: true},
+		{name: "equal json numbers", a: JSONValue(`{"a": 1}`), b: JSONValue(`{"a": 1.0}`), want: 0},
+		{name: "json types", a: JSONValue(`"z"`), b: JSONValue(`1`), want: -1},
+		{name: "json objects by size", a: JSONValue(`{"b": 1}`), b: JSONValue(`{"a": 1, "b": 0}`), want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Compare(tt.a, tt.b)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestJSONContains(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: `{"a": 1, "b": [1, 2]}`, b: `{"b": [2]}`, want: true},
+		{a: `{"a": 1, "b": [1, 2]}`, b: `{"b": 2}`, want: false},
+		{a: `{"a": {"b": 1, "c": 2}}`, b: `{"a": {"c": 2}}`, want: true},
+		{a: `[1, [2, 3]]`, b: `[[3]]`, want: true},
+		{a: `[1, 2]`, b: `2`, want: true},
+		{a: `[[1]]`, b: `1`, want: false},
+		{a: `{"a": 1}`, b: `{}`, want: true},
+		{a: `1.0`, b: `1`, want: true},
+		{a: `{"a": "1"}`, b: `{"a": 1}`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" @> "+tt.b, func(t *testing.T) {
+			a, err := ParseJSON(tt.a)
+			require.NoError(t, err)
+			b, err := ParseJSON(tt.b)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, JSONContains(a, b))
+			if tt.want {
+				// the terms of a document that contains another
+				// include its terms
+				terms := make(map[string]bool)
+				for _, term := range JSONTerms(a) {
+					terms[term] = true
+				}
+				for _, term := range JSONTerms(b) {
+					assert.True(t, terms[term], term)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONValue_Key(t *testing.T) {
+	a, b := JSONValue(`{"a": [1.50, "1.5"]}`), JSONValue(`{"a": [1.5, "1.5"]}`)
+	assert.Equal(t, a.Key(), b.Key())
+	assert.NotEqual(t, a.Key(), JSONValue(`{"a": ["1.5", 1.5]}`).Key())
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "1 day", want: "1 day"},
+		{in: "2 weeks 36 hours", want: "14 days 36:00:00"},
+		{in: "1.5 months", want: "1 mon 15 days"},
+		{in: "3 mins 4.5 secs", want: "00:03:04.5"},
+		{in: "-1 year +2 mons", want: "-10 mons"},
+		{in: "1 decade 1 century", want: "110 years"},
+		{in: "P1Y", wantErr: true},
+		{in: "1 fortnight", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseInterval(tt.in)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestTimestamp_AddInterval(t *testing.T) {
+	ts, err := Timestamp.Coerce("2024-01-31 12:00")
+	require.NoError(t, err)
+	got := ts.(TimestampValue).AddInterval(IntervalValue{Months: 1, Days: 1})
+	assert.Equal(t, "2024-03-01 12:00:00", got.String())
+	assert.Equal(t, "-30 days", ts.(TimestampValue).Sub(got).String())
+
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	tz, err := TimestampTZ.CoerceIn("2024-03-09 12:00", loc)
+	require.NoError(t, err)
+	// a day later is the same time of day across the change to daylight
+	// saving time, 23 hours later
+	next := tz.(TimestampTZValue).AddInterval(IntervalValue{Days: 1}, loc)
+	assert.Equal(t, "2024-03-10 12:00:00-04", TimestampTZ.FormatIn(next, loc))
+	assert.Equal(t, "23:00:00", next.Sub(tz.(TimestampTZValue)).String())
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		round   string
+		wantErr bool
+	}{
+		{in: "1.005", want: "1.005", round: "1.01"},
+		{in: "-1.005", want: "-1.005", round: "-1.01"},
+		{in: "+.5", want: "0.5", round: "0.50"},
+		{in: "12", want: "12", round: "12.00"},
+		{in: "1.5e2", want: "150", round: "150.00"},
+		{in: "15e-3", want: "0.015", round: "0.02"},
+		{in: "-0.004", want: "-0.004", round: "0.00"},
+		{in: "1.2.3", wantErr: true},
+		{in: "e5", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "1e1000", want: "1" + strings.Repeat("0", 1000), round: "1" + strings.Repeat("0", 1000) + ".00"},
+		{in: "1e999999999", wantErr: true},
+		{in: "1e-1001", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDecimal(tt.in)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+			assert.Equal(t, tt.round, got.Round(2).String())
+		})
+	}
+	assert.Equal(t, NewDecimal(150, 2).Key(), NewDecimal(15, 1).Key())
+	assert.Equal(t, "0", NewDecimal(0, 3).Key())
+	_, err := ParseJSON(`{"a": 1e999999999}`)
+	assert.EqualError(t, err, "value overflows numeric format")
+}
+
+func TestT_Format(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  T
+		val  interface{}
+		want string
+	}{
+		{name: "int", typ: Int, val: -7, want: "-7"},
+		{name: "double", typ: Float, val: 0.1, want: "0.1"},
+		{name: "large double", typ: Float, val: 1e15, want: "1e+15"},
+		{name: "small double", typ: Float, val: 0.00001, want: "1e-05"},
+		{name: "real", typ: Real, val: float64(float32(0.1)), want: "0.1"},
+		{name: "large real", typ: Real, val: 1e6, want: "1e+06"},
+		{name: "infinity", typ: Float, val: math.Inf(-1), want: "-Infinity"},
+		{name: "numeric", typ: MakeNumeric(5, 2), val: NewDecimal(100, 2), want: "1.00"},
+		{name: "bool", typ: Bool, val: true, want: "t"},
+		{name: "text", typ: Text, val: "x", want: "x"},
+		{name: "date", typ: Date, val: DateValue(-1), want: "1969-12-31"},
+		{name: "timestamp", typ: Timestamp, val: TimestampValue(1500), want: "1970-01-01 00:00:00.0015"},
+		{name: "timestamptz", typ: TimestampTZ, val: TimestampTZValue(0), want: "1970-01-01 00:00:00+00"},
+		{name: "interval", typ: Interval, val: IntervalValue{Months: 14, Days: 3, Micros: -61e6}, want: "1 year 2 mons 3 days -00:01:01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.typ.Format(tt.val))
+		})
+	}
+}
+
+func TestDecimal_Arith(t *testing.T) {
+	tests := []struct {
+		a, op, b string
+		want     string
+		wantErr  bool
+	}{
+		{a: "1.10", op: "+", b: "2.205", want: "3.305"},
+		{a: "1.10", op: "-", b: "2.205", want: "-1.105"},
+		{a: "2.50", op: "*", b: "-1.5", want: "-3.750"},
+		{a: "1", op: "/", b: "3", want: "0.33333333333333333333"},
+		{a: "10", op: "/", b: "4", want: "2.5000000000000000"},
+		{a: "2", op: "/", b: "3.000", want: "0.66666666666666666667"},
+		{a: "12345678", op: "/", b: "0.001", want: "12345678000.00000000"},
+		{a: "0.0001", op: "/", b: "7", want: "0.000014285714285714285714"},
+		{a: "1", op: "/", b: "0", wantErr: true},
+		{a: "7.5", op: "%", b: "2", want: "1.5"},
+		{a: "-7.5", op: "%", b: "2", want: "-1.5"},
+		{a: "7", op: "%", b: "0.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+tt.op+tt.b, func(t *testing.T) {
+			a, err := ParseDecimal(tt.a)
+			require.NoError(t, err)
+			b, err := ParseDecimal(tt.b)
+			require.NoError(t, err)
+			var got Decimal
+			switch tt.op {
+			case "+":
+				got = a.Add(b)
+			case "-":
+				got = a.Sub(b)
+			case "*":
+				got = a.Mul(b)
+			case "/":
+				got, err = a.Quo(b)
+			case "%":
+				got, err = a.Rem(b)
+			}
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestDecimal_Round(t *testing.T) {
+	tests := []struct {
+		in                      string
+		floor, ceil, abs, round string
+	}{
+		{in: "2.5", floor: "2", ceil: "3", abs: "2.5", round: "0"},
+		{in: "-2.5", floor: "-3", ceil: "-2", abs: "2.5", round: "0"},
+		{in: "1250", floor: "1250", ceil: "1250", abs: "1250", round: "1300"},
+		{in: "-1249.99", floor: "-1250", ceil: "-1249", abs: "1249.99", round: "-1200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			d, err := ParseDecimal(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.floor, d.Floor().String())
+			assert.Equal(t, tt.ceil, d.Ceil().String())
+			assert.Equal(t, tt.abs, d.Abs().String())
+			assert.Equal(t, tt.round, d.Round(-2).String())
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// intRanges are the smallest and largest values of the integer types.
+var intRanges = map[Oid][2]int64{
+	OidInt2: {math.MinInt16, math.MaxInt16},
+	OidInt4: {math.MinInt32, math.MaxInt32},
+	OidInt8: {math.MinInt64, math.MaxInt64},
+}
+
+// Of returns the type of a value. Integers are integer unless they only fit a
+// bigint.
+func Of(v interface{}) T {
+	switch v := v.(type) {
+	case bool:
+		return Bool
+	case int:
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return BigInt
+		}
+		return Int
+	case float64:
+		return Float
+	case Decimal:
+		return Numeric
+	case string:
+		return Text
+	case DateValue:
+		return Date
+	case TimeValue:
+		return Time
+	case TimestampValue:
+		return Timestamp
+	case TimestampTZValue:
+		return TimestampTZ
+	case IntervalValue:
+		return Interval
+	case JSONValue:
+		return JSONB
+	}
+	return Unknown
+}
+
+// Coerce converts a value to the type, checking that it fits its range and
+// modifiers. Text is parsed into a value of the type.
+func (t T) Coerce(v interface{}) (interface{}, error) {
+	return t.CoerceIn(v, time.UTC)
+}
+
+// CoerceIn is like Coerce, but reads times without a time zone and converts
+// between timestamps with and without time zone on the clocks of loc.
+func (t T) CoerceIn(v interface{}, loc *time.Location) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if s, ok := v.(string); ok && t.Family != TextFamily {
+		return t.parse(s, loc)
+	}
+	switch t.Family {
+	case BoolFamily:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case IntFamily:
+		return t.coerceInt(v)
+	case FloatFamily:
+		f, ok := ToFloat(v)
+		if !ok {
+			return nil, t.mismatch(v)
+		}
+		if t.Oid == OidFloat4 {
+			if math.Abs(f) > math.MaxFloat32 && !math.IsInf(f, 0) {
+				return nil, fmt.Errorf("value out of range: overflow")
+			}
+			f = float64(float32(f))
+		}
+		return f, nil
+	case NumericFamily:
+		if f, ok := v.(float64); ok {
+			d, err := DecimalFromFloat(f)
+			if err != nil {
+				return nil, err
+			}
+			return t.fitDecimal(d)
+		}
+		d, ok := ToDecimal(v)
+		if !ok {
+			return nil, t.mismatch(v)
+		}
+		return t.fitDecimal(d)
+	case TextFamily:
+		if s, ok := v.(string); ok {
+			return t.fitText(s)
+		}
+	case DateFamily, TimeFamily, TimestampFamily, TimestampTZFamily, IntervalFamily:
+		if res, ok := t.coerceDateTime(v, loc); ok {
+			return res, nil
+		}
+	case JSONFamily:
+		if j, ok := v.(JSONValue); ok {
+			if t.Oid == OidJSONB {
+				return t.coerceJSON(string(j))
+			}
+			return j, nil
+		}
+	case AnyFamily:
+		return v, nil
+	}
+	return nil, t.mismatch(v)
+}
+
+// ToFloat converts a number to a float64. It reports false for values that
+// aren't numbers.
+func ToFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	case Decimal:
+		return v.Float64(), true
+	}
+	return 0, false
+}
+
+// ToDecimal converts an integer or a decimal to a Decimal. It reports false
+// for other values.
+func ToDecimal(v interface{}) (Decimal, bool) {
+	switch v := v.(type) {
+	case int:
+		return NewDecimal(int64(v), 0), true
+	case Decimal:
+		return v, true
+	}
+	return Decimal{}, false
+}
+
+func (t T) mismatch(v interface{}) error {
+	return fmt.Errorf("cannot convert %v of type %s to %s", v, Of(v), t)
+}
+
+func (t T) coerceInt(v interface{}) (interface{}, error) {
+	var n int64
+	switch v := v.(type) {
+	case int:
+		n = int64(v)
+	case float64:
+		// floats are rounded half away from zero
+		r := math.Round(v)
+		if math.IsNaN(r) || r < math.MinInt64 || r >= math.MaxInt64 {
+			return nil, fmt.Errorf("%s out of range", t)
+		}
+		n = int64(r)
+	case Decimal:
+		var ok bool
+		if n, ok = v.Int64(); !ok {
+			return nil, fmt.Errorf("%s out of range", t)
+		}
+	default:
+		return nil, t.mismatch(v)
+	}
+	if r, ok := intRanges[t.Oid]; ok && (n < r[0] || n > r[1]) {
+		return nil, fmt.Errorf("%s out of range", t)
+	}
+	return int(n), nil
+}
+
+// fitDecimal rounds a decimal to the scale of the type and checks that it has
+// no more digits than its precision.
+func (t T) fitDecimal(d Decimal) (Decimal, error) {
+	if t.Precision == 0 {
+		return d, nil
+	}
+	d = d.Round(t.Scale)
+	if d.IntDigits() > t.Precision-t.Scale {
+		return Decimal{}, fmt.Errorf("numeric field overflow: a field with precision %d, scale %d must round to an absolute value less than 10^%d",
+			t.Precision, t.Scale, t.Precision-t.Scale)
+	}
+	return d, nil
+}
+
+// fitText checks that a string is no longer than the width of the type, and
+// pads it for a CHAR. Extra characters are only dropped when they are spaces.
+func (t T) fitText(s string) (string, error) {
+	if t.Width == 0 {
+		return s, nil
+	}
+	n := utf8.RuneCountInString(s)
+	if n > t.Width {
+		runes := []rune(s)
+		if strings.TrimRight(string(runes[t.Width:]), " ") != "" {
+			return "", fmt.Errorf("value too long for type %s", t)
+		}
+		return string(runes[:t.Width]), nil
+	}
+	if t.Oid == OidBpchar {
+		return s + strings.Repeat(" ", t.Width-n), nil
+	}
+	return s, nil
+}
+
+// parse reads a value of the type from its text representation.
+func (t T) parse(s string, loc *time.Location) (interface{}, error) {
+	str := strings.TrimSpace(s)
+	switch t.Family {
+	case BoolFamily:
+		switch strings.ToLower(str) {
+		case "t", "true", "y", "yes", "on", "1":
+			return true, nil
+		case "f", "false", "n", "no", "off", "0":
+			return false, nil
+		}
+	case IntFamily:
+		n, err := strconv.ParseInt(str, 10, 64)
+		if err == nil {
+			return t.coerceInt(int(n))
+		}
+		if e, ok := err.(*strconv.NumError); ok && e.Err == strconv.ErrRange {
+			return nil, fmt.Errorf("value %q is out of range for type %s", s, t)
+		}
+	case FloatFamily:
+		f, err := strconv.ParseFloat(str, 64)
+		if err == nil {
+			return t.Coerce(f)
+		}
+	case NumericFamily:
+		d, err := ParseDecimal(str)
+		if err == nil {
+			return t.fitDecimal(d)
+		}
+	case DateFamily, TimeFamily, TimestampFamily, TimestampTZFamily, IntervalFamily:
+		if res, ok := t.parseDateTime(str, loc); ok {
+			return res, nil
+		}
+	case JSONFamily:
+		// the text of json values is kept with its spaces
+		return t.coerceJSON(s)
+	case UnknownFamily, AnyFamily:
+		return s, nil
+	}
+	return nil, fmt.Errorf("invalid input syntax for type %s: %q", t, s)
+}
+
+// Compare orders two non-null values of the same family, or two numbers. It
+// returns a negative number when a sorts before b, zero when they are equal
+// and a positive number otherwise.
+func Compare(a, b interface{}) (int, error) {
+	switch av := a.(type) {
+	case int:
+		switch bv := b.(type) {
+		case int:
+			return compareInts(int64(av), int64(bv)), nil
+		case float64:
+			return compareFloats(float64(av), bv), nil
+		case Decimal:
+			return NewDecimal(int64(av), 0).Cmp(bv), nil
+		}
+	case float64:
+		switch bv := b.(type) {
+		case int:
+			return compareFloats(av, float64(bv)), nil
+		case float64:
+			return compareFloats(av, bv), nil
+		case Decimal:
+			return compareFloats(av, bv.Float64()), nil
+		}
+	case Decimal:
+		switch bv := b.(type) {
+		case int:
+			return av.Cmp(NewDecimal(int64(bv), 0)), nil
+		case float64:
+			return compareFloats(av.Float64(), bv), nil
+		case Decimal:
+			return av.Cmp(bv), nil
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv), nil
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			switch {
+			case av == bv:
+				return 0, nil
+			case !av:
+				return -1, nil
+			}
+			return 1, nil
+		}
+	case DateValue:
+		if bv, ok := b.(DateValue); ok {
+			return compareInts(int64(av), int64(bv)), nil
+		}
+	case TimeValue:
+		if bv, ok := b.(TimeValue); ok {
+			return compareInts(int64(av), int64(bv)), nil
+		}
+	case TimestampValue:
+		if bv, ok := b.(TimestampValue); ok {
+			return compareInts(int64(av), int64(bv)), nil
+		}
+	case TimestampTZValue:
+		if bv, ok := b.(TimestampTZValue); ok {
+			return compareInts(int64(av), int64(bv)), nil
+		}
+	case IntervalValue:
+		if bv, ok := b.(IntervalValue); ok {
+			return av.Cmp(bv), nil
+		}
+	case JSONValue:
+		if bv, ok := b.(JSONValue); ok {
+			return CompareJSON(av, bv)
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %v of type %s with %v of type %s", a, Of(a), b, Of(b))
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Format returns the text representation of a non-null value of the type, as
+// Postgres sends it.
+func (t T) Format(v interface{}) string {
+	return t.FormatIn(v, time.UTC)
+}
+
+// FormatIn is like Format, but shows timestamps with time zone on the clocks
+// of loc.
+func (t T) FormatIn(v interface{}, loc *time.Location) string {
+	switch v := v.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return "Infinity"
+		case math.IsInf(v, -1):
+			return "-Infinity"
+		case math.IsNaN(v):
+			return "NaN"
+		}
+		return formatFloat(v, t.Oid == OidFloat4)
+	case Decimal:
+		return v.String()
+	case string:
+		return v
+	case bool:
+		if v {
+			return "t"
+		}
+		return "f"
+	case TimestampTZValue:
+		return formatTimestampTZ(v, loc)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// formatFloat formats a float with the fewest digits that read back to it. The
+// exponent is shown from the number of digits a real or double precision
+// keeps exactly.
+func formatFloat(f float64, real bool) string {
+	bits, digits := 64, 15
+	if real {
+		bits, digits = 32, 6
+	}
+	s := strconv.FormatFloat(f, 'e', -1, bits)
+	exp, _ := strconv.Atoi(s[strings.IndexByte(s, 'e')+1:])
+	if exp < -4 || exp >= digits {
+		return s
+	}
+	return strconv.FormatFloat(f, 'f', -1, bits)
+}